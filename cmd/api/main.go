@@ -2,37 +2,116 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
-	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	api "github.com/you/wallet-watcher/internal/api"
+	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/health"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/metrics"
+	"github.com/you/wallet-watcher/internal/pricing"
 	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/stream"
+	"github.com/you/wallet-watcher/internal/tracing"
 )
 
 func main() {
 	_ = godotenv.Load()
+	logging.Setup("api")
+	// 設定（-config / CONFIG_FILE のファイル + 環境変数）。不正な値なら起動しない
+	cfg := config.MustLoad()
+	// RPC のメトリクスは設定済みのエンドポイントだけを名前で分ける（rpc_url クエリの差し替えは "custom"）
+	metrics.RegisterEndpoints(cfg.Chains)
 
-	port := os.Getenv("APP_PORT")
-	if port == "" {
-		port = "8080"
+	// SIGINT / SIGTERM で停止する
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// トレース（OTEL_TRACES_EXPORTER=otlp のときだけ出力）
+	shutdownTracing, err := tracing.Setup(ctx, "api")
+	if err != nil {
+		logging.Fatal("tracing", "err", err)
 	}
+	defer shutdownTracing(context.Background())
 
 	// DB 接続
-	ctx := context.Background()
-	st, err := store.New(ctx)
+	st, err := store.Open(ctx, cfg.DB)
 	if err != nil {
-		log.Fatalf("db connect: %v", err)
+		logging.Fatal("db connect", "err", err)
 	}
 	defer st.Close()
 
+	// 新着イベントの配信（ワーカーの NOTIFY を LISTEN）
+	hub := stream.NewHub(st)
+	go hub.Run(ctx)
+
+	// USD 評価（価格表 + 設定の取得元）
+	prices, err := pricing.FromConfig(cfg.Pricing, st)
+	if err != nil {
+		logging.Fatal("pricing", "err", err)
+	}
+
 	// ルーティング
-	srv := &api.Server{Store: st}
-	r := api.Routes(srv)
+	srv := &api.Server{Store: st, Hub: hub, Chains: cfg.Chains, Ready: readiness(st, cfg), Prices: prices, PnLMethod: cfg.PnL.Method}
+	httpSrv := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.API.Port),
+		Handler:           api.Routes(srv),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// /stream の接続は Shutdown では終わらないため、Hub を閉じて各接続を正常終了させる
+	httpSrv.RegisterOnShutdown(hub.Close)
+
+	grace := cfg.API.ShutdownGrace.D()
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "port", cfg.API.Port, "shutdown_grace", grace.String())
+		errc <- httpSrv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("listen", "err", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	// 新しい接続の受付を止め、処理中のリクエストを grace まで待つ
+	slog.Info("shutting down, draining requests", "grace", grace.String())
+	sctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := httpSrv.Shutdown(sctx); err != nil {
+		slog.Error("shutdown", "err", err)
+		_ = httpSrv.Close()
+	}
+	slog.Info("api stopped")
+}
+
+// readiness は /readyz のチェックを組み立てる（RPC は api.ready.chains のチェーンの設定先）
+func readiness(st *store.Store, cfg *config.Config) *health.Checker {
+	rc := cfg.API.Ready
+	lim := health.HeartbeatLimits{MaxAge: rc.MaxHeartbeatAge.D(), MaxCursorLag: rc.MaxCursorLag}
 
-	log.Printf("listening on :%s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal(err)
+	c := health.NewChecker(rc.CheckTimeout.D())
+	c.Add("db", health.DB(st))
+	for _, ch := range rc.Chains {
+		switch ch {
+		case "solana":
+			c.Add("rpc.solana", health.RPC(sol.New(cfg.Chains.RPC(ch)).GetSlot))
+		case "sui":
+			c.Add("rpc.sui", health.RPC(sui.New(cfg.Chains.RPC(ch)).GetLatestCheckpoint))
+		}
+		c.Add("worker."+ch, health.Heartbeat(st, ch, lim))
 	}
+	return c
 }
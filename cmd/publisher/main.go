@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/eventbus"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/tracing"
+)
+
+func main() {
+	_ = godotenv.Load()
+	logging.Setup("publisher")
+	// 設定（-config / CONFIG_FILE のファイル + 環境変数）。不正な値なら起動しない
+	cfg := config.MustLoad()
+	pc := cfg.Publisher
+
+	// SIGINT / SIGTERM で停止する
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// トレース（OTEL_TRACES_EXPORTER=otlp のときだけ出力）
+	shutdownTracing, err := tracing.Setup(ctx, "publisher")
+	if err != nil {
+		logging.Fatal("tracing", "err", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	st, err := store.Open(ctx, cfg.DB)
+	if err != nil {
+		logging.Fatal("store", "err", err)
+	}
+	defer st.Close()
+
+	sink, err := eventbus.NewSink(pc)
+	if err != nil {
+		logging.Fatal("sink", "err", err)
+	}
+	defer sink.Close()
+
+	// 配信済みの行を残す期間（0 なら削除しない）
+	if retention := pc.Retention.D(); retention > 0 {
+		go func() {
+			t := time.NewTicker(time.Hour)
+			defer t.Stop()
+			for {
+				if n, err := st.PurgeOutbox(ctx, retention); err != nil && ctx.Err() == nil {
+					slog.Error("purge outbox", "err", err)
+				} else if n > 0 {
+					slog.Info("purged published outbox rows", "rows", n)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+				}
+			}
+		}()
+	}
+
+	grace := pc.ShutdownGrace.D()
+	slog.Info("publisher started", "sink", pc.Sink, "interval", pc.PollInterval.String(), "batch", pc.BatchSize, "shutdown_grace", grace.String())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		eventbus.NewPublisher(st, sink, pc.BatchSize, pc.PollInterval.D(), grace).Run(ctx)
+	}()
+
+	// 停止要求後は中継中のバッチを送り切り、配信済みとして記録してから終える
+	<-ctx.Done()
+	slog.Info("shutting down, waiting for the current batch", "grace", grace.String())
+	select {
+	case <-done:
+		slog.Info("publisher stopped")
+	case <-time.After(grace):
+		// 未記録のバッチは次回起動時に再送される（少なくとも 1 回の配信）
+		slog.Error("publisher stopped", "err", "shutdown grace period exceeded")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/you/wallet-watcher/internal/api"
+	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	"github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/worker"
+)
+
+// addResult は add / import の 1 件分の結果
+type addResult struct {
+	Chain   string `json:"chain"`
+	Address string `json:"address"`
+	// Result は created / existing / invalid / error のいずれか
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+func addTable(rs []addResult) table {
+	t := table{header: []string{"CHAIN", "ADDRESS", "RESULT", "ERROR"}}
+	for _, r := range rs {
+		t.add(r.Chain, r.Address, r.Result, r.Error)
+	}
+	return t
+}
+
+// parseFlags はフラグと位置引数が混在していても解析し、位置引数を返す
+// （backfill <chain> <address> -from N -to M のように後ろにフラグを書けるようにする）
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		if fs.NArg() == 0 {
+			return pos, nil
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// addOne は 1 件を検証して追加する（/register と同じ正規化と検証）
+func (a *app) addOne(ctx context.Context, chain, address string) addResult {
+	chain = strings.ToLower(strings.TrimSpace(chain))
+	address = strings.TrimSpace(address)
+	r := addResult{Chain: chain, Address: address}
+	if err := api.ValidateChainAndAddress(chain, address); err != nil {
+		r.Result, r.Error = "invalid", err.Error()
+		return r
+	}
+	created, err := a.st.AddWatchedAddress(ctx, chain, address)
+	switch {
+	case err != nil:
+		r.Result, r.Error = "error", err.Error()
+	case created:
+		r.Result = "created"
+	default:
+		r.Result = "existing"
+	}
+	return r
+}
+
+// addResults は結果を出力し、失敗があればエラーにする
+func (a *app) addResults(rs []addResult) error {
+	if err := a.out.emit(rs, addTable(rs)); err != nil {
+		return err
+	}
+	failed := 0
+	for _, r := range rs {
+		if r.Result == "invalid" || r.Result == "error" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d failed", failed, len(rs))
+	}
+	return nil
+}
+
+func (a *app) add(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	var rs []addResult
+	for _, addr := range args[1:] {
+		rs = append(rs, a.addOne(ctx, args[0], addr))
+	}
+	return a.addResults(rs)
+}
+
+func (a *app) remove(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	chain := strings.ToLower(args[0])
+	var rs []addResult
+	missing := 0
+	for _, addr := range args[1:] {
+		r := addResult{Chain: chain, Address: addr}
+		n, err := a.st.RemoveWatchedAddress(ctx, chain, addr)
+		switch {
+		case err != nil:
+			r.Result, r.Error = "error", err.Error()
+			missing++
+		case n == 0:
+			r.Result = "not_found"
+			missing++
+		default:
+			r.Result = "removed"
+		}
+		rs = append(rs, r)
+	}
+	if err := a.out.emit(rs, addTable(rs)); err != nil {
+		return err
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d of %d not removed", missing, len(rs))
+	}
+	return nil
+}
+
+func (a *app) list(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	chain := fs.String("chain", store.ChainAll, "solana / sui / all")
+	if pos, err := parseFlags(fs, args); err != nil {
+		return err
+	} else if len(pos) > 0 {
+		return errUsage
+	}
+	ws, err := a.st.ListWatched(ctx, strings.ToLower(*chain))
+	if err != nil {
+		return err
+	}
+	t := table{header: []string{"CHAIN", "ADDRESS", "CURSOR", "CREATED_AT", "UPDATED_AT"}}
+	for _, w := range ws {
+		t.add(w.Chain, w.Address, optInt(w.Cursor), fmtTime(w.CreatedAt), fmtTime(w.UpdatedAt))
+	}
+	return a.out.emit(ws, t)
+}
+
+// importCSV は chain,address の CSV を 1 行ずつ追加する。
+// 1 行目が chain,address のヘッダなら読み飛ばし、空行と # で始まる行は無視する。
+func (a *app) importCSV(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	r := csv.NewReader(in)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var rs []addResult
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if line == 1 && len(rec) >= 2 && strings.EqualFold(strings.TrimSpace(rec[0]), "chain") {
+			continue
+		}
+		if len(rec) != 2 {
+			rs = append(rs, addResult{Result: "invalid", Error: fmt.Sprintf("line %d: want 2 columns (chain,address), got %d", line, len(rec))})
+			continue
+		}
+		rs = append(rs, a.addOne(ctx, rec[0], rec[1]))
+	}
+	return a.addResults(rs)
+}
+
+func (a *app) cursor(ctx context.Context, args []string) error {
+	if len(args) < 3 {
+		return errUsage
+	}
+	chain, address := strings.ToLower(args[1]), args[2]
+	var cur *int64
+	switch {
+	case args[0] == "set" && len(args) == 4:
+		n, err := strconv.ParseInt(args[3], 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid cursor: %q", args[3])
+		}
+		cur = &n
+	case args[0] == "reset" && len(args) == 3:
+	default:
+		return errUsage
+	}
+	if err := a.st.SetCursor(ctx, chain, address, cur); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%s %s is not watched", chain, address)
+		}
+		return err
+	}
+	w := store.WatchedStatus{Chain: chain, Address: address, Cursor: cur}
+	t := table{header: []string{"CHAIN", "ADDRESS", "CURSOR"}}
+	t.add(chain, address, optInt(cur))
+	return a.out.emit(w, t)
+}
+
+// chainWorker は walletctl が使うワーカーの操作（SolanaWorker / SuiWorker）
+type chainWorker interface {
+	Backfill(ctx context.Context, address string, from, to int64) (worker.BackfillResult, error)
+	Reprocess(ctx context.Context, hash string) (*normalize.Event, error)
+}
+
+// newWorker はチェーンのワーカーを作る（通知は送らない）
+func (a *app) newWorker(chain string) (chainWorker, error) {
+	switch chain {
+	case "solana":
+		return worker.NewSolana(a.st, sol.New(a.cfg.Chains.RPC(chain)), a.cfg.Worker, config.Notify{}), nil
+	case "sui":
+		return worker.NewSui(a.st, sui.New(a.cfg.Chains.RPC(chain)), a.cfg.Worker, config.Notify{}), nil
+	}
+	return nil, fmt.Errorf("unsupported chain: %s", chain)
+}
+
+func (a *app) backfill(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	from := fs.Int64("from", 0, "first slot / checkpoint (inclusive)")
+	to := fs.Int64("to", -1, "last slot / checkpoint (inclusive)")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 || *to < 0 || *from < 0 || *from > *to {
+		return errUsage
+	}
+	chain := strings.ToLower(pos[0])
+	w, err := a.newWorker(chain)
+	if err != nil {
+		return err
+	}
+	res, err := w.Backfill(ctx, pos[1], *from, *to)
+	if err != nil {
+		return err
+	}
+	t := table{header: []string{"CHAIN", "ADDRESS", "FROM", "TO", "SCANNED", "STORED", "FAILED"}}
+	t.add(res.Chain, res.Address, strconv.FormatInt(res.From, 10), strconv.FormatInt(res.To, 10),
+		strconv.Itoa(res.Scanned), strconv.Itoa(res.Stored), strconv.Itoa(res.Failed))
+	if err := a.out.emit(res, t); err != nil {
+		return err
+	}
+	if res.Failed > 0 {
+		return fmt.Errorf("%d transactions failed", res.Failed)
+	}
+	return nil
+}
+
+// reprocessResult は reprocess の結果
+type reprocessResult struct {
+	Chain  string  `json:"chain"`
+	TxHash string  `json:"tx_hash"`
+	TS     string  `json:"ts"`
+	Status string  `json:"status"`
+	Method *string `json:"method,omitempty"`
+	Fee    *int64  `json:"fee,omitempty"`
+}
+
+// legacyResult は reprocess -legacy の結果
+type legacyResult struct {
+	Chain   string `json:"chain"`
+	Scanned int    `json:"scanned"`
+	Stored  int    `json:"stored"`
+	Failed  int    `json:"failed"`
+}
+
+func (a *app) reprocess(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	legacy := fs.Bool("legacy", false, "reprocess Sui rows saved without the tx sender")
+	limit := fs.Int("limit", 1000, "max rows for -legacy")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *legacy {
+		if len(args) != 1 || *limit <= 0 {
+			return errUsage
+		}
+		return a.reprocessLegacy(ctx, strings.ToLower(args[0]), *limit)
+	}
+	if len(args) != 2 {
+		return errUsage
+	}
+	chain, hash := strings.ToLower(args[0]), args[1]
+	w, err := a.newWorker(chain)
+	if err != nil {
+		return err
+	}
+	ev, err := w.Reprocess(ctx, hash)
+	if err != nil {
+		return err
+	}
+	res := reprocessResult{Chain: chain, TxHash: ev.TxHash, TS: fmtTime(ev.TS), Status: ev.Status, Method: ev.Method, Fee: ev.Fee}
+	t := table{header: []string{"CHAIN", "TX_HASH", "TS", "STATUS", "METHOD", "FEE"}}
+	t.add(res.Chain, res.TxHash, res.TS, res.Status, optStr(res.Method), optInt(res.Fee))
+	return a.out.emit(res, t)
+}
+
+// reprocessLegacy は送信者を取れない旧形式で保存した Sui の行（sender に transfer の宛先が入っている）を
+// 古い順に最大 limit 件取り直す。残りがあればもう一度流す
+func (a *app) reprocessLegacy(ctx context.Context, chain string, limit int) error {
+	if chain != "sui" {
+		return fmt.Errorf("-legacy is only for sui")
+	}
+	w, err := a.newWorker(chain)
+	if err != nil {
+		return err
+	}
+	hashes, err := a.st.LegacySuiTxHashes(ctx, limit)
+	if err != nil {
+		return err
+	}
+	res := legacyResult{Chain: chain}
+	for _, h := range hashes {
+		res.Scanned++
+		if _, err := w.Reprocess(ctx, h); err != nil {
+			res.Failed++
+			logging.FromContext(ctx).Warn("reprocess transaction", "tx", h, "err", err)
+			continue
+		}
+		res.Stored++
+	}
+	t := table{header: []string{"CHAIN", "SCANNED", "STORED", "FAILED"}}
+	t.add(res.Chain, strconv.Itoa(res.Scanned), strconv.Itoa(res.Stored), strconv.Itoa(res.Failed))
+	if err := a.out.emit(res, t); err != nil {
+		return err
+	}
+	if res.Failed > 0 {
+		return fmt.Errorf("%d transactions failed", res.Failed)
+	}
+	return nil
+}
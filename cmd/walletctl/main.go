@@ -0,0 +1,134 @@
+// walletctl は監視アドレス・カーソル・バックフィル・マイグレーション・価格表・集計・アウトボックスを操作する管理 CLI。
+//
+//	walletctl [-config FILE] [-o table|json] <command> [args]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+const usage = `usage: walletctl [-config FILE] [-o table|json] <command> [args]
+
+commands:
+  add <chain> <address>...            監視アドレスを追加する
+  remove <chain> <address>...         監視アドレスを削除する
+  list [-chain solana|sui|all]        監視アドレスとカーソルを一覧する
+  import <file.csv|->                 CSV（chain,address）から一括追加する
+  cursor set <chain> <address> <n>    カーソル（slot / checkpoint）を書き換える
+  cursor reset <chain> <address>      カーソルを未設定に戻す（次の tick で直近から取り直す）
+  backfill <chain> <address> -from N -to M
+                                      [from, to] の範囲で address が関与する Tx を取り込む
+                                      （カーソルは動かさない。Sui は範囲内の全 Tx を取得して絞り込む）
+  reprocess <chain> <tx_hash>         Tx を取り直して保存し直す
+  reprocess sui -legacy [-limit N]    送信者を取れない旧形式で保存した Sui の行を取り直す
+  status [-chain solana|sui|all]      カーソルとチェーン先頭の差、ワーカーのハートビート
+  migrate [-dir migrations] [-status] [-all]
+                                      未適用のマイグレーションを流す
+  prices import <file.csv|file.json>  価格ファイル（token,ts,usd[,decimals]）を価格表に取り込む
+  rollup [-chain solana|sui|all] [-job daily|edges|all] [-batch N] [-rebuild]
+                                      日次集計とアドレス間のエッジを最新のイベントまで進める（-rebuild で作り直す）
+  partitions list [-chain solana|sui|all]
+                                      tx_events_* の月パーティション（書き出し済みを含む）を一覧する
+  partitions maintain [-chain solana|sui|all]
+                                      パーティションの作成・raw の保持期限・古い月の書き出しを今すぐ行う
+  outbox parked                       配信先に受け付けられず保留になったアウトボックスの行を一覧する
+  outbox requeue [seq]...             保留の行（省略時は全件）を配信待ちに戻す
+`
+
+// errUsage は引数の誤り（終了コード 2）
+var errUsage = errors.New("usage")
+
+// app はサブコマンドが使う共通の状態
+type app struct {
+	cfg *config.Config
+	st  *store.Store
+	out output
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("walletctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	path := fs.String("config", os.Getenv("CONFIG_FILE"), "config file (yaml / toml)")
+	format := fs.String("o", "table", "output format (table / json)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || (*format != "table" && *format != "json") {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(*path)
+	if err != nil {
+		fmt.Fprintln(stderr, "config:", err)
+		return 1
+	}
+
+	// Ctrl-C で処理中の RPC / クエリを止める
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	st, err := store.Open(ctx, cfg.DB)
+	if err != nil {
+		fmt.Fprintln(stderr, "store:", err)
+		return 1
+	}
+	defer st.Close()
+
+	a := &app{cfg: cfg, st: st, out: output{w: stdout, json: *format == "json"}}
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "add":
+		err = a.add(ctx, rest)
+	case "remove":
+		err = a.remove(ctx, rest)
+	case "list":
+		err = a.list(ctx, rest)
+	case "import":
+		err = a.importCSV(ctx, rest)
+	case "cursor":
+		err = a.cursor(ctx, rest)
+	case "backfill":
+		err = a.backfill(ctx, rest)
+	case "reprocess":
+		err = a.reprocess(ctx, rest)
+	case "status":
+		err = a.status(ctx, rest)
+	case "migrate":
+		err = a.migrate(ctx, rest)
+	case "prices":
+		err = a.prices(ctx, rest)
+	case "rollup":
+		err = a.rollup(ctx, rest)
+	case "partitions":
+		err = a.partitions(ctx, rest)
+	case "outbox":
+		err = a.outbox(ctx, rest)
+	default:
+		err = errUsage
+	}
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprint(stderr, usage)
+		return 2
+	case err != nil:
+		fmt.Fprintf(stderr, "%s: %v\n", cmd, err)
+		return 1
+	}
+	return 0
+}
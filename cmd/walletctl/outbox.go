@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// parkedRow は outbox parked の 1 行
+type parkedRow struct {
+	Seq       int64  `json:"seq"`
+	Type      string `json:"type"`
+	Chain     string `json:"chain"`
+	TxHash    string `json:"tx_hash"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error"`
+	ParkedAt  string `json:"parked_at"`
+}
+
+// requeueSummary は outbox requeue の結果
+type requeueSummary struct {
+	Requeued int64 `json:"requeued"`
+}
+
+// outbox は配信先に受け付けられず保留になったアウトボックスの行を一覧する（parked）か、配信待ちに戻す（requeue）
+func (a *app) outbox(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "parked":
+		if len(args) != 1 {
+			return errUsage
+		}
+		parked, err := a.st.ParkedOutbox(ctx)
+		if err != nil {
+			return err
+		}
+		rs := []parkedRow{}
+		t := table{header: []string{"SEQ", "TYPE", "CHAIN", "TX_HASH", "ATTEMPTS", "PARKED_AT", "LAST_ERROR"}}
+		for _, m := range parked {
+			r := parkedRow{Seq: m.ID, Type: m.EventType, Chain: m.Chain, TxHash: m.TxHash, Attempts: m.Attempts,
+				LastError: m.LastError, ParkedAt: fmtTime(m.ParkedAt)}
+			rs = append(rs, r)
+			t.add(strconv.FormatInt(r.Seq, 10), r.Type, r.Chain, r.TxHash, strconv.Itoa(r.Attempts), r.ParkedAt, r.LastError)
+		}
+		return a.out.emit(rs, t)
+	case "requeue":
+		var ids []int64
+		for _, v := range args[1:] {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id <= 0 {
+				return fmt.Errorf("invalid seq: %s", v)
+			}
+			ids = append(ids, id)
+		}
+		n, err := a.st.RequeueOutbox(ctx, ids)
+		if err != nil {
+			return err
+		}
+		t := table{header: []string{"REQUEUED"}}
+		t.add(strconv.FormatInt(n, 10))
+		return a.out.emit(requeueSummary{Requeued: n}, t)
+	}
+	return errUsage
+}
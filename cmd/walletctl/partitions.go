@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/you/wallet-watcher/internal/retention"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// partitionRow は partitions list の 1 行（付いているものと書き出し済みのもの）
+type partitionRow struct {
+	Chain string `json:"chain"`
+	Name  string `json:"name"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	// State は attached / archived
+	State  string `json:"state"`
+	Rows   int64  `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	Path   string `json:"path,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// maintainSummary は partitions maintain の結果（チェーンごと）
+type maintainSummary struct {
+	Chain    string   `json:"chain"`
+	Raw      int      `json:"raw"`
+	Archived []string `json:"archived"`
+	// Held は取得原価が使っているため切り離さなかった月
+	Held string `json:"held,omitempty"`
+}
+
+// partitions は tx_events_* の月パーティションを一覧する（list）か、ワーカーと同じ保守を今すぐ行う（maintain）
+func (a *app) partitions(ctx context.Context, args []string) error {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "maintain") {
+		return errUsage
+	}
+	fs := flag.NewFlagSet("partitions "+args[0], flag.ContinueOnError)
+	chain := fs.String("chain", store.ChainAll, "solana / sui / all")
+	if pos, err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	} else if len(pos) > 0 {
+		return errUsage
+	}
+	chains := []string{"solana", "sui"}
+	if c := strings.ToLower(*chain); c != store.ChainAll {
+		if c != "solana" && c != "sui" {
+			return fmt.Errorf("unsupported chain: %s", *chain)
+		}
+		chains = []string{c}
+	}
+
+	if args[0] == "maintain" {
+		var rs []maintainSummary
+		for _, c := range chains {
+			m := &retention.Maintainer{Store: a.st, Chain: c, Config: a.cfg.Retention}
+			res, err := m.RunOnce(ctx)
+			if err != nil {
+				return err
+			}
+			s := maintainSummary{Chain: c, Raw: res.Raw, Archived: []string{}, Held: res.Held}
+			for _, p := range res.Archived {
+				s.Archived = append(s.Archived, p.Path)
+			}
+			rs = append(rs, s)
+		}
+		t := table{header: []string{"CHAIN", "RAW", "ARCHIVED", "HELD"}}
+		for _, s := range rs {
+			archived, held := "-", "-"
+			if len(s.Archived) > 0 {
+				archived = strings.Join(s.Archived, ",")
+			}
+			if s.Held != "" {
+				held = s.Held
+			}
+			t.add(s.Chain, strconv.Itoa(s.Raw), archived, held)
+		}
+		return a.out.emit(rs, t)
+	}
+
+	var rs []partitionRow
+	for _, c := range chains {
+		archived, err := a.st.ArchivedPartitions(ctx, c)
+		if err != nil {
+			return err
+		}
+		for _, p := range archived {
+			rs = append(rs, partitionRow{Chain: c, Name: p.Name, From: fmtTime(p.From), To: fmtTime(p.To), State: "archived",
+				Rows: p.Rows, Bytes: p.Bytes, Path: p.Path, SHA256: p.SHA256})
+		}
+		parts, err := a.st.TxPartitions(ctx, c)
+		if err != nil {
+			return err
+		}
+		for _, p := range parts {
+			rs = append(rs, partitionRow{Chain: c, Name: p.Name, From: fmtTime(p.From), To: fmtTime(p.To), State: "attached",
+				Rows: p.Rows, Bytes: p.Bytes})
+		}
+	}
+	t := table{header: []string{"CHAIN", "PARTITION", "FROM", "TO", "STATE", "ROWS", "BYTES", "PATH"}}
+	for _, r := range rs {
+		path := r.Path
+		if path == "" {
+			path = "-"
+		}
+		t.add(r.Chain, r.Name, r.From, r.To, r.State, strconv.FormatInt(r.Rows, 10), strconv.FormatInt(r.Bytes, 10), path)
+	}
+	return a.out.emit(rs, t)
+}
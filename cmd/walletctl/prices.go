@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/you/wallet-watcher/internal/pricing"
+)
+
+// importSummary は prices import の結果（トークンごと）
+type importSummary struct {
+	Token    string `json:"token"`
+	Prices   int    `json:"prices"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Decimals *int   `json:"decimals,omitempty"`
+}
+
+func (a *app) prices(ctx context.Context, args []string) error {
+	if len(args) != 2 || args[0] != "import" {
+		return errUsage
+	}
+	fs, err := pricing.LoadFile(args[1])
+	if err != nil {
+		return err
+	}
+	all := fs.All()
+	if err := a.st.UpsertTokenPrices(ctx, all); err != nil {
+		return err
+	}
+	decimals := fs.Decimals()
+	if err := a.st.SetTokenDecimals(ctx, decimals); err != nil {
+		return err
+	}
+
+	// All はトークン・時刻順
+	var rs []importSummary
+	for _, p := range all {
+		if n := len(rs); n > 0 && rs[n-1].Token == p.Token {
+			rs[n-1].Prices++
+			rs[n-1].To = fmtTime(p.TS)
+			continue
+		}
+		r := importSummary{Token: p.Token, Prices: 1, From: fmtTime(p.TS), To: fmtTime(p.TS)}
+		if d, ok := decimals[p.Token]; ok {
+			r.Decimals = &d
+		}
+		rs = append(rs, r)
+	}
+	t := table{header: []string{"TOKEN", "PRICES", "FROM", "TO", "DECIMALS"}}
+	for _, r := range rs {
+		dec := "-"
+		if r.Decimals != nil {
+			dec = strconv.Itoa(*r.Decimals)
+		}
+		t.add(r.Token, strconv.Itoa(r.Prices), r.From, r.To, dec)
+	}
+	return a.out.emit(rs, t)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/you/wallet-watcher/internal/graph"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// 集計ジョブ
+const (
+	jobDaily = "daily"
+	jobEdges = "edges"
+)
+
+// rollupSummary は rollup の結果（チェーン × ジョブごと）
+type rollupSummary struct {
+	Chain   string `json:"chain"`
+	Job     string `json:"job"`
+	Events  int    `json:"events"`
+	Updated int    `json:"updated"`
+	LastSeq int64  `json:"last_seq"`
+}
+
+// rollup は日次集計とアドレス間のエッジを最新のイベントまで進める（-rebuild なら消して全件から作り直す）
+func (a *app) rollup(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rollup", flag.ContinueOnError)
+	chain := fs.String("chain", store.ChainAll, "solana / sui / all")
+	job := fs.String("job", "all", "daily / edges / all")
+	batch := fs.Int("batch", 5000, "1 回のトランザクションで取り込むイベント数")
+	rebuild := fs.Bool("rebuild", false, "集計を消して全件から作り直す")
+	if pos, err := parseFlags(fs, args); err != nil {
+		return err
+	} else if len(pos) > 0 || *batch <= 0 {
+		return errUsage
+	}
+	chains := []string{"solana", "sui"}
+	if c := strings.ToLower(*chain); c != store.ChainAll {
+		if c != "solana" && c != "sui" {
+			return fmt.Errorf("unsupported chain: %s", *chain)
+		}
+		chains = []string{c}
+	}
+	jobs := []string{jobDaily, jobEdges}
+	switch j := strings.ToLower(*job); j {
+	case "all":
+	case jobDaily, jobEdges:
+		jobs = []string{j}
+	default:
+		return fmt.Errorf("unknown job: %s", *job)
+	}
+
+	var rs []rollupSummary
+	for _, c := range chains {
+		for _, j := range jobs {
+			reset := a.st.ResetRollup
+			step := func(ctx context.Context) (store.RollupResult, error) { return a.st.RollupDaily(ctx, c, *batch) }
+			if j == jobEdges {
+				reset = a.st.ResetEdges
+				step = func(ctx context.Context) (store.RollupResult, error) {
+					return a.st.RefreshEdges(ctx, c, *batch, graph.Transfers)
+				}
+			}
+			if *rebuild {
+				if err := reset(ctx, c); err != nil {
+					return err
+				}
+			}
+			s := rollupSummary{Chain: c, Job: j}
+			for {
+				res, err := step(ctx)
+				if err != nil {
+					return err
+				}
+				s.LastSeq = res.LastSeq
+				if res.Events == 0 {
+					break
+				}
+				s.Events += res.Events
+				s.Updated += res.Updated
+			}
+			rs = append(rs, s)
+		}
+	}
+
+	t := table{header: []string{"CHAIN", "JOB", "EVENTS", "UPDATED", "LAST_SEQ"}}
+	for _, s := range rs {
+		t.add(s.Chain, s.Job, strconv.Itoa(s.Events), strconv.Itoa(s.Updated), strconv.FormatInt(s.LastSeq, 10))
+	}
+	return a.out.emit(rs, t)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	"github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// syncStatus は 1 アドレスの同期状況
+type syncStatus struct {
+	store.WatchedStatus
+	// Head はチェーン先頭（slot / checkpoint）。RPC に失敗したら nil
+	Head *int64 `json:"head"`
+	Lag  *int64 `json:"lag"`
+}
+
+// statusReport は status の出力
+type statusReport struct {
+	Addresses  []syncStatus            `json:"addresses"`
+	Heartbeats []store.WorkerHeartbeat `json:"heartbeats"`
+	// HeadErrors はチェーン先頭を取れなかったチェーンとエラー
+	HeadErrors map[string]string `json:"head_errors,omitempty"`
+}
+
+// chainHead はチェーン先頭の slot / checkpoint を返す
+func (a *app) chainHead(ctx context.Context, chain string) (int64, error) {
+	switch chain {
+	case "solana":
+		return sol.New(a.cfg.Chains.RPC(chain)).GetSlot(ctx)
+	case "sui":
+		return sui.New(a.cfg.Chains.RPC(chain)).GetLatestCheckpoint(ctx)
+	}
+	return 0, fmt.Errorf("unsupported chain: %s", chain)
+}
+
+func (a *app) status(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	chain := fs.String("chain", store.ChainAll, "solana / sui / all")
+	if pos, err := parseFlags(fs, args); err != nil {
+		return err
+	} else if len(pos) > 0 {
+		return errUsage
+	}
+	ws, err := a.st.ListWatched(ctx, strings.ToLower(*chain))
+	if err != nil {
+		return err
+	}
+	hbs, err := a.st.LatestHeartbeats(ctx)
+	if err != nil {
+		return err
+	}
+
+	rep := statusReport{Addresses: []syncStatus{}, Heartbeats: []store.WorkerHeartbeat{}}
+	heads := map[string]*int64{}
+	for _, w := range ws {
+		if _, ok := heads[w.Chain]; !ok {
+			heads[w.Chain] = nil
+			if h, err := a.chainHead(ctx, w.Chain); err != nil {
+				if rep.HeadErrors == nil {
+					rep.HeadErrors = map[string]string{}
+				}
+				rep.HeadErrors[w.Chain] = err.Error()
+			} else {
+				heads[w.Chain] = &h
+			}
+		}
+		s := syncStatus{WatchedStatus: w, Head: heads[w.Chain]}
+		if s.Head != nil && w.Cursor != nil {
+			lag := *s.Head - *w.Cursor
+			s.Lag = &lag
+		}
+		rep.Addresses = append(rep.Addresses, s)
+	}
+	for c, hb := range hbs {
+		if *chain == store.ChainAll || strings.EqualFold(*chain, c) {
+			rep.Heartbeats = append(rep.Heartbeats, hb)
+		}
+	}
+	sort.Slice(rep.Heartbeats, func(i, j int) bool { return rep.Heartbeats[i].Chain < rep.Heartbeats[j].Chain })
+
+	addrs := table{header: []string{"CHAIN", "ADDRESS", "CURSOR", "HEAD", "LAG", "UPDATED_AT"}}
+	for _, s := range rep.Addresses {
+		addrs.add(s.Chain, s.Address, optInt(s.Cursor), optInt(s.Head), optInt(s.Lag), fmtTime(s.UpdatedAt))
+	}
+	beats := table{header: []string{"CHAIN", "INSTANCE", "TICK_AT", "AGE", "ADDRESSES", "FAILED", "MAX_LAG", "LAST_ERROR"}}
+	for _, hb := range rep.Heartbeats {
+		beats.add(hb.Chain, hb.Instance, fmtTime(hb.TickAt), hb.Age.Round(1e9).String(),
+			strconv.Itoa(hb.Addresses), strconv.Itoa(hb.Failed), optInt(hb.MaxCursorLag), optStr(hb.LastError))
+	}
+	for c, e := range rep.HeadErrors {
+		fmt.Fprintf(os.Stderr, "warning: %s head: %s\n", c, e)
+	}
+	return a.out.emit(rep, addrs, beats)
+}
+
+func (a *app) migrate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := fs.String("dir", "migrations", "directory of *.sql migrations")
+	statusOnly := fs.Bool("status", false, "show applied / pending migrations without applying")
+	all := fs.Bool("all", false, "re-apply every migration (all files are idempotent)")
+	if pos, err := parseFlags(fs, args); err != nil {
+		return err
+	} else if len(pos) > 0 {
+		return errUsage
+	}
+	fsys := os.DirFS(*dir)
+	ms, err := a.st.Migrations(ctx, fsys)
+	if err != nil {
+		return err
+	}
+	if len(ms) == 0 {
+		return fmt.Errorf("no migrations in %s", *dir)
+	}
+	if !*statusOnly {
+		for _, m := range ms {
+			if m.AppliedAt != nil && !*all {
+				continue
+			}
+			if err := a.st.ApplyMigration(ctx, fsys, m.Name); err != nil {
+				return fmt.Errorf("%s: %w", m.Name, err)
+			}
+			fmt.Fprintln(os.Stderr, "applied", m.Name)
+		}
+		if ms, err = a.st.Migrations(ctx, fsys); err != nil {
+			return err
+		}
+	}
+	t := table{header: []string{"NAME", "APPLIED_AT"}}
+	for _, m := range ms {
+		applied := "pending"
+		if m.AppliedAt != nil {
+			applied = fmtTime(*m.AppliedAt)
+		}
+		t.add(m.Name, applied)
+	}
+	return a.out.emit(ms, t)
+}
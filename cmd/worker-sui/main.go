@@ -2,54 +2,66 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
-	"strconv"
-	"time"
+	"os/signal"
+	"syscall"
 
 	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/metrics"
+	"github.com/you/wallet-watcher/internal/retention"
 	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/tracing"
 	"github.com/you/wallet-watcher/internal/worker"
 )
 
 func main() {
-	ctx := context.Background()
+	logging.Setup("worker-sui")
+	// 設定（-config / CONFIG_FILE のファイル + 環境変数）。不正な値なら起動しない
+	cfg := config.MustLoad()
+	// RPC のメトリクスの endpoint ラベルを設定のネットワーク名にする
+	metrics.RegisterEndpoints(cfg.Chains)
 
-	st, err := store.New(ctx)
+	// SIGINT / SIGTERM で停止する
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// トレース（OTEL_TRACES_EXPORTER=otlp のときだけ出力）
+	shutdownTracing, err := tracing.Setup(ctx, "worker-sui")
 	if err != nil {
-		log.Fatalf("store: %v", err)
+		logging.Fatal("tracing", "err", err)
 	}
-	defer st.Close()
+	defer shutdownTracing(context.Background())
 
-	rpc := os.Getenv("SUI_RPC_URL")
-	if rpc == "" {
-		log.Fatalf("SUI_RPC_URL is required")
+	st, err := store.Open(ctx, cfg.DB)
+	if err != nil {
+		logging.Fatal("store", "err", err)
 	}
-	cl := sui.New(rpc)
+	defer st.Close()
 
-	interval := 5 * time.Second
-	if v := os.Getenv("POLL_INTERVAL_SEC"); v != "" {
-		if d, err := time.ParseDuration(v + "s"); err == nil {
-			interval = d
-		}
-	}
+	cl := sui.New(cfg.Chains.RPC("sui"))
+
+	wc := cfg.Worker
+	w := worker.NewSui(st, cl, wc, cfg.Notify)
+	slog.Info("sui worker started", "network", cfg.Chains.Sui.Network, "interval", wc.PollInterval.String(), "batch", wc.BatchSize, "shutdown_grace", wc.ShutdownGrace.String())
 
-	batch := 10 // 1アドレスあたり一度に取得する最大件数
-	if v := os.Getenv("BATCH_SIZE"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			batch = n
+	// /metrics（Prometheus）
+	go func() {
+		if err := metrics.Serve(ctx, metrics.ListenAddr(wc.MetricsPort)); err != nil {
+			slog.Error("metrics server", "err", err)
 		}
-	}
+	}()
 
-	w := worker.NewSui(st, cl, batch)
-	log.Printf("sui worker started: interval=%v batch=%d", interval, batch)
+	// tx_events_sui の月パーティションの保守（先の月の作成・raw の保持期限・古い月の書き出しと切り離し）
+	go (&retention.Maintainer{Store: st, Chain: "sui", Config: cfg.Retention}).Run(ctx)
 
-	t := time.NewTicker(interval)
-	defer t.Stop()
-	for {
-		if err := w.Tick(ctx); err != nil {
-			log.Printf("tick error: %v", err)
-		}
-		<-t.C
+	// 停止要求後は新しい tick を始めず、処理中のアドレスは保存とカーソル更新まで終える
+	if err := worker.Run(ctx, w, wc.PollInterval.D(), wc.ShutdownGrace.D()); err != nil {
+		// 処理中の接続を待たずに終了する（st.Close は使用中の接続の返却を待つため呼ばない）
+		slog.Error("worker stopped", "err", err)
+		os.Exit(1)
 	}
+	slog.Info("worker stopped")
 }
@@ -0,0 +1,309 @@
+// Package alerts は保存直後の正規化イベントをテナントごとのアラートルールで評価し、
+// 一致したものを alerts テーブルへ記録する。
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// Store はルールの読み出しとアラートの保存先（*store.Store が満たす。テストでは差し替える）
+type Store interface {
+	ListEnabledAlertRules(ctx context.Context, chain string) ([]store.AlertRule, error)
+	InsertAlert(ctx context.Context, a *store.Alert) (bool, error)
+	ClearAlertDedupe(ctx context.Context, tenant, dedupeKey string) error
+}
+
+// BalanceFunc はアドレスの現在残高（トークン → 最小単位）を返す。balance_below の評価に使う。
+type BalanceFunc func(ctx context.Context, address string) (map[string]int64, error)
+
+// rulesTTL はルールをキャッシュする時間（Tx ごとに DB を読まない）
+const rulesTTL = 30 * time.Second
+
+// ValidateRule は rule_type ごとの必須項目を確認する
+func ValidateRule(r *store.AlertRule) error {
+	if r.Chain != "solana" && r.Chain != "sui" {
+		return errors.New("chain must be 'solana' or 'sui'")
+	}
+	if r.Threshold != nil && *r.Threshold < 0 {
+		return errors.New("threshold must be a non-negative integer in minimal units")
+	}
+	has := func(p *string) bool { return p != nil && *p != "" }
+	switch r.Type {
+	case store.RuleIncomingTransfer, store.RuleOutgoingTransfer:
+		if !has(r.Address) {
+			return fmt.Errorf("%s requires 'address'", r.Type)
+		}
+	case store.RuleProgramInteraction:
+		if !has(r.Program) {
+			return fmt.Errorf("%s requires 'program'", r.Type)
+		}
+	case store.RuleBalanceBelow:
+		if !has(r.Address) || !has(r.Token) || r.Threshold == nil {
+			return fmt.Errorf("%s requires 'address', 'token' and 'threshold'", r.Type)
+		}
+	case store.RuleFeeAbove:
+		if r.Threshold == nil {
+			return fmt.Errorf("%s requires 'threshold'", r.Type)
+		}
+	default:
+		return fmt.Errorf("type must be one of %s, %s, %s, %s, %s",
+			store.RuleIncomingTransfer, store.RuleOutgoingTransfer, store.RuleProgramInteraction, store.RuleBalanceBelow, store.RuleFeeAbove)
+	}
+	return nil
+}
+
+// Engine は 1 チェーン分のルール評価器（ワーカーごとに 1 つ）
+type Engine struct {
+	st       Store
+	chain    string
+	balances BalanceFunc
+
+	mu       sync.Mutex
+	rules    []store.AlertRule
+	loadedAt time.Time
+}
+
+// NewEngine は評価器を作る。balances が nil なら balance_below は評価しない。
+func NewEngine(st Store, chain string, balances BalanceFunc) *Engine {
+	return &Engine{st: st, chain: chain, balances: balances}
+}
+
+func (e *Engine) loadRules(ctx context.Context) ([]store.AlertRule, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.rules != nil && time.Since(e.loadedAt) < rulesTTL {
+		return e.rules, nil
+	}
+	rules, err := e.st.ListEnabledAlertRules(ctx, e.chain)
+	if err != nil {
+		return nil, err
+	}
+	e.rules, e.loadedAt = rules, time.Now()
+	return rules, nil
+}
+
+// Process はイベントを全ルールで評価し、新規に記録されたアラートを返す。
+// 同じイベントを再処理しても dedupe_key により重複して記録されない。
+// balance_below は閾値を下回っている間は 1 件だけ記録し、残高が戻ったことを確認したら次に下回ったときに改めて記録する。
+func (e *Engine) Process(ctx context.Context, ev normalize.Event) ([]store.Alert, error) {
+	rules, err := e.loadRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []store.Alert
+	for _, r := range rules {
+		var matches []store.Alert
+		if r.Type == store.RuleBalanceBelow && r.Chain == ev.Chain {
+			a, checked, err := evaluateBalance(ctx, r, ev, e.balances)
+			if err != nil {
+				logging.FromContext(ctx).Warn("evaluate alert rule", "rule_id", r.ID, "tx", ev.TxHash, "err", err)
+				continue
+			}
+			if checked && a == nil {
+				// 閾値以上に戻った。下回っていた間のアラートの dedupe_key を外し、次に下回ったら記録する
+				if err := e.st.ClearAlertDedupe(ctx, r.Tenant, BalanceDedupeKey(r)); err != nil {
+					return out, err
+				}
+			}
+			if a != nil {
+				matches = append(matches, *a)
+			}
+		} else {
+			matches, err = Evaluate(ctx, r, ev, e.balances)
+			if err != nil {
+				logging.FromContext(ctx).Warn("evaluate alert rule", "rule_id", r.ID, "tx", ev.TxHash, "err", err)
+				continue
+			}
+		}
+		for i := range matches {
+			ok, err := e.st.InsertAlert(ctx, &matches[i])
+			if err != nil {
+				return out, err
+			}
+			if ok {
+				out = append(out, matches[i])
+			}
+		}
+	}
+	return out, nil
+}
+
+// Evaluate は 1 ルールを 1 イベントに当てはめ、一致したアラートを返す（保存はしない）
+func Evaluate(ctx context.Context, r store.AlertRule, ev normalize.Event, balances BalanceFunc) ([]store.Alert, error) {
+	if r.Chain != ev.Chain {
+		return nil, nil
+	}
+	eq := func(a, b string) bool { return store.SameAddress(ev.Chain, a, b) }
+
+	switch r.Type {
+	case store.RuleIncomingTransfer, store.RuleOutgoingTransfer:
+		incoming := r.Type == store.RuleIncomingTransfer
+		var out []store.Alert
+		for _, t := range sumTransfers(ev.Transfers, *r.Address, incoming, eq) {
+			if r.Token != nil && *r.Token != "" && t.Token != *r.Token {
+				continue
+			}
+			if r.Threshold != nil && t.Amount <= *r.Threshold {
+				continue
+			}
+			dir, prep := "outgoing", "from"
+			if incoming {
+				dir, prep = "incoming", "to"
+			}
+			msg := fmt.Sprintf("%s %s %s %s %s", dir, normalize.FormatAmount(t.Amount, t.Decimals), t.Token, prep, *r.Address)
+			out = append(out, newAlert(r, ev, t.Token, t.Amount, t.Decimals, msg))
+		}
+		return out, nil
+
+	case store.RuleProgramInteraction:
+		called := false
+		for _, p := range ev.Programs {
+			if eq(p, *r.Program) {
+				called = true
+				break
+			}
+		}
+		if !called || (r.Address != nil && *r.Address != "" && !involves(ev, *r.Address, eq)) {
+			return nil, nil
+		}
+		return []store.Alert{newAlert(r, ev, "", 0, 0, fmt.Sprintf("interaction with program %s", *r.Program))}, nil
+
+	case store.RuleFeeAbove:
+		if ev.Fee == nil || *ev.Fee <= *r.Threshold {
+			return nil, nil
+		}
+		if r.Address != nil && *r.Address != "" && (ev.Sender == nil || !eq(*ev.Sender, *r.Address)) {
+			return nil, nil
+		}
+		token, dec := nativeToken(ev.Chain)
+		msg := fmt.Sprintf("fee %s %s above %s", normalize.FormatAmount(*ev.Fee, dec), token, normalize.FormatAmount(*r.Threshold, dec))
+		return []store.Alert{newAlert(r, ev, token, *ev.Fee, dec, msg)}, nil
+
+	case store.RuleBalanceBelow:
+		a, _, err := evaluateBalance(ctx, r, ev, balances)
+		if a == nil || err != nil {
+			return nil, err
+		}
+		return []store.Alert{*a}, nil
+	}
+	return nil, nil
+}
+
+// evaluateBalance は balance_below を評価する。残高を確認したら checked を true にし、
+// 閾値を下回っていればアラートを返す。対象トークンの残高が変わりうる Tx（出金・入金・ネイティブトークンでの
+// 手数料支払い）のときだけ残高を確認する（入金でも確認するのは閾値以上に戻ったことを知るため）。
+func evaluateBalance(ctx context.Context, r store.AlertRule, ev normalize.Event, balances BalanceFunc) (*store.Alert, bool, error) {
+	if r.Chain != ev.Chain || balances == nil {
+		return nil, false, nil
+	}
+	eq := func(a, b string) bool { return store.SameAddress(ev.Chain, a, b) }
+	dec, touched := -1, false
+	for _, t := range ev.Transfers {
+		if t.Token == *r.Token && (eq(t.From, *r.Address) || eq(t.To, *r.Address)) {
+			dec, touched = t.Decimals, true
+		}
+	}
+	if native, ndec := nativeToken(ev.Chain); native == *r.Token && ev.Sender != nil && eq(*ev.Sender, *r.Address) {
+		dec, touched = ndec, true
+	}
+	if !touched {
+		return nil, false, nil
+	}
+	bals, err := balances(ctx, *r.Address)
+	if err != nil {
+		return nil, false, err
+	}
+	bal := bals[*r.Token]
+	if bal >= *r.Threshold {
+		return nil, true, nil
+	}
+	if dec < 0 {
+		dec = 0
+	}
+	msg := fmt.Sprintf("%s balance of %s is %s, below %s", *r.Token, *r.Address,
+		normalize.FormatAmount(bal, dec), normalize.FormatAmount(*r.Threshold, dec))
+	a := newAlert(r, ev, *r.Token, bal, dec, msg)
+	a.DedupeKey = BalanceDedupeKey(r)
+	return &a, true, nil
+}
+
+// BalanceDedupeKey は balance_below の dedupe_key（ルール × アドレス × トークン。Tx は含めない）。
+// 下回っている間は何度評価しても 1 件にまとまる。
+// 保存済みのキーと回復時の ClearAlertDedupe が合うよう、アドレスは従来どおり 0x 無しで載せる
+func BalanceDedupeKey(r store.AlertRule) string {
+	addr := strings.TrimPrefix(store.CanonicalAddress(r.Chain, *r.Address), "0x")
+	return fmt.Sprintf("%d:%s:%s:%s", r.ID, r.Chain, addr, *r.Token)
+}
+
+// newAlert はルール × Tx（× トークン）を dedupe_key にしたアラートを作る（balance_below は BalanceDedupeKey に置き換える）
+func newAlert(r store.AlertRule, ev normalize.Event, token string, amount int64, decimals int, msg string) store.Alert {
+	id := r.ID
+	a := store.Alert{
+		Tenant:    r.Tenant,
+		RuleID:    &id,
+		RuleType:  r.Type,
+		Chain:     ev.Chain,
+		TxHash:    ev.TxHash,
+		TS:        ev.TS,
+		Address:   r.Address,
+		Message:   msg,
+		DedupeKey: fmt.Sprintf("%d:%s:%s", r.ID, ev.Chain, ev.TxHash),
+	}
+	if token != "" {
+		a.Token, a.Amount, a.Decimals = &token, &amount, &decimals
+		a.DedupeKey += ":" + token
+	}
+	return a
+}
+
+// sumTransfers は address への入金（incoming）/ address からの出金をトークンごとに合算する
+func sumTransfers(ts []normalize.Transfer, address string, incoming bool, eq func(a, b string) bool) []normalize.Transfer {
+	var out []normalize.Transfer
+	idx := map[string]int{}
+	for _, t := range ts {
+		side := t.From
+		if incoming {
+			side = t.To
+		}
+		if side == "" || !eq(side, address) {
+			continue
+		}
+		i, ok := idx[t.Token]
+		if !ok {
+			idx[t.Token] = len(out)
+			out = append(out, normalize.Transfer{Token: t.Token, Decimals: t.Decimals})
+			i = len(out) - 1
+		}
+		out[i].Amount += t.Amount
+	}
+	return out
+}
+
+// involves は address がイベントに関与しているかを返す
+func involves(ev normalize.Event, address string, eq func(a, b string) bool) bool {
+	if ev.Sender != nil && eq(*ev.Sender, address) || ev.Receiver != nil && eq(*ev.Receiver, address) {
+		return true
+	}
+	for _, p := range ev.Participants {
+		if eq(p.Address, address) {
+			return true
+		}
+	}
+	return false
+}
+
+func nativeToken(chain string) (string, int) {
+	if chain == "sui" {
+		return "SUI", normalize.SUIDecimals
+	}
+	return "SOL", normalize.SOLDecimals
+}
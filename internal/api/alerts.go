@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/you/wallet-watcher/internal/alerts"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// テナントは X-Tenant-ID ヘッダーで指定する（省略時は default）
+const (
+	tenantHeader  = "X-Tenant-ID"
+	defaultTenant = "default"
+)
+
+var reTenant = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+func tenantOf(r *http.Request) (string, error) {
+	t := strings.TrimSpace(r.Header.Get(tenantHeader))
+	if t == "" {
+		return defaultTenant, nil
+	}
+	if !reTenant.MatchString(t) {
+		return "", errors.New("invalid " + tenantHeader)
+	}
+	return t, nil
+}
+
+type alertRuleReq struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Chain     string  `json:"chain"`
+	Address   *string `json:"address"`
+	Token     *string `json:"token"`
+	Program   *string `json:"program"`
+	Threshold *int64  `json:"threshold"`
+	Enabled   *bool   `json:"enabled"`
+}
+
+// trimOpt は空白を除き、空なら nil にする
+func trimOpt(p *string) *string {
+	if p == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*p)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// parseAlertRule はリクエストを検証してルールに変換する
+func parseAlertRule(req alertRuleReq, tenant string) (*store.AlertRule, error) {
+	r := &store.AlertRule{
+		Tenant:    tenant,
+		Name:      strings.TrimSpace(req.Name),
+		Type:      strings.ToLower(strings.TrimSpace(req.Type)),
+		Chain:     strings.ToLower(strings.TrimSpace(req.Chain)),
+		Address:   trimOpt(req.Address),
+		Token:     trimOpt(req.Token),
+		Program:   trimOpt(req.Program),
+		Threshold: req.Threshold,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+	if err := alerts.ValidateRule(r); err != nil {
+		return nil, err
+	}
+	if r.Address != nil {
+		if err := validateChainAndAddress(r.Chain, *r.Address); err != nil {
+			return nil, fmt.Errorf("invalid 'address': %v", err)
+		}
+	}
+	if r.Program != nil {
+		if err := validateChainAndAddress(r.Chain, *r.Program); err != nil {
+			return nil, fmt.Errorf("invalid 'program': %v", err)
+		}
+	}
+	if len(r.Name) > 200 {
+		return nil, errors.New("'name' is too long")
+	}
+	return r, nil
+}
+
+func (s *Server) handleListAlertRules(w http.ResponseWriter, r *http.Request) {
+	tenant, err := tenantOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	rules, err := s.Store.ListAlertRules(ctx, tenant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"rules": rules})
+}
+
+func (s *Server) handleCreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	tenant, err := tenantOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req alertRuleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	rule, err := parseAlertRule(req, tenant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if err := s.Store.CreateAlertRule(ctx, rule); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+func (s *Server) handleDeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	tenant, err := tenantOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid rule id", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	switch err := s.Store.DeleteAlertRule(ctx, tenant, id); {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "rule not found", http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleListAlerts はテナントのアラートを新しい順に返す（before に next_before を渡して次ページ）
+func (s *Server) handleListAlerts(w http.ResponseWriter, r *http.Request) {
+	tenant, err := tenantOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	aq := store.AlertQuery{Limit: 50}
+	if v := q.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 200 {
+			aq.Limit = n
+		}
+	}
+	for name, dst := range map[string]**int64{"before": &aq.BeforeID, "rule_id": &aq.RuleID} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				http.Error(w, fmt.Sprintf("invalid '%s'", name), http.StatusBadRequest)
+				return
+			}
+			*dst = &n
+		}
+	}
+	if v := strings.ToLower(q.Get("chain")); v != "" {
+		if v != "solana" && v != "sui" {
+			http.Error(w, "chain must be 'solana' or 'sui'", http.StatusBadRequest)
+			return
+		}
+		aq.Chain = &v
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	list, err := s.Store.ListAlerts(ctx, tenant, aq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := map[string]any{"alerts": list}
+	if len(list) == aq.Limit {
+		resp["next_before"] = list[len(list)-1].ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
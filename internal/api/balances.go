@@ -11,6 +11,9 @@ import (
 	"github.com/go-chi/chi/v5"
 	solana "github.com/you/wallet-watcher/internal/chains/solana"
 	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/portfolio"
+	"github.com/you/wallet-watcher/internal/pricing"
+	"github.com/you/wallet-watcher/internal/store"
 )
 
 // BalancesResponse represents the response for /balances endpoint
@@ -51,7 +54,7 @@ func (s *Server) handleSolanaBalances(w http.ResponseWriter, r *http.Request) {
 
 	response := BalancesResponse{
 		Address:  address,
-		Balances: balances,
+		Balances: s.Prices.ValueBalances(ctx, solanaBalances(balances)),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -93,7 +96,7 @@ func (s *Server) handleSuiBalances(w http.ResponseWriter, r *http.Request) {
 
 	response := BalancesResponse{
 		Address:  address,
-		Balances: balances,
+		Balances: s.Prices.ValueBalances(ctx, suiBalances(balances)),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -108,6 +111,12 @@ func (s *Server) handleBalances(w http.ResponseWriter, r *http.Request) {
 	chain := strings.ToLower(r.URL.Query().Get("chain"))
 	address := r.URL.Query().Get("address")
 
+	// tag / group 指定時は該当する監視アドレスの残高をまとめて返す
+	if address == "" && (r.URL.Query().Has("tag") || r.URL.Query().Has("group")) {
+		s.handleLabeledBalances(w, r)
+		return
+	}
+
 	if chain == "" || address == "" {
 		http.Error(w, "chain and address parameters are required", http.StatusBadRequest)
 		return
@@ -122,7 +131,7 @@ func (s *Server) handleBalances(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	var balances interface{}
+	var balances []store.TokenBalance
 	var err error
 
 	switch chain {
@@ -133,7 +142,9 @@ func (s *Server) handleBalances(w http.ResponseWriter, r *http.Request) {
 			rpcURL = "https://api.mainnet-beta.solana.com"
 		}
 		client := solana.New(rpcURL)
-		balances, err = client.GetBalances(ctx, address)
+		var bs []solana.Balance
+		bs, err = client.GetBalances(ctx, address)
+		balances = solanaBalances(bs)
 	case "sui":
 		// Get Sui RPC URL from environment
 		rpcURL := r.URL.Query().Get("rpc_url")
@@ -141,7 +152,9 @@ func (s *Server) handleBalances(w http.ResponseWriter, r *http.Request) {
 			rpcURL = "https://fullnode.mainnet.sui.io:443"
 		}
 		client := sui.New(rpcURL)
-		balances, err = client.GetBalances(ctx, address)
+		var bs []sui.Balance
+		bs, err = client.GetBalances(ctx, address)
+		balances = suiBalances(bs)
 	default:
 		http.Error(w, "chain must be 'solana' or 'sui'", http.StatusBadRequest)
 		return
@@ -154,7 +167,7 @@ func (s *Server) handleBalances(w http.ResponseWriter, r *http.Request) {
 
 	response := BalancesResponse{
 		Address:  address,
-		Balances: balances,
+		Balances: s.Prices.ValueBalances(ctx, balances),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -163,3 +176,44 @@ func (s *Server) handleBalances(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// handleLabeledBalances は tag / group（と任意の chain）に該当する監視アドレスの残高を返す。
+// 取得に失敗したアドレスは error を付けて返し、全体は失敗させない。
+func (s *Server) handleLabeledBalances(w http.ResponseWriter, r *http.Request) {
+	wallets, ok := s.watchedWallets(w, r, labeledBalancesMax)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+	out := portfolio.Collect(ctx, wallets, s.fetchBalances(r), portfolio.Options{Snapshots: s.snapshots(r)})
+	valued := make([]valuedWallet, len(out))
+	for i, wb := range out {
+		valued[i] = valuedWallet{WalletBalances: wb, Balances: s.Prices.ValueBalances(ctx, wb.Balances)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"addresses": valued})
+}
+
+// valuedWallet は 1 アドレス分の残高に現在の USD 評価額を付けたもの
+type valuedWallet struct {
+	portfolio.WalletBalances
+	Balances []pricing.Valued `json:"balances"`
+}
+
+func solanaBalances(bs []solana.Balance) []store.TokenBalance {
+	out := make([]store.TokenBalance, len(bs))
+	for i, b := range bs {
+		out[i] = store.TokenBalance{Token: b.Token, Amount: b.Amount}
+	}
+	return out
+}
+
+func suiBalances(bs []sui.Balance) []store.TokenBalance {
+	out := make([]store.TokenBalance, len(bs))
+	for i, b := range bs {
+		out[i] = store.TokenBalance{Token: b.Token, Amount: b.Amount}
+	}
+	return out
+}
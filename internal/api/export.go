@@ -0,0 +1,291 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// エクスポート形式
+const (
+	exportCSV         = "csv"
+	exportNDJSON      = "ndjson"
+	exportKoinly      = "koinly"
+	exportCoinTracker = "cointracker"
+)
+
+// exportFlushEvery は何行ごとにクライアントへ flush するか
+const exportFlushEvery = 200
+
+// ExportRow はエクスポートの 1 行。Tx 内の移動 1 件、または手数料 1 件に対応する。
+// Amount は decimals を適用済みの 10 進文字列。
+type ExportRow struct {
+	Chain  string    `json:"chain"`
+	TxHash string    `json:"tx_hash"`
+	TS     time.Time `json:"ts"`
+	// Type: "transfer" / "fee"
+	Type string `json:"type"`
+	// Direction: 対象アドレスから見た向き（"in" / "out"）
+	Direction string `json:"direction,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Token     string `json:"token"`
+	Amount    string `json:"amount"`
+	Status    string `json:"status,omitempty"`
+	Method    string `json:"method,omitempty"`
+	// FromLabel / ToLabel は From / To が監視アドレスならそのラベル
+	FromLabel string `json:"from_label,omitempty"`
+	ToLabel   string `json:"to_label,omitempty"`
+}
+
+// nativeToken はチェーンのネイティブトークン（手数料の通貨）
+func nativeToken(chain string) (string, int) {
+	if chain == "sui" {
+		return "SUI", normalize.SUIDecimals
+	}
+	return "SOL", normalize.SOLDecimals
+}
+
+// exportRows はイベント 1 件を address 視点の行に展開する（Sui のコインの桁数は dec で引く）
+func exportRows(ctx context.Context, e store.TxEvent, raw []byte, address string, dec *normalize.SuiDecimals) []ExportRow {
+	base := ExportRow{Chain: e.Chain, TxHash: e.TxHash, TS: e.TS.UTC()}
+	if e.Status != nil {
+		base.Status = *e.Status
+	}
+	if e.Method != nil {
+		base.Method = *e.Method
+	}
+	feeToken, feeDecimals := nativeToken(e.Chain)
+
+	var transfers []normalize.Transfer
+	fee := e.Fee
+	if ev, ok := normalize.FromRaw(e.Chain, e.TxHash, e.TS, raw); ok {
+		dec.Apply(ctx, &ev)
+		transfers = ev.Transfers
+		// Sui はストレージリベートを差し引いた実質のガス代（0022 より前に保存した行の fee は差し引く前の額）
+		fee = ev.Fee
+	} else if e.Token != nil && e.Amount != nil {
+		// raw が無い行はイベント行の token / amount をそのまま使う
+		t := normalize.Transfer{Token: *e.Token, Amount: *e.Amount}
+		if *e.Token == feeToken {
+			t.Decimals = feeDecimals
+		}
+		if e.Sender != nil {
+			t.From = *e.Sender
+		}
+		if e.Receiver != nil {
+			t.To = *e.Receiver
+		}
+		transfers = []normalize.Transfer{t}
+	}
+
+	// ラベルはイベントの送受信者の分だけ分かる
+	labelOf := func(a string) string {
+		switch {
+		case a == "":
+		case e.Sender != nil && e.SenderLabel != nil && store.SameAddress(e.Chain, *e.Sender, a):
+			return *e.SenderLabel
+		case e.Receiver != nil && e.ReceiverLabel != nil && store.SameAddress(e.Chain, *e.Receiver, a):
+			return *e.ReceiverLabel
+		}
+		return ""
+	}
+
+	var out []ExportRow
+	for _, t := range transfers {
+		row := base
+		row.Type, row.From, row.To, row.Token = "transfer", t.From, t.To, t.Token
+		row.FromLabel, row.ToLabel = labelOf(t.From), labelOf(t.To)
+		row.Amount = normalize.FormatAmount(t.Amount, t.Decimals)
+		switch {
+		case store.SameAddress(e.Chain, t.From, address):
+			row.Direction = "out"
+		case store.SameAddress(e.Chain, t.To, address):
+			row.Direction = "in"
+		default:
+			continue
+		}
+		out = append(out, row)
+	}
+
+	// 手数料は送信者（fee payer）が払った場合のみ別行で出す
+	if fee != nil && *fee > 0 && e.Sender != nil && store.SameAddress(e.Chain, *e.Sender, address) {
+		row := base
+		row.Type, row.Direction, row.From, row.Token = "fee", "out", *e.Sender, feeToken
+		row.FromLabel = labelOf(*e.Sender)
+		row.Amount = normalize.FormatAmount(*fee, feeDecimals)
+		out = append(out, row)
+	}
+	return out
+}
+
+// exportWriter は形式ごとの行の書き出し
+type exportWriter interface {
+	Header() error
+	Write(r ExportRow) error
+	Flush() error
+}
+
+type csvExport struct{ w *csv.Writer }
+
+func (c *csvExport) Header() error {
+	return c.w.Write([]string{"chain", "tx_hash", "ts", "type", "direction", "from", "to", "token", "amount", "status", "method", "from_label", "to_label"})
+}
+func (c *csvExport) Write(r ExportRow) error {
+	return c.w.Write([]string{r.Chain, r.TxHash, r.TS.Format(time.RFC3339), r.Type, r.Direction, r.From, r.To, r.Token, r.Amount, r.Status, r.Method, r.FromLabel, r.ToLabel})
+}
+func (c *csvExport) Flush() error { c.w.Flush(); return c.w.Error() }
+
+type ndjsonExport struct{ enc *json.Encoder }
+
+func (n *ndjsonExport) Header() error           { return nil }
+func (n *ndjsonExport) Write(r ExportRow) error { return n.enc.Encode(r) }
+func (n *ndjsonExport) Flush() error            { return nil }
+
+// koinlyExport は Koinly の Universal CSV 形式
+type koinlyExport struct{ w *csv.Writer }
+
+func (k *koinlyExport) Header() error {
+	return k.w.Write([]string{"Date", "Sent Amount", "Sent Currency", "Received Amount", "Received Currency",
+		"Fee Amount", "Fee Currency", "Net Worth Amount", "Net Worth Currency", "Label", "Description", "TxHash"})
+}
+func (k *koinlyExport) Write(r ExportRow) error {
+	rec := make([]string, 12)
+	rec[0] = r.TS.Format("2006-01-02 15:04:05") + " UTC"
+	switch {
+	case r.Type == "fee":
+		rec[5], rec[6], rec[9], rec[10] = r.Amount, r.Token, "cost", "network fee"
+	case r.Direction == "out":
+		rec[1], rec[2] = r.Amount, r.Token
+	default:
+		rec[3], rec[4] = r.Amount, r.Token
+	}
+	if rec[10] == "" {
+		rec[10] = r.Method
+		// 相手が監視アドレスならラベルを添える
+		cp := r.FromLabel
+		if r.Direction == "out" {
+			cp = r.ToLabel
+		}
+		switch {
+		case cp == "":
+		case rec[10] == "":
+			rec[10] = cp
+		default:
+			rec[10] += " (" + cp + ")"
+		}
+	}
+	rec[11] = r.TxHash
+	return k.w.Write(rec)
+}
+func (k *koinlyExport) Flush() error { k.w.Flush(); return k.w.Error() }
+
+// coinTrackerExport は CoinTracker の CSV インポート形式
+type coinTrackerExport struct{ w *csv.Writer }
+
+func (c *coinTrackerExport) Header() error {
+	return c.w.Write([]string{"Date", "Received Quantity", "Received Currency", "Sent Quantity", "Sent Currency",
+		"Fee Amount", "Fee Currency", "Tag"})
+}
+func (c *coinTrackerExport) Write(r ExportRow) error {
+	rec := make([]string, 8)
+	rec[0] = r.TS.Format("01/02/2006 15:04:05")
+	switch {
+	case r.Type == "fee":
+		rec[5], rec[6] = r.Amount, r.Token
+	case r.Direction == "out":
+		rec[3], rec[4] = r.Amount, r.Token
+	default:
+		rec[1], rec[2] = r.Amount, r.Token
+	}
+	return c.w.Write(rec)
+}
+func (c *coinTrackerExport) Flush() error { c.w.Flush(); return c.w.Error() }
+
+// newExportWriter は format に対応する書き出しと Content-Type / 拡張子を返す
+func newExportWriter(format string, w io.Writer) (exportWriter, string, string, error) {
+	switch format {
+	case "", exportCSV:
+		return &csvExport{csv.NewWriter(w)}, "text/csv; charset=utf-8", "csv", nil
+	case exportNDJSON:
+		return &ndjsonExport{json.NewEncoder(w)}, "application/x-ndjson", "ndjson", nil
+	case exportKoinly:
+		return &koinlyExport{csv.NewWriter(w)}, "text/csv; charset=utf-8", "csv", nil
+	case exportCoinTracker:
+		return &coinTrackerExport{csv.NewWriter(w)}, "text/csv; charset=utf-8", "csv", nil
+	default:
+		return nil, "", "", fmt.Errorf("format must be '%s', '%s', '%s' or '%s'", exportCSV, exportNDJSON, exportKoinly, exportCoinTracker)
+	}
+}
+
+// handleHistoryExport は /history と同じ絞り込みで全件を CSV / NDJSON / 会計ソフト形式で流す
+func (s *Server) handleHistoryExport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	chain, err := parseHistoryChain(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	addr := strings.TrimSpace(q.Get("address"))
+	if addr == "" {
+		http.Error(w, "address parameter is required", http.StatusBadRequest)
+		return
+	}
+	if err := validateHistoryAddress(chain, addr); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hq := store.HistoryQuery{Address: &addr}
+	if err := parseHistoryFilters(q, chain, &hq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(q.Get("format")))
+	ew, contentType, ext, err := newExportWriter(format, w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="history-%s-%s.%s"`, chain, addr, ext))
+	if err := ew.Header(); err != nil {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	dec := &normalize.SuiDecimals{Store: s.Store}
+	n := 0
+	err = s.Store.StreamTxEvents(r.Context(), chain, hq, func(e store.TxEvent, raw []byte) error {
+		for _, row := range exportRows(r.Context(), e, raw, addr, dec) {
+			if err := ew.Write(row); err != nil {
+				return err
+			}
+		}
+		if n++; n%exportFlushEvery == 0 {
+			if err := ew.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// ヘッダー送信後なのでステータスは変えられない。途中で切れたことをログに残す
+		logging.FromContext(r.Context()).Error("export aborted", "chain", chain, "address", addr, "err", err)
+		return
+	}
+	_ = ew.Flush()
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/graph"
+	"github.com/you/wallet-watcher/internal/logging"
+)
+
+// parseGraphQuery は /graph のクエリ（depth / limit / token / format）を読む
+func parseGraphQuery(q url.Values) (graph.Options, string, error) {
+	opt := graph.Options{Depth: graph.DefaultDepth, PerAddress: graph.DefaultPerAddress}
+	if v := strings.TrimSpace(q.Get("depth")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > graph.MaxDepth {
+			return opt, "", fmt.Errorf("invalid 'depth' (1-%d)", graph.MaxDepth)
+		}
+		opt.Depth = n
+	}
+	if v := strings.TrimSpace(q.Get("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > graph.MaxPerAddress {
+			return opt, "", fmt.Errorf("invalid 'limit' (1-%d)", graph.MaxPerAddress)
+		}
+		opt.PerAddress = n
+	}
+	if v := strings.TrimSpace(q.Get("token")); v != "" {
+		if len(v) > 256 {
+			return opt, "", errors.New("'token' is too long")
+		}
+		opt.Token = &v
+	}
+	format := strings.ToLower(strings.TrimSpace(q.Get("format")))
+	switch format {
+	case "":
+		format = graph.FormatJSON
+	case graph.FormatJSON, graph.FormatGraphML, graph.FormatDOT:
+	default:
+		return opt, "", errors.New("format must be 'json', 'graphml' or 'dot'")
+	}
+	return opt, format, nil
+}
+
+// handleGraph はアドレスを中心に depth ホップまでの資金の流れ（counterparty_edges）を
+// JSON / GraphML / DOT で返す。各ホップでアドレスごとに tx_count の多い limit 件のエッジをたどる。
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	chain, addr, err := watchedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opt, format, err := parseGraphQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	g, err := graph.Build(ctx, s.Store, chain, addr, opt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", graph.ContentType(format))
+	switch format {
+	case graph.FormatGraphML:
+		err = graph.WriteGraphML(w, g)
+	case graph.FormatDOT:
+		err = graph.WriteDOT(w, g)
+	default:
+		err = json.NewEncoder(w).Encode(g)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("write graph", "format", format, "err", err)
+	}
+}
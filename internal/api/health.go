@@ -0,0 +1,27 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// handleHealthz は liveness。プロセスが応答できれば依存先に関係なく 200 を返す。
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleReadyz は readiness。依存先のチェック結果を返し、1 つでも失敗すれば 503 にする。
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if s.Ready == nil {
+		http.Error(w, "readiness checks are not configured", http.StatusServiceUnavailable)
+		return
+	}
+	rep := s.Ready.Run(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !rep.OK() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(rep)
+}
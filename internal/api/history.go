@@ -1,24 +1,37 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/you/wallet-watcher/internal/store"
 )
 
+// historyPricingTimeout は /history の USD 評価（価格表・価格の取得元）に使う時間の上限
+const historyPricingTimeout = 3 * time.Second
+
 func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
-	chain := strings.ToLower(strings.TrimSpace(q.Get("chain")))
-	if chain != "solana" && chain != "sui" {
-		http.Error(w, "chain must be 'solana' or 'sui'", http.StatusBadRequest)
+	chain, err := parseHistoryChain(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	addr := strings.TrimSpace(q.Get("address"))
 	var addrPtr *string
 	if addr != "" {
+		if err := validateHistoryAddress(chain, addr); err != nil {
+			http.Error(w, fmt.Sprintf("invalid 'address': %v", err), http.StatusBadRequest)
+			return
+		}
 		addrPtr = &addr
 	}
 
@@ -29,30 +42,183 @@ func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var beforePtr *time.Time
-	if v := q.Get("before"); v != "" {
-		t, err := time.Parse(time.RFC3339, v)
-		if err != nil {
-			http.Error(w, "invalid 'before' format (use RFC3339)", http.StatusBadRequest)
-			return
-		}
-		beforePtr = &t
+	before, err := parseHistoryCursor(q.Get("before"))
+	if err != nil {
+		http.Error(w, "invalid 'before' (use cursor or RFC3339)", http.StatusBadRequest)
+		return
+	}
+	after, err := parseHistoryCursor(q.Get("after"))
+	if err != nil {
+		http.Error(w, "invalid 'after' (use cursor or RFC3339)", http.StatusBadRequest)
+		return
+	}
+
+	hq := store.HistoryQuery{
+		Address: addrPtr,
+		Limit:   limit,
+		Before:  before,
+		After:   after,
+	}
+	if err := parseHistoryFilters(q, chain, &hq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	events, err := s.Store.ListTxEvents(r.Context(), chain, addrPtr, limit, beforePtr)
+	events, err := s.Store.ListTxEvents(r.Context(), chain, hq)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// Tx 時刻の価格で USD 評価額を付ける。価格の取得元が遅くても一覧は返すよう、短い時間で打ち切る
+	// （間に合わなかったイベントは USD 項目なしで返す）
+	pctx, cancel := context.WithTimeout(r.Context(), historyPricingTimeout)
+	s.Prices.ValueEvents(pctx, events)
+	cancel()
 
 	resp := map[string]any{"events": events}
-	if len(events) == limit {
-		// 次ページ用カーソル（最終行の ts を返す）
-		nextBefore := events[len(events)-1].TS.UTC().Format(time.RFC3339)
-		resp["next_before"] = nextBefore
+	if len(events) > 0 {
+		// 新着側へのカーソル（after に渡す）
+		resp["prev_cursor"] = store.CursorOf(events[0]).Encode()
+	}
+	// after のみ指定時はカーソルより古い行が必ず存在する
+	if len(events) == limit || (after != nil && before == nil && len(events) > 0) {
+		last := events[len(events)-1]
+		resp["next_cursor"] = store.CursorOf(last).Encode()
+		// 旧クライアント向け（最終行の ts を返す）
+		resp["next_before"] = last.TS.UTC().Format(time.RFC3339)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	enc := json.NewEncoder(w)
 	_ = enc.Encode(resp)
 }
+
+// parseHistoryChain は chain を読む。省略時は全チェーン横断のフィード
+func parseHistoryChain(q url.Values) (string, error) {
+	chain := strings.ToLower(strings.TrimSpace(q.Get("chain")))
+	if chain == "" {
+		chain = store.ChainAll
+	}
+	if chain != "solana" && chain != "sui" && chain != store.ChainAll {
+		return "", errors.New("chain must be 'solana', 'sui' or 'all'")
+	}
+	return chain, nil
+}
+
+// validateHistoryAddress は chain=all ならいずれかのチェーンのアドレスとして妥当かを見る
+func validateHistoryAddress(chain, addr string) error {
+	if chain == store.ChainAll {
+		if validateChainAndAddress("solana", addr) != nil && validateChainAndAddress("sui", addr) != nil {
+			return errors.New("not a solana or sui address")
+		}
+		return nil
+	}
+	return validateChainAndAddress(chain, addr)
+}
+
+// parseHistoryCursor は before/after の値を解釈する。
+// 不透明カーソルのほか、互換のため RFC3339 のタイムスタンプも受け付ける
+// （その場合は ts の境界のみで比較され、従来の `ts < before` / `ts > after` と同じ意味になる）。
+func parseHistoryCursor(v string) (*store.HistoryCursor, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &store.HistoryCursor{TS: t}, nil
+	}
+	c, err := store.DecodeHistoryCursor(v)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// parseHistoryFilters は絞り込み用クエリを検証して hq に設定する
+func parseHistoryFilters(q url.Values, chain string, hq *store.HistoryQuery) error {
+	switch d := strings.ToLower(strings.TrimSpace(q.Get("direction"))); d {
+	case "", "all":
+	case "in", "out":
+		if hq.Address == nil {
+			return errors.New("'direction' requires 'address'")
+		}
+		hq.Direction = d
+	default:
+		return errors.New("direction must be 'in', 'out' or 'all'")
+	}
+
+	if v := strings.TrimSpace(q.Get("counterparty")); v != "" {
+		if err := validateHistoryAddress(chain, v); err != nil {
+			return fmt.Errorf("invalid 'counterparty': %v", err)
+		}
+		hq.Counterparty = &v
+	}
+	// token / min_amount / max_amount は行の token・amount 列で絞る。ワーカーが正規化した代表の移動から
+	// token・amount を保存するようになる前の行は列が NULL のため一致しない（README 参照）
+	if v := strings.TrimSpace(q.Get("token")); v != "" {
+		hq.Token = &v
+	}
+	if v := strings.TrimSpace(q.Get("method")); v != "" {
+		hq.Method = &v
+	}
+
+	switch st := strings.ToLower(strings.TrimSpace(q.Get("status"))); st {
+	case "":
+	case store.TxStatusSuccess, store.TxStatusFailed:
+		hq.Status = &st
+	default:
+		return errors.New("status must be 'success' or 'failed'")
+	}
+
+	f, err := parseAddressFilter(q)
+	if err != nil {
+		return err
+	}
+	hq.Tag, hq.Group = f.Tag, f.Group
+
+	if hq.MinAmount, err = parseAmountParam(q, "min_amount"); err != nil {
+		return err
+	}
+	if hq.MaxAmount, err = parseAmountParam(q, "max_amount"); err != nil {
+		return err
+	}
+	if hq.MinAmount != nil && hq.MaxAmount != nil && *hq.MinAmount > *hq.MaxAmount {
+		return errors.New("'min_amount' must be <= 'max_amount'")
+	}
+
+	if hq.From, err = parseTimeParam(q, "from"); err != nil {
+		return err
+	}
+	if hq.To, err = parseTimeParam(q, "to"); err != nil {
+		return err
+	}
+	if hq.From != nil && hq.To != nil && !hq.From.Before(*hq.To) {
+		return errors.New("'from' must be earlier than 'to'")
+	}
+	return nil
+}
+
+// parseAmountParam は最小単位（lamports / MIST 等）の非負整数を読む
+func parseAmountParam(q url.Values, name string) (*int64, error) {
+	v := strings.TrimSpace(q.Get(name))
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid '%s' (use a non-negative integer in base units)", name)
+	}
+	return &n, nil
+}
+
+func parseTimeParam(q url.Values, name string) (*time.Time, error) {
+	v := strings.TrimSpace(q.Get(name))
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s' format (use RFC3339)", name)
+	}
+	return &t, nil
+}
@@ -0,0 +1,334 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// ラベル・タグ・グループ名の上限と書式
+const (
+	maxLabelLen = 128
+	maxTags     = 32
+)
+
+var (
+	reTag   = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]{0,63}$`)
+	reGroup = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+)
+
+// parseLabel は空白を除き、空なら nil（ラベルを消す）にする
+func parseLabel(p *string) (*string, error) {
+	v := trimOpt(p)
+	if v == nil {
+		return nil, nil
+	}
+	if len([]rune(*v)) > maxLabelLen {
+		return nil, fmt.Errorf("'label' is too long (max %d characters)", maxLabelLen)
+	}
+	if strings.IndexFunc(*v, unicode.IsControl) >= 0 {
+		return nil, errors.New("'label' must not contain control characters")
+	}
+	return v, nil
+}
+
+// parseTag はタグを小文字にして検証する
+func parseTag(v string) (string, error) {
+	t := strings.ToLower(strings.TrimSpace(v))
+	if !reTag.MatchString(t) {
+		return "", fmt.Errorf("invalid tag %q (use a-z, 0-9, _ . : -; max 64 characters)", v)
+	}
+	return t, nil
+}
+
+// parseTags はタグを検証し、重複を除いて名前順にする
+func parseTags(vs []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, v := range vs {
+		t, err := parseTag(v)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	if len(out) > maxTags {
+		return nil, fmt.Errorf("too many tags (max %d)", maxTags)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func parseGroupName(v string) (string, error) {
+	g := strings.TrimSpace(v)
+	if !reGroup.MatchString(g) {
+		return "", fmt.Errorf("invalid group name %q (use A-Z, a-z, 0-9, _ . -; max 64 characters)", v)
+	}
+	return g, nil
+}
+
+// parseAddressFilter は tag / group クエリを読む（/history・/balances・/addresses 共通）
+func parseAddressFilter(q url.Values) (store.AddressFilter, error) {
+	var f store.AddressFilter
+	if v := q.Get("tag"); v != "" {
+		t, err := parseTag(v)
+		if err != nil {
+			return f, err
+		}
+		f.Tag = &t
+	}
+	if v := q.Get("group"); v != "" {
+		g, err := parseGroupName(v)
+		if err != nil {
+			return f, err
+		}
+		f.Group = &g
+	}
+	return f, nil
+}
+
+// watchedParam は {chain}/{address} を読んで検証する
+func watchedParam(r *http.Request) (string, string, error) {
+	chain := strings.ToLower(chi.URLParam(r, "chain"))
+	addr := strings.TrimSpace(chi.URLParam(r, "address"))
+	if err := validateChainAndAddress(chain, addr); err != nil {
+		return "", "", err
+	}
+	return chain, addr, nil
+}
+
+func groupParam(r *http.Request) (string, error) {
+	return parseGroupName(chi.URLParam(r, "name"))
+}
+
+// handleListAddresses は監視アドレスとラベル・タグ・グループを返す（chain / tag / group で絞り込み）
+func (s *Server) handleListAddresses(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	chain, err := parseHistoryChain(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, err := parseAddressFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	list, err := s.Store.ListAddressMeta(ctx, chain, f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"addresses": list})
+}
+
+func (s *Server) handleGetAddress(w http.ResponseWriter, r *http.Request) {
+	chain, addr, err := watchedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	m, err := s.Store.GetAddressMeta(ctx, chain, addr)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "address is not watched", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(m)
+}
+
+type addressMetaReq struct {
+	Label *string  `json:"label"`
+	Tags  []string `json:"tags"`
+}
+
+// handleSetAddress は監視アドレスのラベルとタグを置き換える（label を null / 空にすると消える）
+func (s *Server) handleSetAddress(w http.ResponseWriter, r *http.Request) {
+	chain, addr, err := watchedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req addressMetaReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	label, err := parseLabel(req.Label)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTags(req.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if err := s.Store.SetAddressMeta(ctx, chain, addr, label, tags); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "address is not watched", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m, err := s.Store.GetAddressMeta(ctx, chain, addr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(m)
+}
+
+func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	list, err := s.Store.ListAddressGroups(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"groups": list})
+}
+
+type groupReq struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req groupReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	name, err := parseGroupName(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	g := store.AddressGroup{Name: name, Description: trimOpt(req.Description)}
+	if g.Description != nil && len(*g.Description) > 500 {
+		http.Error(w, "'description' is too long", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	switch err := s.Store.CreateAddressGroup(ctx, &g); {
+	case errors.Is(err, store.ErrConflict):
+		http.Error(w, "group already exists", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(g)
+}
+
+// handleGetGroup はグループと所属する監視アドレスを返す
+func (s *Server) handleGetGroup(w http.ResponseWriter, r *http.Request) {
+	name, err := groupParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	g, err := s.Store.GetAddressGroup(ctx, name)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "group not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	members, err := s.Store.ListAddressMeta(ctx, store.ChainAll, store.AddressFilter{Group: &name})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"group": g, "members": members})
+}
+
+func (s *Server) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	name, err := groupParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	switch err := s.Store.DeleteAddressGroup(ctx, name); {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "group not found", http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleAddGroupMember は監視アドレスをグループに加える（既に所属していても 204）
+func (s *Server) handleAddGroupMember(w http.ResponseWriter, r *http.Request) {
+	s.groupMember(w, r, s.Store.AddGroupMember, "group or watched address not found")
+}
+
+func (s *Server) handleRemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	s.groupMember(w, r, s.Store.RemoveGroupMember, "address is not a member of the group")
+}
+
+func (s *Server) groupMember(w http.ResponseWriter, r *http.Request, op func(ctx context.Context, group, chain, address string) error, notFound string) {
+	name, err := groupParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	chain, addr, err := watchedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	switch err := op(ctx, name, chain, addr); {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, notFound, http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/you/wallet-watcher/internal/notify"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+type channelReq struct {
+	Name    string          `json:"name"`
+	Type    string          `json:"type"`
+	Config  json.RawMessage `json:"config"`
+	Enabled *bool           `json:"enabled"`
+}
+
+// redactChannel は設定と最後のエラーの秘匿値を伏せたチャネルを返す
+func redactChannel(c store.NotificationChannel) store.NotificationChannel {
+	var cfg notify.Config
+	if json.Unmarshal(c.Config, &cfg) == nil {
+		c.Config, _ = json.Marshal(cfg.Redacted())
+		if c.LastError != nil {
+			msg := cfg.RedactText(*c.LastError)
+			c.LastError = &msg
+		}
+	} else {
+		c.Config = json.RawMessage(`{}`)
+		c.LastError = nil
+	}
+	return c
+}
+
+func channelID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid channel id")
+	}
+	return id, nil
+}
+
+func (s *Server) handleListChannels(w http.ResponseWriter, r *http.Request) {
+	tenant, err := tenantOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	chs, err := s.Store.ListNotificationChannels(ctx, tenant, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range chs {
+		chs[i] = redactChannel(chs[i])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"channels": chs})
+}
+
+func (s *Server) handleCreateChannel(w http.ResponseWriter, r *http.Request) {
+	tenant, err := tenantOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req channelReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	ch := store.NotificationChannel{
+		Tenant:  tenant,
+		Name:    strings.TrimSpace(req.Name),
+		Type:    strings.ToLower(strings.TrimSpace(req.Type)),
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	cfg, err := notify.ParseConfig(ch.Type, req.Config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// 未知のキーを落として正規化した設定を保存する
+	ch.Config, _ = json.Marshal(cfg)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if err := s.Store.CreateNotificationChannel(ctx, &ch); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(redactChannel(ch))
+}
+
+func (s *Server) handleDeleteChannel(w http.ResponseWriter, r *http.Request) {
+	tenant, err := tenantOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := channelID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	switch err := s.Store.DeleteNotificationChannel(ctx, tenant, id); {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "channel not found", http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleTestChannel はサンプルのアラートを送って設定を確認する（結果は失敗状況にも記録される）
+func (s *Server) handleTestChannel(w http.ResponseWriter, r *http.Request) {
+	tenant, err := tenantOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := channelID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	ch, err := s.Store.GetNotificationChannel(ctx, tenant, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "channel not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	amount, decimals, token := int64(1_500_000_000), 9, "SOL"
+	sample := store.Alert{
+		Tenant:   tenant,
+		RuleType: "test",
+		Chain:    "solana",
+		TxHash:   "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW",
+		TS:       time.Now().UTC(),
+		Token:    &token,
+		Amount:   &amount,
+		Decimals: &decimals,
+		Message:  "test notification from wallet-watcher",
+	}
+	sendErr := notify.SendAlert(ctx, *ch, sample)
+	_ = s.Store.RecordNotificationResult(ctx, ch.ID, sendErr)
+
+	resp := map[string]any{"ok": sendErr == nil}
+	if sendErr != nil {
+		resp["error"] = sendErr.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if sendErr != nil {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
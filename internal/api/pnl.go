@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/pnl"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// pnlQuery は /pnl のクエリ
+type pnlQuery struct {
+	chain, address, method string
+	year                   int
+	lots                   bool
+}
+
+// parsePnLQuery は /pnl のクエリを検証する（method 省略時は defMethod）
+func parsePnLQuery(q url.Values, defMethod string) (pnlQuery, error) {
+	pq := pnlQuery{
+		chain:   strings.ToLower(strings.TrimSpace(q.Get("chain"))),
+		address: strings.TrimSpace(q.Get("address")),
+		method:  strings.ToLower(strings.TrimSpace(q.Get("method"))),
+	}
+	if pq.chain == "" || pq.address == "" {
+		return pq, errors.New("chain and address parameters are required")
+	}
+	if err := validateChainAndAddress(pq.chain, pq.address); err != nil {
+		return pq, err
+	}
+	if pq.method == "" {
+		pq.method = defMethod
+	}
+	if pq.method == "" {
+		pq.method = pnl.FIFO
+	}
+	if !pnl.ValidMethod(pq.method) {
+		return pq, errors.New("method must be 'fifo', 'lifo' or 'average'")
+	}
+	if v := strings.TrimSpace(q.Get("year")); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 2000 || y > 2100 {
+			return pq, errors.New("invalid 'year' (use a 4-digit year)")
+		}
+		pq.year = y
+	}
+	if v := strings.TrimSpace(q.Get("lots")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return pq, errors.New("invalid 'lots' (use true or false)")
+		}
+		pq.lots = b
+	}
+	return pq, nil
+}
+
+// handlePnL はアドレスの全イベントを古い順に再生して取得ロット・処分を書き直し、
+// トークンごとの実現・含み損益と年ごとの譲渡損益を返す。
+// year を指定するとその年の処分と経費の明細を、lots=true なら未処分のロットを含める。
+func (s *Server) handlePnL(w http.ResponseWriter, r *http.Request) {
+	pq, err := parsePnLQuery(r.URL.Query(), s.PnLMethod)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	var events []pnl.Event
+	addr := pq.address
+	err = s.Store.StreamTxEvents(ctx, pq.chain, store.HistoryQuery{Address: &addr}, func(e store.TxEvent, raw []byte) error {
+		// raw があれば正規化し直してすべての移動を再生する（スワップの両方の脚を拾う）。無ければ保存済みの 1 行から
+		if ev, ok := normalize.FromRaw(pq.chain, e.TxHash, e.TS, raw); ok {
+			events = append(events, pnl.FromEvent(pq.address, ev)...)
+			return nil
+		}
+		events = append(events, pnl.FromTxEvent(pq.chain, pq.address, e))
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pnl.Price(ctx, s.Prices, events)
+	res := pnl.Replay(pq.method, events)
+	if err := s.Store.ReplaceCostBasis(ctx, pq.chain, pq.address, pq.method, res.Lots, res.Disposals); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 含み損益は保有数量を現在価格で評価する
+	market := map[string]float64{}
+	for _, v := range s.Prices.ValueBalances(ctx, res.Held()) {
+		if v.USDValue != nil {
+			market[v.Token] = *v.USDValue
+		}
+	}
+	rep := pnl.BuildReport(pq.chain, pq.address, res, pnl.ReportOptions{MarketUSD: market, Year: pq.year, OpenLots: pq.lots})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rep)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	solana "github.com/you/wallet-watcher/internal/chains/solana"
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/portfolio"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// 1 リクエストで残高を取るアドレス数の上限
+const (
+	labeledBalancesMax = 100
+	portfolioMax       = 500
+)
+
+// fetchBalances はチェーンの RPC から残高を取る FetchFunc を返す（rpc_url クエリも効く）
+func (s *Server) fetchBalances(r *http.Request) portfolio.FetchFunc {
+	return func(ctx context.Context, chain, address string) ([]store.TokenBalance, error) {
+		var out []store.TokenBalance
+		switch chain {
+		case "solana":
+			bs, err := solana.New(s.rpcURLFor(r, chain)).GetBalances(ctx, address)
+			if err != nil {
+				return nil, err
+			}
+			out = solanaBalances(bs)
+		case "sui":
+			bs, err := sui.New(s.rpcURLFor(r, chain)).GetBalances(ctx, address)
+			if err != nil {
+				return nil, err
+			}
+			out = suiBalances(bs)
+		default:
+			return nil, fmt.Errorf("unsupported chain: %s", chain)
+		}
+		return out, nil
+	}
+}
+
+// snapshots は残高スナップショットの保存先（Store が無ければ使わない）。
+// rpc_url で RPC を差し替えたリクエストは、その RPC の残高を保存せず、保存済みのものも返さない
+func (s *Server) snapshots(r *http.Request) portfolio.Snapshots {
+	if s.Store == nil || r.URL.Query().Get("rpc_url") != "" {
+		return nil
+	}
+	return s.Store
+}
+
+// watchedWallets は chain / tag / group に該当する監視アドレスを読む。
+// 不正なクエリや max 件を超えたときはエラーを書いて false を返す。
+func (s *Server) watchedWallets(w http.ResponseWriter, r *http.Request, max int) ([]portfolio.Wallet, bool) {
+	q := r.URL.Query()
+	chain, err := parseHistoryChain(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	f, err := parseAddressFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	list, err := s.Store.ListAddressMeta(ctx, chain, f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(list) > max {
+		http.Error(w, fmt.Sprintf("too many addresses (%d, max %d); narrow with chain, tag or group", len(list), max), http.StatusBadRequest)
+		return nil, false
+	}
+	wallets := make([]portfolio.Wallet, len(list))
+	for i, m := range list {
+		wallets[i] = portfolio.Wallet{Chain: m.Chain, Address: m.Address, Label: m.Label}
+	}
+	return wallets, true
+}
+
+// handlePortfolio は group / tag（省略時は全監視アドレス）の残高を並行に取得し、
+// チェーン × トークンの合計とアドレスごとの内訳を返す。
+// max_age（秒）以内のスナップショットがあるアドレスは RPC を呼ばない。
+func (s *Server) handlePortfolio(w http.ResponseWriter, r *http.Request) {
+	var maxAge time.Duration
+	if v := strings.TrimSpace(r.URL.Query().Get("max_age")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid 'max_age' (use a non-negative number of seconds)", http.StatusBadRequest)
+			return
+		}
+		maxAge = time.Duration(n) * time.Second
+	}
+	wallets, ok := s.watchedWallets(w, r, portfolioMax)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	balances := portfolio.Collect(ctx, wallets, s.fetchBalances(r), portfolio.Options{
+		MaxAge:    maxAge,
+		Snapshots: s.snapshots(r),
+	})
+	p := portfolio.Aggregate(balances)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(p)
+}
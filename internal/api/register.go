@@ -10,25 +10,93 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/health"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/metrics"
+	"github.com/you/wallet-watcher/internal/pricing"
 	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/stream"
+	"github.com/you/wallet-watcher/internal/tracing"
 )
 
-type Server struct{ Store *store.Store }
+type Server struct {
+	Store *store.Store
+	// Hub は /stream の配信元（nil なら /stream は 503）
+	Hub *stream.Hub
+	// Chains は /tx のオンデマンド取得に使う RPC の設定
+	Chains config.Chains
+	// Ready は /readyz の依存先チェック（nil なら /readyz は 503）
+	Ready *health.Checker
+	// Prices は /balances・/history の USD 評価（nil なら USD 項目を付けない）
+	Prices *pricing.Service
+	// PnLMethod は /pnl の既定の原価の計算方法（空なら fifo）
+	PnLMethod string
+}
 
 func Routes(s *Server) http.Handler {
 	r := chi.NewRouter()
+	// トレース → リクエスト ID とアクセスログ（trace_id 付き）→ メトリクス
+	r.Use(tracing.Middleware)
+	r.Use(logging.Middleware)
+	r.Use(metrics.Middleware)
+	r.Handle("/metrics", metrics.Handler())
+
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("ok"))
 	})
+	// liveness / readiness（DB・RPC・ワーカーのハートビート）
+	r.Get("/healthz", handleHealthz)
+	r.Get("/readyz", s.handleReadyz)
 	r.Post("/register", s.handleRegister)
+	r.Post("/register/bulk", s.handleRegisterBulk)
+
+	// 監視アドレスのラベル・タグ / グループ
+	r.Get("/addresses", s.handleListAddresses)
+	r.Get("/addresses/{chain}/{address}", s.handleGetAddress)
+	r.Put("/addresses/{chain}/{address}", s.handleSetAddress)
+	// アドレスの通算と日次の系列（日次集計から）
+	r.Get("/addresses/{chain}/{address}/summary", s.handleAddressSummary)
+	r.Get("/groups", s.handleListGroups)
+	r.Post("/groups", s.handleCreateGroup)
+	r.Get("/groups/{name}", s.handleGetGroup)
+	r.Delete("/groups/{name}", s.handleDeleteGroup)
+	r.Put("/groups/{name}/members/{chain}/{address}", s.handleAddGroupMember)
+	r.Delete("/groups/{name}/members/{chain}/{address}", s.handleRemoveGroupMember)
 
 	r.Get("/history", s.handleHistory)
+	r.Get("/history/export", s.handleHistoryExport)
+	r.Get("/tx/{chain}/{hash}", s.handleTxDetail)
+	// アドレス間の資金の流れ（近傍のグラフ）
+	r.Get("/graph/{chain}/{address}", s.handleGraph)
+
+	// アラートルール / アラート（テナントは X-Tenant-ID）
+	r.Get("/alerts/rules", s.handleListAlertRules)
+	r.Post("/alerts/rules", s.handleCreateAlertRule)
+	r.Delete("/alerts/rules/{id}", s.handleDeleteAlertRule)
+	r.Get("/alerts", s.handleListAlerts)
+
+	// 通知チャネル（Slack / Discord / Telegram / メール）
+	r.Get("/notify/channels", s.handleListChannels)
+	r.Post("/notify/channels", s.handleCreateChannel)
+	r.Delete("/notify/channels/{id}", s.handleDeleteChannel)
+	r.Post("/notify/channels/{id}/test", s.handleTestChannel)
+
+	// 新着イベントの配信（SSE / WebSocket）
+	r.Get("/stream", s.handleStream)
+	r.Get("/stream/ws", s.handleStreamWS)
 	
 	// Balances endpoints
 	r.Get("/balances", s.handleBalances)
 	r.Get("/balances/solana/{address}", s.handleSolanaBalances)
 	r.Get("/balances/sui/{address}", s.handleSuiBalances)
+
+	// 複数アドレス・チェーンの残高の合算
+	r.Get("/portfolio", s.handlePortfolio)
+
+	// 取得原価（FIFO / LIFO / 移動平均）と実現・含み損益
+	r.Get("/pnl", s.handlePnL)
 	
 	return r
 }
@@ -45,6 +113,11 @@ var (
 	reHex = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{40,64}$`) // Sui: 0x + hex（緩め）
 )
 
+// ValidateChainAndAddress は /register と同じ規則でチェーンとアドレスを検証する（walletctl 用）
+func ValidateChainAndAddress(chain, addr string) error {
+	return validateChainAndAddress(chain, addr)
+}
+
 func validateChainAndAddress(chain, addr string) error {
 	switch strings.ToLower(chain) {
 	case "solana":
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// /register/bulk の上限（本文のバイト数と件数）
+const (
+	bulkMaxBytes = 4 << 20
+	bulkMaxItems = 10000
+)
+
+// 一括登録の各項目の結果
+const (
+	bulkCreated  = "created"
+	bulkExisting = "existing"
+	bulkInvalid  = "invalid"
+)
+
+type bulkItemResult struct {
+	// Index は入力の何件目か（0 始まり。CSV のヘッダ行は数えない）
+	Index   int    `json:"index"`
+	Chain   string `json:"chain"`
+	Address string `json:"address"`
+	Result  string `json:"result"`
+	Error   string `json:"error,omitempty"`
+}
+
+type bulkResp struct {
+	Created  int              `json:"created"`
+	Existing int              `json:"existing"`
+	Invalid  int              `json:"invalid"`
+	Results  []bulkItemResult `json:"results"`
+}
+
+// errBulkTooMany は件数が bulkMaxItems を超えたとき
+var errBulkTooMany = fmt.Errorf("too many items (max %d)", bulkMaxItems)
+
+// handleRegisterBulk は JSON 配列（[{chain,address}...]）または CSV（chain,address）を受け取り、
+// 全件を検証してから有効なものを 1 トランザクションで登録する。
+// CSV は本文（Content-Type: text/csv）か multipart/form-data の file フィールドで送る。
+func (s *Server) handleRegisterBulk(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, bulkMaxBytes)
+	items, err := readBulkItems(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			http.Error(w, fmt.Sprintf("request body too large (max %d bytes)", bulkMaxBytes), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, errBulkTooMany):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	if len(items) == 0 {
+		http.Error(w, "no items", http.StatusBadRequest)
+		return
+	}
+
+	// 全件を検証し、有効なものだけを（リクエスト内の重複を除いて）登録対象にする
+	results := make([]bulkItemResult, len(items))
+	var keys []store.WatchKey
+	first := map[store.WatchKey]int{}
+	for i, it := range items {
+		res := bulkItemResult{Index: i, Chain: it.Chain, Address: it.Address}
+		if it.err != nil {
+			res.Result, res.Error = bulkInvalid, it.err.Error()
+		} else if err := validateChainAndAddress(it.Chain, it.Address); err != nil {
+			res.Result, res.Error = bulkInvalid, err.Error()
+		} else {
+			k := store.WatchKey{Chain: it.Chain, Address: it.Address}
+			if _, dup := first[k]; !dup {
+				first[k] = i
+				keys = append(keys, k)
+			}
+		}
+		results[i] = res
+	}
+
+	created := map[store.WatchKey]bool{}
+	if len(keys) > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		if created, err = s.Store.AddWatchedAddresses(ctx, keys); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp := bulkResp{Results: results}
+	for i := range results {
+		res := &results[i]
+		if res.Result == bulkInvalid {
+			resp.Invalid++
+			continue
+		}
+		// 新規に作られたのは最初に現れた項目だけ（同じリクエスト内の 2 件目以降は existing）
+		k := store.WatchKey{Chain: res.Chain, Address: res.Address}
+		if created[k] && first[k] == i {
+			res.Result = bulkCreated
+			resp.Created++
+		} else {
+			res.Result = bulkExisting
+			resp.Existing++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// bulkItem は入力の 1 件（err は CSV の列数違いなど、検証以前の誤り）
+type bulkItem struct {
+	Chain   string
+	Address string
+	err     error
+}
+
+// readBulkItems は Content-Type に応じて本文を項目の一覧にする（chain は小文字化、前後の空白は除く）
+func readBulkItems(r *http.Request) ([]bulkItem, error) {
+	ct := r.Header.Get("Content-Type")
+	mt := "application/json"
+	if ct != "" {
+		var err error
+		if mt, _, err = mime.ParseMediaType(ct); err != nil {
+			return nil, fmt.Errorf("invalid content type: %w", err)
+		}
+	}
+
+	var items []bulkItem
+	switch mt {
+	case "application/json":
+		var reqs []registerReq
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, err
+			}
+			return nil, errors.New("invalid json: want an array of {chain,address}")
+		}
+		if len(reqs) > bulkMaxItems {
+			return nil, errBulkTooMany
+		}
+		for _, q := range reqs {
+			items = append(items, bulkItem{Chain: q.Chain, Address: q.Address})
+		}
+	case "text/csv":
+		var err error
+		if items, err = readBulkCSV(r.Body); err != nil {
+			return nil, err
+		}
+	case "multipart/form-data":
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, err
+			}
+			return nil, errors.New("multipart form requires a CSV file field named 'file'")
+		}
+		defer f.Close()
+		if items, err = readBulkCSV(f); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content type: %s (application/json, text/csv or multipart/form-data)", mt)
+	}
+
+	for i := range items {
+		items[i].Chain = strings.ToLower(strings.TrimSpace(items[i].Chain))
+		items[i].Address = strings.TrimSpace(items[i].Address)
+	}
+	return items, nil
+}
+
+// readBulkCSV は chain,address の CSV を読む。
+// 1 行目が chain,address のヘッダなら読み飛ばし、空行と # で始まる行は無視する。
+func readBulkCSV(in io.Reader) ([]bulkItem, error) {
+	cr := csv.NewReader(in)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var items []bulkItem
+	for row := 0; ; row++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		if row == 0 && len(rec) >= 1 && strings.EqualFold(strings.TrimSpace(rec[0]), "chain") {
+			continue
+		}
+		if len(items) == bulkMaxItems {
+			return nil, errBulkTooMany
+		}
+		it := bulkItem{}
+		if len(rec) != 2 {
+			it.err = fmt.Errorf("want 2 columns (chain,address), got %d", len(rec))
+		} else {
+			it.Chain, it.Address = rec[0], rec[1]
+		}
+		items = append(items, it)
+	}
+}
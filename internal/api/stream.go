@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/stream"
+)
+
+const (
+	// maxStreamAddresses は 1 接続で購読できるアドレス数の上限
+	maxStreamAddresses = 100
+	// streamPingInterval は接続維持のための ping 間隔
+	streamPingInterval = 15 * time.Second
+)
+
+// parseStreamRequest は /stream の購読条件と再開位置を解釈する。
+// address はカンマ区切り・複数指定のどちらでもよい。
+// 再開位置は cursor（受け取った最後のイベントの cursor）、なければ SSE の Last-Event-ID を使う。
+func parseStreamRequest(r *http.Request) (stream.Filter, *int64, error) {
+	q := r.URL.Query()
+	chain, err := parseHistoryChain(q)
+	if err != nil {
+		return stream.Filter{}, nil, err
+	}
+	f := stream.Filter{Chain: chain}
+	for _, v := range q["address"] {
+		for _, a := range strings.Split(v, ",") {
+			a = strings.TrimSpace(a)
+			if a == "" {
+				continue
+			}
+			if err := validateHistoryAddress(chain, a); err != nil {
+				return f, nil, fmt.Errorf("invalid 'address' %q: %v", a, err)
+			}
+			f.Addresses = append(f.Addresses, a)
+		}
+	}
+	if len(f.Addresses) > maxStreamAddresses {
+		return f, nil, fmt.Errorf("too many addresses (max %d)", maxStreamAddresses)
+	}
+
+	after, err := parseStreamCursor(q, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		return f, nil, err
+	}
+	return f, after, nil
+}
+
+func parseStreamCursor(q url.Values, lastEventID string) (*int64, error) {
+	v := strings.TrimSpace(q.Get("cursor"))
+	if v == "" {
+		v = strings.TrimSpace(lastEventID)
+	}
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return nil, errors.New("invalid 'cursor' (use the cursor of the last received event)")
+	}
+	return &n, nil
+}
+
+// handleStream は新着イベントを Server-Sent Events で流す。
+// 各イベントの id に cursor を載せるので、EventSource の自動再接続（Last-Event-ID）で続きから再開できる。
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	f, after, err := parseStreamRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.Hub == nil {
+		http.Error(w, "stream is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// ping とイベント送信が同時に書き込まないようにする
+	var mu sync.Mutex
+	write := func(s string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := fmt.Fprint(w, s); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	if err := write("retry: 3000\n\n"); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		t := time.NewTicker(streamPingInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if write(": ping\n\n") != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err = s.Hub.Serve(ctx, f, after, func(e store.StreamEvent) error {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return write(fmt.Sprintf("id: %d\nevent: tx\ndata: %s\n\n", e.Cursor, b))
+	})
+	if err != nil && ctx.Err() == nil {
+		// 切断理由を伝える（クライアントは最後の id から再接続すれば補填される）
+		b, _ := json.Marshal(map[string]string{"error": err.Error()})
+		_ = write(fmt.Sprintf("event: error\ndata: %s\n\n", b))
+	}
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// 他の API と同様にオリジン制限はしない
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// handleStreamWS は /stream と同じ購読条件で新着イベントを WebSocket のテキストメッセージとして流す
+func (s *Server) handleStreamWS(w http.ResponseWriter, r *http.Request) {
+	f, after, err := parseStreamRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.Hub == nil {
+		http.Error(w, "stream is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade がエラーレスポンスを返している
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// 受信側: クライアントからのメッセージは使わないが、close / pong を処理するため読み続ける
+	conn.SetReadDeadline(time.Now().Add(2 * streamPingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * streamPingInterval))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		t := time.NewTicker(streamPingInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				// WriteControl は他の書き込みと並行に呼べる
+				if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)) != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err = s.Hub.Serve(ctx, f, after, func(e store.StreamEvent) error {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(e)
+	})
+	code, reason := websocket.CloseNormalClosure, ""
+	switch {
+	case ctx.Err() != nil:
+	case err != nil:
+		code, reason = websocket.CloseTryAgainLater, err.Error()
+	default:
+		// Hub が閉じられた（サーバー停止）。クライアントは最後の cursor から別のインスタンスへ再接続できる
+		code, reason = websocket.CloseGoingAway, "server shutting down"
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+}
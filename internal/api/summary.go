@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// 日次の系列に含める日数（既定と上限）
+const (
+	defaultSummaryDays = 30
+	maxSummaryDays     = 366
+)
+
+// parseSummaryQuery は days（今日を含む直近の日数）と token を読む
+func parseSummaryQuery(q url.Values, now time.Time) (store.SummaryQuery, error) {
+	days := defaultSummaryDays
+	if v := strings.TrimSpace(q.Get("days")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSummaryDays {
+			return store.SummaryQuery{}, errors.New("invalid 'days' (1-366)")
+		}
+		days = n
+	}
+	sq := store.SummaryQuery{Since: now.UTC().AddDate(0, 0, -(days - 1))}
+	if v := strings.TrimSpace(q.Get("token")); v != "" {
+		if len(v) > 256 {
+			return sq, errors.New("'token' is too long")
+		}
+		sq.Token = &v
+	}
+	return sq, nil
+}
+
+// handleAddressSummary は日次集計（address_daily）からアドレスの初回・最終の出現、
+// トークンごとの通算と直近 days 日の日次の系列を返す（tx_events_* は読まない）
+func (s *Server) handleAddressSummary(w http.ResponseWriter, r *http.Request) {
+	chain, addr, err := watchedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sq, err := parseSummaryQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	sum, err := s.Store.GetAddressSummary(ctx, chain, addr, sq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sum)
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	solana "github.com/you/wallet-watcher/internal/chains/solana"
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// rpcURLFor は rpc_url クエリ → 設定（chains.<chain>）の順で RPC エンドポイントを決める。
+// 設定が空（テスト等）なら mainnet の公開エンドポイントを使う。
+func (s *Server) rpcURLFor(r *http.Request, chain string) string {
+	if v := r.URL.Query().Get("rpc_url"); v != "" {
+		return v
+	}
+	if v := s.Chains.RPC(chain); v != "" {
+		return v
+	}
+	return config.Default().Chains.RPC(chain)
+}
+
+// suiDecimals は Sui のコインの桁数の引き方。rpc_url で RPC を差し替えたときは
+// その応答を token_decimals に登録しないよう、登録済みの桁数だけを見る
+func (s *Server) suiDecimals(r *http.Request) *normalize.SuiDecimals {
+	d := &normalize.SuiDecimals{Store: s.Store}
+	if r.URL.Query().Get("rpc_url") == "" {
+		d.Client = sui.New(s.rpcURLFor(r, "sui"))
+	}
+	return d
+}
+
+// TxDetailResponse represents the response for /tx/{chain}/{hash}
+type TxDetailResponse struct {
+	Chain string `json:"chain"`
+	// Stored は DB に保存済みか（false なら RPC からオンデマンド取得した結果）
+	Stored       bool                 `json:"stored"`
+	Event        store.TxEvent        `json:"event"`
+	Transfers    []normalize.Transfer `json:"transfers"`
+	Participants []store.Participant  `json:"participants"`
+	Fee          map[string]int64     `json:"fee"`
+	Raw          json.RawMessage      `json:"raw,omitempty"`
+}
+
+func validateTxHash(hash string) error {
+	// Solana の署名 / Sui のダイジェストはどちらも Base58
+	if len(hash) < 32 || len(hash) > 100 || !reB58.MatchString(hash) {
+		return errors.New("invalid tx hash")
+	}
+	return nil
+}
+
+func (s *Server) handleTxDetail(w http.ResponseWriter, r *http.Request) {
+	chain := strings.ToLower(chi.URLParam(r, "chain"))
+	hash := chi.URLParam(r, "hash")
+	if chain != "solana" && chain != "sui" {
+		http.Error(w, "chain must be 'solana' or 'sui'", http.StatusBadRequest)
+		return
+	}
+	if err := validateTxHash(hash); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	withRaw, _ := strconv.ParseBool(r.URL.Query().Get("raw"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var resp TxDetailResponse
+	d, err := s.Store.GetTxEvent(ctx, chain, hash)
+	switch {
+	case err == nil:
+		resp = storedTxDetail(ctx, chain, d, s.suiDecimals(r))
+	case errors.Is(err, store.ErrNotFound):
+		// 未保存なら RPC から取得して同じ形に正規化する
+		ev, err := fetchTx(ctx, chain, s.rpcURLFor(r, chain), hash)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to fetch tx: %v", err), http.StatusBadGateway)
+			return
+		}
+		if ev == nil {
+			http.Error(w, "tx not found", http.StatusNotFound)
+			return
+		}
+		s.suiDecimals(r).Apply(ctx, ev)
+		resp = TxDetailResponse{
+			Chain:        chain,
+			Event:        ev.TxEvent(),
+			Transfers:    ev.Transfers,
+			Participants: ev.Participants,
+			Fee:          ev.FeeBreakdown,
+			Raw:          ev.Raw,
+		}
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !withRaw {
+		resp.Raw = nil
+	}
+	if resp.Transfers == nil {
+		resp.Transfers = []normalize.Transfer{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// storedTxDetail は保存済みの raw を再度正規化して移動・手数料内訳を復元する
+func storedTxDetail(ctx context.Context, chain string, d *store.TxDetail, dec *normalize.SuiDecimals) TxDetailResponse {
+	resp := TxDetailResponse{
+		Chain:        chain,
+		Stored:       true,
+		Event:        d.Event,
+		Participants: d.Participants,
+		Raw:          d.Raw,
+	}
+	if ev, ok := normalize.FromRaw(chain, d.Event.TxHash, d.Event.TS, d.Raw); ok {
+		dec.Apply(ctx, &ev)
+		resp.Transfers = ev.Transfers
+		resp.Fee = ev.FeeBreakdown
+	}
+	return resp
+}
+
+// fetchTx は RPC から Tx を取得して正規化する（存在しなければ nil）
+func fetchTx(ctx context.Context, chain, rpcURL, hash string) (*normalize.Event, error) {
+	switch chain {
+	case "solana":
+		tx, err := solana.New(rpcURL).GetTransaction(ctx, hash)
+		if err != nil || tx == nil {
+			return nil, err
+		}
+		ev := normalize.Solana(hash, tx)
+		return &ev, nil
+	case "sui":
+		tx, err := sui.New(rpcURL).GetTransactionBlockDetailed(ctx, hash)
+		if errors.Is(err, sui.ErrTxNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		ev := normalize.Sui(tx, 0)
+		ev.TxHash = hash
+		return &ev, nil
+	default:
+		return nil, fmt.Errorf("unsupported chain: %s", chain)
+	}
+}
@@ -8,6 +8,11 @@ import (
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/metrics"
+	"github.com/you/wallet-watcher/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Client struct {
@@ -24,6 +29,27 @@ func New(url string) *Client {
 	}
 }
 
+var rpcTracer = tracing.Tracer("chains/solana")
+
+// begin は RPC 呼び出し 1 回分のスパンを開始し、終了時に呼ぶ関数を返す。
+// 終了時にスパンを閉じ、レイテンシとエラーをメトリクスと debug ログに記録する。
+func (c *Client) begin(ctx context.Context, method string) (context.Context, func(error)) {
+	start := time.Now()
+	endpoint := metrics.Endpoint(c.URL)
+	ctx, span := rpcTracer.Start(ctx, "solana."+method, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracing.RPCAttrs("solana", method, endpoint)...))
+	return ctx, func(err error) {
+		tracing.End(span, err)
+		metrics.ObserveRPC("solana", method, c.URL, start, err)
+		args := []any{"rpc", "solana", "method", method, "duration_ms", time.Since(start).Milliseconds()}
+		if err != nil {
+			args = append(args, "err", err)
+		}
+		// 失敗の扱いは呼び出し元が決めてログに残す
+		logging.FromContext(ctx).Debug("rpc call", args...)
+	}
+}
+
 // ---- JSON-RPC payload ----
 type rpcRequest struct {
 	Jsonrpc string      `json:"jsonrpc"`
@@ -50,16 +76,28 @@ type rpcErrorBody struct {
 }
 
 func (c *Client) GetSignaturesForAddress(ctx context.Context, address string, limit int) ([]SigInfo, error) {
+	return c.GetSignaturesForAddressBefore(ctx, address, limit, "")
+}
+
+// GetSignaturesForAddressBefore は before の署名より古いものを新しい順に最大 limit 件返す
+// （before が空なら最新から。古い範囲を遡るバックフィルに使う）
+func (c *Client) GetSignaturesForAddressBefore(ctx context.Context, address string, limit int, before string) (_ []SigInfo, err error) {
+	ctx, done := c.begin(ctx, "getSignaturesForAddress")
+	defer func() { done(err) }()
 	if limit <= 0 {
 		limit = 1
 	}
+	opts := map[string]any{"limit": limit}
+	if before != "" {
+		opts["before"] = before
+	}
 	req := rpcRequest{
 		Jsonrpc: "2.0",
 		ID:      1,
 		Method:  "getSignaturesForAddress",
 		Params: []interface{}{
 			address,
-			map[string]int{"limit": limit},
+			opts,
 		},
 	}
 	b, _ := json.Marshal(req)
@@ -82,6 +120,43 @@ func (c *Client) GetSignaturesForAddress(ctx context.Context, address string, li
 	return out.Result, nil
 }
 
+// ---- getSlot ----
+
+type getSlotResp struct {
+	Result uint64        `json:"result"`
+	Error  *rpcErrorBody `json:"error,omitempty"`
+}
+
+// GetSlot は最新の slot を返す（カーソル遅れの計測に使う）
+func (c *Client) GetSlot(ctx context.Context) (_ int64, err error) {
+	ctx, done := c.begin(ctx, "getSlot")
+	defer func() { done(err) }()
+	req := rpcRequest{
+		Jsonrpc: "2.0",
+		ID:      1,
+		Method:  "getSlot",
+		Params:  []interface{}{map[string]string{"commitment": "confirmed"}},
+	}
+	b, _ := json.Marshal(req)
+	httpReq, _ := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(b))
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var out getSlotResp
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, err
+	}
+	if out.Error != nil {
+		return 0, fmt.Errorf("rpc error %d: %s", out.Error.Code, out.Error.Message)
+	}
+	return int64(out.Result), nil
+}
+
 // ---- getTransaction ----
 
 type getTxResp struct {
@@ -101,15 +176,45 @@ type TxMeta struct {
 	Err         interface{} `json:"err"`
 	PreBalances []uint64    `json:"preBalances"`
 	PostBalances []uint64   `json:"postBalances"`
+	PreTokenBalances  []TokenBalance `json:"preTokenBalances"`
+	PostTokenBalances []TokenBalance `json:"postTokenBalances"`
+	// LoadedAddresses は v0 Tx がアドレス参照テーブルから読み込んだアカウント。
+	// 残高配列は accountKeys → writable → readonly の順で並ぶ
+	LoadedAddresses *LoadedAddresses `json:"loadedAddresses,omitempty"`
+}
+
+// LoadedAddresses はアドレス参照テーブル経由のアカウント（v0 Tx のみ）
+type LoadedAddresses struct {
+	Writable []string `json:"writable"`
+	Readonly []string `json:"readonly"`
+}
+
+// TokenBalance は SPL トークンアカウントの Tx 前後残高
+type TokenBalance struct {
+	AccountIndex  int    `json:"accountIndex"`
+	Mint          string `json:"mint"`
+	Owner         string `json:"owner"`
+	UITokenAmount struct {
+		Amount   string `json:"amount"`
+		Decimals int    `json:"decimals"`
+	} `json:"uiTokenAmount"`
 }
 type EncodedTx struct {
 	Message struct {
-		AccountKeys []string `json:"accountKeys"`
+		AccountKeys  []string      `json:"accountKeys"`
+		Instructions []Instruction `json:"instructions"`
 	} `json:"message"`
 	Signatures []string `json:"signatures"`
 }
 
-func (c *Client) GetTransaction(ctx context.Context, signature string) (*TransactionWithMeta, error) {
+// Instruction はトップレベルの命令（呼び出し先プログラムは accountKeys の添字）
+type Instruction struct {
+	ProgramIDIndex int `json:"programIdIndex"`
+}
+
+func (c *Client) GetTransaction(ctx context.Context, signature string) (_ *TransactionWithMeta, err error) {
+	ctx, done := c.begin(ctx, "getTransaction")
+	defer func() { done(err) }()
 	req := rpcRequest{
 		Jsonrpc: "2.0",
 		ID:      1,
@@ -203,7 +308,9 @@ func (c *Client) GetBalances(ctx context.Context, address string) ([]Balance, er
 	return balances, nil
 }
 
-func (c *Client) getSOLBalance(ctx context.Context, address string) (uint64, error) {
+func (c *Client) getSOLBalance(ctx context.Context, address string) (_ uint64, err error) {
+	ctx, done := c.begin(ctx, "getBalance")
+	defer func() { done(err) }()
 	req := rpcRequest{
 		Jsonrpc: "2.0",
 		ID:      1,
@@ -232,7 +339,9 @@ func (c *Client) getSOLBalance(ctx context.Context, address string) (uint64, err
 	return out.Result.Value, nil
 }
 
-func (c *Client) getTokenBalances(ctx context.Context, address string) ([]Balance, error) {
+func (c *Client) getTokenBalances(ctx context.Context, address string) (_ []Balance, err error) {
+	ctx, done := c.begin(ctx, "getTokenAccountsByOwner")
+	defer func() { done(err) }()
 	req := rpcRequest{
 		Jsonrpc: "2.0",
 		ID:      1,
@@ -4,10 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/metrics"
+	"github.com/you/wallet-watcher/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Client struct {
@@ -41,7 +48,27 @@ type rpcEnvelope struct {
 	Error  *rpcError       `json:"error,omitempty"`
 }
 
-func (c *Client) call(ctx context.Context, method string, params any, out any) *rpcError {
+var rpcTracer = tracing.Tracer("chains/sui")
+
+func (c *Client) call(ctx context.Context, method string, params any, out any) (rerr *rpcError) {
+	start := time.Now()
+	ctx, span := rpcTracer.Start(ctx, "sui."+method, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracing.RPCAttrs("sui", method, metrics.Endpoint(c.URL))...))
+	defer func() {
+		var err error
+		if rerr != nil {
+			err = rerr
+		}
+		tracing.End(span, err)
+		metrics.ObserveRPC("sui", method, c.URL, start, err)
+		// suix_* → sui_* のフォールバックがあるため失敗も debug に留める（最終的な失敗は呼び出し元が記録する）
+		args := []any{"rpc", "sui", "method", method, "duration_ms", time.Since(start).Milliseconds()}
+		if err != nil {
+			args = append(args, "err", err)
+		}
+		logging.FromContext(ctx).Debug("rpc call", args...)
+	}()
+
 	b, _ := json.Marshal(rpcRequest{Jsonrpc: "2.0", ID: 1, Method: method, Params: params})
 	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(b))
 	req.Header.Set("Content-Type", "application/json")
@@ -208,6 +235,15 @@ func (c *Client) GetCheckpointSummary(ctx context.Context, cursor *string, limit
 	return &result, nil
 }
 
+// GetLatestCheckpoint は最新の checkpoint 番号を返す（カーソル遅れの計測に使う）
+func (c *Client) GetLatestCheckpoint(ctx context.Context) (int64, error) {
+	var seq Uint64Flex
+	if err := c.call(ctx, "sui_getLatestCheckpointSequenceNumber", []any{}, &seq); err != nil {
+		return 0, err
+	}
+	return int64(seq), nil
+}
+
 /* -------- getTransactionBlock (詳細版) -------- */
 
 type TransactionBlockDetailed struct {
@@ -215,6 +251,7 @@ type TransactionBlockDetailed struct {
 	TimestampMs *Uint64Flex `json:"timestampMs"`
 	Transaction struct {
 		Data struct {
+			Sender  string `json:"sender"`
 			Message struct {
 				Inputs []struct {
 					Type string `json:"type"`
@@ -240,6 +277,43 @@ type TransactionBlockDetailed struct {
 		} `json:"gasUsed"`
 		TransactionDigest string `json:"transactionDigest"`
 	} `json:"effects"`
+	BalanceChanges []BalanceChange `json:"balanceChanges"`
+}
+
+// BalanceChange は Tx によるアドレスごとのコイン残高変化（amount は符号付き）
+type BalanceChange struct {
+	Owner    BalanceOwner `json:"owner"`
+	CoinType string       `json:"coinType"`
+	Amount   string       `json:"amount"`
+}
+
+// BalanceOwner は {"AddressOwner": "0x..."} / {"ObjectOwner": ...} / "Immutable" 等を受ける
+type BalanceOwner struct {
+	AddressOwner string `json:"AddressOwner,omitempty"`
+	ObjectOwner  string `json:"ObjectOwner,omitempty"`
+}
+
+func (o *BalanceOwner) UnmarshalJSON(b []byte) error {
+	// 文字列（"Immutable" 等）はアドレスを持たない
+	if len(b) > 0 && b[0] == '"' {
+		return nil
+	}
+	type plain BalanceOwner
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*o = BalanceOwner(p)
+	return nil
+}
+
+// ErrTxNotFound はダイジェストの Tx がノードに無い（未確定・存在しない）ことを表す
+var ErrTxNotFound = errors.New("transaction not found")
+
+// isTxNotFound は sui_getTransactionBlock の「Tx が無い」エラーか
+// （ノードは専用のコードを返さず -32602 と "Could not find the referenced transaction" で返す）
+func isTxNotFound(e *rpcError) bool {
+	return strings.Contains(e.Message, "Could not find the referenced transaction")
 }
 
 func (c *Client) GetTransactionBlockDetailed(ctx context.Context, digest string) (*TransactionBlockDetailed, error) {
@@ -256,11 +330,31 @@ func (c *Client) GetTransactionBlockDetailed(ctx context.Context, digest string)
 	
 	var result TransactionBlockDetailed
 	if err := c.call(ctx, "sui_getTransactionBlock", params, &result); err != nil {
+		if isTxNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrTxNotFound, digest)
+		}
 		return nil, fmt.Errorf("rpc error %d: %s", err.Code, err.Message)
 	}
 	return &result, nil
 }
 
+/* -------- GetCoinMetadata -------- */
+
+// CoinMetadata は suix_getCoinMetadata の結果（必要な項目のみ）
+type CoinMetadata struct {
+	Decimals int    `json:"decimals"`
+	Symbol   string `json:"symbol"`
+}
+
+// GetCoinMetadata はコインの型（例: 0x...::usdc::USDC）の桁数とシンボルを返す（メタデータが無ければ nil）
+func (c *Client) GetCoinMetadata(ctx context.Context, coinType string) (*CoinMetadata, error) {
+	var result *CoinMetadata
+	if err := c.call(ctx, "suix_getCoinMetadata", []any{coinType}, &result); err != nil {
+		return nil, fmt.Errorf("rpc error %d: %s", err.Code, err.Message)
+	}
+	return result, nil
+}
+
 /* -------- GetBalances -------- */
 
 type Balance struct {
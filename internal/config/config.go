@@ -0,0 +1,480 @@
+// Package config は全バイナリの設定を型付きの構造体として読み込む。
+// 既定値 → 設定ファイル（YAML / TOML）→ 環境変数の順に上書きし、最後に検証する。
+// 不正な値は起動時にまとめてエラーにする（黙って既定値に戻さない）。
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config はすべての設定。各バイナリは必要な部分だけを使う。
+type Config struct {
+	DB        DB        `yaml:"db" toml:"db" json:"db"`
+	Chains    Chains    `yaml:"chains" toml:"chains" json:"chains"`
+	Worker    Worker    `yaml:"worker" toml:"worker" json:"worker"`
+	API       API       `yaml:"api" toml:"api" json:"api"`
+	Publisher Publisher `yaml:"publisher" toml:"publisher" json:"publisher"`
+	Notify    Notify    `yaml:"notify" toml:"notify" json:"notify"`
+	Pricing   Pricing   `yaml:"pricing" toml:"pricing" json:"pricing"`
+	PnL       PnL       `yaml:"pnl" toml:"pnl" json:"pnl"`
+	Retention Retention `yaml:"retention" toml:"retention" json:"retention"`
+}
+
+// DB は Postgres の接続とプールの設定
+type DB struct {
+	// URL は接続文字列（DATABASE_URL）。パスワードを含むため表示時は伏せる。
+	URL             string   `yaml:"url" toml:"url" json:"url"`
+	MaxConns        int32    `yaml:"max_conns" toml:"max_conns" json:"max_conns"`
+	MinConns        int32    `yaml:"min_conns" toml:"min_conns" json:"min_conns"`
+	MaxConnLifetime Duration `yaml:"max_conn_lifetime" toml:"max_conn_lifetime" json:"max_conn_lifetime"`
+	MaxConnIdleTime Duration `yaml:"max_conn_idle_time" toml:"max_conn_idle_time" json:"max_conn_idle_time"`
+	// ConnectTimeout は起動時の接続確認（Ping）の上限
+	ConnectTimeout Duration `yaml:"connect_timeout" toml:"connect_timeout" json:"connect_timeout"`
+}
+
+// チェーンのネットワーク
+const (
+	NetworkMainnet  = "mainnet"
+	NetworkTestnet  = "testnet"
+	NetworkDevnet   = "devnet"
+	NetworkLocalnet = "localnet"
+)
+
+// Chain は 1 チェーン分の接続先。RPCURL が空ならネットワークの公開エンドポイントを使う。
+type Chain struct {
+	Network string `yaml:"network" toml:"network" json:"network"`
+	RPCURL  string `yaml:"rpc_url" toml:"rpc_url" json:"rpc_url"`
+}
+
+// Chains はチェーンごとの接続先
+type Chains struct {
+	Solana Chain `yaml:"solana" toml:"solana" json:"solana"`
+	Sui    Chain `yaml:"sui" toml:"sui" json:"sui"`
+}
+
+var publicRPC = map[string]map[string]string{
+	"solana": {
+		NetworkMainnet:  "https://api.mainnet-beta.solana.com",
+		NetworkTestnet:  "https://api.testnet.solana.com",
+		NetworkDevnet:   "https://api.devnet.solana.com",
+		NetworkLocalnet: "http://127.0.0.1:8899",
+	},
+	"sui": {
+		NetworkMainnet:  "https://fullnode.mainnet.sui.io:443",
+		NetworkTestnet:  "https://fullnode.testnet.sui.io:443",
+		NetworkDevnet:   "https://fullnode.devnet.sui.io:443",
+		NetworkLocalnet: "http://127.0.0.1:9000",
+	},
+}
+
+// Get は chain（"solana" / "sui"）の設定を返す
+func (c Chains) Get(chain string) (Chain, bool) {
+	switch chain {
+	case "solana":
+		return c.Solana, true
+	case "sui":
+		return c.Sui, true
+	}
+	return Chain{}, false
+}
+
+// RPC は chain の RPC エンドポイントを返す（rpc_url → ネットワークの公開エンドポイント）
+func (c Chains) RPC(chain string) string {
+	ch, ok := c.Get(chain)
+	if !ok {
+		return ""
+	}
+	if ch.RPCURL != "" {
+		return ch.RPCURL
+	}
+	return publicRPC[chain][ch.Network]
+}
+
+// Worker はチェーンワーカーの設定
+type Worker struct {
+	PollInterval Duration `yaml:"poll_interval" toml:"poll_interval" json:"poll_interval"`
+	// BatchSize は 1 アドレスあたり一度に取得する最大件数
+	BatchSize int `yaml:"batch_size" toml:"batch_size" json:"batch_size"`
+	// MaxAddresses は 1 tick で処理するアドレス数の上限
+	MaxAddresses int `yaml:"max_addresses" toml:"max_addresses" json:"max_addresses"`
+	// MetricsPort は /metrics のポート（"9090"）または "host:port"
+	MetricsPort   string   `yaml:"metrics_port" toml:"metrics_port" json:"metrics_port"`
+	ShutdownGrace Duration `yaml:"shutdown_grace" toml:"shutdown_grace" json:"shutdown_grace"`
+}
+
+// API は API サーバーの設定
+type API struct {
+	Port          int      `yaml:"port" toml:"port" json:"port"`
+	ShutdownGrace Duration `yaml:"shutdown_grace" toml:"shutdown_grace" json:"shutdown_grace"`
+	Ready         Ready    `yaml:"ready" toml:"ready" json:"ready"`
+}
+
+// Ready は /readyz の閾値
+type Ready struct {
+	CheckTimeout Duration `yaml:"check_timeout" toml:"check_timeout" json:"check_timeout"`
+	// MaxHeartbeatAge は 0 なら確認しない
+	MaxHeartbeatAge Duration `yaml:"max_heartbeat_age" toml:"max_heartbeat_age" json:"max_heartbeat_age"`
+	// MaxCursorLag は slot / checkpoint 数。0 なら確認しない
+	MaxCursorLag int64    `yaml:"max_cursor_lag" toml:"max_cursor_lag" json:"max_cursor_lag"`
+	Chains       []string `yaml:"chains" toml:"chains" json:"chains"`
+}
+
+// 配信先の種類（publisher.sink）
+const (
+	SinkRedis = "redis"
+	SinkNATS  = "nats"
+	SinkKafka = "kafka"
+	SinkFile  = "file"
+)
+
+// Publisher はアウトボックス中継の設定
+type Publisher struct {
+	Sink         string   `yaml:"sink" toml:"sink" json:"sink"`
+	PollInterval Duration `yaml:"poll_interval" toml:"poll_interval" json:"poll_interval"`
+	BatchSize    int      `yaml:"batch_size" toml:"batch_size" json:"batch_size"`
+	// Retention は配信済みの行を残す期間（0 なら削除しない）
+	Retention     Duration `yaml:"retention" toml:"retention" json:"retention"`
+	ShutdownGrace Duration `yaml:"shutdown_grace" toml:"shutdown_grace" json:"shutdown_grace"`
+	Redis         Redis    `yaml:"redis" toml:"redis" json:"redis"`
+	NATS          NATS     `yaml:"nats" toml:"nats" json:"nats"`
+	Kafka         Kafka    `yaml:"kafka" toml:"kafka" json:"kafka"`
+	File          File     `yaml:"file" toml:"file" json:"file"`
+}
+
+type Redis struct {
+	URL    string `yaml:"url" toml:"url" json:"url"`
+	Stream string `yaml:"stream" toml:"stream" json:"stream"`
+}
+
+type NATS struct {
+	URL     string `yaml:"url" toml:"url" json:"url"`
+	Subject string `yaml:"subject" toml:"subject" json:"subject"`
+}
+
+type Kafka struct {
+	Brokers []string `yaml:"brokers" toml:"brokers" json:"brokers"`
+	Topic   string   `yaml:"topic" toml:"topic" json:"topic"`
+}
+
+type File struct {
+	Path string `yaml:"path" toml:"path" json:"path"`
+}
+
+// Notify はアラート通知の設定（通知先そのものはテナントごとに DB で管理する）
+type Notify struct {
+	// Enabled が false ならワーカーはアラートを記録するだけで送信しない
+	Enabled bool `yaml:"enabled" toml:"enabled" json:"enabled"`
+	// SendTimeout は 1 チャネルへの 1 回の送信の上限
+	SendTimeout Duration `yaml:"send_timeout" toml:"send_timeout" json:"send_timeout"`
+}
+
+// 価格の取得元（pricing.source）。空なら token_prices の価格表だけを使う
+const (
+	PriceSourceFile = "file"
+	PriceSourceHTTP = "http"
+)
+
+// Pricing は USD 評価の設定
+type Pricing struct {
+	Source string `yaml:"source" toml:"source" json:"source"`
+	// File は source が file のときの価格ファイル（.csv / .json）
+	File string    `yaml:"file" toml:"file" json:"file"`
+	HTTP PriceHTTP `yaml:"http" toml:"http" json:"http"`
+	// Fresh は現在価格として価格表の値をそのまま使う期間
+	Fresh Duration `yaml:"fresh" toml:"fresh" json:"fresh"`
+	// Tolerance は評価時刻からさかのぼって価格を探す範囲
+	Tolerance Duration `yaml:"tolerance" toml:"tolerance" json:"tolerance"`
+}
+
+// PriceHTTP は CoinGecko 互換の価格 API
+type PriceHTTP struct {
+	URL          string `yaml:"url" toml:"url" json:"url"`
+	APIKey       string `yaml:"api_key" toml:"api_key" json:"api_key"`
+	APIKeyHeader string `yaml:"api_key_header" toml:"api_key_header" json:"api_key_header"`
+	// IDs はトークン → プロバイダのコイン ID
+	IDs map[string]string `yaml:"ids" toml:"ids" json:"ids"`
+}
+
+// PnL は取得原価・損益の設定
+type PnL struct {
+	// Method は既定の原価の計算方法（fifo / lifo / average）。/pnl の method で上書きできる
+	Method string `yaml:"method" toml:"method" json:"method"`
+}
+
+// raw の保持期限を過ぎた行の扱い（retention.raw_mode）
+const (
+	RawCompress = "compress"
+	RawDrop     = "drop"
+)
+
+// Retention は tx_events_* の月パーティションの保守の設定（ワーカーが定期的に行う）
+type Retention struct {
+	// Interval は保守の間隔
+	Interval Duration `yaml:"interval" toml:"interval" json:"interval"`
+	// PartitionsAhead は当月から何か月先までのパーティションを作っておくか
+	PartitionsAhead int `yaml:"partitions_ahead" toml:"partitions_ahead" json:"partitions_ahead"`
+	// RawAfter を過ぎた行の raw を RawMode で処理する（0 なら残す）
+	RawAfter Duration `yaml:"raw_after" toml:"raw_after" json:"raw_after"`
+	RawMode  string   `yaml:"raw_mode" toml:"raw_mode" json:"raw_mode"`
+	// ArchiveAfter を過ぎた月を ArchiveDir に NDJSON.gz で書き出して切り離す（0 ならしない）
+	ArchiveAfter Duration `yaml:"archive_after" toml:"archive_after" json:"archive_after"`
+	ArchiveDir   string   `yaml:"archive_dir" toml:"archive_dir" json:"archive_dir"`
+	// BatchSize は raw の処理で 1 回に更新する行数
+	BatchSize int `yaml:"batch_size" toml:"batch_size" json:"batch_size"`
+}
+
+// Default は既定値の設定を返す（従来の環境変数の既定値と同じ）
+func Default() Config {
+	return Config{
+		DB: DB{
+			MaxConns:        5,
+			MaxConnLifetime: Duration(time.Hour),
+			MaxConnIdleTime: Duration(30 * time.Minute),
+			ConnectTimeout:  Duration(5 * time.Second),
+		},
+		Chains: Chains{
+			Solana: Chain{Network: NetworkMainnet},
+			Sui:    Chain{Network: NetworkMainnet},
+		},
+		Worker: Worker{
+			PollInterval:  Duration(5 * time.Second),
+			BatchSize:     10,
+			MaxAddresses:  200,
+			MetricsPort:   "9090",
+			ShutdownGrace: Duration(20 * time.Second),
+		},
+		API: API{
+			Port:          8080,
+			ShutdownGrace: Duration(20 * time.Second),
+			Ready: Ready{
+				CheckTimeout:    Duration(2 * time.Second),
+				MaxHeartbeatAge: Duration(2 * time.Minute),
+				Chains:          []string{"solana", "sui"},
+			},
+		},
+		Publisher: Publisher{
+			PollInterval:  Duration(time.Second),
+			BatchSize:     100,
+			Retention:     Duration(7 * 24 * time.Hour),
+			ShutdownGrace: Duration(20 * time.Second),
+			Redis:         Redis{URL: "redis://localhost:6379/0", Stream: "wallet-watcher:events"},
+			NATS:          NATS{URL: "nats://localhost:4222", Subject: "wallet-watcher.events"},
+			Kafka:         Kafka{Topic: "wallet-watcher.events"},
+			File:          File{Path: "outbox.ndjson"},
+		},
+		Notify: Notify{
+			Enabled:     true,
+			SendTimeout: Duration(10 * time.Second),
+		},
+		Pricing: Pricing{
+			HTTP: PriceHTTP{
+				URL:          "https://api.coingecko.com/api/v3",
+				APIKeyHeader: "x-cg-demo-api-key",
+				IDs:          map[string]string{"SOL": "solana", "SUI": "sui"},
+			},
+			Fresh:     Duration(10 * time.Minute),
+			Tolerance: Duration(24 * time.Hour),
+		},
+		PnL: PnL{Method: "fifo"},
+		Retention: Retention{
+			Interval:        Duration(time.Hour),
+			PartitionsAhead: 3,
+			RawMode:         RawCompress,
+			ArchiveDir:      "archive",
+			BatchSize:       1000,
+		},
+	}
+}
+
+// Validate は値の範囲・形式を確認し、問題をすべてまとめて返す
+func (c *Config) Validate() error {
+	var errs []error
+	bad := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.DB.URL == "" {
+		bad("db.url", "is required (DATABASE_URL)")
+	}
+	if c.DB.MaxConns <= 0 {
+		bad("db.max_conns", "must be > 0, got %d", c.DB.MaxConns)
+	}
+	if c.DB.MinConns < 0 || c.DB.MinConns > c.DB.MaxConns {
+		bad("db.min_conns", "must be between 0 and db.max_conns (%d), got %d", c.DB.MaxConns, c.DB.MinConns)
+	}
+	if c.DB.MaxConnLifetime < 0 {
+		bad("db.max_conn_lifetime", "must not be negative")
+	}
+	if c.DB.MaxConnIdleTime < 0 {
+		bad("db.max_conn_idle_time", "must not be negative")
+	}
+	if c.DB.ConnectTimeout <= 0 {
+		bad("db.connect_timeout", "must be > 0")
+	}
+
+	for _, name := range []string{"solana", "sui"} {
+		ch, _ := c.Chains.Get(name)
+		if _, ok := publicRPC[name][ch.Network]; !ok {
+			bad("chains."+name+".network", "must be mainnet, testnet, devnet or localnet, got %q", ch.Network)
+		}
+		if ch.RPCURL != "" {
+			if err := checkURL(ch.RPCURL, "http", "https"); err != nil {
+				bad("chains."+name+".rpc_url", "%v", err)
+			}
+		}
+	}
+
+	if c.Worker.PollInterval <= 0 {
+		bad("worker.poll_interval", "must be > 0")
+	}
+	if c.Worker.BatchSize <= 0 {
+		bad("worker.batch_size", "must be > 0, got %d", c.Worker.BatchSize)
+	}
+	if c.Worker.MaxAddresses <= 0 {
+		bad("worker.max_addresses", "must be > 0, got %d", c.Worker.MaxAddresses)
+	}
+	if !validListen(c.Worker.MetricsPort) {
+		bad("worker.metrics_port", "must be a port (1-65535) or host:port, got %q", c.Worker.MetricsPort)
+	}
+	if c.Worker.ShutdownGrace <= 0 {
+		bad("worker.shutdown_grace", "must be > 0")
+	}
+
+	if !validPort(c.API.Port) {
+		bad("api.port", "must be 1-65535, got %d", c.API.Port)
+	}
+	if c.API.ShutdownGrace <= 0 {
+		bad("api.shutdown_grace", "must be > 0")
+	}
+	if c.API.Ready.CheckTimeout <= 0 {
+		bad("api.ready.check_timeout", "must be > 0")
+	}
+	if c.API.Ready.MaxHeartbeatAge < 0 {
+		bad("api.ready.max_heartbeat_age", "must not be negative")
+	}
+	if c.API.Ready.MaxCursorLag < 0 {
+		bad("api.ready.max_cursor_lag", "must not be negative")
+	}
+	for _, ch := range c.API.Ready.Chains {
+		if _, ok := c.Chains.Get(ch); !ok {
+			bad("api.ready.chains", "unknown chain %q (use solana or sui)", ch)
+		}
+	}
+
+	switch c.Publisher.Sink {
+	case "", SinkRedis, SinkNATS, SinkFile:
+	case SinkKafka:
+		if len(c.Publisher.Kafka.Brokers) == 0 {
+			bad("publisher.kafka.brokers", "is required for kafka sink (KAFKA_BROKERS)")
+		}
+	default:
+		bad("publisher.sink", "must be redis, nats, kafka or file, got %q", c.Publisher.Sink)
+	}
+	if c.Publisher.PollInterval <= 0 {
+		bad("publisher.poll_interval", "must be > 0")
+	}
+	if c.Publisher.BatchSize <= 0 {
+		bad("publisher.batch_size", "must be > 0, got %d", c.Publisher.BatchSize)
+	}
+	if c.Publisher.Retention < 0 {
+		bad("publisher.retention", "must not be negative")
+	}
+	if c.Publisher.ShutdownGrace <= 0 {
+		bad("publisher.shutdown_grace", "must be > 0")
+	}
+
+	if c.Notify.SendTimeout <= 0 {
+		bad("notify.send_timeout", "must be > 0")
+	}
+
+	switch c.Pricing.Source {
+	case "":
+	case PriceSourceFile:
+		if c.Pricing.File == "" {
+			bad("pricing.file", "is required for file source (PRICE_FILE)")
+		}
+	case PriceSourceHTTP:
+		if err := checkURL(c.Pricing.HTTP.URL, "http", "https"); err != nil {
+			bad("pricing.http.url", "%v", err)
+		}
+	default:
+		bad("pricing.source", "must be file or http (or empty), got %q", c.Pricing.Source)
+	}
+	if c.Pricing.Fresh <= 0 {
+		bad("pricing.fresh", "must be > 0")
+	}
+	if c.Pricing.Tolerance <= 0 {
+		bad("pricing.tolerance", "must be > 0")
+	}
+
+	switch c.PnL.Method {
+	case "fifo", "lifo", "average":
+	default:
+		bad("pnl.method", "must be fifo, lifo or average, got %q", c.PnL.Method)
+	}
+
+	if c.Retention.Interval <= 0 {
+		bad("retention.interval", "must be > 0")
+	}
+	if c.Retention.PartitionsAhead < 0 || c.Retention.PartitionsAhead > 24 {
+		bad("retention.partitions_ahead", "must be between 0 and 24, got %d", c.Retention.PartitionsAhead)
+	}
+	if c.Retention.RawAfter < 0 {
+		bad("retention.raw_after", "must not be negative")
+	}
+	switch c.Retention.RawMode {
+	case RawCompress, RawDrop:
+	default:
+		bad("retention.raw_mode", "must be compress or drop, got %q", c.Retention.RawMode)
+	}
+	if c.Retention.ArchiveAfter < 0 {
+		bad("retention.archive_after", "must not be negative")
+	}
+	if c.Retention.ArchiveAfter > 0 && c.Retention.ArchiveDir == "" {
+		bad("retention.archive_dir", "is required when retention.archive_after is set (ARCHIVE_DIR)")
+	}
+	if c.Retention.BatchSize <= 0 {
+		bad("retention.batch_size", "must be > 0, got %d", c.Retention.BatchSize)
+	}
+	return errors.Join(errs...)
+}
+
+// Warnings は不正ではないが注意が要る設定を 1 件 1 行で返す
+func (c *Config) Warnings() []string {
+	var out []string
+	if c.Retention.ArchiveAfter > 0 {
+		// 切り離した月は tx_events_* から消え、書き出したファイルは読み戻さない
+		out = append(out, "retention.archive_after: archived months are no longer replayed by /pnl or re-aggregated by walletctl rollup -rebuild "+
+			"(their rollups and edges are kept); months that cost basis depends on are not archived")
+	}
+	return out
+}
+
+func validPort(p int) bool { return p > 0 && p <= 65535 }
+
+func validListen(v string) bool {
+	if _, p, err := net.SplitHostPort(v); err == nil {
+		v = p
+	}
+	n, err := strconv.Atoi(v)
+	return err == nil && validPort(n)
+}
+
+func checkURL(raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) && u.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be a %s URL with a host", strings.Join(schemes, " / "))
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Duration は設定ファイルでは "5s" / "1m30s" のような文字列で書く time.Duration
+type Duration time.Duration
+
+// D は time.Duration を返す
+func (d Duration) D() time.Duration { return time.Duration(d) }
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+func (d Duration) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (use e.g. 5s, 1m30s)", b)
+	}
+	*d = Duration(v)
+	return nil
+}
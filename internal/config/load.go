@@ -0,0 +1,287 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Load は既定値に path の設定ファイル（空なら読まない）と環境変数を重ねて検証する。
+// ファイルの未知のキーや、数値として読めない環境変数はエラーにする。
+func Load(path string) (*Config, error) {
+	cfg, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// load は検証前の設定を返す（config print は不正な値も表示する）
+func load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := readFile(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(&cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func readFile(path string, cfg *Config) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(b), cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if und := md.Undecoded(); len(und) > 0 {
+			keys := make([]string, len(und))
+			for i, k := range und {
+				keys[i] = k.String()
+			}
+			return fmt.Errorf("%s: unknown keys: %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("%s: unsupported config format %q (use .yaml, .yml or .toml)", path, ext)
+	}
+	return nil
+}
+
+// envBinding は環境変数 1 つ分の上書き
+type envBinding struct {
+	key string
+	set func(v string) error
+}
+
+// envBindings は環境変数と設定項目の対応（従来の変数名と単位をそのまま使う）
+func envBindings(c *Config) []envBinding {
+	return []envBinding{
+		{"DATABASE_URL", str(&c.DB.URL)},
+		{"DB_MAX_CONNS", int32s(&c.DB.MaxConns)},
+		{"DB_MIN_CONNS", int32s(&c.DB.MinConns)},
+
+		{"SOLANA_NETWORK", str(&c.Chains.Solana.Network)},
+		{"SOLANA_RPC_URL", str(&c.Chains.Solana.RPCURL)},
+		{"SUI_NETWORK", str(&c.Chains.Sui.Network)},
+		{"SUI_RPC_URL", str(&c.Chains.Sui.RPCURL)},
+
+		{"POLL_INTERVAL_SEC", unit(&c.Worker.PollInterval, time.Second)},
+		{"BATCH_SIZE", ints(&c.Worker.BatchSize)},
+		{"MAX_ADDRESSES", ints(&c.Worker.MaxAddresses)},
+		{"METRICS_PORT", str(&c.Worker.MetricsPort)},
+		// 全プロセス共通
+		{"SHUTDOWN_GRACE_SEC", func(v string) error {
+			for _, d := range []*Duration{&c.Worker.ShutdownGrace, &c.API.ShutdownGrace, &c.Publisher.ShutdownGrace} {
+				if err := unit(d, time.Second)(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+
+		{"APP_PORT", ints(&c.API.Port)},
+		{"READY_CHECK_TIMEOUT_MS", unit(&c.API.Ready.CheckTimeout, time.Millisecond)},
+		{"READY_MAX_HEARTBEAT_AGE_SEC", unit(&c.API.Ready.MaxHeartbeatAge, time.Second)},
+		{"READY_MAX_CURSOR_LAG", int64s(&c.API.Ready.MaxCursorLag)},
+		{"READY_CHAINS", list(&c.API.Ready.Chains)},
+
+		{"OUTBOX_SINK", func(v string) error { c.Publisher.Sink = strings.ToLower(v); return nil }},
+		{"OUTBOX_POLL_INTERVAL_MS", unit(&c.Publisher.PollInterval, time.Millisecond)},
+		{"OUTBOX_BATCH_SIZE", ints(&c.Publisher.BatchSize)},
+		{"OUTBOX_RETENTION_HOURS", unit(&c.Publisher.Retention, time.Hour)},
+		{"REDIS_URL", str(&c.Publisher.Redis.URL)},
+		{"OUTBOX_REDIS_STREAM", str(&c.Publisher.Redis.Stream)},
+		{"NATS_URL", str(&c.Publisher.NATS.URL)},
+		{"OUTBOX_NATS_SUBJECT", str(&c.Publisher.NATS.Subject)},
+		{"KAFKA_BROKERS", list(&c.Publisher.Kafka.Brokers)},
+		{"OUTBOX_KAFKA_TOPIC", str(&c.Publisher.Kafka.Topic)},
+		{"OUTBOX_FILE_PATH", str(&c.Publisher.File.Path)},
+
+		{"NOTIFY_ENABLED", bools(&c.Notify.Enabled)},
+		{"NOTIFY_SEND_TIMEOUT_SEC", unit(&c.Notify.SendTimeout, time.Second)},
+
+		{"PRICE_SOURCE", func(v string) error { c.Pricing.Source = strings.ToLower(v); return nil }},
+		{"PRICE_FILE", str(&c.Pricing.File)},
+		{"PRICE_API_URL", str(&c.Pricing.HTTP.URL)},
+		{"PRICE_API_KEY", str(&c.Pricing.HTTP.APIKey)},
+		{"PRICE_API_KEY_HEADER", str(&c.Pricing.HTTP.APIKeyHeader)},
+		{"PRICE_IDS", pairs(&c.Pricing.HTTP.IDs)},
+		{"PRICE_FRESH_SEC", unit(&c.Pricing.Fresh, time.Second)},
+		{"PRICE_TOLERANCE_HOURS", unit(&c.Pricing.Tolerance, time.Hour)},
+
+		{"PNL_METHOD", func(v string) error { c.PnL.Method = strings.ToLower(v); return nil }},
+
+		{"RETENTION_INTERVAL_MIN", unit(&c.Retention.Interval, time.Minute)},
+		{"PARTITIONS_AHEAD", ints(&c.Retention.PartitionsAhead)},
+		{"RAW_RETENTION_DAYS", unit(&c.Retention.RawAfter, day)},
+		{"RAW_RETENTION_MODE", func(v string) error { c.Retention.RawMode = strings.ToLower(v); return nil }},
+		{"ARCHIVE_AFTER_DAYS", unit(&c.Retention.ArchiveAfter, day)},
+		{"ARCHIVE_DIR", str(&c.Retention.ArchiveDir)},
+		{"RETENTION_BATCH_SIZE", ints(&c.Retention.BatchSize)},
+	}
+}
+
+// EnvKeys は設定を上書きできる環境変数の一覧を返す
+func EnvKeys() []string {
+	var c Config
+	bs := envBindings(&c)
+	keys := make([]string, len(bs))
+	for i, b := range bs {
+		keys[i] = b.key
+	}
+	return keys
+}
+
+// applyEnv は設定されている（空でない）環境変数で cfg を上書きする
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	var errs []string
+	for _, b := range envBindings(cfg) {
+		v, ok := lookup(b.key)
+		if !ok || strings.TrimSpace(v) == "" {
+			continue
+		}
+		if err := b.set(strings.TrimSpace(v)); err != nil {
+			errs = append(errs, fmt.Sprintf("%s=%q: %v", b.key, v, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid environment:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func str(p *string) func(string) error {
+	return func(v string) error { *p = v; return nil }
+}
+
+func ints(p *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("not an integer")
+		}
+		*p = n
+		return nil
+	}
+}
+
+func int32s(p *int32) func(string) error {
+	return func(v string) error {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("not an integer")
+		}
+		*p = int32(n)
+		return nil
+	}
+}
+
+func int64s(p *int64) func(string) error {
+	return func(v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("not an integer")
+		}
+		*p = n
+		return nil
+	}
+}
+
+func bools(p *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("not a boolean (use true or false)")
+		}
+		*p = b
+		return nil
+	}
+}
+
+// unit は整数 × 単位の環境変数（POLL_INTERVAL_SEC=5 など）を読む
+func unit(p *Duration, u time.Duration) func(string) error {
+	return func(v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("not an integer number of %s", unitName(u))
+		}
+		*p = Duration(time.Duration(n) * u)
+		return nil
+	}
+}
+
+// day は日数の環境変数の単位
+const day = 24 * time.Hour
+
+func unitName(u time.Duration) string {
+	switch u {
+	case time.Millisecond:
+		return "milliseconds"
+	case time.Minute:
+		return "minutes"
+	case time.Hour:
+		return "hours"
+	case day:
+		return "days"
+	}
+	return "seconds"
+}
+
+// list はカンマ区切りの環境変数を読む（空要素は捨てる）
+func list(p *[]string) func(string) error {
+	return func(v string) error {
+		var out []string
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+		*p = out
+		return nil
+	}
+}
+
+// pairs は "KEY=value,KEY2=value2" を読む（全体を置き換える）
+func pairs(p *map[string]string) func(string) error {
+	return func(v string) error {
+		out := map[string]string{}
+		for _, kv := range strings.Split(v, ",") {
+			if kv = strings.TrimSpace(kv); kv == "" {
+				continue
+			}
+			k, val, ok := strings.Cut(kv, "=")
+			k, val = strings.TrimSpace(k), strings.TrimSpace(val)
+			if !ok || k == "" || val == "" {
+				return fmt.Errorf("want KEY=value pairs separated by commas")
+			}
+			out[k] = val
+		}
+		*p = out
+		return nil
+	}
+}
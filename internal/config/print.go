@@ -0,0 +1,158 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "***"
+
+// Redacted は秘匿値（接続文字列のパスワード、RPC URL のパス・クエリに載る API キー）を伏せた複製を返す
+func (c Config) Redacted() Config {
+	c.DB.URL = redactUserinfo(c.DB.URL)
+	c.Chains.Solana.RPCURL = redactRPC(c.Chains.Solana.RPCURL)
+	c.Chains.Sui.RPCURL = redactRPC(c.Chains.Sui.RPCURL)
+	c.Publisher.Redis.URL = redactUserinfo(c.Publisher.Redis.URL)
+	c.Publisher.NATS.URL = redactUserinfo(c.Publisher.NATS.URL)
+	if c.Pricing.HTTP.APIKey != "" {
+		c.Pricing.HTTP.APIKey = redacted
+	}
+	return c
+}
+
+// redactUserinfo は接続文字列のパスワードを伏せる。URL なら userinfo と password 系のクエリ
+// （?password= / ?sslpassword= など）を、key=value 形式の DSN なら password= の値を伏せる。
+// どちらでもないものは丸ごと伏せる
+func redactUserinfo(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		if strings.Contains(raw, "=") {
+			return dsnPassword.ReplaceAllString(raw, "${1}"+redacted)
+		}
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	if q := u.Query(); len(q) > 0 {
+		changed := false
+		for k := range q {
+			if strings.Contains(strings.ToLower(k), "password") {
+				q.Set(k, redacted)
+				changed = true
+			}
+		}
+		if changed {
+			u.RawQuery = q.Encode()
+		}
+	}
+	// userinfo・クエリでは * がエスケープされるので戻す
+	return strings.ReplaceAll(u.String(), "%2A%2A%2A", redacted)
+}
+
+// dsnPassword は key=value 形式の DSN の password（sslpassword なども含む）の値。値は '...' で囲まれていることがある
+var dsnPassword = regexp.MustCompile(`(?i)(\b\w*password\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s]*)`)
+
+// redactRPC は RPC URL を scheme://host だけ残して伏せる（プロバイダの API キーはパスやクエリに載る）
+func redactRPC(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	out := u.Scheme + "://" + u.Host
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		out += "/" + redacted
+	}
+	return out
+}
+
+// Print は format（yaml / toml / json）で設定を書き出す
+func (c Config) Print(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "", "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(c)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	}
+	return fmt.Errorf("unsupported format %q (use yaml, toml or json)", format)
+}
+
+// MustLoad はコマンドライン引数と環境変数から設定を読む（各バイナリの main 用）。
+//
+//	<bin> [-config path]                        設定を読んで起動する
+//	<bin> [-config path] config print [-format] 秘匿値を伏せた実効設定を表示して終了する
+//
+// 設定ファイルは -config、無ければ CONFIG_FILE から。不正な値があれば内容を表示して終了する。
+func MustLoad() *Config {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	path := fs.String("config", os.Getenv("CONFIG_FILE"), "config file (.yaml / .yml / .toml)")
+	_ = fs.Parse(os.Args[1:])
+
+	if args := fs.Args(); len(args) > 0 {
+		if len(args) < 2 || args[0] != "config" || args[1] != "print" {
+			fmt.Fprintf(os.Stderr, "unknown command %q (usage: %s [-config path] [config print [-format yaml|toml|json]])\n", strings.Join(args, " "), fs.Name())
+			os.Exit(2)
+		}
+		os.Exit(printCommand(*path, args[2:]))
+	}
+
+	cfg, err := Load(*path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, w := range cfg.Warnings() {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	return cfg
+}
+
+// printCommand は config print を実行し、終了コードを返す。
+// 不正な値があっても読めた範囲の設定を表示し、エラーは stderr に出す。
+func printCommand(path string, args []string) int {
+	fs := flag.NewFlagSet("config print", flag.ExitOnError)
+	format := fs.String("format", "yaml", "output format: yaml, toml or json")
+	_ = fs.Parse(args)
+
+	cfg, err := load(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := cfg.Redacted().Print(os.Stdout, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config:\n%v\n", err)
+		return 1
+	}
+	for _, w := range cfg.Warnings() {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+// Package eventbus は event_outbox の行を外部のイベントバスへ中継する。
+// 購読側が頼れるのは次のことだけ:
+//   - 配信は少なくとも 1 回（at-least-once）。同じメッセージが重複して届くことがある
+//   - Message.Seq（event_outbox.id）はメッセージごとに一意で、再送しても変わらない（重複排除のキー）
+//   - 1 回の Publish に渡すバッチの中では Seq の昇順
+//   - 配信先に受け付けられなかったメッセージは遅らせて再送し、OutboxMaxAttempts 回で保留にする。
+//     保留中のメッセージは walletctl outbox requeue で戻すまで届かない（後続のメッセージは止めない）
+//
+// バッチをまたいだ順序は保証しない。id は INSERT 時に採番されコミット順とは限らず、publisher を複数動かすと
+// バッチが並行して流れ、失敗したバッチ・遅らせたメッセージは後のバッチより遅れて届く。
+// Seq が前のメッセージより小さくても欠落や巻き戻りとして扱わないこと（順序が要るなら TS を使う）。
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// Message は外部へ流すメッセージ
+type Message struct {
+	// Seq はメッセージごとに一意な番号（重複排除のキー。届く順に増えるとは限らない）
+	Seq    int64           `json:"seq"`
+	Type   string          `json:"type"`
+	Chain  string          `json:"chain"`
+	TxHash string          `json:"tx_hash"`
+	TS     time.Time       `json:"ts"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// FromOutbox はアウトボックスの行をメッセージに変換する
+func FromOutbox(m store.OutboxMessage) Message {
+	return Message{Seq: m.ID, Type: m.EventType, Chain: m.Chain, TxHash: m.TxHash, TS: m.TS.UTC(), Data: m.Payload}
+}
+
+// Sink はメッセージの配信先。Publish はバッチ全体が受け付けられたときだけ nil を返すこと。
+type Sink interface {
+	Publish(ctx context.Context, msgs []Message) error
+	Close() error
+}
+
+// 配信先の種類（publisher.sink / OUTBOX_SINK）
+const (
+	SinkRedis = config.SinkRedis
+	SinkNATS  = config.SinkNATS
+	SinkKafka = config.SinkKafka
+	SinkFile  = config.SinkFile
+)
+
+// NewSink は publisher の設定から配信先を作る
+func NewSink(c config.Publisher) (Sink, error) {
+	switch c.Sink {
+	case SinkRedis:
+		return NewRedisSink(c.Redis.URL, c.Redis.Stream, 0)
+	case SinkNATS:
+		return NewNATSSink(c.NATS.URL, c.NATS.Subject)
+	case SinkKafka:
+		if len(c.Kafka.Brokers) == 0 {
+			return nil, fmt.Errorf("KAFKA_BROKERS is required for kafka sink")
+		}
+		return NewKafkaSink(c.Kafka.Brokers, c.Kafka.Topic), nil
+	case SinkFile:
+		return NewFileSink(c.File.Path)
+	case "":
+		return nil, fmt.Errorf("OUTBOX_SINK is required (redis / nats / kafka / file)")
+	default:
+		return nil, fmt.Errorf("unknown OUTBOX_SINK: %s", c.Sink)
+	}
+}
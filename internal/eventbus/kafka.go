@@ -0,0 +1,55 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSink は Kafka 互換のブローカー（Kafka / Redpanda など）の topic へ書き込む。
+// キーは chain:tx_hash、ヘッダー seq にシーケンス番号を載せる。
+type KafkaSink struct {
+	w KafkaWriter
+}
+
+// KafkaWriter は KafkaSink の書き込み先（*kafka.Writer が満たす。テストでは差し替える）
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// NewKafkaSinkWriter は w に書き込む KafkaSink を作る
+func NewKafkaSinkWriter(w KafkaWriter) *KafkaSink {
+	return &KafkaSink{w: w}
+}
+
+func NewKafkaSink(brokers []string, topic string) *KafkaSink {
+	return NewKafkaSinkWriter(&kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.Hash{},
+		// 全レプリカに書けてから配信済みにする
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	})
+}
+
+func (s *KafkaSink) Publish(ctx context.Context, msgs []Message) error {
+	out := make([]kafka.Message, 0, len(msgs))
+	for _, m := range msgs {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		out = append(out, kafka.Message{
+			Key:     []byte(m.Chain + ":" + m.TxHash),
+			Value:   b,
+			Headers: []kafka.Header{{Key: "seq", Value: []byte(strconv.FormatInt(m.Seq, 10))}},
+		})
+	}
+	return s.w.WriteMessages(ctx, out...)
+}
+
+func (s *KafkaSink) Close() error { return s.w.Close() }
@@ -0,0 +1,45 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink は subject.<chain> に publish する。
+// Nats-Msg-Id に seq を載せるので、JetStream のストリームで受ければ重複が自動で除かれる。
+type NATSSink struct {
+	nc      *nats.Conn
+	subject string
+}
+
+func NewNATSSink(url, subject string) (*NATSSink, error) {
+	nc, err := nats.Connect(url, nats.Name("wallet-watcher-publisher"))
+	if err != nil {
+		return nil, err
+	}
+	return &NATSSink{nc: nc, subject: subject}, nil
+}
+
+func (s *NATSSink) Publish(ctx context.Context, msgs []Message) error {
+	for _, m := range msgs {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		msg := nats.NewMsg(s.subject + "." + m.Chain)
+		msg.Data = b
+		msg.Header.Set(nats.MsgIdHdr, strconv.FormatInt(m.Seq, 10))
+		if err := s.nc.PublishMsg(msg); err != nil {
+			return err
+		}
+	}
+	// サーバーが受け取ったことを確認してから配信済みにする
+	return s.nc.FlushWithContext(ctx)
+}
+
+func (s *NATSSink) Close() error {
+	return s.nc.Drain()
+}
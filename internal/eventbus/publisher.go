@@ -0,0 +1,109 @@
+package eventbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// Outbox はアウトボックスの読み出し元（*store.Store が満たす。テストでは差し替える）
+type Outbox interface {
+	RelayOutbox(ctx context.Context, limit int, publish func([]store.OutboxMessage) error) (int, error)
+}
+
+// Publisher はアウトボックスの未配信行を Sink へ中継する
+type Publisher struct {
+	ob       Outbox
+	sink     Sink
+	batch    int
+	interval time.Duration
+	// grace は停止要求後に中継中のバッチを続ける時間の上限
+	grace time.Duration
+}
+
+func NewPublisher(ob Outbox, sink Sink, batch int, interval, grace time.Duration) *Publisher {
+	if batch <= 0 {
+		batch = 100
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if grace <= 0 {
+		grace = publishTimeout
+	}
+	return &Publisher{ob: ob, sink: sink, batch: batch, interval: interval, grace: grace}
+}
+
+// publishTimeout は 1 バッチの Publish に使う時間の上限（NATS の Flush など、期限の無い context を受け付けない配信先がある）
+const publishTimeout = 30 * time.Second
+
+// RelayOnce は未配信行を 1 バッチ分中継し、配信した件数を返す。
+// バッチが失敗したら 1 件ずつ送り直し、受け付けられなかった行だけを store.OutboxRejected で返す
+// （その行は遅らせて再試行され、何度も失敗すると保留になる。後続の行は止めない）。
+func (p *Publisher) RelayOnce(ctx context.Context) (int, error) {
+	return p.ob.RelayOutbox(ctx, p.batch, func(rows []store.OutboxMessage) error {
+		msgs := make([]Message, len(rows))
+		for i, r := range rows {
+			msgs[i] = FromOutbox(r)
+		}
+		pctx, cancel := context.WithTimeout(ctx, publishTimeout)
+		defer cancel()
+		err := p.sink.Publish(pctx, msgs)
+		if err == nil || len(msgs) == 1 {
+			return err
+		}
+		// どの行が原因か分からないので 1 件ずつ送り直す。全件だめなら配信先が使えないとみなす
+		rejected := store.OutboxRejected{}
+		for _, m := range msgs {
+			if merr := p.sink.Publish(pctx, []Message{m}); merr != nil {
+				rejected[m.Seq] = merr
+			}
+		}
+		if len(rejected) == len(msgs) {
+			return err
+		}
+		for seq, merr := range rejected {
+			logging.FromContext(ctx).Warn("outbox message rejected", "seq", seq, "err", merr)
+		}
+		return rejected
+	})
+}
+
+// Run は ctx がキャンセルされるまで中継を続ける。
+// 未配信が残っている間は続けて流し、空になったら interval 待つ。失敗時は待ち時間を倍にして再試行する。
+// キャンセル時に中継中のバッチは最後まで送り、配信済みとして記録してから戻る。
+// ただしキャンセルから grace を過ぎても終わらなければ打ち切る（行はロールバックされ、次回再送される）。
+func (p *Publisher) Run(ctx context.Context) {
+	bctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	defer context.AfterFunc(ctx, func() { time.AfterFunc(p.grace, cancel) })()
+
+	wait := p.interval
+	for ctx.Err() == nil {
+		n, err := p.RelayOnce(bctx)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			logging.FromContext(ctx).Warn("relay outbox", "err", err, "retry_in", wait.String())
+		case n == p.batch:
+			// まだ残っている
+			wait = p.interval
+			continue
+		default:
+			wait = p.interval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if err != nil && wait < time.Minute {
+			wait *= 2
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisSink は Redis Streams に XADD する。
+// エントリのフィールド: seq / type / chain / tx_hash / message（Message の JSON）
+type RedisSink struct {
+	rdb    *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewRedisSink は url（redis://host:port/db）の stream へ書き込む配信先を作る。
+// maxLen > 0 ならストリームをおおよそその長さに切り詰める。
+func NewRedisSink(url, stream string, maxLen int64) (*RedisSink, error) {
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisSink{rdb: redis.NewClient(opt), stream: stream, maxLen: maxLen}, nil
+}
+
+func (s *RedisSink) Publish(ctx context.Context, msgs []Message) error {
+	pipe := s.rdb.Pipeline()
+	for _, m := range msgs {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		args := &redis.XAddArgs{
+			Stream: s.stream,
+			Values: map[string]any{
+				"seq":     strconv.FormatInt(m.Seq, 10),
+				"type":    m.Type,
+				"chain":   m.Chain,
+				"tx_hash": m.TxHash,
+				"message": string(b),
+			},
+		}
+		if s.maxLen > 0 {
+			args.MaxLen, args.Approx = s.maxLen, true
+		}
+		pipe.XAdd(ctx, args)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisSink) Close() error { return s.rdb.Close() }
@@ -0,0 +1,143 @@
+// Package graph はアドレス間の資金の流れ（counterparty_edges）から、あるアドレスを中心にした
+// 近傍のグラフを組み立て、JSON / GraphML / DOT で書き出す。
+package graph
+
+import (
+	"context"
+
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// 探索の既定と上限
+const (
+	DefaultDepth      = 2
+	MaxDepth          = 3
+	DefaultPerAddress = 20
+	MaxPerAddress     = 100
+	// DefaultMaxNodes を超えるノードは追加しない（Truncated にする）
+	DefaultMaxNodes = 500
+)
+
+// Transfers は保存済みイベントをエッジの元になる移動に展開する。
+// raw を正規化できれば Tx 内の全ての移動を、できなければイベント行の sender / receiver / token / amount を使う。
+// 失敗した Tx は移動にしない。
+func Transfers(e store.TxEvent, raw []byte) []store.EdgeTransfer {
+	if e.Status != nil && *e.Status == store.TxStatusFailed {
+		return nil
+	}
+	if ev, ok := normalize.FromRaw(e.Chain, e.TxHash, e.TS, raw); ok {
+		out := make([]store.EdgeTransfer, 0, len(ev.Transfers))
+		for _, t := range ev.Transfers {
+			out = append(out, store.EdgeTransfer{From: t.From, To: t.To, Token: t.Token, Amount: t.Amount})
+		}
+		return out
+	}
+	if e.Sender == nil || e.Receiver == nil || e.Token == nil || e.Amount == nil {
+		return nil
+	}
+	return []store.EdgeTransfer{{From: *e.Sender, To: *e.Receiver, Token: *e.Token, Amount: *e.Amount}}
+}
+
+// Source はエッジとラベルの読み出し（*store.Store が満たす）
+type Source interface {
+	NeighborEdges(ctx context.Context, chain string, addrs []string, q store.EdgeQuery) ([]store.Edge, error)
+	AddressLabels(ctx context.Context, chain string, addrs []string) (map[string]string, error)
+}
+
+// Options は探索の条件（0 は既定値）
+type Options struct {
+	Depth      int
+	PerAddress int
+	MaxNodes   int
+	Token      *string
+}
+
+// Node はグラフのノード（アドレス）
+type Node struct {
+	Address string `json:"address"`
+	// Label は監視アドレスのラベル
+	Label string `json:"label,omitempty"`
+	// Depth は中心からのホップ数（中心は 0）
+	Depth int `json:"depth"`
+	// TxCount はグラフに含まれるエッジの tx_count の合計
+	TxCount int `json:"tx_count"`
+}
+
+// Graph は中心アドレスの近傍
+type Graph struct {
+	Chain string       `json:"chain"`
+	Root  string       `json:"root"`
+	Depth int          `json:"depth"`
+	Nodes []Node       `json:"nodes"`
+	Edges []store.Edge `json:"edges"`
+	// Truncated はノード数の上限で探索を打ち切ったか
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Build は root から depth ホップまでのエッジをたどって近傍のグラフを組み立てる。
+// 各ホップで直前に見つかったアドレスのエッジを tx_count の多い順に PerAddress 件まで読み、
+// ノードは最初に見つかったホップを深さとする（両端がグラフに入ったエッジだけを返す）。
+func Build(ctx context.Context, src Source, chain, root string, opt Options) (*Graph, error) {
+	if opt.Depth <= 0 {
+		opt.Depth = DefaultDepth
+	}
+	if opt.PerAddress <= 0 {
+		opt.PerAddress = DefaultPerAddress
+	}
+	if opt.MaxNodes <= 0 {
+		opt.MaxNodes = DefaultMaxNodes
+	}
+	root = store.CanonicalAddress(chain, root)
+	g := &Graph{Chain: chain, Root: root, Depth: opt.Depth, Nodes: []Node{{Address: root}}, Edges: []store.Edge{}}
+	index := map[string]int{root: 0}
+	seen := map[[3]string]bool{}
+
+	frontier := []string{root}
+	for d := 1; d <= opt.Depth && len(frontier) > 0; d++ {
+		edges, err := src.NeighborEdges(ctx, chain, frontier, store.EdgeQuery{Token: opt.Token, PerAddress: opt.PerAddress})
+		if err != nil {
+			return nil, err
+		}
+		var next []string
+		for _, e := range edges {
+			k := [3]string{e.From, e.To, e.Token}
+			if seen[k] {
+				continue
+			}
+			var missing []string
+			for _, a := range []string{e.From, e.To} {
+				if _, found := index[a]; !found {
+					missing = append(missing, a)
+				}
+			}
+			if len(g.Nodes)+len(missing) > opt.MaxNodes {
+				g.Truncated = true
+				continue
+			}
+			for _, a := range missing {
+				index[a] = len(g.Nodes)
+				g.Nodes = append(g.Nodes, Node{Address: a, Depth: d})
+				next = append(next, a)
+			}
+			seen[k] = true
+			g.Edges = append(g.Edges, e)
+			g.Nodes[index[e.From]].TxCount += e.TxCount
+			g.Nodes[index[e.To]].TxCount += e.TxCount
+		}
+		frontier = next
+	}
+
+	addrs := make([]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		addrs[i] = n.Address
+	}
+	labels, err := src.AddressLabels(ctx, chain, addrs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range g.Nodes {
+		g.Nodes[i].Label = labels[g.Nodes[i].Address]
+	}
+	return g, nil
+}
@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 書き出し形式
+const (
+	FormatJSON    = "json"
+	FormatGraphML = "graphml"
+	FormatDOT     = "dot"
+)
+
+// ContentType は形式ごとの Content-Type
+func ContentType(format string) string {
+	switch format {
+	case FormatGraphML:
+		return "application/graphml+xml"
+	case FormatDOT:
+		return "text/vnd.graphviz; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// graphmlKeys は GraphML のノード・エッジの属性（id, for, attr.name, attr.type）
+var graphmlKeys = [][4]string{
+	{"n_label", "node", "label", "string"},
+	{"n_depth", "node", "depth", "int"},
+	{"n_tx_count", "node", "tx_count", "int"},
+	{"e_token", "edge", "token", "string"},
+	{"e_total_amount", "edge", "total_amount", "long"},
+	{"e_tx_count", "edge", "tx_count", "int"},
+	{"e_first_ts", "edge", "first_ts", "string"},
+	{"e_last_ts", "edge", "last_ts", "string"},
+}
+
+// WriteGraphML は g を GraphML（有向グラフ、ノード ID はアドレス）で書き出す
+func WriteGraphML(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	esc := func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	data := func(key, v string) {
+		fmt.Fprintf(bw, "      <data key=\"%s\">%s</data>\n", key, esc(v))
+	}
+
+	bw.WriteString(xml.Header)
+	bw.WriteString("<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	for _, k := range graphmlKeys {
+		fmt.Fprintf(bw, "  <key id=\"%s\" for=\"%s\" attr.name=\"%s\" attr.type=\"%s\"/>\n", k[0], k[1], k[2], k[3])
+	}
+	fmt.Fprintf(bw, "  <graph id=\"%s\" edgedefault=\"directed\">\n", esc(g.Chain+":"+g.Root))
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "    <node id=\"%s\">\n", esc(n.Address))
+		if n.Label != "" {
+			data("n_label", n.Label)
+		}
+		data("n_depth", strconv.Itoa(n.Depth))
+		data("n_tx_count", strconv.Itoa(n.TxCount))
+		bw.WriteString("    </node>\n")
+	}
+	for i, e := range g.Edges {
+		fmt.Fprintf(bw, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i, esc(e.From), esc(e.To))
+		data("e_token", e.Token)
+		data("e_total_amount", strconv.FormatInt(e.TotalAmount, 10))
+		data("e_tx_count", strconv.Itoa(e.TxCount))
+		data("e_first_ts", e.FirstTS.UTC().Format(time.RFC3339))
+		data("e_last_ts", e.LastTS.UTC().Format(time.RFC3339))
+		bw.WriteString("    </edge>\n")
+	}
+	bw.WriteString("  </graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+// WriteDOT は g を Graphviz の DOT（digraph）で書き出す。
+// ノードはラベル（無ければ短縮したアドレス）、エッジは「トークン ×tx 数」を表示し、tx 数を太さに使う。
+func WriteDOT(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph %s {\n", dotQuote(g.Chain+":"+g.Root))
+	bw.WriteString("  rankdir=LR;\n  node [shape=box, fontname=\"monospace\"];\n")
+	for _, n := range g.Nodes {
+		label := n.Label
+		if label == "" {
+			label = shortAddr(n.Address)
+		}
+		attrs := "label=" + dotQuote(label) + ", tooltip=" + dotQuote(n.Address)
+		if n.Depth == 0 {
+			attrs += ", style=bold"
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", dotQuote(n.Address), attrs)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [label=%s, weight=%d, penwidth=%s];\n",
+			dotQuote(e.From), dotQuote(e.To),
+			dotQuote(fmt.Sprintf("%s ×%d", shortAddr(e.Token), e.TxCount)),
+			e.TxCount, penWidth(e.TxCount))
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// dotQuote は DOT の二重引用符付き ID にする
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// shortAddr は長いアドレス・mint / coin type を先頭と末尾だけにする
+func shortAddr(s string) string {
+	if len(s) <= 16 {
+		return s
+	}
+	return s[:6] + "…" + s[len(s)-4:]
+}
+
+// penWidth は tx 数に応じた線の太さ（1〜5）
+func penWidth(n int) string {
+	w := 1.0
+	for v := n; v > 1 && w < 5; v /= 4 {
+		w += 0.5
+	}
+	return strconv.FormatFloat(w, 'f', 1, 64)
+}
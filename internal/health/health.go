@@ -0,0 +1,193 @@
+// Package health は /readyz の依存先チェック（DB・RPC・ワーカーのハートビート）を行う。
+// 各チェックは並行に実行し、タイムアウトを超えたものや閾値を超えたものを失敗とする。
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// Status はチェック / レポート全体の結果
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc は 1 つの依存先を確認する。detail はレポートにそのまま載る（nil 可）。
+type CheckFunc func(ctx context.Context) (detail any, err error)
+
+// Result は 1 チェック分の結果
+type Result struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	Detail     any    `json:"detail,omitempty"`
+}
+
+// Report は /readyz のレスポンス
+type Report struct {
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Result  `json:"checks"`
+}
+
+// OK はすべてのチェックが通ったかを返す
+func (r Report) OK() bool { return r.Status == StatusOK }
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker は登録されたチェックをまとめて実行する
+type Checker struct {
+	timeout time.Duration
+	checks  []check
+}
+
+// NewChecker は 1 チェックあたり timeout で打ち切る Checker を返す
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Add はチェックを登録する（レポートは登録順）
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Run はすべてのチェックを並行に実行する。1 つでも失敗すれば全体も失敗になる。
+func (c *Checker) Run(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func(i int, ch check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	rep := Report{Status: StatusOK, CheckedAt: time.Now().UTC(), Checks: results}
+	for _, r := range results {
+		if r.Status != StatusOK {
+			rep.Status = StatusFail
+		}
+	}
+	return rep
+}
+
+func (c *Checker) run(ctx context.Context, ch check) Result {
+	cctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type outcome struct {
+		detail any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		d, err := ch.fn(cctx)
+		done <- outcome{d, err}
+	}()
+
+	res := Result{Name: ch.name, Status: StatusOK}
+	select {
+	case o := <-done:
+		res.Detail = o.detail
+		if o.err != nil {
+			res.Status, res.Error = StatusFail, o.err.Error()
+		}
+	case <-cctx.Done():
+		// ctx を見ないチェックでも打ち切る
+		res.Status, res.Error = StatusFail, fmt.Sprintf("timed out after %s", c.timeout)
+	}
+	res.DurationMS = time.Since(start).Milliseconds()
+	return res
+}
+
+// Pinger は DB の疎通確認先（*store.Store が満たす）
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// DB は DB プールの疎通を確認する
+func DB(p Pinger) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		return nil, p.Ping(ctx)
+	}
+}
+
+// RPC はチェーン先頭（slot / checkpoint）を取得できるかを確認する
+func RPC(head func(ctx context.Context) (int64, error)) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		h, err := head(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int64{"head": h}, nil
+	}
+}
+
+// HeartbeatSource はハートビートの読み出し元（*store.Store が満たす。テストでは差し替える）
+type HeartbeatSource interface {
+	LatestHeartbeats(ctx context.Context) (map[string]store.WorkerHeartbeat, error)
+}
+
+// HeartbeatLimits はハートビートの閾値。0 の項目は確認しない。
+type HeartbeatLimits struct {
+	// MaxAge は最後の tick からの経過時間の上限
+	MaxAge time.Duration
+	// MaxCursorLag はチェーン先頭 − カーソルの上限（slot / checkpoint 数）
+	MaxCursorLag int64
+}
+
+// heartbeatDetail はハートビートチェックの detail
+type heartbeatDetail struct {
+	Instance     string    `json:"instance"`
+	TickAt       time.Time `json:"tick_at"`
+	AgeSec       float64   `json:"age_sec"`
+	Addresses    int       `json:"addresses"`
+	Failed       int       `json:"failed"`
+	MaxCursorLag *int64    `json:"max_cursor_lag,omitempty"`
+	LastError    *string   `json:"last_error,omitempty"`
+}
+
+// Heartbeat は chain のワーカーの直近のハートビートが閾値内かを確認する
+func Heartbeat(src HeartbeatSource, chain string, lim HeartbeatLimits) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		hbs, err := src.LatestHeartbeats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		hb, ok := hbs[chain]
+		if !ok {
+			return nil, fmt.Errorf("no heartbeat from %s worker", chain)
+		}
+		d := heartbeatDetail{
+			Instance:     hb.Instance,
+			TickAt:       hb.TickAt,
+			AgeSec:       hb.Age.Seconds(),
+			Addresses:    hb.Addresses,
+			Failed:       hb.Failed,
+			MaxCursorLag: hb.MaxCursorLag,
+			LastError:    hb.LastError,
+		}
+		if lim.MaxAge > 0 && hb.Age > lim.MaxAge {
+			return d, fmt.Errorf("last heartbeat %s ago exceeds %s", hb.Age.Round(time.Second), lim.MaxAge)
+		}
+		if lim.MaxCursorLag > 0 && hb.MaxCursorLag != nil && *hb.MaxCursorLag > lim.MaxCursorLag {
+			return d, fmt.Errorf("cursor lag %d exceeds %d", *hb.MaxCursorLag, lim.MaxCursorLag)
+		}
+		return d, nil
+	}
+}
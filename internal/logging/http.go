@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader はリクエスト ID を受け渡すヘッダー（クライアント指定があればそれを使う）
+const RequestIDHeader = "X-Request-ID"
+
+var reRequestID = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+
+// Middleware はリクエストごとに ID を割り当ててレスポンスヘッダーと ctx のロガーに載せ、
+// 完了時にアクセスログを 1 行出す
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(RequestIDHeader)
+		if !reRequestID.MatchString(id) {
+			id = NewID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := WithTrace(NewContext(r.Context(), slog.Default().With("request_id", id)))
+		l := FromContext(ctx)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		route := ""
+		if rc := chi.RouteContext(ctx); rc != nil {
+			route = rc.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		l.LogAttrs(ctx, level, "http request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.String("remote", r.RemoteAddr),
+		)
+	})
+}
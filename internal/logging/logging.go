@@ -0,0 +1,96 @@
+// Package logging は log/slog による構造化ログの設定と、
+// リクエスト ID・tick・アドレスなどの相関フィールドを context で受け渡す仕組みを提供する。
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ParseLevel は LOG_LEVEL の値（debug / info / warn / error、大文字小文字は問わない）を解釈する
+func ParseLevel(v string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("invalid log level %q (use debug, info, warn or error)", v)
+}
+
+// New は w に出力するロガーを作る。format は "json"（既定）または "text"。
+func New(w io.Writer, format string, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if strings.EqualFold(format, "text") {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// Setup は LOG_LEVEL / LOG_FORMAT からロガーを作り、service 属性を付けて既定のロガーにする。
+// 標準 log パッケージの出力（依存ライブラリ等）も同じハンドラに流れる。
+func Setup(service string) *slog.Logger {
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	l := New(os.Stdout, os.Getenv("LOG_FORMAT"), level).With("service", service)
+	slog.SetDefault(l)
+	log.SetFlags(0)
+	if err != nil {
+		l.Warn("falling back to info level", "err", err)
+	}
+	return l
+}
+
+// Fatal はエラーを記録して終了する（log.Fatal の置き換え）
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type ctxKey struct{}
+
+// FromContext は ctx に載ったロガー（無ければ既定のロガー）を返す
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// NewContext は ctx にロガーを載せる
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// With は ctx のロガーにフィールドを足して載せ直す。以降 FromContext で取ったロガーの全行に付く。
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// WithTrace は ctx にスパンがあれば trace_id をロガーに足す（トレースとログを突き合わせるため）
+func WithTrace(ctx context.Context) context.Context {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ctx
+	}
+	return With(ctx, "trace_id", sc.TraceID().String())
+}
+
+// NewID はリクエスト・tick の相関 ID（16 桁の 16 進）を作る
+func NewID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,202 @@
+// Package metrics は API・ワーカーが公開する Prometheus メトリクスを定義する。
+// 各プロセスは Handler() を /metrics に載せ、計測箇所はこのパッケージの値を直接更新する。
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/you/wallet-watcher/internal/config"
+)
+
+const namespace = "wallet_watcher"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// TickDuration はワーカー 1 tick（登録アドレス一巡）の所要時間
+	TickDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "worker_tick_duration_seconds",
+		Help:      "Duration of one worker tick over all watched addresses.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"chain"})
+
+	// AddressesProcessed は処理したアドレス数（result: ok / error）
+	AddressesProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "worker_addresses_processed_total",
+		Help:      "Number of watched addresses processed by workers.",
+	}, []string{"chain", "result"})
+
+	// EventsInserted は新規に保存した Tx イベント数（重複で捨てたものは数えない）
+	EventsInserted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_inserted_total",
+		Help:      "Number of newly inserted tx events.",
+	}, []string{"chain"})
+
+	// CursorLag はチェーン先頭（slot / checkpoint）と保存済みカーソルの差
+	CursorLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_cursor_lag",
+		Help:      "Chain head (slot or checkpoint) minus the stored cursor of a watched address.",
+	}, []string{"chain", "address"})
+
+	// RPCDuration / RPCErrors は JSON-RPC 呼び出しのレイテンシとエラー数。
+	// endpoint は設定済みの RPC ならネットワーク名、それ以外は "custom"（RegisterEndpoint を参照）
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Latency of JSON-RPC calls by method and configured endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"chain", "method", "endpoint"})
+	RPCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Number of failed JSON-RPC calls by method and configured endpoint.",
+	}, []string{"chain", "method", "endpoint"})
+
+	// DBQueryDuration は SQL の所要時間（op は文の先頭キーワード）
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of database queries by statement type.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"op", "result"})
+
+	// HTTPDuration は API リクエストの所要時間（route は chi のルートパターン）
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		TickDuration, AddressesProcessed, EventsInserted, CursorLag,
+		RPCDuration, RPCErrors, DBQueryDuration, HTTPDuration,
+	)
+}
+
+// Handler は /metrics のハンドラ
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Serve は addr で /metrics だけを返す HTTP サーバーを起動する（ワーカー用）。ctx が終わると停止する。
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(sctx)
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Endpoint は RPC URL をラベル用に scheme://host へ縮める（パスやクエリの API キーを載せない）
+func Endpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// CustomEndpoint は設定に無い RPC（API の rpc_url クエリで差し替えたもの）のラベル
+const CustomEndpoint = "custom"
+
+var (
+	endpointsMu sync.RWMutex
+	// endpoints は Endpoint(URL) → ラベル名。ラベルの値を設定済みのものに限り、系列が増え続けないようにする
+	endpoints = map[string]string{}
+)
+
+// RegisterEndpoint は設定済みの RPC を name のラベルで記録するよう登録する
+func RegisterEndpoint(name, rawURL string) {
+	endpointsMu.Lock()
+	defer endpointsMu.Unlock()
+	endpoints[Endpoint(rawURL)] = name
+}
+
+// RegisterEndpoints は chains の各チェーンの RPC をネットワーク名（"mainnet" など）で登録する
+func RegisterEndpoints(chains config.Chains) {
+	for _, chain := range []string{"solana", "sui"} {
+		ch, _ := chains.Get(chain)
+		name := ch.Network
+		if name == "" {
+			name = chain
+		}
+		if u := chains.RPC(chain); u != "" {
+			RegisterEndpoint(name, u)
+		}
+	}
+}
+
+// EndpointLabel は rawURL のラベル値を返す。登録されていない RPC はすべて CustomEndpoint にまとめる
+func EndpointLabel(rawURL string) string {
+	endpointsMu.RLock()
+	defer endpointsMu.RUnlock()
+	if name, ok := endpoints[Endpoint(rawURL)]; ok {
+		return name
+	}
+	return CustomEndpoint
+}
+
+// ObserveRPC は JSON-RPC 呼び出し 1 回分を記録する（endpoint のラベルは EndpointLabel）
+func ObserveRPC(chain, method, endpoint string, start time.Time, err error) {
+	ep := EndpointLabel(endpoint)
+	RPCDuration.WithLabelValues(chain, method, ep).Observe(time.Since(start).Seconds())
+	if err != nil {
+		RPCErrors.WithLabelValues(chain, method, ep).Inc()
+	}
+}
+
+// Middleware は chi のルートパターンごとに HTTP リクエストの所要時間を記録する
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+			route = rc.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		HTTPDuration.WithLabelValues(r.Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ListenAddr は METRICS_PORT のような値（"9090" / ":9090" / "0.0.0.0:9090"）を listen アドレスにする
+func ListenAddr(v string) string {
+	if _, _, err := net.SplitHostPort(v); err == nil {
+		return v
+	}
+	return ":" + v
+}
@@ -0,0 +1,111 @@
+package normalize
+
+import (
+	"context"
+	"sync"
+
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/logging"
+)
+
+// DecimalsStore は桁数の登録先（token_decimals）
+type DecimalsStore interface {
+	TokenDecimals(ctx context.Context, tokens []string) (map[string]int, error)
+	SetTokenDecimals(ctx context.Context, decimals map[string]int) error
+}
+
+// SuiDecimals は Sui の SUI 以外のコイン（Token はコインの型）の桁数を引いて移動に付ける。
+// balanceChanges には桁数が無いため、token_decimals → suix_getCoinMetadata の順に探し、
+// RPC で分かった桁数は token_decimals に登録する（保存済みの raw を正規化し直す API はそれを使う）。
+// 見つからないコインは桁数 0（最小単位のまま）で残す。
+type SuiDecimals struct {
+	Store DecimalsStore
+	// Client が nil なら token_decimals だけを見る（見つからなかったコインも覚えて問い合わせ直さない）
+	Client *sui.Client
+
+	mu      sync.Mutex
+	known   map[string]int
+	missing map[string]bool
+}
+
+// Apply は ev の移動のうち桁数が分からないコインに桁数を付ける（Sui 以外のイベントは何もしない）
+func (d *SuiDecimals) Apply(ctx context.Context, ev *Event) {
+	if d == nil || ev.Chain != "sui" {
+		return
+	}
+	var tokens []string
+	for _, t := range ev.Transfers {
+		if t.Token != "SUI" && t.Decimals == 0 {
+			tokens = append(tokens, t.Token)
+		}
+	}
+	if len(tokens) == 0 {
+		return
+	}
+	dec := d.lookup(ctx, tokens)
+	for i, t := range ev.Transfers {
+		if v, ok := dec[t.Token]; ok && t.Token != "SUI" && t.Decimals == 0 {
+			ev.Transfers[i].Decimals = v
+		}
+	}
+}
+
+func (d *SuiDecimals) lookup(ctx context.Context, tokens []string) map[string]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.known == nil {
+		d.known, d.missing = map[string]int{}, map[string]bool{}
+	}
+	log := logging.FromContext(ctx)
+
+	var ask []string
+	for _, t := range tokens {
+		if _, ok := d.known[t]; !ok && !d.missing[t] {
+			ask = append(ask, t)
+		}
+	}
+	if len(ask) > 0 && d.Store != nil {
+		found, err := d.Store.TokenDecimals(ctx, ask)
+		if err != nil {
+			log.Warn("token decimals lookup failed", "err", err)
+		}
+		for t, v := range found {
+			d.known[t] = v
+		}
+	}
+
+	learned := map[string]int{}
+	for _, t := range ask {
+		if _, ok := d.known[t]; ok {
+			continue
+		}
+		if d.Client == nil {
+			d.missing[t] = true
+			continue
+		}
+		// RPC の失敗は覚えない（次のイベントで問い合わせ直す）
+		md, err := d.Client.GetCoinMetadata(ctx, t)
+		if err != nil {
+			log.Warn("coin metadata lookup failed", "coin", t, "err", err)
+			continue
+		}
+		if md == nil {
+			d.missing[t] = true
+			continue
+		}
+		d.known[t], learned[t] = md.Decimals, md.Decimals
+	}
+	if len(learned) > 0 && d.Store != nil {
+		if err := d.Store.SetTokenDecimals(ctx, learned); err != nil {
+			log.Warn("save token decimals failed", "err", err)
+		}
+	}
+
+	out := make(map[string]int, len(tokens))
+	for _, t := range tokens {
+		if v, ok := d.known[t]; ok {
+			out[t] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,241 @@
+// Package normalize はチェーン固有の Tx を共通のイベント形式へ変換する。
+// ワーカーの保存処理と API の Tx 詳細（未保存 Tx のオンデマンド取得）で共用する。
+package normalize
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// Transfer は Tx 内の 1 件の資産移動。
+// From が空ならミント（流入元なし）、To が空ならバーン（流出先なし）を表す。
+type Transfer struct {
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Token    string `json:"token"`
+	Amount   int64  `json:"amount"`
+	Decimals int    `json:"decimals"`
+}
+
+// BalanceChange はアドレス × トークンごとの残高変化（手数料を除く）
+type BalanceChange struct {
+	Address  string
+	Token    string
+	Delta    int64
+	Decimals int
+}
+
+// Event は正規化済みの Tx
+type Event struct {
+	Chain    string
+	TxHash   string
+	TS       time.Time
+	Sender   *string
+	Receiver *string
+	Token    *string
+	Amount   *int64
+	// Fee は送信者（fee payer）が払った手数料。Sui はストレージリベートを差し引いた実質の額（FeeBreakdown["net"]）
+	Fee *int64
+	// FeeBreakdown は手数料の内訳（キーはチェーンごとに異なる）
+	FeeBreakdown map[string]int64
+	Method       *string
+	Status       string
+	Transfers    []Transfer
+	Participants []store.Participant
+	// Programs は Tx が呼び出したプログラム / パッケージ（Solana: programId, Sui: MoveCall の package）
+	Programs []string
+	Raw      []byte
+}
+
+// Input は store への保存形式に変換する
+func (e Event) Input() store.TxEventInput {
+	status := e.Status
+	return store.TxEventInput{
+		TxHash:       e.TxHash,
+		TS:           e.TS,
+		Sender:       e.Sender,
+		Receiver:     e.Receiver,
+		Token:        e.Token,
+		Amount:       e.Amount,
+		Fee:          e.Fee,
+		Method:       e.Method,
+		Status:       &status,
+		Raw:          e.Raw,
+		Participants: e.Participants,
+	}
+}
+
+// PairTransfers は残高変化を送信側と受信側で突き合わせて Transfer に変換する。
+// トークンごとに減少側・増加側を金額の大きい順に貪欲に対応付け、
+// 対応しきれない残りはミント / バーンとして出力する。
+func PairTransfers(changes []BalanceChange) []Transfer {
+	type side struct {
+		addr   string
+		amount int64
+	}
+	tokens := []string{}
+	decimals := map[string]int{}
+	outs := map[string][]side{}
+	ins := map[string][]side{}
+	for _, c := range changes {
+		if c.Delta == 0 {
+			continue
+		}
+		if _, ok := decimals[c.Token]; !ok {
+			tokens = append(tokens, c.Token)
+			decimals[c.Token] = c.Decimals
+		}
+		if c.Delta < 0 {
+			outs[c.Token] = append(outs[c.Token], side{c.Address, -c.Delta})
+		} else {
+			ins[c.Token] = append(ins[c.Token], side{c.Address, c.Delta})
+		}
+	}
+
+	byAmount := func(xs []side) {
+		sort.SliceStable(xs, func(i, j int) bool {
+			if xs[i].amount != xs[j].amount {
+				return xs[i].amount > xs[j].amount
+			}
+			return xs[i].addr < xs[j].addr
+		})
+	}
+
+	var out []Transfer
+	for _, tok := range tokens {
+		from, to := outs[tok], ins[tok]
+		byAmount(from)
+		byAmount(to)
+		i, j := 0, 0
+		for i < len(from) && j < len(to) {
+			amt := min(from[i].amount, to[j].amount)
+			out = append(out, Transfer{From: from[i].addr, To: to[j].addr, Token: tok, Amount: amt, Decimals: decimals[tok]})
+			from[i].amount -= amt
+			to[j].amount -= amt
+			if from[i].amount == 0 {
+				i++
+			}
+			if to[j].amount == 0 {
+				j++
+			}
+		}
+		for ; i < len(from); i++ {
+			out = append(out, Transfer{From: from[i].addr, Token: tok, Amount: from[i].amount, Decimals: decimals[tok]})
+		}
+		for ; j < len(to); j++ {
+			out = append(out, Transfer{To: to[j].addr, Token: tok, Amount: to[j].amount, Decimals: decimals[tok]})
+		}
+	}
+	return out
+}
+
+// primaryTransfer はイベント行の token / amount に使う代表の移動を選ぶ（送信者発を優先）
+func primaryTransfer(ts []Transfer, sender *string) *Transfer {
+	if sender != nil {
+		for i := range ts {
+			if ts[i].From == *sender {
+				return &ts[i]
+			}
+		}
+	}
+	if len(ts) > 0 {
+		return &ts[0]
+	}
+	return nil
+}
+
+// setPrimary は代表の移動から token / amount を設定する
+func (e *Event) setPrimary() {
+	if p := primaryTransfer(e.Transfers, e.Sender); p != nil {
+		tok, amt := p.Token, p.Amount
+		e.Token, e.Amount = &tok, &amt
+	}
+}
+
+// Involves は address が送信者・受信者・関与アドレスのいずれかかを返す（表記の違いは正規化して比べる）
+func (e Event) Involves(address string) bool {
+	if e.Sender != nil && store.SameAddress(e.Chain, *e.Sender, address) ||
+		e.Receiver != nil && store.SameAddress(e.Chain, *e.Receiver, address) {
+		return true
+	}
+	for _, p := range e.Participants {
+		if store.SameAddress(e.Chain, p.Address, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// TxEvent は API レスポンス用の読み出し形式に変換する
+func (e Event) TxEvent() store.TxEvent {
+	status := e.Status
+	return store.TxEvent{
+		Chain:    e.Chain,
+		TxHash:   e.TxHash,
+		TS:       e.TS,
+		Sender:   e.Sender,
+		Receiver: e.Receiver,
+		Token:    e.Token,
+		Amount:   e.Amount,
+		Fee:      e.Fee,
+		Method:   e.Method,
+		Status:   &status,
+	}
+}
+
+// FromRaw は保存済みの raw JSON を再度正規化する（raw が無い / 解釈できない場合は ok=false）
+func FromRaw(chain, txHash string, ts time.Time, raw []byte) (ev Event, ok bool) {
+	if len(raw) == 0 {
+		return Event{}, false
+	}
+	switch chain {
+	case "solana":
+		var tx sol.TransactionWithMeta
+		if json.Unmarshal(raw, &tx) != nil {
+			return Event{}, false
+		}
+		ev = Solana(txHash, &tx)
+	case "sui":
+		var tx sui.TransactionBlockDetailed
+		if json.Unmarshal(raw, &tx) != nil {
+			return Event{}, false
+		}
+		ev = Sui(&tx, uint64(ts.UnixMilli()))
+		ev.TxHash = txHash
+	default:
+		return Event{}, false
+	}
+	return ev, true
+}
+
+// FormatAmount は最小単位の整数を decimals 桁の 10 進文字列にする（例: 1500000000, 9 → "1.5"）
+func FormatAmount(amount int64, decimals int) string {
+	if decimals <= 0 {
+		return strconv.FormatInt(amount, 10)
+	}
+	neg := amount < 0
+	u := uint64(amount)
+	if neg {
+		u = uint64(-amount)
+	}
+	s := strconv.FormatUint(u, 10)
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	intPart, frac := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+	out := intPart
+	if frac != "" {
+		out += "." + frac
+	}
+	if neg {
+		out = "-" + out
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package normalize
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+const (
+	// SOLDecimals は lamports → SOL の桁数
+	SOLDecimals = 9
+	// solanaBaseFeePerSig は署名 1 つあたりの基本手数料（lamports）
+	solanaBaseFeePerSig = 5000
+)
+
+// Solana は getTransaction の結果を正規化する
+func Solana(signature string, tx *sol.TransactionWithMeta) Event {
+	ev := Event{Chain: "solana", TxHash: signature, Status: store.TxStatusSuccess}
+
+	if tx.BlockTime != nil && *tx.BlockTime > 0 {
+		ev.TS = time.Unix(*tx.BlockTime, 0).UTC()
+	} else {
+		ev.TS = time.Now().UTC()
+	}
+
+	keys := solanaAccountKeys(tx)
+	if len(keys) > 0 {
+		s := keys[0]
+		ev.Sender = &s
+	}
+	if len(keys) > 1 {
+		r := keys[1]
+		ev.Receiver = &r
+	}
+
+	if tx.Meta != nil {
+		f := int64(tx.Meta.Fee)
+		ev.Fee = &f
+		base := int64(solanaBaseFeePerSig * len(tx.Transaction.Signatures))
+		if base > f {
+			base = f
+		}
+		ev.FeeBreakdown = map[string]int64{"total": f, "base": base, "priority": f - base}
+		if tx.Meta.Err != nil {
+			ev.Status = store.TxStatusFailed
+		}
+		ev.Transfers = PairTransfers(solanaBalanceChanges(keys, tx.Meta))
+	}
+	ev.setPrimary()
+
+	seen := map[string]bool{}
+	for _, ix := range tx.Transaction.Message.Instructions {
+		if ix.ProgramIDIndex >= 0 && ix.ProgramIDIndex < len(keys) && !seen[keys[ix.ProgramIDIndex]] {
+			seen[keys[ix.ProgramIDIndex]] = true
+			ev.Programs = append(ev.Programs, keys[ix.ProgramIDIndex])
+		}
+	}
+
+	// Tx に登場した全アカウントを participants として記録
+	for _, k := range keys {
+		ev.Participants = append(ev.Participants, store.Participant{Address: k, Role: store.RoleAccount})
+	}
+
+	ev.Raw, _ = json.Marshal(tx)
+	return ev
+}
+
+// solanaAccountKeys は静的な accountKeys の後ろに、アドレス参照テーブルから読み込んだ
+// writable → readonly のアカウントを連結する（meta の残高・accountIndex はこの並びを指す）
+func solanaAccountKeys(tx *sol.TransactionWithMeta) []string {
+	static := tx.Transaction.Message.AccountKeys
+	if tx.Meta == nil || tx.Meta.LoadedAddresses == nil {
+		return static
+	}
+	la := tx.Meta.LoadedAddresses
+	keys := make([]string, 0, len(static)+len(la.Writable)+len(la.Readonly))
+	keys = append(keys, static...)
+	keys = append(keys, la.Writable...)
+	return append(keys, la.Readonly...)
+}
+
+// solanaBalanceChanges は SOL と SPL トークンの残高変化を集計する。
+// 失敗 Tx では手数料以外の残高は動かないため空になる。
+func solanaBalanceChanges(keys []string, meta *sol.TxMeta) []BalanceChange {
+	var out []BalanceChange
+
+	for i := 0; i < len(keys) && i < len(meta.PreBalances) && i < len(meta.PostBalances); i++ {
+		delta := int64(meta.PostBalances[i]) - int64(meta.PreBalances[i])
+		if i == 0 {
+			// 手数料は fee payer（先頭アカウント）から引かれるので移動からは除く
+			delta += int64(meta.Fee)
+		}
+		if delta != 0 {
+			out = append(out, BalanceChange{Address: keys[i], Token: "SOL", Delta: delta, Decimals: SOLDecimals})
+		}
+	}
+
+	// accountIndex（トークンアカウント）ごとに前後を突き合わせる
+	pre := map[int]sol.TokenBalance{}
+	for _, b := range meta.PreTokenBalances {
+		pre[b.AccountIndex] = b
+	}
+	seen := map[int]bool{}
+	add := func(b sol.TokenBalance, preAmt, postAmt int64) {
+		owner := b.Owner
+		if owner == "" && b.AccountIndex < len(keys) {
+			owner = keys[b.AccountIndex]
+		}
+		if d := postAmt - preAmt; d != 0 {
+			out = append(out, BalanceChange{Address: owner, Token: b.Mint, Delta: d, Decimals: b.UITokenAmount.Decimals})
+		}
+	}
+	for _, post := range meta.PostTokenBalances {
+		seen[post.AccountIndex] = true
+		var preAmt int64
+		if p, ok := pre[post.AccountIndex]; ok {
+			preAmt = parseAmount(p.UITokenAmount.Amount)
+		}
+		add(post, preAmt, parseAmount(post.UITokenAmount.Amount))
+	}
+	// Tx 後に閉じられたトークンアカウント
+	for _, p := range meta.PreTokenBalances {
+		if !seen[p.AccountIndex] {
+			add(p, parseAmount(p.UITokenAmount.Amount), 0)
+		}
+	}
+	return out
+}
+
+func parseAmount(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
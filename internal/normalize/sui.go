@@ -0,0 +1,120 @@
+package normalize
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+const (
+	// SUIDecimals は MIST → SUI の桁数
+	SUIDecimals = 9
+	suiCoinType = "0x2::sui::SUI"
+)
+
+// Sui は sui_getTransactionBlock（詳細版）の結果を正規化する。
+// timestampMs はチェックポイントの時刻（0 なら Tx 側の timestampMs → 現在時刻の順に使う）。
+func Sui(tx *sui.TransactionBlockDetailed, timestampMs uint64) Event {
+	digest := tx.Digest
+	if digest == "" {
+		digest = tx.Effects.TransactionDigest
+	}
+	ev := Event{Chain: "sui", TxHash: digest, Status: store.TxStatusSuccess}
+	// 失敗した Tx も status=failed として保存する（ガス代は消費されるため）
+	if tx.Effects.Status.Status != "success" {
+		ev.Status = store.TxStatusFailed
+	}
+
+	if timestampMs == 0 {
+		timestampMs, _ = tx.TimestampMs.Value()
+	}
+	if timestampMs > 0 {
+		ev.TS = time.Unix(int64(timestampMs/1000), int64((timestampMs%1000)*1000000)).UTC()
+	} else {
+		ev.TS = time.Now().UTC()
+	}
+
+	// 送信者は Tx の sender、受信者は pure 引数のアドレス（transfer の宛先）から推定
+	if s := tx.Transaction.Data.Sender; s != "" {
+		ev.Sender = &s
+	}
+	for _, input := range tx.Transaction.Data.Message.Inputs {
+		if input.Type == "pure" && input.ValueType == "address" {
+			r := input.Value
+			if ev.Sender == nil {
+				// sender が取れない旧形式のレスポンスでは従来通り送信者として扱う
+				ev.Sender = &r
+			} else if r != *ev.Sender {
+				ev.Receiver = &r
+			}
+			break
+		}
+	}
+
+	// ガス代。Fee はストレージリベートを差し引いた実質のガス代（送信者の SUI 残高の減り。負になることもある）
+	gas := tx.Effects.GasUsed
+	comp, _ := gas.ComputationCost.Value()
+	storage, _ := gas.StorageCost.Value()
+	rebate, _ := gas.StorageRebate.Value()
+	nonRefundable, _ := gas.NonRefundableStorageFee.Value()
+	net := int64(comp) + int64(storage) - int64(rebate)
+	if comp+storage > 0 {
+		f := net
+		ev.Fee = &f
+	}
+	ev.FeeBreakdown = map[string]int64{
+		"computation":            int64(comp),
+		"storage":                int64(storage),
+		"storage_rebate":         int64(rebate),
+		"non_refundable_storage": int64(nonRefundable),
+		"net":                    net,
+	}
+
+	// メソッド名を抽出（簡易版）
+	if len(tx.Transaction.Data.Message.Transactions) > 0 {
+		kind := tx.Transaction.Data.Message.Transactions[0].Kind
+		ev.Method = &kind
+	}
+
+	var changes []BalanceChange
+	for _, bc := range tx.BalanceChanges {
+		addr := bc.Owner.AddressOwner
+		if addr == "" {
+			continue
+		}
+		delta, err := strconv.ParseInt(bc.Amount, 10, 64)
+		if err != nil {
+			continue
+		}
+		token, dec := bc.CoinType, 0
+		if token == suiCoinType {
+			token, dec = "SUI", SUIDecimals
+			// 送信者の SUI 変化にはガス代が含まれるので移動からは除く
+			if ev.Sender != nil && addr == *ev.Sender {
+				delta += net
+			}
+		}
+		changes = append(changes, BalanceChange{Address: addr, Token: token, Delta: delta, Decimals: dec})
+	}
+	ev.Transfers = PairTransfers(changes)
+	ev.setPrimary()
+
+	// MoveCall の呼び出し先パッケージ
+	seen := map[string]bool{}
+	for _, t := range tx.Transaction.Data.Message.Transactions {
+		if pkg, ok := t.Data["package"].(string); ok && pkg != "" && !seen[pkg] {
+			seen[pkg] = true
+			ev.Programs = append(ev.Programs, pkg)
+		}
+	}
+
+	for _, c := range changes {
+		ev.Participants = append(ev.Participants, store.Participant{Address: c.Address, Role: store.RoleAccount})
+	}
+
+	ev.Raw, _ = json.Marshal(tx)
+	return ev
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// ChannelStore はチャネルの読み出しと送信結果の記録先（*store.Store が満たす。テストでは差し替える）
+type ChannelStore interface {
+	ListNotificationChannels(ctx context.Context, tenant string, enabledOnly bool) ([]store.NotificationChannel, error)
+	RecordNotificationResult(ctx context.Context, id int64, sendErr error) error
+}
+
+// defaultSendTimeout は 1 チャネルへの 1 回の送信にかける時間の上限（既定）
+const defaultSendTimeout = 10 * time.Second
+
+// Dispatcher はアラートをテナントの有効なチャネルすべてへ送る
+type Dispatcher struct {
+	st      ChannelStore
+	timeout time.Duration
+}
+
+// NewDispatcher は 1 回の送信を sendTimeout で打ち切る Dispatcher を返す（0 以下なら既定の 10 秒）
+func NewDispatcher(st ChannelStore, sendTimeout time.Duration) *Dispatcher {
+	if sendTimeout <= 0 {
+		sendTimeout = defaultSendTimeout
+	}
+	return &Dispatcher{st: st, timeout: sendTimeout}
+}
+
+// Dispatch はアラートごとにテナントのチャネルへ送り、成功 / 失敗をチャネルに記録する。
+// あるチャネルの失敗は他のチャネルへの送信を妨げない。
+func (d *Dispatcher) Dispatch(ctx context.Context, alerts []store.Alert) {
+	channels := map[string][]store.NotificationChannel{}
+	for _, a := range alerts {
+		chs, ok := channels[a.Tenant]
+		if !ok {
+			var err error
+			chs, err = d.st.ListNotificationChannels(ctx, a.Tenant, true)
+			if err != nil {
+				logging.FromContext(ctx).Error("list notification channels", "tenant", a.Tenant, "err", err)
+				continue
+			}
+			channels[a.Tenant] = chs
+		}
+		for _, ch := range chs {
+			sctx, cancel := context.WithTimeout(ctx, d.timeout)
+			err := SendAlert(sctx, ch, a)
+			cancel()
+			if err != nil {
+				logging.FromContext(ctx).Warn("send notification", "channel_id", ch.ID, "channel_type", ch.Type, "alert_id", a.ID, "err", err)
+			}
+			if rerr := d.st.RecordNotificationResult(ctx, ch.ID, err); rerr != nil {
+				logging.FromContext(ctx).Error("record notification result", "channel_id", ch.ID, "err", rerr)
+			}
+		}
+	}
+}
+
+// SendAlert は 1 チャネルへアラートを 1 件送る（ctx に期限が無ければ既定の上限で打ち切る）
+func SendAlert(ctx context.Context, ch store.NotificationChannel, a store.Alert) error {
+	cfg, err := ParseConfig(ch.Type, ch.Config)
+	if err != nil {
+		return err
+	}
+	m, err := Render(ch.Type, cfg.Template, a)
+	if err != nil {
+		return err
+	}
+	s, err := NewSender(ch.Type, cfg)
+	if err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultSendTimeout)
+		defer cancel()
+	}
+	// エラーは last_error・ログ・テスト送信の応答に出るため、秘匿値が紛れていれば伏せる
+	if err := s.Send(ctx, m); err != nil {
+		if msg := cfg.RedactText(err.Error()); msg != err.Error() {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// Message は送信する通知（Subject はメールの件名、Text は本文）
+type Message struct {
+	Subject string
+	Text    string
+}
+
+// TemplateData はテンプレートに渡す値
+type TemplateData struct {
+	Title    string
+	Message  string
+	RuleType string
+	Chain    string
+	TxHash   string
+	TS       string
+	Address  string
+	// Label は Address のラベル（無ければ空）
+	Label string
+	Token string
+	// Amount は decimals 適用済みの金額（例: "1.5"）。金額の無いアラートでは空
+	Amount     string
+	TxURL      string
+	AddressURL string
+}
+
+// ExplorerTxURL はチェーンのエクスプローラーの Tx ページ
+func ExplorerTxURL(chain, hash string) string {
+	if chain == "sui" {
+		return "https://suiscan.xyz/mainnet/tx/" + hash
+	}
+	return "https://solscan.io/tx/" + hash
+}
+
+// ExplorerAddressURL はチェーンのエクスプローラーのアドレスページ
+func ExplorerAddressURL(chain, addr string) string {
+	if chain == "sui" {
+		return "https://suiscan.xyz/mainnet/account/" + addr
+	}
+	return "https://solscan.io/account/" + addr
+}
+
+// 種別ごとの既定テンプレート（Slack は mrkdwn、Discord は Markdown）
+var defaultTemplates = map[string]string{
+	store.ChannelSlack: "*{{.Title}}*\n{{.Message}}" +
+		"{{if .Amount}}\nAmount: {{.Amount}} {{.Token}}{{end}}" +
+		"{{if .AddressURL}}\nAddress: {{if .Label}}{{.Label}} {{end}}<{{.AddressURL}}|{{.Address}}>{{end}}" +
+		"\n<{{.TxURL}}|View transaction> ({{.TS}})",
+	store.ChannelDiscord: "**{{.Title}}**\n{{.Message}}" +
+		"{{if .Amount}}\nAmount: {{.Amount}} {{.Token}}{{end}}" +
+		"{{if .AddressURL}}\nAddress: {{if .Label}}{{.Label}} {{end}}[{{.Address}}](<{{.AddressURL}}>){{end}}" +
+		"\n[View transaction](<{{.TxURL}}>) ({{.TS}})",
+	store.ChannelTelegram: "{{.Title}}\n{{.Message}}" +
+		"{{if .Amount}}\nAmount: {{.Amount}} {{.Token}}{{end}}" +
+		"{{if .Label}}\nAddress: {{.Label}}{{end}}" +
+		"\n{{.TxURL}}",
+	store.ChannelEmail: "{{.Message}}\n\n" +
+		"Rule:    {{.RuleType}}\nChain:   {{.Chain}}\nTime:    {{.TS}}" +
+		"{{if .Amount}}\nAmount:  {{.Amount}} {{.Token}}{{end}}" +
+		"{{if .Address}}\nAddress: {{if .Label}}{{.Label}} ({{.Address}}){{else}}{{.Address}}{{end}}\n         {{.AddressURL}}{{end}}" +
+		"\nTx:      {{.TxHash}}\n         {{.TxURL}}\n",
+}
+
+func parseTemplate(s string) (*template.Template, error) {
+	return template.New("notify").Option("missingkey=error").Parse(s)
+}
+
+// DataOf はアラートからテンプレート用の値を作る
+func DataOf(a store.Alert) TemplateData {
+	d := TemplateData{
+		Title:    fmt.Sprintf("[%s] %s alert", a.Chain, a.RuleType),
+		Message:  a.Message,
+		RuleType: a.RuleType,
+		Chain:    a.Chain,
+		TxHash:   a.TxHash,
+		TS:       a.TS.UTC().Format(time.RFC3339),
+		TxURL:    ExplorerTxURL(a.Chain, a.TxHash),
+	}
+	if a.Address != nil {
+		d.Address = *a.Address
+		d.AddressURL = ExplorerAddressURL(a.Chain, *a.Address)
+	}
+	if a.AddressLabel != nil {
+		d.Label = *a.AddressLabel
+	}
+	if a.Token != nil {
+		d.Token = *a.Token
+	}
+	if a.Amount != nil {
+		dec := 0
+		if a.Decimals != nil {
+			dec = *a.Decimals
+		}
+		d.Amount = normalize.FormatAmount(*a.Amount, dec)
+	}
+	return d
+}
+
+// Render はチャネル種別（と任意の上書きテンプレート）でアラートの通知文を作る
+func Render(channelType, tmpl string, a store.Alert) (Message, error) {
+	if tmpl == "" {
+		tmpl = defaultTemplates[channelType]
+	}
+	t, err := parseTemplate(tmpl)
+	if err != nil {
+		return Message{}, err
+	}
+	d := DataOf(a)
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, d); err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: "[wallet-watcher] " + d.Title, Text: buf.String()}, nil
+}
@@ -0,0 +1,152 @@
+// Package notify はアラートをテナントの通知チャネル（Slack / Discord / Telegram / メール）へ送る。
+// 文面は text/template で組み立て、エクスプローラーへのリンクと decimals 適用済みの金額を含める。
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// Config は notification_channels.config の中身（種別ごとに使う項目が異なる）
+type Config struct {
+	// slack / discord
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// telegram（APIURL は省略時 https://api.telegram.org）
+	BotToken string `json:"bot_token,omitempty"`
+	ChatID   string `json:"chat_id,omitempty"`
+	APIURL   string `json:"api_url,omitempty"`
+	// email
+	SMTPAddr string   `json:"smtp_addr,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	// Template は本文の text/template（省略時は種別ごとの既定）
+	Template string `json:"template,omitempty"`
+}
+
+// ParseConfig は種別に対して設定を検証する
+func ParseConfig(channelType string, raw json.RawMessage) (Config, error) {
+	var c Config
+	if len(raw) == 0 {
+		return c, errors.New("config is required")
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, fmt.Errorf("invalid config: %v", err)
+	}
+	httpURL := func(name, v string) error {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("config.%s must be an http(s) URL", name)
+		}
+		return nil
+	}
+	switch channelType {
+	case store.ChannelSlack, store.ChannelDiscord:
+		if err := httpURL("webhook_url", c.WebhookURL); err != nil {
+			return c, err
+		}
+	case store.ChannelTelegram:
+		if c.BotToken == "" || c.ChatID == "" {
+			return c, errors.New("telegram requires config.bot_token and config.chat_id")
+		}
+		if c.APIURL != "" {
+			if err := httpURL("api_url", c.APIURL); err != nil {
+				return c, err
+			}
+		}
+	case store.ChannelEmail:
+		if !strings.Contains(c.SMTPAddr, ":") {
+			return c, errors.New("email requires config.smtp_addr (host:port)")
+		}
+		if _, err := mail.ParseAddress(c.From); err != nil {
+			return c, errors.New("email requires a valid config.from")
+		}
+		if len(c.To) == 0 {
+			return c, errors.New("email requires config.to")
+		}
+		for _, to := range c.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return c, fmt.Errorf("invalid recipient %q", to)
+			}
+		}
+	default:
+		return c, fmt.Errorf("type must be one of %s, %s, %s, %s", store.ChannelSlack, store.ChannelDiscord, store.ChannelTelegram, store.ChannelEmail)
+	}
+	if c.Template != "" {
+		if _, err := parseTemplate(c.Template); err != nil {
+			return c, fmt.Errorf("invalid config.template: %v", err)
+		}
+	}
+	return c, nil
+}
+
+// Redacted は API で返すための、秘匿値（webhook URL・トークン・パスワード）を伏せた設定
+func (c Config) Redacted() Config {
+	mask := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "***"
+	}
+	if c.WebhookURL != "" {
+		c.WebhookURL = redactURL(c.WebhookURL)
+	}
+	c.BotToken = mask(c.BotToken)
+	c.Password = mask(c.Password)
+	return c
+}
+
+// RedactText は s（送信エラーなど）に含まれる秘匿値を伏せる
+func (c Config) RedactText(s string) string {
+	if c.WebhookURL != "" {
+		s = strings.ReplaceAll(s, c.WebhookURL, redactURL(c.WebhookURL))
+	}
+	for _, secret := range []string{c.BotToken, c.Password} {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "***")
+		}
+	}
+	return s
+}
+
+// redactURL は URL をスキームとホストだけにする（パス・クエリに秘匿値が入るため）
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return "***"
+	}
+	return u.Scheme + "://" + u.Host + "/***"
+}
+
+// Sender は 1 チャネルへの送信
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// NewSender は種別と設定から送信器を作る
+func NewSender(channelType string, c Config) (Sender, error) {
+	switch channelType {
+	case store.ChannelSlack:
+		return &slackSender{url: c.WebhookURL}, nil
+	case store.ChannelDiscord:
+		return &discordSender{url: c.WebhookURL}, nil
+	case store.ChannelTelegram:
+		api := c.APIURL
+		if api == "" {
+			api = "https://api.telegram.org"
+		}
+		return &telegramSender{api: strings.TrimRight(api, "/"), token: c.BotToken, chatID: c.ChatID}, nil
+	case store.ChannelEmail:
+		return &emailSender{addr: c.SMTPAddr, username: c.Username, password: c.Password, from: c.From, to: c.To}, nil
+	default:
+		return nil, fmt.Errorf("unsupported channel type: %s", channelType)
+	}
+}
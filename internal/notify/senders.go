@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON は JSON を POST し、2xx 以外をエラーにする。
+// webhook URL や Telegram のトークンは URL の一部なので、エラーに URL をそのまま載せない（last_error とログに残るため）
+func postJSON(ctx context.Context, endpoint string, body any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("invalid url %s", redactURL(endpoint))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			return fmt.Errorf("post %s: %w", redactURL(endpoint), ue.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// slackSender は Slack の Incoming Webhook
+type slackSender struct{ url string }
+
+func (s *slackSender) Send(ctx context.Context, m Message) error {
+	return postJSON(ctx, s.url, map[string]any{"text": m.Text, "unfurl_links": false})
+}
+
+// discordSender は Discord の Webhook（content は 2000 文字まで）
+type discordSender struct{ url string }
+
+func (s *discordSender) Send(ctx context.Context, m Message) error {
+	text := m.Text
+	if r := []rune(text); len(r) > 2000 {
+		text = string(r[:1997]) + "..."
+	}
+	return postJSON(ctx, s.url, map[string]any{"content": text})
+}
+
+// telegramSender は Telegram Bot API の sendMessage
+type telegramSender struct{ api, token, chatID string }
+
+func (s *telegramSender) Send(ctx context.Context, m Message) error {
+	return postJSON(ctx, s.api+"/bot"+s.token+"/sendMessage", map[string]any{
+		"chat_id":                  s.chatID,
+		"text":                     m.Text,
+		"disable_web_page_preview": true,
+	})
+}
@@ -0,0 +1,383 @@
+// Package pnl はアドレスのイベントを古い順に再生して取得ロットと処分を記録し、
+// 実現損益・含み損益と年ごとの譲渡損益を求める（FIFO / LIFO / 移動平均）。
+//
+// 受取は取得、送付は処分として扱う。アドレスが払った手数料は、ネイティブトークンの処分（kind=fee）として
+// 数量を減らすとともに、同じ Tx の送付の譲渡対価から差し引く（送付の無い Tx では経費として別に集計する）。
+package pnl
+
+import (
+	"context"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/pricing"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// 原価の計算方法
+const (
+	FIFO    = "fifo"
+	LIFO    = "lifo"
+	Average = "average"
+)
+
+// ValidMethod は FIFO / LIFO / Average のいずれかかを返す
+func ValidMethod(m string) bool {
+	return m == FIFO || m == LIFO || m == Average
+}
+
+// 処分の種類
+const (
+	KindTransfer = "transfer"
+	KindFee      = "fee"
+)
+
+// 保有期間の区分
+const (
+	TermShort = "short"
+	TermLong  = "long"
+)
+
+// LongTermAfter より長く保有したロットの処分は長期（long）
+const LongTermAfter = 365 * 24 * time.Hour
+
+// nativeToken はチェーンの手数料トークン
+var nativeToken = map[string]string{"solana": "SOL", "sui": "SUI"}
+
+// Event はアドレスから見た 1 イベント
+type Event struct {
+	TxHash string
+	TS     time.Time
+	Token  string
+	// Delta は受取なら正、送付なら負。失敗した Tx・自己送金・無関係なら 0
+	Delta int64
+	// ValueUSD は |Delta| の Tx 時刻の USD 評価（分からなければ nil）
+	ValueUSD *float64
+	// Fee はアドレスが払った手数料（FeeToken の最小単位）
+	FeeToken string
+	Fee      int64
+	FeeUSD   *float64
+}
+
+// FromTxEvent は保存済みイベントを address から見た Event にする。
+// 手数料は送信者（fee payer）が払ったものとして扱う。
+func FromTxEvent(chain, address string, e store.TxEvent) Event {
+	ev := Event{TxHash: e.TxHash, TS: e.TS, FeeToken: nativeToken[chain]}
+	isSender := e.Sender != nil && store.SameAddress(chain, *e.Sender, address)
+	isReceiver := e.Receiver != nil && store.SameAddress(chain, *e.Receiver, address)
+	if isSender && e.Fee != nil && *e.Fee > 0 {
+		ev.Fee = *e.Fee
+	}
+	failed := e.Status != nil && *e.Status == store.TxStatusFailed
+	if failed || e.Token == nil || e.Amount == nil || *e.Amount <= 0 || isSender == isReceiver {
+		return ev
+	}
+	ev.Token = *e.Token
+	if isReceiver {
+		ev.Delta = *e.Amount
+	} else {
+		ev.Delta = -*e.Amount
+	}
+	return ev
+}
+
+// FromEvent は raw から正規化し直したイベントを address から見た Event にする。
+// 移動（Transfers）をトークンごとに差し引きし、増えたトークンは取得、減ったトークンは処分として
+// 1 つずつ Event にする（スワップの両方の脚が残る。自己送金は差し引き 0 になる）。
+// 手数料は送信者が払ったものとして、処分があれば最初の処分に、無ければ最初の Event に付ける。
+func FromEvent(address string, ev normalize.Event) []Event {
+	var out []Event
+	idx := map[string]int{}
+	if ev.Status != store.TxStatusFailed {
+		for _, t := range ev.Transfers {
+			var d int64
+			if t.To != "" && store.SameAddress(ev.Chain, t.To, address) {
+				d += t.Amount
+			}
+			if t.From != "" && store.SameAddress(ev.Chain, t.From, address) {
+				d -= t.Amount
+			}
+			if d == 0 {
+				continue
+			}
+			i, ok := idx[t.Token]
+			if !ok {
+				i = len(out)
+				idx[t.Token] = i
+				out = append(out, Event{TxHash: ev.TxHash, TS: ev.TS, Token: t.Token, FeeToken: nativeToken[ev.Chain]})
+			}
+			out[i].Delta += d
+		}
+	}
+	n := 0
+	for _, e := range out {
+		if e.Delta != 0 {
+			out[n] = e
+			n++
+		}
+	}
+	out = out[:n]
+
+	if ev.Fee != nil && *ev.Fee > 0 && ev.Sender != nil && store.SameAddress(ev.Chain, *ev.Sender, address) {
+		at := -1
+		for i, e := range out {
+			if e.Delta < 0 {
+				at = i
+				break
+			}
+		}
+		if at < 0 {
+			if len(out) == 0 {
+				out = append(out, Event{TxHash: ev.TxHash, TS: ev.TS, FeeToken: nativeToken[ev.Chain]})
+			}
+			at = 0
+		}
+		out[at].Fee = *ev.Fee
+	}
+	return out
+}
+
+// Valuer は Tx 時刻の USD 評価（*pricing.Service が満たす）
+type Valuer interface {
+	ValuesAt(ctx context.Context, items []pricing.Item) []*float64
+}
+
+// Price は各イベントの移動量と手数料を Tx 時刻の価格で評価する
+func Price(ctx context.Context, v Valuer, events []Event) {
+	var items []pricing.Item
+	type ref struct {
+		i   int
+		fee bool
+	}
+	var refs []ref
+	for i, e := range events {
+		if e.Delta != 0 {
+			items = append(items, pricing.Item{Token: e.Token, Amount: abs(e.Delta), At: e.TS})
+			refs = append(refs, ref{i, false})
+		}
+		if e.Fee > 0 {
+			items = append(items, pricing.Item{Token: e.FeeToken, Amount: e.Fee, At: e.TS})
+			refs = append(refs, ref{i, true})
+		}
+	}
+	if len(items) == 0 {
+		return
+	}
+	values := v.ValuesAt(ctx, items)
+	for j, r := range refs {
+		if r.fee {
+			events[r.i].FeeUSD = values[j]
+		} else {
+			events[r.i].ValueUSD = values[j]
+		}
+	}
+}
+
+// Expense は送付を伴わない Tx の手数料（経費）
+type Expense struct {
+	TxHash string    `json:"tx_hash"`
+	TS     time.Time `json:"ts"`
+	Token  string    `json:"token"`
+	USD    float64   `json:"usd"`
+}
+
+// Result は再生の結果
+type Result struct {
+	Method    string
+	Lots      []store.CostLot
+	Disposals []store.CostDisposal
+	Expenses  []Expense
+	// FeesUSD は手数料トークンごとの支払手数料の合計（評価できた分）
+	FeesUSD map[string]float64
+	// Unpriced はトークンごとの価格が分からなかった取得・処分の件数
+	Unpriced map[string]int
+}
+
+// book はトークンごとの保有状況
+type book struct {
+	// open は未処分の数量が残るロット（Result.Lots の添字）の取得順
+	open []int
+	// qty / cost は移動平均用の保有数量と原価
+	qty  int64
+	cost float64
+}
+
+type replayer struct {
+	res   Result
+	books map[string]*book
+}
+
+// Replay は events（古い順）を method で再生する
+func Replay(method string, events []Event) Result {
+	r := &replayer{
+		res:   Result{Method: method, FeesUSD: map[string]float64{}, Unpriced: map[string]int{}},
+		books: map[string]*book{},
+	}
+	for _, e := range events {
+		var feeUSD float64
+		if e.Fee > 0 && e.FeeUSD != nil {
+			feeUSD = *e.FeeUSD
+			r.res.FeesUSD[e.FeeToken] += feeUSD
+		}
+		switch {
+		case e.Delta > 0:
+			r.acquire(e, e.Delta, e.ValueUSD)
+		case e.Delta < 0:
+			// 手数料は譲渡対価から差し引く（売却費用）
+			proceeds, priced := 0.0, e.ValueUSD != nil
+			if priced {
+				proceeds = *e.ValueUSD - feeUSD
+			}
+			r.dispose(e, e.Token, KindTransfer, -e.Delta, proceeds, priced)
+		}
+		if e.Fee > 0 {
+			r.dispose(e, e.FeeToken, KindFee, e.Fee, feeUSD, e.FeeUSD != nil)
+			if e.Delta >= 0 && e.FeeUSD != nil {
+				r.res.Expenses = append(r.res.Expenses, Expense{TxHash: e.TxHash, TS: e.TS, Token: e.FeeToken, USD: feeUSD})
+			}
+		}
+	}
+	if method == Average {
+		// 残っているロットの原価は平均単価で置き直す
+		for _, b := range r.books {
+			for _, i := range b.open {
+				lot := &r.res.Lots[i]
+				lot.RemainingCostUSD = round(b.cost * float64(lot.Remaining) / float64(b.qty))
+			}
+		}
+	}
+	return r.res
+}
+
+func (r *replayer) book(token string) *book {
+	b := r.books[token]
+	if b == nil {
+		b = &book{}
+		r.books[token] = b
+	}
+	return b
+}
+
+func (r *replayer) acquire(e Event, amount int64, usd *float64) {
+	cost := 0.0
+	if usd != nil {
+		cost = *usd
+	} else {
+		r.res.Unpriced[e.Token]++
+	}
+	r.res.Lots = append(r.res.Lots, store.CostLot{
+		Seq:              len(r.res.Lots) + 1,
+		Token:            e.Token,
+		TxHash:           e.TxHash,
+		TS:               e.TS,
+		Amount:           amount,
+		CostUSD:          cost,
+		Remaining:        amount,
+		RemainingCostUSD: cost,
+		Unpriced:         usd == nil,
+	})
+	b := r.book(e.Token)
+	b.open = append(b.open, len(r.res.Lots)-1)
+	b.qty += amount
+	b.cost += cost
+}
+
+// dispose は token を amount だけ処分する。proceeds は amount 全体の譲渡対価
+func (r *replayer) dispose(e Event, token, kind string, amount int64, proceeds float64, priced bool) {
+	if !priced {
+		r.res.Unpriced[token]++
+	}
+	b := r.book(token)
+	total := amount
+
+	if r.res.Method == Average {
+		matched := min(amount, b.qty)
+		if matched > 0 {
+			cost := b.cost * float64(matched) / float64(b.qty)
+			b.qty -= matched
+			b.cost -= cost
+			// ロットの残量は取得順に減らす（原価は平均で按分）
+			r.consume(b, matched)
+			r.addDisposal(e, token, kind, matched, share(proceeds, matched, total), cost, nil)
+			amount -= matched
+		}
+	} else {
+		for amount > 0 && len(b.open) > 0 {
+			pos := 0
+			if r.res.Method == LIFO {
+				pos = len(b.open) - 1
+			}
+			lot := &r.res.Lots[b.open[pos]]
+			q := min(amount, lot.Remaining)
+			cost := lot.RemainingCostUSD * float64(q) / float64(lot.Remaining)
+			lot.Remaining -= q
+			lot.RemainingCostUSD -= cost
+			if lot.Remaining == 0 {
+				lot.RemainingCostUSD = 0
+				b.open = append(b.open[:pos], b.open[pos+1:]...)
+			}
+			b.qty -= q
+			b.cost -= cost
+			r.addDisposal(e, token, kind, q, share(proceeds, q, total), cost, lot)
+			amount -= q
+		}
+	}
+
+	// 取得記録の無い数量（監視開始前の残高など）は原価 0
+	if amount > 0 {
+		r.addDisposal(e, token, kind, amount, share(proceeds, amount, total), 0, nil)
+		r.res.Disposals[len(r.res.Disposals)-1].Unmatched = true
+	}
+}
+
+// consume は移動平均のロットの残量を取得順に減らす
+func (r *replayer) consume(b *book, amount int64) {
+	for amount > 0 && len(b.open) > 0 {
+		lot := &r.res.Lots[b.open[0]]
+		q := min(amount, lot.Remaining)
+		lot.RemainingCostUSD -= lot.RemainingCostUSD * float64(q) / float64(lot.Remaining)
+		lot.Remaining -= q
+		if lot.Remaining == 0 {
+			lot.RemainingCostUSD = 0
+			b.open = b.open[1:]
+		}
+		amount -= q
+	}
+}
+
+func (r *replayer) addDisposal(e Event, token, kind string, amount int64, proceeds, cost float64, lot *store.CostLot) {
+	d := store.CostDisposal{
+		Seq:         len(r.res.Disposals) + 1,
+		Token:       token,
+		TxHash:      e.TxHash,
+		TS:          e.TS,
+		Kind:        kind,
+		Amount:      amount,
+		ProceedsUSD: round(proceeds),
+		CostUSD:     round(cost),
+		GainUSD:     round(proceeds - cost),
+	}
+	if lot != nil {
+		seq, at := lot.Seq, lot.TS
+		d.LotSeq, d.AcquiredAt = &seq, &at
+		d.Term = TermShort
+		if e.TS.Sub(at) > LongTermAfter {
+			d.Term = TermLong
+		}
+	}
+	r.res.Disposals = append(r.res.Disposals, d)
+}
+
+func share(v float64, part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return v * float64(part) / float64(total)
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
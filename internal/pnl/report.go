@@ -0,0 +1,213 @@
+package pnl
+
+import (
+	"math"
+	"sort"
+
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// TokenSummary はトークンごとの損益
+type TokenSummary struct {
+	Token string `json:"token"`
+	// Held は未処分の数量、CostBasisUSD はその原価
+	Held         int64   `json:"held"`
+	CostBasisUSD float64 `json:"cost_basis_usd"`
+	// MarketValueUSD / UnrealizedUSD は現在価格が分かる場合のみ
+	MarketValueUSD *float64 `json:"market_value_usd,omitempty"`
+	UnrealizedUSD  *float64 `json:"unrealized_usd,omitempty"`
+	Acquired       int64    `json:"acquired"`
+	Disposed       int64    `json:"disposed"`
+	ProceedsUSD    float64  `json:"proceeds_usd"`
+	RealizedUSD    float64  `json:"realized_usd"`
+	// FeesUSD はこのトークンで払った手数料
+	FeesUSD float64 `json:"fees_usd,omitempty"`
+	// Unmatched は取得記録の無い処分数量、Unpriced は価格が分からなかった件数
+	Unmatched int64 `json:"unmatched,omitempty"`
+	Unpriced  int   `json:"unpriced,omitempty"`
+}
+
+// TaxYear は処分日（UTC）の年ごとの譲渡損益
+type TaxYear struct {
+	Year         int     `json:"year"`
+	Disposals    int     `json:"disposals"`
+	ProceedsUSD  float64 `json:"proceeds_usd"`
+	CostUSD      float64 `json:"cost_usd"`
+	GainUSD      float64 `json:"gain_usd"`
+	ShortTermUSD float64 `json:"short_term_usd"`
+	LongTermUSD  float64 `json:"long_term_usd"`
+	// ExpensesUSD は送付を伴わない Tx の手数料（譲渡損益には含めない）
+	ExpensesUSD float64 `json:"expenses_usd"`
+	// Details は year を指定したときだけ付ける処分の明細
+	Details  []store.CostDisposal `json:"details,omitempty"`
+	Expenses []Expense            `json:"expenses,omitempty"`
+}
+
+// Totals は全トークンの合計
+type Totals struct {
+	RealizedUSD float64 `json:"realized_usd"`
+	// UnrealizedUSD は現在価格が分かるトークンだけの合計
+	UnrealizedUSD float64 `json:"unrealized_usd"`
+	FeesUSD       float64 `json:"fees_usd"`
+}
+
+// Report は GET /pnl の応答
+type Report struct {
+	Chain    string          `json:"chain"`
+	Address  string          `json:"address"`
+	Method   string          `json:"method"`
+	Tokens   []TokenSummary  `json:"tokens"`
+	Totals   Totals          `json:"totals"`
+	TaxYears []TaxYear       `json:"tax_years"`
+	OpenLots []store.CostLot `json:"open_lots,omitempty"`
+}
+
+// Held はトークンごとの未処分の数量（現在価格での評価用）
+func (r Result) Held() []store.TokenBalance {
+	held := map[string]int64{}
+	var order []string
+	for _, l := range r.Lots {
+		if l.Remaining <= 0 {
+			continue
+		}
+		if _, ok := held[l.Token]; !ok {
+			order = append(order, l.Token)
+		}
+		held[l.Token] += l.Remaining
+	}
+	out := make([]store.TokenBalance, len(order))
+	for i, t := range order {
+		out[i] = store.TokenBalance{Token: t, Amount: held[t]}
+	}
+	return out
+}
+
+// ReportOptions は Report の組み立て方
+type ReportOptions struct {
+	// MarketUSD はトークンごとの保有数量の現在の USD 評価（分からないトークンは含めない）
+	MarketUSD map[string]float64
+	// Year が 0 でなければその年だけを明細付きで返す
+	Year int
+	// OpenLots なら未処分のロットを含める
+	OpenLots bool
+}
+
+// BuildReport は再生結果をトークン別・年別に集計する
+func BuildReport(chain, address string, res Result, opt ReportOptions) Report {
+	rep := Report{Chain: chain, Address: address, Method: res.Method, Tokens: []TokenSummary{}, TaxYears: []TaxYear{}}
+
+	tokens := map[string]*TokenSummary{}
+	tok := func(t string) *TokenSummary {
+		s := tokens[t]
+		if s == nil {
+			s = &TokenSummary{Token: t}
+			tokens[t] = s
+		}
+		return s
+	}
+	for _, l := range res.Lots {
+		s := tok(l.Token)
+		s.Acquired += l.Amount
+		s.Held += l.Remaining
+		s.CostBasisUSD += l.RemainingCostUSD
+		if l.Remaining > 0 && opt.OpenLots {
+			rep.OpenLots = append(rep.OpenLots, l)
+		}
+	}
+
+	years := map[int]*TaxYear{}
+	year := func(y int) *TaxYear {
+		ty := years[y]
+		if ty == nil {
+			ty = &TaxYear{Year: y}
+			years[y] = ty
+		}
+		return ty
+	}
+	for _, d := range res.Disposals {
+		s := tok(d.Token)
+		s.Disposed += d.Amount
+		s.ProceedsUSD += d.ProceedsUSD
+		s.RealizedUSD += d.GainUSD
+		if d.Unmatched {
+			s.Unmatched += d.Amount
+		}
+
+		y := d.TS.UTC().Year()
+		if opt.Year != 0 && y != opt.Year {
+			continue
+		}
+		ty := year(y)
+		ty.Disposals++
+		ty.ProceedsUSD += d.ProceedsUSD
+		ty.CostUSD += d.CostUSD
+		ty.GainUSD += d.GainUSD
+		switch d.Term {
+		case TermShort:
+			ty.ShortTermUSD += d.GainUSD
+		case TermLong:
+			ty.LongTermUSD += d.GainUSD
+		}
+		if opt.Year != 0 {
+			ty.Details = append(ty.Details, d)
+		}
+	}
+	for _, e := range res.Expenses {
+		y := e.TS.UTC().Year()
+		if opt.Year != 0 && y != opt.Year {
+			continue
+		}
+		ty := year(y)
+		ty.ExpensesUSD += e.USD
+		if opt.Year != 0 {
+			ty.Expenses = append(ty.Expenses, e)
+		}
+	}
+	if opt.Year != 0 {
+		// 処分の無い年でも空の集計を返す
+		year(opt.Year)
+	}
+
+	for t, fee := range res.FeesUSD {
+		tok(t).FeesUSD += fee
+		rep.Totals.FeesUSD += fee
+	}
+	for t, n := range res.Unpriced {
+		tok(t).Unpriced += n
+	}
+
+	for _, s := range tokens {
+		s.CostBasisUSD = round(s.CostBasisUSD)
+		s.ProceedsUSD = round(s.ProceedsUSD)
+		s.RealizedUSD = round(s.RealizedUSD)
+		s.FeesUSD = round(s.FeesUSD)
+		if mv, ok := opt.MarketUSD[s.Token]; ok && s.Held > 0 {
+			mv, u := round(mv), round(mv-s.CostBasisUSD)
+			s.MarketValueUSD, s.UnrealizedUSD = &mv, &u
+			rep.Totals.UnrealizedUSD += u
+		}
+		rep.Totals.RealizedUSD += s.RealizedUSD
+		rep.Tokens = append(rep.Tokens, *s)
+	}
+	sort.Slice(rep.Tokens, func(i, j int) bool { return rep.Tokens[i].Token < rep.Tokens[j].Token })
+	rep.Totals.RealizedUSD = round(rep.Totals.RealizedUSD)
+	rep.Totals.UnrealizedUSD = round(rep.Totals.UnrealizedUSD)
+	rep.Totals.FeesUSD = round(rep.Totals.FeesUSD)
+
+	for _, ty := range years {
+		ty.ProceedsUSD = round(ty.ProceedsUSD)
+		ty.CostUSD = round(ty.CostUSD)
+		ty.GainUSD = round(ty.GainUSD)
+		ty.ShortTermUSD = round(ty.ShortTermUSD)
+		ty.LongTermUSD = round(ty.LongTermUSD)
+		ty.ExpensesUSD = round(ty.ExpensesUSD)
+		rep.TaxYears = append(rep.TaxYears, *ty)
+	}
+	sort.Slice(rep.TaxYears, func(i, j int) bool { return rep.TaxYears[i].Year < rep.TaxYears[j].Year })
+	return rep
+}
+
+// round は USD を 1e-8 に丸める（浮動小数の誤差を表示に出さない）
+func round(v float64) float64 {
+	return math.Round(v*1e8) / 1e8
+}
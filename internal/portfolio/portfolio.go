@@ -0,0 +1,180 @@
+// Package portfolio は複数アドレス・複数チェーンの残高を並行に集め、トークンごとに合算する。
+// 取得に失敗したアドレスは全体を失敗させず、そのアドレスのエラーとして返す。
+package portfolio
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// 残高の出どころ
+const (
+	SourceLive  = "live"
+	SourceCache = "cache"
+)
+
+// DefaultConcurrency は RPC の同時呼び出し数の既定
+const DefaultConcurrency = 8
+
+// Wallet は集計対象のアドレス
+type Wallet struct {
+	Chain   string  `json:"chain"`
+	Address string  `json:"address"`
+	Label   *string `json:"label,omitempty"`
+}
+
+// WalletBalances は 1 アドレス分の結果。Error があれば Balances は空
+type WalletBalances struct {
+	Wallet
+	Balances []store.TokenBalance `json:"balances"`
+	// Source は live（RPC）/ cache（スナップショット）
+	Source    string     `json:"source,omitempty"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// TokenTotal はチェーン × トークンの合計（最小単位）と保有アドレス数
+type TokenTotal struct {
+	Chain   string `json:"chain"`
+	Token   string `json:"token"`
+	Amount  int64  `json:"amount"`
+	Wallets int    `json:"wallets"`
+}
+
+// Portfolio は集計結果
+type Portfolio struct {
+	Totals  []TokenTotal     `json:"totals"`
+	Wallets []WalletBalances `json:"wallets"`
+	// Failed は取得できなかったアドレス数（Totals に含まれない）
+	Failed int `json:"failed"`
+}
+
+// FetchFunc はアドレスの現在残高を RPC から取得する
+type FetchFunc func(ctx context.Context, chain, address string) ([]store.TokenBalance, error)
+
+// Snapshots は残高スナップショットの保存先（*store.Store が満たす。テストでは差し替える）
+type Snapshots interface {
+	GetBalanceSnapshots(ctx context.Context, keys []store.WatchKey) (map[store.WatchKey]store.BalanceSnapshot, error)
+	SaveBalanceSnapshot(ctx context.Context, chain, address string, balances []store.TokenBalance) error
+}
+
+// Options は Collect の設定
+type Options struct {
+	// Concurrency は RPC の同時呼び出し数（0 以下なら DefaultConcurrency）
+	Concurrency int
+	// MaxAge 以内に取得したスナップショットがあれば RPC を呼ばない（0 なら常に RPC）
+	MaxAge time.Duration
+	// Snapshots が nil ならスナップショットを読み書きしない
+	Snapshots Snapshots
+}
+
+// Collect は wallets の残高を並行に集める（結果は wallets と同じ順）。
+// RPC から取れた残高はスナップショットとして保存する。
+func Collect(ctx context.Context, wallets []Wallet, fetch FetchFunc, opt Options) []WalletBalances {
+	out := make([]WalletBalances, len(wallets))
+	for i, w := range wallets {
+		out[i] = WalletBalances{Wallet: w, Balances: []store.TokenBalance{}}
+	}
+
+	if opt.Snapshots != nil && opt.MaxAge > 0 && len(wallets) > 0 {
+		keys := make([]store.WatchKey, len(wallets))
+		for i, w := range wallets {
+			keys[i] = store.WatchKey{Chain: w.Chain, Address: w.Address}
+		}
+		snaps, err := opt.Snapshots.GetBalanceSnapshots(ctx, keys)
+		if err != nil {
+			// スナップショットが読めなくても RPC で続ける
+			logging.FromContext(ctx).Warn("read balance snapshots", "err", err)
+		}
+		for i, k := range keys {
+			if s, ok := snaps[k]; ok && time.Since(s.FetchedAt) <= opt.MaxAge {
+				at := s.FetchedAt
+				out[i].Balances, out[i].Source, out[i].FetchedAt = s.Balances, SourceCache, &at
+			}
+		}
+	}
+
+	n := opt.Concurrency
+	if n <= 0 {
+		n = DefaultConcurrency
+	}
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i := range out {
+		if out[i].Source == SourceCache {
+			continue
+		}
+		wg.Add(1)
+		go func(wb *WalletBalances) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				wb.Error = ctx.Err().Error()
+				return
+			}
+			defer func() { <-sem }()
+
+			bals, err := fetch(ctx, wb.Chain, wb.Address)
+			if err != nil {
+				wb.Error = err.Error()
+				return
+			}
+			if bals == nil {
+				bals = []store.TokenBalance{}
+			}
+			now := time.Now().UTC()
+			wb.Balances, wb.Source, wb.FetchedAt = bals, SourceLive, &now
+			if opt.Snapshots != nil {
+				if err := opt.Snapshots.SaveBalanceSnapshot(ctx, wb.Chain, wb.Address, bals); err != nil {
+					logging.FromContext(ctx).Warn("save balance snapshot", "chain", wb.Chain, "address", wb.Address, "err", err)
+				}
+			}
+		}(&out[i])
+	}
+	wg.Wait()
+	return out
+}
+
+// Aggregate は取得できたアドレスの残高をチェーン × トークンごとに合算する
+// （並びはチェーン、金額の大きい順、トークン名の順）
+func Aggregate(wallets []WalletBalances) Portfolio {
+	type key struct{ chain, token string }
+	sums := map[key]*TokenTotal{}
+	p := Portfolio{Wallets: wallets, Totals: []TokenTotal{}}
+	for _, w := range wallets {
+		if w.Error != "" {
+			p.Failed++
+			continue
+		}
+		for _, b := range w.Balances {
+			k := key{w.Chain, b.Token}
+			t, ok := sums[k]
+			if !ok {
+				t = &TokenTotal{Chain: w.Chain, Token: b.Token}
+				sums[k] = t
+			}
+			t.Amount += b.Amount
+			t.Wallets++
+		}
+	}
+	for _, t := range sums {
+		p.Totals = append(p.Totals, *t)
+	}
+	sort.Slice(p.Totals, func(i, j int) bool {
+		a, b := p.Totals[i], p.Totals[j]
+		if a.Chain != b.Chain {
+			return a.Chain < b.Chain
+		}
+		if a.Amount != b.Amount {
+			return a.Amount > b.Amount
+		}
+		return a.Token < b.Token
+	})
+	return p
+}
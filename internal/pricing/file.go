@@ -0,0 +1,220 @@
+package pricing
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// FileSource はローカルの CSV / JSON ファイルから読んだ価格（メモリ上に持つ）。
+//
+// CSV は token,ts,usd[,decimals] の列（1 行目がヘッダなら読み飛ばす）、
+// JSON は [{"token": "SOL", "ts": "...", "usd": 150.2, "decimals": 9}, ...]。
+// ts は RFC3339・YYYY-MM-DD（UTC の 0 時）・UNIX 秒のいずれか。
+type FileSource struct {
+	byToken  map[string][]store.TokenPrice
+	decimals map[string]int
+}
+
+// fileRow はファイル 1 行分
+type fileRow struct {
+	Token    string          `json:"token"`
+	TS       json.RawMessage `json:"ts"`
+	USD      float64         `json:"usd"`
+	Decimals *int            `json:"decimals,omitempty"`
+}
+
+// LoadFile は拡張子（.csv / .json）で形式を判断して読む
+func LoadFile(path string) (*FileSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var fs *FileSource
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		fs, err = ParseCSV(f)
+	case ".json":
+		fs, err = ParseJSON(f)
+	default:
+		return nil, fmt.Errorf("%s: unsupported price file format %q (use .csv or .json)", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return fs, nil
+}
+
+// ParseCSV は token,ts,usd[,decimals] の CSV を読む
+func ParseCSV(r io.Reader) (*FileSource, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	fs := newFileSource()
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && len(rec) > 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "token") {
+			continue
+		}
+		if len(rec) != 3 && len(rec) != 4 {
+			return nil, fmt.Errorf("line %d: want 3 or 4 columns (token,ts,usd[,decimals]), got %d", line, len(rec))
+		}
+		ts, err := parseTS(strings.TrimSpace(rec[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		usd, err := strconv.ParseFloat(strings.TrimSpace(rec[2]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid usd %q", line, rec[2])
+		}
+		var dec *int
+		if len(rec) == 4 && strings.TrimSpace(rec[3]) != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(rec[3]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid decimals %q", line, rec[3])
+			}
+			dec = &n
+		}
+		if err := fs.add(strings.TrimSpace(rec[0]), ts, usd, dec); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+	fs.sort()
+	return fs, nil
+}
+
+// ParseJSON は行オブジェクトの配列を読む
+func ParseJSON(r io.Reader) (*FileSource, error) {
+	var rows []fileRow
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("invalid json: %v", err)
+	}
+	fs := newFileSource()
+	for i, row := range rows {
+		ts, err := parseTSJSON(row.TS)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %v", i, err)
+		}
+		if err := fs.add(strings.TrimSpace(row.Token), ts, row.USD, row.Decimals); err != nil {
+			return nil, fmt.Errorf("item %d: %v", i, err)
+		}
+	}
+	fs.sort()
+	return fs, nil
+}
+
+func newFileSource() *FileSource {
+	return &FileSource{byToken: map[string][]store.TokenPrice{}, decimals: map[string]int{}}
+}
+
+func (fs *FileSource) add(token string, ts time.Time, usd float64, dec *int) error {
+	if token == "" {
+		return errors.New("token is required")
+	}
+	if usd < 0 {
+		return fmt.Errorf("usd must not be negative, got %v", usd)
+	}
+	if dec != nil {
+		if *dec < 0 || *dec > 30 {
+			return fmt.Errorf("decimals must be 0-30, got %d", *dec)
+		}
+		if prev, ok := fs.decimals[token]; ok && prev != *dec {
+			return fmt.Errorf("conflicting decimals for %s (%d and %d)", token, prev, *dec)
+		}
+		fs.decimals[token] = *dec
+	}
+	fs.byToken[token] = append(fs.byToken[token], store.TokenPrice{Token: token, TS: ts, USD: usd, Source: config.PriceSourceFile})
+	return nil
+}
+
+func (fs *FileSource) sort() {
+	for _, ps := range fs.byToken {
+		sort.SliceStable(ps, func(i, j int) bool { return ps[i].TS.Before(ps[j].TS) })
+	}
+}
+
+func (fs *FileSource) Name() string { return config.PriceSourceFile }
+
+// Prices は各トークンについて at 以前で最も新しい価格を返す
+func (fs *FileSource) Prices(_ context.Context, tokens []string, at time.Time) ([]store.TokenPrice, error) {
+	var out []store.TokenPrice
+	for _, t := range tokens {
+		ps := fs.byToken[t]
+		// at より後の最初の位置の 1 つ手前
+		i := sort.Search(len(ps), func(i int) bool { return ps[i].TS.After(at) })
+		if i > 0 {
+			out = append(out, ps[i-1])
+		}
+	}
+	return out, nil
+}
+
+// All はファイルの全価格を token・時刻順に返す（walletctl prices import 用）
+func (fs *FileSource) All() []store.TokenPrice {
+	tokens := make([]string, 0, len(fs.byToken))
+	for t := range fs.byToken {
+		tokens = append(tokens, t)
+	}
+	sort.Strings(tokens)
+	var out []store.TokenPrice
+	for _, t := range tokens {
+		out = append(out, fs.byToken[t]...)
+	}
+	return out
+}
+
+// Decimals はファイルに書かれていたトークンの桁数
+func (fs *FileSource) Decimals() map[string]int {
+	out := make(map[string]int, len(fs.decimals))
+	for t, d := range fs.decimals {
+		out[t] = d
+	}
+	return out
+}
+
+// parseTS は RFC3339・YYYY-MM-DD・UNIX 秒を受け付ける
+func parseTS(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(n, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid ts %q (use RFC3339, YYYY-MM-DD or unix seconds)", v)
+}
+
+func parseTSJSON(raw json.RawMessage) (time.Time, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return parseTS(strings.TrimSpace(s))
+	}
+	var n int64
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return time.Unix(n, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid ts %s (use RFC3339, YYYY-MM-DD or unix seconds)", string(raw))
+}
@@ -0,0 +1,155 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// recentWindow より新しい時刻は現在価格（/simple/price）で、それより古い時刻は日次の履歴で引く
+const recentWindow = 10 * time.Minute
+
+// HTTPSource は CoinGecko 互換の HTTP API から価格を引く。
+// テストでは BaseURL を httptest のサーバーに向けて差し替える。
+type HTTPSource struct {
+	// BaseURL は API のルート（例: https://api.coingecko.com/api/v3）
+	BaseURL string
+	// APIKey は APIKeyHeader で送る（空なら送らない）
+	APIKey       string
+	APIKeyHeader string
+	// IDs はトークン（"SOL" / mint / coin type）→ プロバイダのコイン ID。無いトークンは引かない
+	IDs    map[string]string
+	Client *http.Client
+	// Now はテスト用（nil なら time.Now）
+	Now func() time.Time
+}
+
+func (h *HTTPSource) Name() string { return config.PriceSourceHTTP }
+
+func (h *HTTPSource) Prices(ctx context.Context, tokens []string, at time.Time) ([]store.TokenPrice, error) {
+	ids := map[string][]string{}
+	var order []string
+	for _, t := range tokens {
+		id, ok := h.IDs[t]
+		if !ok || id == "" {
+			continue
+		}
+		if _, ok := ids[id]; !ok {
+			order = append(order, id)
+		}
+		ids[id] = append(ids[id], t)
+	}
+	if len(order) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+	if now().Sub(at) < recentWindow {
+		return h.current(ctx, order, ids, at)
+	}
+	var out []store.TokenPrice
+	for _, id := range order {
+		ps, err := h.history(ctx, id, ids[id], at)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, ps...)
+	}
+	return out, nil
+}
+
+// current は /simple/price で現在価格を引く（ts はプロバイダの更新時刻、at より後なら at）
+func (h *HTTPSource) current(ctx context.Context, order []string, ids map[string][]string, at time.Time) ([]store.TokenPrice, error) {
+	q := url.Values{}
+	q.Set("ids", strings.Join(order, ","))
+	q.Set("vs_currencies", "usd")
+	q.Set("include_last_updated_at", "true")
+	var resp map[string]struct {
+		USD           *float64 `json:"usd"`
+		LastUpdatedAt int64    `json:"last_updated_at"`
+	}
+	if err := h.get(ctx, "/simple/price", q, &resp); err != nil {
+		return nil, err
+	}
+	var out []store.TokenPrice
+	for _, id := range order {
+		r, ok := resp[id]
+		if !ok || r.USD == nil {
+			continue
+		}
+		ts := at
+		if r.LastUpdatedAt > 0 {
+			if t := time.Unix(r.LastUpdatedAt, 0).UTC(); t.Before(at) {
+				ts = t
+			}
+		}
+		for _, t := range ids[id] {
+			out = append(out, store.TokenPrice{Token: t, TS: ts, USD: *r.USD, Source: config.PriceSourceHTTP})
+		}
+	}
+	return out, nil
+}
+
+// history は /coins/{id}/history で at の日（UTC）の 0 時の価格を引く
+func (h *HTTPSource) history(ctx context.Context, id string, tokens []string, at time.Time) ([]store.TokenPrice, error) {
+	day := at.UTC().Truncate(24 * time.Hour)
+	q := url.Values{}
+	q.Set("date", day.Format("02-01-2006"))
+	q.Set("localization", "false")
+	var resp struct {
+		MarketData *struct {
+			CurrentPrice map[string]float64 `json:"current_price"`
+		} `json:"market_data"`
+	}
+	if err := h.get(ctx, "/coins/"+url.PathEscape(id)+"/history", q, &resp); err != nil {
+		return nil, err
+	}
+	if resp.MarketData == nil {
+		return nil, nil
+	}
+	usd, ok := resp.MarketData.CurrentPrice["usd"]
+	if !ok {
+		return nil, nil
+	}
+	out := make([]store.TokenPrice, 0, len(tokens))
+	for _, t := range tokens {
+		out = append(out, store.TokenPrice{Token: t, TS: day, USD: usd, Source: config.PriceSourceHTTP})
+	}
+	return out, nil
+}
+
+func (h *HTTPSource) get(ctx context.Context, path string, q url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(h.BaseURL, "/")+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if h.APIKey != "" && h.APIKeyHeader != "" {
+		req.Header.Set(h.APIKeyHeader, h.APIKey)
+	}
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("price api %s: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,391 @@
+// Package pricing はトークンの USD 価格を引き、残高・イベントを USD で評価する。
+// 価格は token_prices（token × 時刻）に貯め、足りないものだけ PriceSource（ファイル / HTTP）に問い合わせて保存する。
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// PriceSource は価格の取得元
+type PriceSource interface {
+	Name() string
+	// Prices は tokens の at 時点（以前で最も新しい）の USD 価格を返す。分からないトークンは含めない
+	Prices(ctx context.Context, tokens []string, at time.Time) ([]store.TokenPrice, error)
+}
+
+// Table は価格表（*store.Store が満たす）
+type Table interface {
+	TokenPricesAt(ctx context.Context, lookups []store.PriceLookup, tolerance time.Duration) ([]*store.TokenPrice, error)
+	UpsertTokenPrices(ctx context.Context, prices []store.TokenPrice) error
+	TokenDecimals(ctx context.Context, tokens []string) (map[string]int, error)
+}
+
+// 既定値
+const (
+	DefaultFresh     = 10 * time.Minute
+	DefaultTolerance = 24 * time.Hour
+	// maxSourceCalls は 1 回の評価で PriceSource を呼ぶ回数の上限（残りは次回以降に回す）
+	maxSourceCalls = 10
+	// missTTL は Source に価格が無かったトークン × 日を問い合わせ直さない期間
+	missTTL = 30 * time.Minute
+)
+
+// nativeDecimals は token_decimals に無くても分かるネイティブトークンの桁数
+var nativeDecimals = map[string]int{"SOL": 9, "SUI": 9}
+
+// Options は Service の設定
+type Options struct {
+	// Source は価格表に無い（または古い）価格の取得元。nil なら価格表だけを見る
+	Source PriceSource
+	// Fresh は現在価格として価格表の値をそのまま使う期間（過ぎたら Source に取り直す）
+	Fresh time.Duration
+	// Tolerance は評価時刻からさかのぼって価格を探す範囲
+	Tolerance time.Duration
+	// Now はテスト用（nil なら time.Now）
+	Now func() time.Time
+}
+
+// Service は価格表と PriceSource をまとめて評価する。nil の *Service は何も評価しない。
+type Service struct {
+	table Table
+	opt   Options
+
+	mu sync.Mutex
+	// misses は Source に価格が無かったトークン × 日（UTC）→ 問い合わせ直してよい時刻
+	misses map[missKey]time.Time
+}
+
+type missKey struct {
+	token string
+	day   time.Time
+}
+
+func NewService(t Table, opt Options) *Service {
+	if opt.Fresh <= 0 {
+		opt.Fresh = DefaultFresh
+	}
+	if opt.Tolerance <= 0 {
+		opt.Tolerance = DefaultTolerance
+	}
+	if opt.Now == nil {
+		opt.Now = time.Now
+	}
+	return &Service{table: t, opt: opt, misses: map[missKey]time.Time{}}
+}
+
+// FromConfig は設定の取得元（なし / file / http）で Service を作る
+func FromConfig(c config.Pricing, t Table) (*Service, error) {
+	var src PriceSource
+	switch c.Source {
+	case "":
+	case config.PriceSourceFile:
+		fs, err := LoadFile(c.File)
+		if err != nil {
+			return nil, err
+		}
+		src = fs
+	case config.PriceSourceHTTP:
+		src = &HTTPSource{
+			BaseURL:      c.HTTP.URL,
+			APIKey:       c.HTTP.APIKey,
+			APIKeyHeader: c.HTTP.APIKeyHeader,
+			IDs:          c.HTTP.IDs,
+		}
+	default:
+		return nil, fmt.Errorf("unknown price source %q", c.Source)
+	}
+	return NewService(t, Options{Source: src, Fresh: time.Duration(c.Fresh), Tolerance: time.Duration(c.Tolerance)}), nil
+}
+
+// Value は最小単位の amount を USD に換算する
+func Value(amount int64, decimals int, usd float64) float64 {
+	v := float64(amount) / math.Pow10(decimals) * usd
+	// 浮動小数の誤差を表示に出さない
+	return math.Round(v*1e8) / 1e8
+}
+
+// Valued は USD 評価額付きの残高（価格が分からなければ USD 項目は付かない）
+type Valued struct {
+	Token    string     `json:"token"`
+	Amount   int64      `json:"amount"`
+	USDPrice *float64   `json:"usd_price,omitempty"`
+	USDValue *float64   `json:"usd_value,omitempty"`
+	PriceTS  *time.Time `json:"price_ts,omitempty"`
+}
+
+// ValueBalances は残高を現在価格で評価する
+func (s *Service) ValueBalances(ctx context.Context, bs []store.TokenBalance) []Valued {
+	out := make([]Valued, len(bs))
+	for i, b := range bs {
+		out[i] = Valued{Token: b.Token, Amount: b.Amount}
+	}
+	if s == nil || len(bs) == 0 {
+		return out
+	}
+	now := s.opt.Now()
+	lookups := make([]store.PriceLookup, len(bs))
+	for i, b := range bs {
+		lookups[i] = store.PriceLookup{Token: b.Token, At: now}
+	}
+	prices, decimals := s.resolve(ctx, lookups, s.opt.Fresh)
+	for i := range out {
+		p := prices[i]
+		if p == nil {
+			continue
+		}
+		d, ok := decimals[out[i].Token]
+		if !ok {
+			continue
+		}
+		usd, v, ts := p.USD, Value(out[i].Amount, d, p.USD), p.TS
+		out[i].USDPrice, out[i].USDValue, out[i].PriceTS = &usd, &v, &ts
+	}
+	return out
+}
+
+// Item は評価する量（最小単位）と時刻
+type Item struct {
+	Token  string
+	Amount int64
+	At     time.Time
+}
+
+// ValuesAt は各 Item を At 時点の価格で評価する（価格・桁数が分からなければ nil）
+func (s *Service) ValuesAt(ctx context.Context, items []Item) []*float64 {
+	out := make([]*float64, len(items))
+	if s == nil || len(items) == 0 {
+		return out
+	}
+	lookups := make([]store.PriceLookup, len(items))
+	for i, it := range items {
+		lookups[i] = store.PriceLookup{Token: it.Token, At: it.At}
+	}
+	prices, decimals := s.resolve(ctx, lookups, 0)
+	for i, p := range prices {
+		if p == nil {
+			continue
+		}
+		d, ok := decimals[p.Token]
+		if !ok {
+			continue
+		}
+		v := Value(items[i].Amount, d, p.USD)
+		out[i] = &v
+	}
+	return out
+}
+
+// ValueEvents は各イベントの amount を Tx 時刻の価格で評価し、USDPrice / USDValue を埋める
+func (s *Service) ValueEvents(ctx context.Context, events []store.TxEvent) {
+	if s == nil {
+		return
+	}
+	var idx []int
+	var lookups []store.PriceLookup
+	for i, e := range events {
+		if e.Token == nil || e.Amount == nil {
+			continue
+		}
+		idx = append(idx, i)
+		lookups = append(lookups, store.PriceLookup{Token: *e.Token, At: e.TS})
+	}
+	if len(lookups) == 0 {
+		return
+	}
+	prices, decimals := s.resolve(ctx, lookups, 0)
+	for j, i := range idx {
+		p := prices[j]
+		if p == nil {
+			continue
+		}
+		d, ok := decimals[p.Token]
+		if !ok {
+			continue
+		}
+		usd, v := p.USD, Value(*events[i].Amount, d, p.USD)
+		events[i].USDPrice, events[i].USDValue = &usd, &v
+	}
+}
+
+// resolve は lookups の価格とトークンの桁数を返す。
+// 価格表に無いもの（refresh > 0 なら at から refresh より古いものも）は Source に問い合わせて保存する。
+// 価格表・Source の失敗はログに残し、分かった分だけで評価する。
+func (s *Service) resolve(ctx context.Context, lookups []store.PriceLookup, refresh time.Duration) ([]*store.TokenPrice, map[string]int) {
+	log := logging.FromContext(ctx)
+	prices, err := s.table.TokenPricesAt(ctx, lookups, s.opt.Tolerance)
+	if err != nil {
+		log.Warn("price lookup failed", "err", err)
+		prices = make([]*store.TokenPrice, len(lookups))
+	}
+	if s.opt.Source != nil {
+		s.fill(ctx, lookups, prices, refresh)
+	}
+
+	tokens := make([]string, 0, len(lookups))
+	seen := map[string]bool{}
+	for _, l := range lookups {
+		if !seen[l.Token] {
+			seen[l.Token] = true
+			tokens = append(tokens, l.Token)
+		}
+	}
+	decimals, err := s.table.TokenDecimals(ctx, tokens)
+	if err != nil {
+		log.Warn("token decimals lookup failed", "err", err)
+		decimals = map[string]int{}
+	}
+	if fs, ok := s.opt.Source.(*FileSource); ok {
+		for t, d := range fs.Decimals() {
+			if _, ok := decimals[t]; !ok {
+				decimals[t] = d
+			}
+		}
+	}
+	for t, d := range nativeDecimals {
+		if _, ok := decimals[t]; !ok {
+			decimals[t] = d
+		}
+	}
+	return prices, decimals
+}
+
+// fill は足りない価格を Source から取って prices を埋め、価格表に保存する。
+// 呼び出しは日（UTC）ごとにまとめ（HTTP の履歴は日次なので 1 日 1 回で足りる）、maxSourceCalls 回までに抑える。
+// Source に価格が無かったトークン × 日は missTTL の間問い合わせ直さない。
+func (s *Service) fill(ctx context.Context, lookups []store.PriceLookup, prices []*store.TokenPrice, refresh time.Duration) {
+	type group struct {
+		at     time.Time
+		tokens []string
+		idx    []int
+	}
+	now := s.opt.Now()
+	groups := map[time.Time]*group{}
+	for i, l := range lookups {
+		p := prices[i]
+		if p != nil && (refresh <= 0 || l.At.Sub(p.TS) <= refresh) {
+			continue
+		}
+		key := l.At.UTC().Truncate(24 * time.Hour)
+		if s.missed(missKey{l.Token, key}, now) {
+			continue
+		}
+		g := groups[key]
+		if g == nil {
+			g = &group{at: l.At}
+			groups[key] = g
+		}
+		// グループ内で最も早い時刻で問い合わせれば、どの lookup にも「以前の価格」として使える
+		if l.At.Before(g.at) {
+			g.at = l.At
+		}
+		if !containsStr(g.tokens, l.Token) {
+			g.tokens = append(g.tokens, l.Token)
+		}
+		g.idx = append(g.idx, i)
+	}
+	if len(groups) == 0 {
+		return
+	}
+	keys := make([]time.Time, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	// 新しい時刻を優先する
+	sort.Slice(keys, func(i, j int) bool { return keys[i].After(keys[j]) })
+	if len(keys) > maxSourceCalls {
+		keys = keys[:maxSourceCalls]
+	}
+
+	log := logging.FromContext(ctx)
+	var fetched []store.TokenPrice
+	for _, k := range keys {
+		g := groups[k]
+		got, err := s.opt.Source.Prices(ctx, g.tokens, g.at)
+		if err != nil {
+			log.Warn("price source failed", "source", s.opt.Source.Name(), "at", g.at, "err", err)
+			continue
+		}
+		for _, i := range g.idx {
+			if p := best(got, lookups[i], s.opt.Tolerance); p != nil {
+				prices[i] = p
+			}
+		}
+		for _, t := range g.tokens {
+			if !hasToken(got, t) {
+				s.miss(missKey{t, k}, now)
+			}
+		}
+		fetched = append(fetched, got...)
+	}
+	if len(fetched) > 0 {
+		if err := s.table.UpsertTokenPrices(ctx, fetched); err != nil {
+			log.Warn("saving prices failed", "err", err)
+		}
+	}
+}
+
+// missed は k を Source に問い合わせずに済ませてよいかを返す
+func (s *Service) missed(k missKey, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	until, ok := s.misses[k]
+	return ok && now.Before(until)
+}
+
+// miss は k を missTTL の間覚える（期限の切れたものはここで捨てる）
+func (s *Service) miss(k missKey, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for mk, until := range s.misses {
+		if !now.Before(until) {
+			delete(s.misses, mk)
+		}
+	}
+	s.misses[k] = now.Add(missTTL)
+}
+
+func hasToken(ps []store.TokenPrice, token string) bool {
+	for _, p := range ps {
+		if p.Token == token {
+			return true
+		}
+	}
+	return false
+}
+
+// best は got のうち l.At 以前 tolerance 以内で最も新しい l.Token の価格を返す
+func best(got []store.TokenPrice, l store.PriceLookup, tolerance time.Duration) *store.TokenPrice {
+	var out *store.TokenPrice
+	for i := range got {
+		p := &got[i]
+		if p.Token != l.Token || p.TS.After(l.At) || l.At.Sub(p.TS) >= tolerance {
+			continue
+		}
+		if out == nil || p.TS.After(out.TS) {
+			out = p
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	cp := *out
+	return &cp
+}
+
+func containsStr(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
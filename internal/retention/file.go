@@ -0,0 +1,144 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// FileSink はパーティション 1 つを <dir>/<chain>/<パーティション名>.ndjson.gz に書き出す。
+// 一時ファイルに書いて fsync してから置き場所に移すため、途中で止まっても書きかけのファイルは残らない。
+// 同じ名前のファイルが既にある（切り離した月に後から Tx が入った）ときは .2, .3 ... を付ける。
+type FileSink struct {
+	dir  string
+	name string
+	tmp  *os.File
+	hash hash.Hash
+	size countWriter
+	zw   *gzip.Writer
+	bw   *bufio.Writer
+	enc  *json.Encoder
+	// path は Commit で確定した置き場所
+	path string
+}
+
+// countWriter は書き込んだバイト数を数える
+type countWriter struct{ n int64 }
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// NewFileSink は dir の下に p の書き出し先を用意する
+func NewFileSink(dir string, p store.TxPartition) (*FileSink, error) {
+	if dir == "" {
+		return nil, errors.New("archive dir is empty")
+	}
+	d := filepath.Join(dir, p.Chain)
+	if err := os.MkdirAll(d, 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(d, "."+p.Name+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	s := &FileSink{dir: d, name: p.Name, tmp: tmp, hash: sha256.New()}
+	s.zw = gzip.NewWriter(io.MultiWriter(tmp, s.hash, &s.size))
+	s.zw.Name = p.Name + ".ndjson"
+	s.bw = bufio.NewWriter(s.zw)
+	s.enc = json.NewEncoder(s.bw)
+	return s, nil
+}
+
+// Write はイベント 1 件を 1 行の JSON で書く
+func (s *FileSink) Write(r store.ArchiveRow) error {
+	return s.enc.Encode(r)
+}
+
+// Commit は書き出しを閉じて fsync し、置き場所に移す
+func (s *FileSink) Commit() (store.ArchiveFile, error) {
+	if err := s.bw.Flush(); err != nil {
+		return store.ArchiveFile{}, err
+	}
+	if err := s.zw.Close(); err != nil {
+		return store.ArchiveFile{}, err
+	}
+	if err := s.tmp.Sync(); err != nil {
+		return store.ArchiveFile{}, err
+	}
+	if err := s.tmp.Close(); err != nil {
+		return store.ArchiveFile{}, err
+	}
+	// 既存のファイルを上書きしないよう、リンクの作成（既にあれば失敗する）で名前を決める
+	for i := 1; ; i++ {
+		path := filepath.Join(s.dir, s.name+".ndjson.gz")
+		if i > 1 {
+			path = filepath.Join(s.dir, fmt.Sprintf("%s.%d.ndjson.gz", s.name, i))
+		}
+		err := os.Link(s.tmp.Name(), path)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return store.ArchiveFile{}, err
+		}
+		s.path = path
+		break
+	}
+	if err := os.Remove(s.tmp.Name()); err != nil {
+		return store.ArchiveFile{}, err
+	}
+	if d, err := os.Open(s.dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return store.ArchiveFile{Path: s.path, Bytes: s.size.n, SHA256: hex.EncodeToString(s.hash.Sum(nil))}, nil
+}
+
+// Abort は一時ファイル（Commit 済みなら置き場所のファイル）を消す
+func (s *FileSink) Abort() {
+	s.tmp.Close()
+	os.Remove(s.tmp.Name())
+	if s.path != "" {
+		os.Remove(s.path)
+	}
+}
+
+// ReadFile は FileSink が書き出したファイルを読み、1 行ずつ fn に渡す
+func ReadFile(path string, fn func(r store.ArchiveRow) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	dec := json.NewDecoder(zr)
+	for {
+		var r store.ArchiveRow
+		err := dec.Decode(&r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+}
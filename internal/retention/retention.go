@@ -0,0 +1,141 @@
+// Package retention は tx_events_* の月パーティションを保守する。
+// 先の月のパーティションを用意し、保持期限を過ぎた raw を圧縮（または削除）し、
+// 古い月をローカルの NDJSON.gz に書き出してから切り離す。
+package retention
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// Store はパーティションの操作（*store.Store が満たす）
+type Store interface {
+	EnsurePartitions(ctx context.Context, chain string, ahead int) error
+	TxPartitions(ctx context.Context, chain string) ([]store.TxPartition, error)
+	CompressRaw(ctx context.Context, chain string, before time.Time, limit int) (int, error)
+	DropRaw(ctx context.Context, chain string, before time.Time, limit int) (int, error)
+	ArchivePartition(ctx context.Context, p store.TxPartition, sink store.ArchiveSink) (store.ArchivedPartition, error)
+}
+
+// Maintainer は 1 チェーン分の保守
+type Maintainer struct {
+	Store  Store
+	Chain  string
+	Config config.Retention
+	// Now は現在時刻（nil なら time.Now）
+	Now func() time.Time
+}
+
+// Result は 1 回の保守の結果
+type Result struct {
+	// Raw は raw を圧縮・削除した行数
+	Raw      int
+	Archived []store.ArchivedPartition
+	// Held は取得原価が使っているため切り離さなかった月（以降の月も切り離さない。空なら無し）
+	Held string
+}
+
+// Due は now 時点で書き出す月（To が now - after 以前のもの）を古い順に返す。当月以降は含めない。
+func Due(parts []store.TxPartition, now time.Time, after time.Duration) []store.TxPartition {
+	if after <= 0 {
+		return nil
+	}
+	now = now.UTC()
+	cutoff := now.Add(-after)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var out []store.TxPartition
+	for _, p := range parts {
+		if !p.To.After(cutoff) && !p.To.After(month) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// RunOnce は先の月のパーティションの作成、raw の保持期限の処理、古い月の書き出しと切り離しを順に行う
+func (m *Maintainer) RunOnce(ctx context.Context) (Result, error) {
+	var res Result
+	now := time.Now
+	if m.Now != nil {
+		now = m.Now
+	}
+	c := m.Config
+
+	if err := m.Store.EnsurePartitions(ctx, m.Chain, c.PartitionsAhead); err != nil {
+		return res, err
+	}
+
+	if c.RawAfter > 0 {
+		before := now().Add(-c.RawAfter.D())
+		process := m.Store.CompressRaw
+		if c.RawMode == config.RawDrop {
+			process = m.Store.DropRaw
+		}
+		for {
+			n, err := process(ctx, m.Chain, before, c.BatchSize)
+			if err != nil {
+				return res, err
+			}
+			res.Raw += n
+			if n < c.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+
+	if c.ArchiveAfter > 0 {
+		parts, err := m.Store.TxPartitions(ctx, m.Chain)
+		if err != nil {
+			return res, err
+		}
+		for _, p := range Due(parts, now(), c.ArchiveAfter.D()) {
+			sink, err := NewFileSink(c.ArchiveDir, p)
+			if err != nil {
+				return res, err
+			}
+			rec, err := m.Store.ArchivePartition(ctx, p, sink)
+			if errors.Is(err, store.ErrCostBasisDepends) {
+				// 切り離すと /pnl の再生で取得原価が合わなくなる。月は古い順に連続して切り離すので、以降の月も残す
+				res.Held = p.Name
+				break
+			}
+			if err != nil {
+				return res, err
+			}
+			res.Archived = append(res.Archived, rec)
+		}
+	}
+	return res, nil
+}
+
+// Run は Config.Interval ごとに RunOnce を繰り返す（失敗しても止めず、次の回でやり直す）
+func (m *Maintainer) Run(ctx context.Context) {
+	log := logging.FromContext(ctx).With("chain", m.Chain)
+	t := time.NewTicker(m.Config.Interval.D())
+	defer t.Stop()
+	for {
+		res, err := m.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("partition maintenance", "err", err)
+		}
+		if res.Raw > 0 {
+			log.Info("processed raw past retention", "rows", res.Raw, "mode", m.Config.RawMode)
+		}
+		for _, a := range res.Archived {
+			log.Info("archived partition", "partition", a.Name, "rows", a.Rows, "path", a.Path, "bytes", a.Bytes)
+		}
+		if res.Held != "" {
+			log.Warn("partition not archived: cost basis depends on it", "partition", res.Held)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
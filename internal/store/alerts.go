@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// alert_rules.rule_type
+const (
+	RuleIncomingTransfer   = "incoming_transfer"
+	RuleOutgoingTransfer   = "outgoing_transfer"
+	RuleProgramInteraction = "program_interaction"
+	RuleBalanceBelow       = "balance_below"
+	RuleFeeAbove           = "fee_above"
+)
+
+// AlertRule はテナントごとのアラート条件。Threshold は最小単位。
+type AlertRule struct {
+	ID        int64     `json:"id"`
+	Tenant    string    `json:"tenant"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Chain     string    `json:"chain"`
+	Address   *string   `json:"address,omitempty"`
+	Token     *string   `json:"token,omitempty"`
+	Program   *string   `json:"program,omitempty"`
+	Threshold *int64    `json:"threshold,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Alert はルールに一致したイベントの記録
+type Alert struct {
+	ID       int64     `json:"id"`
+	Tenant   string    `json:"tenant"`
+	RuleID   *int64    `json:"rule_id,omitempty"`
+	RuleType string    `json:"rule_type"`
+	Chain    string    `json:"chain"`
+	TxHash   string    `json:"tx_hash"`
+	TS       time.Time `json:"ts"`
+	Address  *string   `json:"address,omitempty"`
+	// AddressLabel は発生時点の Address のラベル（監視アドレスでラベルがあれば）
+	AddressLabel *string `json:"address_label,omitempty"`
+	Token        *string `json:"token,omitempty"`
+	Amount       *int64  `json:"amount,omitempty"`
+	// Decimals は Amount の桁数（表示用）
+	Decimals *int   `json:"decimals,omitempty"`
+	Message  string `json:"message"`
+	// DedupeKey はテナント内で一意（同じルール × Tx のアラートは 1 件だけ）
+	DedupeKey string    `json:"dedupe_key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const alertRuleCols = `id, tenant, name, rule_type, chain, address, token, program, threshold, enabled, created_at`
+
+func scanAlertRules(rows pgx.Rows) ([]AlertRule, error) {
+	defer rows.Close()
+	out := []AlertRule{}
+	for rows.Next() {
+		var r AlertRule
+		if err := rows.Scan(&r.ID, &r.Tenant, &r.Name, &r.Type, &r.Chain, &r.Address, &r.Token, &r.Program, &r.Threshold, &r.Enabled, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// ListAlertRules はテナントのルールを作成順に返す
+func (s *Store) ListAlertRules(ctx context.Context, tenant string) ([]AlertRule, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+alertRuleCols+` FROM alert_rules WHERE tenant = $1 ORDER BY id`, tenant)
+	if err != nil {
+		return nil, err
+	}
+	return scanAlertRules(rows)
+}
+
+// ListEnabledAlertRules はワーカー用に、チェーンの有効なルールを全テナント分返す
+func (s *Store) ListEnabledAlertRules(ctx context.Context, chain string) ([]AlertRule, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+alertRuleCols+` FROM alert_rules WHERE chain = $1 AND enabled ORDER BY id`, chain)
+	if err != nil {
+		return nil, err
+	}
+	return scanAlertRules(rows)
+}
+
+// CreateAlertRule はルールを保存し、採番された ID と作成時刻を r に設定する
+func (s *Store) CreateAlertRule(ctx context.Context, r *AlertRule) error {
+	return s.Pool.QueryRow(ctx, `
+		INSERT INTO alert_rules (tenant, name, rule_type, chain, address, token, program, threshold, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, r.Tenant, r.Name, r.Type, r.Chain, r.Address, r.Token, r.Program, r.Threshold, r.Enabled).Scan(&r.ID, &r.CreatedAt)
+}
+
+// DeleteAlertRule はテナントのルールを削除する（過去のアラートは rule_id が NULL になって残る）
+func (s *Store) DeleteAlertRule(ctx context.Context, tenant string, id int64) error {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM alert_rules WHERE tenant = $1 AND id = $2`, tenant, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// InsertAlert はアラートを保存する。同じ dedupe_key が既にあれば何もせず false を返す。
+func (s *Store) InsertAlert(ctx context.Context, a *Alert) (bool, error) {
+	rows, err := s.Pool.Query(ctx, `
+		INSERT INTO alerts (tenant, rule_id, rule_type, chain, tx_hash, ts, address, token, amount, decimals, message, dedupe_key, address_label)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, address_label($4, $7))
+		ON CONFLICT (tenant, dedupe_key) DO NOTHING
+		RETURNING id, created_at, address_label
+	`, a.Tenant, a.RuleID, a.RuleType, a.Chain, a.TxHash, a.TS, a.Address, a.Token, a.Amount, a.Decimals, a.Message, a.DedupeKey)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	inserted := false
+	for rows.Next() {
+		if err := rows.Scan(&a.ID, &a.CreatedAt, &a.AddressLabel); err != nil {
+			return false, err
+		}
+		inserted = true
+	}
+	return inserted, rows.Err()
+}
+
+// ClearAlertDedupe は dedupe_key が一致するアラートの dedupe_key を "<key>:<id>" に付け替える。
+// アラート自体は残し、同じ dedupe_key のアラートを次に記録できるようにする（balance_below の回復時に使う）。
+func (s *Store) ClearAlertDedupe(ctx context.Context, tenant, dedupeKey string) error {
+	_, err := s.Pool.Exec(ctx, `
+		UPDATE alerts SET dedupe_key = dedupe_key || ':' || id
+		WHERE tenant = $1 AND dedupe_key = $2
+	`, tenant, dedupeKey)
+	return err
+}
+
+// AlertQuery は ListAlerts の検索条件
+type AlertQuery struct {
+	Limit int
+	// BeforeID: この ID より古いアラート（次ページ）
+	BeforeID *int64
+	RuleID   *int64
+	Chain    *string
+}
+
+// ListAlerts はテナントのアラートを新しい順に返す
+func (s *Store) ListAlerts(ctx context.Context, tenant string, aq AlertQuery) ([]Alert, error) {
+	limit := aq.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	args := []any{tenant}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	q := `
+		SELECT id, tenant, rule_id, rule_type, chain, tx_hash, ts, address, address_label, token, amount, decimals, message, dedupe_key, created_at
+		FROM alerts
+		WHERE tenant = $1`
+	if aq.BeforeID != nil {
+		q += " AND id < " + arg(*aq.BeforeID)
+	}
+	if aq.RuleID != nil {
+		q += " AND rule_id = " + arg(*aq.RuleID)
+	}
+	if aq.Chain != nil {
+		q += " AND chain = " + arg(*aq.Chain)
+	}
+	q += " ORDER BY id DESC LIMIT " + arg(limit)
+
+	rows, err := s.Pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Alert, 0, limit)
+	for rows.Next() {
+		var a Alert
+		if err := rows.Scan(&a.ID, &a.Tenant, &a.RuleID, &a.RuleType, &a.Chain, &a.TxHash, &a.TS, &a.Address, &a.AddressLabel, &a.Token, &a.Amount, &a.Decimals, &a.Message, &a.DedupeKey, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
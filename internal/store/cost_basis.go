@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CostLot は取得ロット（受取 1 件分）
+type CostLot struct {
+	Seq     int       `json:"seq"`
+	Token   string    `json:"token"`
+	TxHash  string    `json:"tx_hash"`
+	TS      time.Time `json:"ts"`
+	Amount  int64     `json:"amount"`
+	CostUSD float64   `json:"cost_usd"`
+	// Remaining / RemainingCostUSD はまだ処分していない数量とその原価
+	Remaining        int64   `json:"remaining"`
+	RemainingCostUSD float64 `json:"remaining_cost_usd"`
+	// Unpriced は取得時の価格が分からず原価 0 として扱ったか
+	Unpriced bool `json:"unpriced,omitempty"`
+}
+
+// CostDisposal は処分（送付・手数料）。FIFO / LIFO では消費したロットごとに 1 件
+type CostDisposal struct {
+	Seq         int       `json:"seq"`
+	Token       string    `json:"token"`
+	TxHash      string    `json:"tx_hash"`
+	TS          time.Time `json:"ts"`
+	Kind        string    `json:"kind"`
+	Amount      int64     `json:"amount"`
+	ProceedsUSD float64   `json:"proceeds_usd"`
+	CostUSD     float64   `json:"cost_usd"`
+	GainUSD     float64   `json:"gain_usd"`
+	// LotSeq / AcquiredAt は対応する取得ロット（移動平均・取得記録の無い処分では nil）
+	LotSeq     *int       `json:"lot_seq,omitempty"`
+	AcquiredAt *time.Time `json:"acquired_at,omitempty"`
+	// Term は short / long（保有 1 年超）。移動平均では空
+	Term string `json:"term,omitempty"`
+	// Unmatched は取得記録の無い数量の処分（原価 0）
+	Unmatched bool `json:"unmatched,omitempty"`
+}
+
+// ReplaceCostBasis は (chain, address, method) のロットと処分を丸ごと書き直す。
+// 同じ (chain, address, method) を同時に書き直すと DELETE 後の COPY が主キーで衝突するため、
+// トランザクション単位のアドバイザリロックで順番に行う（後の方が最終結果になる）
+func (s *Store) ReplaceCostBasis(ctx context.Context, chain, address, method string, lots []CostLot, disposals []CostDisposal) error {
+	address = normAddr(chain, address)
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || ':' || $2 || ':' || $3))`, chain, address, method); err != nil {
+		return err
+	}
+
+	for _, t := range []string{"cost_lots", "cost_disposals"} {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+t+` WHERE chain = $1 AND address = $2 AND method = $3`, chain, address, method); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"cost_lots"},
+		[]string{"chain", "address", "method", "seq", "token", "tx_hash", "ts", "amount", "cost_usd", "remaining", "remaining_cost_usd", "unpriced"},
+		pgx.CopyFromSlice(len(lots), func(i int) ([]any, error) {
+			l := lots[i]
+			return []any{chain, address, method, l.Seq, l.Token, l.TxHash, l.TS, l.Amount, l.CostUSD, l.Remaining, l.RemainingCostUSD, l.Unpriced}, nil
+		}),
+	); err != nil {
+		return err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"cost_disposals"},
+		[]string{"chain", "address", "method", "seq", "token", "tx_hash", "ts", "kind", "amount", "proceeds_usd", "cost_usd", "gain_usd", "lot_seq", "acquired_at", "term", "unmatched"},
+		pgx.CopyFromSlice(len(disposals), func(i int) ([]any, error) {
+			d := disposals[i]
+			return []any{chain, address, method, d.Seq, d.Token, d.TxHash, d.TS, d.Kind, d.Amount, d.ProceedsUSD, d.CostUSD, d.GainUSD, d.LotSeq, d.AcquiredAt, d.Term, d.Unmatched}, nil
+		}),
+	); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -4,29 +4,50 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"time"
+	"sync"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/you/wallet-watcher/internal/config"
 )
 
-type Store struct{ Pool *pgxpool.Pool }
+type Store struct {
+	Pool *pgxpool.Pool
+	// partitions は作成を確認済みの tx_events_* の月パーティション（テーブル名_pYYYY_MM）
+	partitions sync.Map
+}
 
+// New は DATABASE_URL と既定のプール設定で接続する（テスト・ツール用）
 func New(ctx context.Context) (*Store, error) {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
+	cfg := config.Default().DB
+	cfg.URL = os.Getenv("DATABASE_URL")
+	return Open(ctx, cfg)
+}
+
+// Open は設定どおりのプールを作り、接続を確認する
+func Open(ctx context.Context, c config.DB) (*Store, error) {
+	if c.URL == "" {
 		return nil, fmt.Errorf("DATABASE_URL is empty")
 	}
-	cfg, err := pgxpool.ParseConfig(dsn)
+	cfg, err := pgxpool.ParseConfig(c.URL)
 	if err != nil {
 		return nil, err
 	}
-	cfg.MaxConns = 5
+	cfg.MaxConns = c.MaxConns
+	cfg.MinConns = c.MinConns
+	if c.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = c.MaxConnLifetime.D()
+	}
+	if c.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = c.MaxConnIdleTime.D()
+	}
+	// クエリごとにスパンを作り、所要時間を /metrics に出す
+	cfg.ConnConfig.Tracer = queryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
-	pctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	pctx, cancel := context.WithTimeout(ctx, c.ConnectTimeout.D())
 	defer cancel()
 	if err := pool.Ping(pctx); err != nil {
 		pool.Close()
@@ -36,4 +57,3 @@ func New(ctx context.Context) (*Store, error) {
 }
 
 func (s *Store) Close() { s.Pool.Close() }
-
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// EdgeTransfer は Tx 内の 1 件の移動（エッジの集計の元）
+type EdgeTransfer struct {
+	From   string
+	To     string
+	Token  string
+	Amount int64
+}
+
+// Edge は (from, to, token) ごとの通算
+type Edge struct {
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Token       string    `json:"token"`
+	TotalAmount int64     `json:"total_amount"`
+	TxCount     int       `json:"tx_count"`
+	FirstTS     time.Time `json:"first_ts"`
+	LastTS      time.Time `json:"last_ts"`
+}
+
+// edgeKey はエッジの単位（正規化済み）
+type edgeKey struct {
+	from, to, token string
+}
+
+// displayAddr は正規化済みのアドレスを表示用に戻す（Sui は 0x を付ける）
+func displayAddr(chain, addr string) string {
+	if chain == "sui" {
+		return "0x" + addr
+	}
+	return addr
+}
+
+// RefreshEdges は chain のエッジの集計済み位置（edge_state.last_seq）より後のイベントを最大 limit 件読み、
+// decode で Tx の移動に展開して edge_transfers を tx_hash 単位で置き換え、
+// 置き換えの前後で触れた (from, to, token) を edge_transfers から集計し直して位置を進める。
+// 日次集計と同じく、実行中のトランザクションより後に書かれた行（settledCol）に着いたらそこで止める。
+// 送信元・送信先の無い移動（ミント・バーン）と自己送金はエッジにしない。
+func (s *Store) RefreshEdges(ctx context.Context, chain string, limit int, decode func(e TxEvent, raw []byte) []EdgeTransfer) (RollupResult, error) {
+	var res RollupResult
+	table, ok := historyTables[chain]
+	if !ok {
+		return res, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO edge_state (chain) VALUES ($1) ON CONFLICT DO NOTHING`, chain); err != nil {
+		return res, err
+	}
+	if err := tx.QueryRow(ctx, `SELECT last_seq FROM edge_state WHERE chain = $1 FOR UPDATE`, chain).Scan(&res.LastSeq); err != nil {
+		return res, err
+	}
+
+	type event struct {
+		e   TxEvent
+		raw []byte
+	}
+	var events []event
+	rows, err := tx.Query(ctx, `
+		SELECT seq, tx_hash, ts, sender, receiver, token, amount, fee, method, status, raw::text, raw_gz, `+settledCol+`
+		  FROM `+table+`
+		 WHERE seq > $1
+		 ORDER BY seq
+		 LIMIT $2
+	`, res.LastSeq, limit)
+	if err != nil {
+		return res, err
+	}
+	for rows.Next() {
+		ev := event{e: TxEvent{Chain: chain}}
+		var (
+			seq     int64
+			raw     *string
+			gz      []byte
+			settled bool
+		)
+		if err := rows.Scan(&seq, &ev.e.TxHash, &ev.e.TS, &ev.e.Sender, &ev.e.Receiver, &ev.e.Token, &ev.e.Amount, &ev.e.Fee, &ev.e.Method, &ev.e.Status, &raw, &gz, &settled); err != nil {
+			rows.Close()
+			return res, err
+		}
+		if !settled {
+			break
+		}
+		res.LastSeq = seq
+		ev.raw = rawBytes(raw, gz)
+		events = append(events, ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+	res.Events = len(events)
+	if res.Events == 0 {
+		return res, nil
+	}
+
+	touched := map[edgeKey]bool{}
+	hashes := make([]string, 0, len(events))
+	var (
+		copyRows [][]any
+		next     = map[string]int{}
+	)
+	for _, ev := range events {
+		hashes = append(hashes, ev.e.TxHash)
+		for _, t := range decode(ev.e, ev.raw) {
+			from, to := normAddr(chain, t.From), normAddr(chain, t.To)
+			if from == "" || to == "" || from == to || t.Token == "" || t.Amount <= 0 {
+				continue
+			}
+			touched[edgeKey{from, to, t.Token}] = true
+			idx := next[ev.e.TxHash]
+			next[ev.e.TxHash] = idx + 1
+			copyRows = append(copyRows, []any{chain, ev.e.TxHash, idx, ev.e.TS, from, to, t.Token, t.Amount})
+		}
+	}
+
+	// 取り直した Tx の古い移動も集計し直す対象にする
+	rows, err = tx.Query(ctx, `
+		DELETE FROM edge_transfers
+		 WHERE chain = $1 AND tx_hash = ANY($2)
+		RETURNING from_addr, to_addr, token
+	`, chain, hashes)
+	if err != nil {
+		return res, err
+	}
+	for rows.Next() {
+		var k edgeKey
+		if err := rows.Scan(&k.from, &k.to, &k.token); err != nil {
+			rows.Close()
+			return res, err
+		}
+		touched[k] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"edge_transfers"},
+		[]string{"chain", "tx_hash", "idx", "ts", "from_addr", "to_addr", "token", "amount"},
+		pgx.CopyFromRows(copyRows),
+	); err != nil {
+		return res, err
+	}
+
+	if len(touched) > 0 {
+		froms := make([]string, 0, len(touched))
+		tos := make([]string, 0, len(touched))
+		tokens := make([]string, 0, len(touched))
+		for k := range touched {
+			froms, tos, tokens = append(froms, k.from), append(tos, k.to), append(tokens, k.token)
+		}
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM counterparty_edges e
+			USING unnest($2::text[], $3::text[], $4::text[]) AS p(from_addr, to_addr, token)
+			WHERE e.chain = $1 AND e.from_addr = p.from_addr AND e.to_addr = p.to_addr AND e.token = p.token
+		`, chain, froms, tos, tokens); err != nil {
+			return res, err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO counterparty_edges (chain, from_addr, to_addr, token, total_amount, tx_count, first_ts, last_ts)
+			SELECT $1, t.from_addr, t.to_addr, t.token, SUM(t.amount), COUNT(DISTINCT t.tx_hash), MIN(t.ts), MAX(t.ts)
+			  FROM unnest($2::text[], $3::text[], $4::text[]) AS p(from_addr, to_addr, token)
+			  JOIN edge_transfers t
+			    ON t.chain = $1 AND t.from_addr = p.from_addr AND t.to_addr = p.to_addr AND t.token = p.token
+			 GROUP BY t.from_addr, t.to_addr, t.token
+		`, chain, froms, tos, tokens); err != nil {
+			return res, err
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE edge_state SET last_seq = $2, updated_at = now() WHERE chain = $1`, chain, res.LastSeq); err != nil {
+		return res, err
+	}
+	res.Updated = len(touched)
+	return res, tx.Commit(ctx)
+}
+
+// ResetEdges は chain のエッジを消して集計済み位置を 0 に戻す（次の RefreshEdges から全件を集計し直す）。
+// 書き出して切り離した月の移動は元のイベントが無いので残し、counterparty_edges はその移動から集計し直す
+func (s *Store) ResetEdges(ctx context.Context, chain string) error {
+	if _, ok := historyTables[chain]; !ok {
+		return fmt.Errorf("unsupported chain: %s", chain)
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	for _, q := range []string{
+		`INSERT INTO edge_state (chain) VALUES ($1) ON CONFLICT (chain) DO UPDATE SET last_seq = 0, updated_at = now()`,
+		`DELETE FROM edge_transfers WHERE chain = $1 AND ts >= ` + archivedUntil,
+		`DELETE FROM counterparty_edges WHERE chain = $1`,
+		`INSERT INTO counterparty_edges (chain, from_addr, to_addr, token, total_amount, tx_count, first_ts, last_ts)
+		 SELECT $1, from_addr, to_addr, token, SUM(amount), COUNT(DISTINCT tx_hash), MIN(ts), MAX(ts)
+		   FROM edge_transfers
+		  WHERE chain = $1
+		  GROUP BY from_addr, to_addr, token`,
+	} {
+		if _, err := tx.Exec(ctx, q, chain); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// EdgeQuery は NeighborEdges の条件
+type EdgeQuery struct {
+	// Token を指定するとそのトークンのエッジだけ
+	Token *string
+	// PerAddress はアドレスごとに返すエッジの上限（tx_count の多い順）
+	PerAddress int
+}
+
+// NeighborEdges は addrs のいずれかを送信元・送信先とするエッジを、アドレスごとに tx_count・合計額の多い順で
+// 最大 PerAddress 件ずつ返す（同じエッジは 1 回だけ。アドレスは表示用の表記）
+func (s *Store) NeighborEdges(ctx context.Context, chain string, addrs []string, q EdgeQuery) ([]Edge, error) {
+	if _, ok := historyTables[chain]; !ok {
+		return nil, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	norm := make([]string, len(addrs))
+	for i, a := range addrs {
+		norm[i] = normAddr(chain, a)
+	}
+	args := []any{chain, norm, q.PerAddress}
+	tokenCond := ""
+	if q.Token != nil {
+		args = append(args, *q.Token)
+		tokenCond = " AND e.token = $4"
+	}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT DISTINCT n.from_addr, n.to_addr, n.token, n.total_amount::bigint, n.tx_count, n.first_ts, n.last_ts
+		  FROM unnest($2::text[]) AS a(addr)
+		 CROSS JOIN LATERAL (
+		   SELECT e.from_addr, e.to_addr, e.token, e.total_amount, e.tx_count, e.first_ts, e.last_ts
+		     FROM counterparty_edges e
+		    WHERE e.chain = $1 AND (e.from_addr = a.addr OR e.to_addr = a.addr)`+tokenCond+`
+		    ORDER BY e.tx_count DESC, e.total_amount DESC, e.from_addr, e.to_addr, e.token
+		    LIMIT $3
+		 ) n
+		 ORDER BY n.tx_count DESC, n.from_addr, n.to_addr, n.token
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Edge
+	for rows.Next() {
+		var e Edge
+		if err := rows.Scan(&e.From, &e.To, &e.Token, &e.TotalAmount, &e.TxCount, &e.FirstTS, &e.LastTS); err != nil {
+			return nil, err
+		}
+		e.From, e.To = displayAddr(chain, e.From), displayAddr(chain, e.To)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// AddressLabels は addrs のうち監視アドレスでラベルのあるもののラベル（キーは引数の表記）
+func (s *Store) AddressLabels(ctx context.Context, chain string, addrs []string) (map[string]string, error) {
+	out := map[string]string{}
+	if len(addrs) == 0 {
+		return out, nil
+	}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT a, l
+		  FROM unnest($2::text[]) AS a
+		 CROSS JOIN LATERAL address_label($1, a) AS l
+		 WHERE l IS NOT NULL
+	`, chain, addrs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a, l string
+		if err := rows.Scan(&a, &l); err != nil {
+			return nil, err
+		}
+		out[a] = l
+	}
+	return out, rows.Err()
+}
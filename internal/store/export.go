@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// exportFetchSize はサーバーサイドカーソルから 1 回に FETCH する行数
+const exportFetchSize = 500
+
+// StreamTxEvents は条件に合う全イベントを古い順に fn へ渡す。
+// ListTxEvents と違い件数上限はなく、サーバーサイドカーソルで少しずつ読み出すため
+// 全件をメモリに載せない（hq.Limit は無視する）。
+func (s *Store) StreamTxEvents(ctx context.Context, chain string, hq HistoryQuery, fn func(e TxEvent, raw []byte) error) error {
+	chains, err := historyChains(chain)
+	if err != nil {
+		return err
+	}
+
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	parts := make([]string, 0, len(chains))
+	for _, c := range chains {
+		parts = append(parts, historySelect(c, hq, arg, true))
+	}
+	q := "SELECT * FROM (" + strings.Join(parts, " UNION ALL ") + ") u ORDER BY ts ASC, tx_hash ASC"
+
+	// カーソルはトランザクション内でのみ有効
+	tx, err := s.Pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DECLARE export_cur NO SCROLL CURSOR FOR "+q, args...); err != nil {
+		return err
+	}
+
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf("FETCH FORWARD %d FROM export_cur", exportFetchSize))
+		if err != nil {
+			return err
+		}
+		n := 0
+		for rows.Next() {
+			n++
+			var e TxEvent
+			var (
+				raw *string
+				gz  []byte
+			)
+			if err := rows.Scan(&e.Chain, &e.TxHash, &e.TS, &e.Sender, &e.Receiver, &e.Token, &e.Amount, &e.Fee, &e.Method, &e.Status, &e.SenderLabel, &e.ReceiverLabel, &raw, &gz); err != nil {
+				rows.Close()
+				return err
+			}
+			if err := fn(e, rawBytes(raw, gz)); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n < exportFetchSize {
+			break
+		}
+	}
+	return tx.Commit(ctx)
+}
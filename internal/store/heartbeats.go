@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// WorkerHeartbeat はワーカーの直近の tick の記録
+type WorkerHeartbeat struct {
+	Chain     string    `json:"chain"`
+	Instance  string    `json:"instance"`
+	TickAt    time.Time `json:"tick_at"`
+	Addresses int       `json:"addresses"`
+	Failed    int       `json:"failed"`
+	// MaxCursorLag はチェーン先頭（slot / checkpoint）− カーソルの最大値（不明なら nil）
+	MaxCursorLag *int64  `json:"max_cursor_lag,omitempty"`
+	LastError    *string `json:"last_error,omitempty"`
+	// Age は DB の時刻で測った tick_at からの経過時間（LatestHeartbeats だけが設定する）
+	Age time.Duration `json:"-"`
+}
+
+// RecordHeartbeat は tick の結果を記録する（tick_at は DB の now()）
+func (s *Store) RecordHeartbeat(ctx context.Context, hb WorkerHeartbeat) error {
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO worker_heartbeats (chain, instance, tick_at, addresses, failed, max_cursor_lag, last_error)
+		VALUES ($1, $2, now(), $3, $4, $5, $6)
+		ON CONFLICT (chain, instance) DO UPDATE SET
+		  tick_at = now(),
+		  addresses = EXCLUDED.addresses,
+		  failed = EXCLUDED.failed,
+		  max_cursor_lag = EXCLUDED.max_cursor_lag,
+		  last_error = EXCLUDED.last_error
+	`, hb.Chain, hb.Instance, hb.Addresses, hb.Failed, hb.MaxCursorLag, hb.LastError)
+	return err
+}
+
+// LatestHeartbeats はチェーンごとに最も新しいハートビートを返す
+func (s *Store) LatestHeartbeats(ctx context.Context) (map[string]WorkerHeartbeat, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT DISTINCT ON (chain)
+		  chain, instance, tick_at, addresses, failed, max_cursor_lag, last_error,
+		  extract(epoch FROM now() - tick_at)::float8
+		FROM worker_heartbeats
+		ORDER BY chain, tick_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]WorkerHeartbeat{}
+	for rows.Next() {
+		var hb WorkerHeartbeat
+		var age float64
+		if err := rows.Scan(&hb.Chain, &hb.Instance, &hb.TickAt, &hb.Addresses, &hb.Failed, &hb.MaxCursorLag, &hb.LastError, &age); err != nil {
+			return nil, err
+		}
+		hb.Age = time.Duration(age * float64(time.Second))
+		out[hb.Chain] = hb
+	}
+	return out, rows.Err()
+}
+
+// Ping は DB プールから接続を借りて疎通を確認する
+func (s *Store) Ping(ctx context.Context) error { return s.Pool.Ping(ctx) }
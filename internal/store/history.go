@@ -2,93 +2,321 @@ package store
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 )
 
 type TxEvent struct {
-	TxHash   string     `json:"tx_hash"`
-	TS       time.Time  `json:"ts"`
-	Sender   *string    `json:"sender,omitempty"`
-	Receiver *string    `json:"receiver,omitempty"`
-	Token    *string    `json:"token,omitempty"`
-	Amount   *int64     `json:"amount,omitempty"`
-	Fee      *int64     `json:"fee,omitempty"`
-	Method   *string    `json:"method,omitempty"`
+	Chain    string    `json:"chain"`
+	TxHash   string    `json:"tx_hash"`
+	TS       time.Time `json:"ts"`
+	Sender   *string   `json:"sender,omitempty"`
+	Receiver *string   `json:"receiver,omitempty"`
+	Token    *string   `json:"token,omitempty"`
+	Amount   *int64    `json:"amount,omitempty"`
+	Fee      *int64    `json:"fee,omitempty"`
+	Method   *string   `json:"method,omitempty"`
+	Status   *string   `json:"status,omitempty"`
+	// SenderLabel / ReceiverLabel は送受信者が監視アドレスならそのラベル
+	SenderLabel   *string `json:"sender_label,omitempty"`
+	ReceiverLabel *string `json:"receiver_label,omitempty"`
+	// USDPrice / USDValue は Tx 時刻の価格による評価（API が pricing で埋める。DB には無い）
+	USDPrice *float64 `json:"usd_price,omitempty"`
+	USDValue *float64 `json:"usd_value,omitempty"`
 }
 
-func (s *Store) ListTxEvents(ctx context.Context, chain string, address *string, limit int, before *time.Time) ([]TxEvent, error) {
-    if limit <= 0 || limit > 200 { limit = 50 }
+// ChainAll は ListTxEvents で全チェーンを横断する指定
+const ChainAll = "all"
 
-    var q string
-    args := []any{}
+// Tx の実行結果
+const (
+	TxStatusSuccess = "success"
+	TxStatusFailed  = "failed"
+)
 
-    switch chain {
-    case "solana":
-        q = `
-          SELECT tx_hash, ts, sender, receiver, token,
-                 NULLIF(amount::text,'')::bigint AS amount,
-                 NULLIF(fee::text,'')::bigint    AS fee,
-                 method
-          FROM tx_events_solana
-          WHERE 1=1
-        `
-    case "sui":
-        q = `
-          SELECT tx_hash, ts, sender, receiver, token,
+// HistoryCursor は (ts, tx_hash) のキーセットページング用カーソル。
+// 同一秒に複数 Tx があっても tx_hash で順序が一意に決まるため取りこぼさない。
+type HistoryCursor struct {
+	TS time.Time
+	// TxHash が空なら ts だけの境界（RFC3339 の旧形式。ts == TS の行は前後どちらにも含めない）
+	TxHash string
+}
+
+// CursorOf はイベントの位置を指すカーソルを返す
+func CursorOf(e TxEvent) HistoryCursor {
+	return HistoryCursor{TS: e.TS, TxHash: e.TxHash}
+}
+
+// Encode はクライアントに返す不透明な文字列へ変換する
+func (c HistoryCursor) Encode() string {
+	s := c.TS.UTC().Format(time.RFC3339Nano) + "|" + c.TxHash
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// DecodeHistoryCursor は Encode で作った文字列を復元する
+func DecodeHistoryCursor(s string) (HistoryCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return HistoryCursor{}, ErrInvalidCursor
+	}
+	tsPart, hash, ok := strings.Cut(string(b), "|")
+	if !ok || hash == "" {
+		return HistoryCursor{}, ErrInvalidCursor
+	}
+	ts, err := time.Parse(time.RFC3339Nano, tsPart)
+	if err != nil {
+		return HistoryCursor{}, ErrInvalidCursor
+	}
+	return HistoryCursor{TS: ts, TxHash: hash}, nil
+}
+
+// HistoryQuery は ListTxEvents の検索条件
+type HistoryQuery struct {
+	Address *string
+	Limit   int
+	// Before: このカーソルより古いイベント（降順の次ページ）
+	Before *HistoryCursor
+	// After: このカーソルより新しいイベント（前ページ / 新着の取得）
+	After *HistoryCursor
+
+	// Direction: "in"（Address が受信側）/ "out"（Address が送信側）。Address 必須
+	Direction string
+	// Counterparty: 取引相手。Address 指定時はその相手側、未指定時は送受信いずれか
+	Counterparty *string
+	Token        *string
+	Method       *string
+	Status       *string
+	MinAmount    *int64
+	MaxAmount    *int64
+	// From 以上 To 未満
+	From *time.Time
+	To   *time.Time
+
+	// Tag / Group: 該当する監視アドレスのいずれかが関与したイベント（Address と併用すると AND）
+	Tag   *string
+	Group *string
+}
+
+// addrExpr は比較用に正規化したアドレス列の SQL 式を返す。
+// Sui は 0x の有無・大文字小文字の揺れを吸収する。
+func addrExpr(chain, col string) string {
+	if chain == "sui" {
+		return fmt.Sprintf("lower(regexp_replace(COALESCE(%s,''), '^0x', ''))", col)
+	}
+	return col
+}
+
+// CanonicalAddress はアドレスをチェーンごとの正規の表記にする（Sui は小文字・0x 付き、Solana はそのまま）。
+// アドレスの正規化・比較はすべてこれを通す（addrExpr は同じ規則の SQL 版）。
+func CanonicalAddress(chain, addr string) string {
+	if chain == "sui" {
+		return "0x" + strings.ToLower(strings.TrimPrefix(addr, "0x"))
+	}
+	return addr
+}
+
+// normAddr は CanonicalAddress を DB に保存・比較する表記にする（Sui は 0x を外す。displayAddr の逆）
+func normAddr(chain, addr string) string {
+	a := CanonicalAddress(chain, addr)
+	if chain == "sui" {
+		return strings.TrimPrefix(a, "0x")
+	}
+	return a
+}
+
+// SameAddress は CanonicalAddress で 2 つのアドレスが同じかを見る
+func SameAddress(chain, a, b string) bool {
+	return CanonicalAddress(chain, a) == CanonicalAddress(chain, b)
+}
+
+// historyTables はチェーンごとのイベントテーブル
+var historyTables = map[string]string{
+	"solana": "tx_events_solana",
+	"sui":    "tx_events_sui",
+}
+
+// historyChains は chain 指定（ChainAll 含む）を対象チェーンの一覧に展開する
+func historyChains(chain string) ([]string, error) {
+	switch chain {
+	case "solana", "sui":
+		return []string{chain}, nil
+	case ChainAll:
+		return []string{"solana", "sui"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported chain: %s", chain)
+	}
+}
+
+// ListTxEvents は条件に合うイベントを新しい順に返す。
+// chain に ChainAll を指定すると全チェーンのテーブルを (ts, tx_hash) 順にマージする。
+// After 指定時はカーソル直後から昇順に limit 件取り、降順に並べ替えて返す。
+func (s *Store) ListTxEvents(ctx context.Context, chain string, hq HistoryQuery) ([]TxEvent, error) {
+	limit := hq.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	chains, err := historyChains(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{}
+	// arg は引数を追加してプレースホルダを返す
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// Before と同時指定の場合は範囲指定として通常の降順で返す
+	ascending := hq.After != nil && hq.Before == nil
+	order := " ORDER BY ts DESC, tx_hash DESC"
+	if ascending {
+		order = " ORDER BY ts ASC, tx_hash ASC"
+	}
+	lim := arg(limit)
+
+	var q string
+	if len(chains) == 1 {
+		q = historySelect(chains[0], hq, arg, false) + order + " LIMIT " + lim
+	} else {
+		// 各テーブルから limit 件ずつ取り、まとめて並べ直す
+		parts := make([]string, 0, len(chains))
+		for _, c := range chains {
+			parts = append(parts, "("+historySelect(c, hq, arg, false)+order+" LIMIT "+lim+")")
+		}
+		q = "SELECT * FROM (" + strings.Join(parts, " UNION ALL ") + ") u" + order + " LIMIT " + lim
+	}
+
+	rows, err := s.Pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]TxEvent, 0, limit)
+	for rows.Next() {
+		var e TxEvent
+		if err := rows.Scan(&e.Chain, &e.TxHash, &e.TS, &e.Sender, &e.Receiver, &e.Token, &e.Amount, &e.Fee, &e.Method, &e.Status, &e.SenderLabel, &e.ReceiverLabel); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if ascending {
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return out, nil
+}
+
+// historySelect は 1 チェーン分の SELECT ... WHERE を組み立てる（ORDER BY / LIMIT は呼び出し側）。
+// withRaw なら末尾に raw::text と raw_gz の列を加える（rawBytes で JSON にする）。
+func historySelect(chain string, hq HistoryQuery, arg func(any) string, withRaw bool) string {
+	cols := ""
+	if withRaw {
+		cols = ", raw::text AS raw, raw_gz"
+	}
+	c := arg(chain)
+	q := fmt.Sprintf(`
+          SELECT %s::text AS chain, tx_hash, ts, sender, receiver, token,
                  NULLIF(amount::text,'')::bigint AS amount,
                  NULLIF(fee::text,'')::bigint    AS fee,
-                 method
-          FROM tx_events_sui
+                 method, status, %s%s
+          FROM %s e
           WHERE 1=1
-        `
-    default:
-        return nil, fmt.Errorf("unsupported chain: %s", chain)
-    }
-
-    if address != nil && *address != "" {
-        switch chain {
-        case "solana":
-            // 厳密一致 + raw にも含まれていればヒット
-            args = append(args, *address, *address, "%"+*address+"%")
-            q += fmt.Sprintf(`
-              AND (
-                sender = $%d OR receiver = $%d OR raw::text ILIKE $%d
-              )`, len(args)-2, len(args)-1, len(args))
-        case "sui":
-            // 0xを外して小文字へ（DB側の sender/receiver も同様に比較）
-			      addrNorm := strings.ToLower(strings.TrimPrefix(*address, "0x"))
-			      args = append(args, addrNorm, addrNorm, "%"+addrNorm+"%")
-			      q += fmt.Sprintf(`
-			      	AND (
-			      		lower(regexp_replace(COALESCE(sender,''), '^0x', '')) = $%d
-			      		OR lower(regexp_replace(COALESCE(receiver,''), '^0x', '')) = $%d
-			      		OR lower(raw::text) ILIKE $%d
-			      	)
-			      `, len(args)-2, len(args)-1, len(args))
-        }
-    }
-
-    if before != nil {
-        args = append(args, *before)
-        q += fmt.Sprintf(" AND ts < $%d", len(args))
-    }
-
-    args = append(args, limit)
-    q += fmt.Sprintf(" ORDER BY ts DESC LIMIT $%d", len(args))
-
-    rows, err := s.Pool.Query(ctx, q, args...)
-    if err != nil { return nil, err }
-    defer rows.Close()
-
-    out := make([]TxEvent, 0, limit)
-    for rows.Next() {
-        var e TxEvent
-        if err := rows.Scan(&e.TxHash, &e.TS, &e.Sender, &e.Receiver, &e.Token, &e.Amount, &e.Fee, &e.Method); err != nil {
-            return nil, err
-        }
-        out = append(out, e)
-    }
-    return out, rows.Err()
+        `, c, labelCols(c, ""), cols, historyTables[chain])
+
+	sender, receiver := addrExpr(chain, "sender"), addrExpr(chain, "receiver")
+	hasAddr := hq.Address != nil && *hq.Address != ""
+	if hasAddr {
+		// tx_participants（chain, address で索引済み）経由で完全一致させる
+		sub := fmt.Sprintf(`
+              AND EXISTS (
+                SELECT 1 FROM tx_participants p
+                WHERE p.chain = %s AND p.address = %s
+                  AND p.tx_hash = e.tx_hash AND p.ts = e.ts`, arg(chain), arg(normAddr(chain, *hq.Address)))
+		switch hq.Direction {
+		case "in":
+			sub += " AND p.role = " + arg(RoleReceiver)
+		case "out":
+			sub += " AND p.role = " + arg(RoleSender)
+		}
+		q += sub + "\n              )"
+	}
+
+	if hq.Tag != nil || hq.Group != nil {
+		// タグ / グループに該当する監視アドレス（正規化済み）のいずれかが関与している
+		f := AddressFilter{Tag: hq.Tag, Group: hq.Group}
+		q += fmt.Sprintf(`
+              AND EXISTS (
+                SELECT 1 FROM tx_participants p
+                WHERE p.chain = %s AND p.tx_hash = e.tx_hash AND p.ts = e.ts
+                  AND p.address IN (SELECT %s FROM %s w WHERE 1=1%s)
+              )`, arg(chain), addrExpr(chain, "w.address"), watchedTables[chain].table, watchedMatch(chain, f, arg))
+	}
+
+	if hq.Counterparty != nil && *hq.Counterparty != "" {
+		cp := normAddr(chain, *hq.Counterparty)
+		switch {
+		case hasAddr && hq.Direction == "in":
+			q += fmt.Sprintf(" AND %s = %s", sender, arg(cp))
+		case hasAddr && hq.Direction == "out":
+			q += fmt.Sprintf(" AND %s = %s", receiver, arg(cp))
+		case hasAddr:
+			a := normAddr(chain, *hq.Address)
+			q += fmt.Sprintf(" AND ((%s = %s AND %s = %s) OR (%s = %s AND %s = %s))",
+				sender, arg(a), receiver, arg(cp), receiver, arg(a), sender, arg(cp))
+		default:
+			q += fmt.Sprintf(" AND (%s = %s OR %s = %s)", sender, arg(cp), receiver, arg(cp))
+		}
+	}
+
+	if hq.Token != nil {
+		q += " AND token = " + arg(*hq.Token)
+	}
+	if hq.Method != nil {
+		q += " AND method = " + arg(*hq.Method)
+	}
+	if hq.Status != nil {
+		q += " AND status = " + arg(*hq.Status)
+	}
+	if hq.MinAmount != nil {
+		q += " AND amount >= " + arg(*hq.MinAmount)
+	}
+	if hq.MaxAmount != nil {
+		q += " AND amount <= " + arg(*hq.MaxAmount)
+	}
+	if hq.From != nil {
+		q += " AND ts >= " + arg(*hq.From)
+	}
+	if hq.To != nil {
+		q += " AND ts < " + arg(*hq.To)
+	}
+
+	// tx_hash の無いカーソル（RFC3339 の旧形式）は ts だけで比べる（before も after も ts == T を含めない）
+	if hq.Before != nil {
+		if hq.Before.TxHash == "" {
+			q += " AND ts < " + arg(hq.Before.TS)
+		} else {
+			q += fmt.Sprintf(" AND (ts, tx_hash) < (%s, %s)", arg(hq.Before.TS), arg(hq.Before.TxHash))
+		}
+	}
+	if hq.After != nil {
+		if hq.After.TxHash == "" {
+			q += " AND ts > " + arg(hq.After.TS)
+		} else {
+			q += fmt.Sprintf(" AND (ts, tx_hash) > (%s, %s)", arg(hq.After.TS), arg(hq.After.TxHash))
+		}
+	}
+	return q
 }
@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrConflict は一意制約に反したとき（同名のグループ等）
+var ErrConflict = errors.New("conflict")
+
+// AddressMeta は監視アドレスのラベル・タグ・所属グループ
+type AddressMeta struct {
+	Chain     string    `json:"chain"`
+	Address   string    `json:"address"`
+	Label     *string   `json:"label"`
+	Tags      []string  `json:"tags"`
+	Groups    []string  `json:"groups"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AddressFilter は監視アドレスをタグ / グループで絞り込む条件（両方なら AND）
+type AddressFilter struct {
+	Tag   *string
+	Group *string
+}
+
+// AddressGroup は名前付きのアドレスのグループ
+type AddressGroup struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	Members     int       `json:"members"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// labelCols は送受信者のラベル列（sender_label, receiver_label）の SELECT 句。chain はプレースホルダ
+func labelCols(chain, prefix string) string {
+	return fmt.Sprintf("address_label(%s, %ssender) AS sender_label, address_label(%s, %sreceiver) AS receiver_label",
+		chain, prefix, chain, prefix)
+}
+
+// watchedMatch は w（watched_addresses_*）の行が f に合う条件を返す（先頭は " AND "）
+func watchedMatch(chain string, f AddressFilter, arg func(any) string) string {
+	q := ""
+	if f.Tag != nil {
+		q += " AND w.tags @> ARRAY[" + arg(*f.Tag) + "::text]"
+	}
+	if f.Group != nil {
+		q += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM address_group_members m JOIN address_groups g ON g.id = m.group_id
+			WHERE g.name = %s AND m.chain = %s AND m.address = w.address)`, arg(*f.Group), arg(chain))
+	}
+	return q
+}
+
+// metaSelect は 1 チェーン分の AddressMeta の SELECT を組み立てる
+func metaSelect(chain string, f AddressFilter, arg func(any) string) string {
+	c := arg(chain)
+	return `
+		SELECT ` + c + `::text, w.address, w.label, w.tags,
+		       ARRAY(SELECT g.name FROM address_group_members m JOIN address_groups g ON g.id = m.group_id
+		             WHERE m.chain = ` + c + ` AND m.address = w.address ORDER BY g.name),
+		       w.created_at, w.updated_at
+		FROM ` + watchedTables[chain].table + ` w
+		WHERE 1=1` + watchedMatch(chain, f, arg)
+}
+
+func scanAddressMeta(rows pgx.Rows) ([]AddressMeta, error) {
+	defer rows.Close()
+	out := []AddressMeta{}
+	for rows.Next() {
+		var m AddressMeta
+		if err := rows.Scan(&m.Chain, &m.Address, &m.Label, &m.Tags, &m.Groups, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// ListAddressMeta は監視アドレスのラベル等を登録順に返す（chain が ChainAll なら全チェーン）
+func (s *Store) ListAddressMeta(ctx context.Context, chain string, f AddressFilter) ([]AddressMeta, error) {
+	chains, err := historyChains(chain)
+	if err != nil {
+		return nil, err
+	}
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	parts := make([]string, 0, len(chains))
+	for _, c := range chains {
+		parts = append(parts, metaSelect(c, f, arg))
+	}
+	q := "SELECT * FROM (" + strings.Join(parts, " UNION ALL ") + ") u ORDER BY 6, 1, 2"
+	rows, err := s.Pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanAddressMeta(rows)
+}
+
+// GetAddressMeta は 1 アドレス分を返す（Sui は 0x の有無・大文字小文字を問わない）。未登録なら ErrNotFound。
+func (s *Store) GetAddressMeta(ctx context.Context, chain, address string) (AddressMeta, error) {
+	if _, ok := watchedTables[chain]; !ok {
+		return AddressMeta{}, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	q := metaSelect(chain, AddressFilter{}, arg) +
+		fmt.Sprintf(" AND %s = %s LIMIT 1", addrExpr(chain, "w.address"), arg(normAddr(chain, address)))
+	rows, err := s.Pool.Query(ctx, q, args...)
+	if err != nil {
+		return AddressMeta{}, err
+	}
+	ms, err := scanAddressMeta(rows)
+	if err != nil {
+		return AddressMeta{}, err
+	}
+	if len(ms) == 0 {
+		return AddressMeta{}, ErrNotFound
+	}
+	return ms[0], nil
+}
+
+// SetAddressMeta は監視アドレスのラベルとタグを置き換える（label が nil なら消す）。未登録なら ErrNotFound。
+func (s *Store) SetAddressMeta(ctx context.Context, chain, address string, label *string, tags []string) error {
+	t, ok := watchedTables[chain]
+	if !ok {
+		return fmt.Errorf("unsupported chain: %s", chain)
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	ct, err := s.Pool.Exec(ctx, `
+		UPDATE `+t.table+` SET label = $2, tags = $3, updated_at = NOW()
+		WHERE `+addrExpr(chain, "address")+` = $1
+	`, normAddr(chain, address), label, tags)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListAddressGroups はグループを名前順に返す（Members は所属する監視アドレスの数）
+func (s *Store) ListAddressGroups(ctx context.Context) ([]AddressGroup, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT g.id, g.name, g.description,
+		       (SELECT count(*) FROM address_group_members m WHERE m.group_id = g.id),
+		       g.created_at
+		FROM address_groups g
+		ORDER BY g.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []AddressGroup{}
+	for rows.Next() {
+		var g AddressGroup
+		if err := rows.Scan(&g.ID, &g.Name, &g.Description, &g.Members, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+// GetAddressGroup は名前でグループを返す（無ければ ErrNotFound）
+func (s *Store) GetAddressGroup(ctx context.Context, name string) (AddressGroup, error) {
+	var g AddressGroup
+	err := s.Pool.QueryRow(ctx, `
+		SELECT g.id, g.name, g.description,
+		       (SELECT count(*) FROM address_group_members m WHERE m.group_id = g.id),
+		       g.created_at
+		FROM address_groups g
+		WHERE g.name = $1
+	`, name).Scan(&g.ID, &g.Name, &g.Description, &g.Members, &g.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return g, ErrNotFound
+	}
+	return g, err
+}
+
+// CreateAddressGroup はグループを作り、採番された ID と作成時刻を g に設定する。同名があれば ErrConflict。
+func (s *Store) CreateAddressGroup(ctx context.Context, g *AddressGroup) error {
+	err := s.Pool.QueryRow(ctx, `
+		INSERT INTO address_groups (name, description) VALUES ($1, $2)
+		RETURNING id, created_at
+	`, g.Name, g.Description).Scan(&g.ID, &g.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrConflict
+	}
+	return err
+}
+
+// DeleteAddressGroup はグループと所属を消す（アドレス自体は残る）。無ければ ErrNotFound。
+func (s *Store) DeleteAddressGroup(ctx context.Context, name string) error {
+	ct, err := s.Pool.Exec(ctx, `DELETE FROM address_groups WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddGroupMember は監視アドレスをグループに加える（既に所属していれば何もしない）。
+// グループか監視アドレスが無ければ ErrNotFound。
+func (s *Store) AddGroupMember(ctx context.Context, group, chain, address string) error {
+	t, ok := watchedTables[chain]
+	if !ok {
+		return fmt.Errorf("unsupported chain: %s", chain)
+	}
+	ct, err := s.Pool.Exec(ctx, `
+		INSERT INTO address_group_members (group_id, chain, address)
+		SELECT g.id, $2, w.address
+		FROM address_groups g, `+t.table+` w
+		WHERE g.name = $1 AND `+addrExpr(chain, "w.address")+` = $3
+		ON CONFLICT DO NOTHING
+	`, group, chain, normAddr(chain, address))
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		// 既に所属しているのか、グループ / アドレスが無いのかを区別する
+		var exists bool
+		if err := s.Pool.QueryRow(ctx, `
+			SELECT EXISTS (
+			  SELECT 1 FROM address_group_members m JOIN address_groups g ON g.id = m.group_id
+			  WHERE g.name = $1 AND m.chain = $2 AND `+addrExpr(chain, "m.address")+` = $3)
+		`, group, chain, normAddr(chain, address)).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+	}
+	return nil
+}
+
+// RemoveGroupMember はアドレスをグループから外す。所属していなければ ErrNotFound。
+func (s *Store) RemoveGroupMember(ctx context.Context, group, chain, address string) error {
+	ct, err := s.Pool.Exec(ctx, `
+		DELETE FROM address_group_members m USING address_groups g
+		WHERE g.id = m.group_id AND g.name = $1 AND m.chain = $2 AND `+addrExpr(chain, "m.address")+` = $3
+	`, group, chain, normAddr(chain, address))
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Migration はマイグレーションファイルと適用状況
+type Migration struct {
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// migrationsTable は適用済みのマイグレーションの記録（make migrate で流した環境でも作られる）
+const migrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+	  name        text        PRIMARY KEY,
+	  applied_at  timestamptz NOT NULL DEFAULT now()
+	)`
+
+// Migrations は dir の *.sql を名前順に並べ、適用済みかどうかを返す
+func (s *Store) Migrations(ctx context.Context, dir fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(dir, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	if _, err := s.Pool.Exec(ctx, migrationsTable); err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[string]time.Time{}
+	for rows.Next() {
+		var n string
+		var at time.Time
+		if err := rows.Scan(&n, &at); err != nil {
+			return nil, err
+		}
+		applied[n] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]Migration, len(names))
+	for i, n := range names {
+		out[i] = Migration{Name: n}
+		if at, ok := applied[n]; ok {
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// ApplyMigration は 1 ファイルを 1 トランザクションで流し、適用済みとして記録する。
+// 各ファイルは何度流しても安全に書かれているので、all 指定の再適用もできる。
+func (s *Store) ApplyMigration(ctx context.Context, dir fs.FS, name string) error {
+	b, err := fs.ReadFile(dir, name)
+	if err != nil {
+		return err
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	// 引数なしの Exec は simple protocol なので複数文をそのまま流せる
+	if _, err := tx.Exec(ctx, strings.TrimSpace(string(b))); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO schema_migrations (name) VALUES ($1)
+		ON CONFLICT (name) DO UPDATE SET applied_at = now()
+	`, name); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// notification_channels.channel_type
+const (
+	ChannelSlack    = "slack"
+	ChannelDiscord  = "discord"
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+)
+
+// NotificationChannel はテナントの通知先と、その送信失敗の状況
+type NotificationChannel struct {
+	ID     int64  `json:"id"`
+	Tenant string `json:"tenant"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	// Config は種別ごとの設定（webhook_url / bot_token 等。API では秘匿値を伏せて返す）
+	Config  json.RawMessage `json:"config"`
+	Enabled bool            `json:"enabled"`
+
+	FailureCount        int64      `json:"failure_count"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           *string    `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+const channelCols = `id, tenant, name, channel_type, config::text, enabled,
+	failure_count, consecutive_failures, last_error, last_failure_at, last_success_at, created_at`
+
+func scanChannels(rows pgx.Rows) ([]NotificationChannel, error) {
+	defer rows.Close()
+	out := []NotificationChannel{}
+	for rows.Next() {
+		var c NotificationChannel
+		var cfg string
+		if err := rows.Scan(&c.ID, &c.Tenant, &c.Name, &c.Type, &cfg, &c.Enabled,
+			&c.FailureCount, &c.ConsecutiveFailures, &c.LastError, &c.LastFailureAt, &c.LastSuccessAt, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.Config = json.RawMessage(cfg)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// ListNotificationChannels はテナントのチャネルを作成順に返す（enabledOnly なら有効なものだけ）
+func (s *Store) ListNotificationChannels(ctx context.Context, tenant string, enabledOnly bool) ([]NotificationChannel, error) {
+	q := `SELECT ` + channelCols + ` FROM notification_channels WHERE tenant = $1`
+	if enabledOnly {
+		q += ` AND enabled`
+	}
+	rows, err := s.Pool.Query(ctx, q+` ORDER BY id`, tenant)
+	if err != nil {
+		return nil, err
+	}
+	return scanChannels(rows)
+}
+
+// GetNotificationChannel はテナントのチャネルを 1 件返す
+func (s *Store) GetNotificationChannel(ctx context.Context, tenant string, id int64) (*NotificationChannel, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+channelCols+` FROM notification_channels WHERE tenant = $1 AND id = $2`, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := scanChannels(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(cs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &cs[0], nil
+}
+
+// CreateNotificationChannel はチャネルを保存し、採番された ID と作成時刻を c に設定する
+func (s *Store) CreateNotificationChannel(ctx context.Context, c *NotificationChannel) error {
+	return s.Pool.QueryRow(ctx, `
+		INSERT INTO notification_channels (tenant, name, channel_type, config, enabled)
+		VALUES ($1, $2, $3, $4::jsonb, $5)
+		RETURNING id, created_at
+	`, c.Tenant, c.Name, c.Type, string(c.Config), c.Enabled).Scan(&c.ID, &c.CreatedAt)
+}
+
+// DeleteNotificationChannel はテナントのチャネルを削除する
+func (s *Store) DeleteNotificationChannel(ctx context.Context, tenant string, id int64) error {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM notification_channels WHERE tenant = $1 AND id = $2`, tenant, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RecordNotificationResult は送信結果をチャネルの失敗状況に反映する（sendErr が nil なら成功）
+func (s *Store) RecordNotificationResult(ctx context.Context, id int64, sendErr error) error {
+	if sendErr == nil {
+		_, err := s.Pool.Exec(ctx, `
+			UPDATE notification_channels
+			SET consecutive_failures = 0, last_success_at = now()
+			WHERE id = $1
+		`, id)
+		return err
+	}
+	_, err := s.Pool.Exec(ctx, `
+		UPDATE notification_channels
+		SET failure_count = failure_count + 1,
+		    consecutive_failures = consecutive_failures + 1,
+		    last_error = $2,
+		    last_failure_at = now()
+		WHERE id = $1
+	`, id, sendErr.Error())
+	return err
+}
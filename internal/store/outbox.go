@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// アウトボックスのイベント種別
+const EventTxInserted = "tx.inserted"
+
+// OutboxMaxAttempts は行が配信先に受け付けられなかったときに保留（parked）にするまでの回数
+const OutboxMaxAttempts = 10
+
+// OutboxRejected は publish がバッチのうち一部の行だけを受け付けなかったことを表す（id → 理由）。
+// 配信先そのものが使えないときは OutboxRejected ではないエラーを返すこと。
+type OutboxRejected map[int64]error
+
+func (r OutboxRejected) Error() string {
+	return fmt.Sprintf("%d outbox rows rejected", len(r))
+}
+
+// TxInsertedPayload は EventTxInserted の payload
+type TxInsertedPayload struct {
+	TxEvent
+	Participants []Participant `json:"participants"`
+}
+
+// OutboxMessage は event_outbox の 1 行。ID が購読側に見えるシーケンス番号になる。
+// Attempts はその行が配信先に受け付けられなかった回数。
+type OutboxMessage struct {
+	ID        int64
+	EventType string
+	Chain     string
+	TxHash    string
+	TS        time.Time
+	Payload   json.RawMessage
+	Attempts  int
+}
+
+// outboxPayload は保存するイベントから EventTxInserted の payload を作る（ラベルは保存時点のもの）
+func (ev TxEventInput) outboxPayload(chain string, senderLabel, receiverLabel *string) ([]byte, error) {
+	return json.Marshal(TxInsertedPayload{
+		TxEvent: TxEvent{
+			Chain:         chain,
+			TxHash:        ev.TxHash,
+			TS:            ev.TS,
+			Sender:        ev.Sender,
+			Receiver:      ev.Receiver,
+			Token:         ev.Token,
+			Amount:        ev.Amount,
+			Fee:           ev.Fee,
+			Method:        ev.Method,
+			Status:        ev.Status,
+			SenderLabel:   senderLabel,
+			ReceiverLabel: receiverLabel,
+		},
+		Participants: ev.ParticipantRows(chain),
+	})
+}
+
+// RelayOutbox は配信できる未配信の行を id 順に最大 limit 件取り出して publish に渡す。
+// publish が成功すれば配信済みにする。OutboxRejected を返したときは、それ以外の行を配信済みにし、
+// 受け付けられなかった行は attempts を増やして次の試行を遅らせる（OutboxMaxAttempts 回で保留にする）。
+// その他のエラー（配信先が使えない）は last_error だけを記録して全行を残す。
+// 行は FOR UPDATE SKIP LOCKED で確保するため publisher を複数動かしても同じ行を同時に扱わない。
+// publish 成功後にコミットできなかった行は次回もう一度配信される（少なくとも 1 回）。
+// id 順に取り出すのはバッチ内の並びだけで、配信全体の順序は保証しない（採番順とコミット順の違い、
+// 複数の publisher、失敗したバッチの再送、遅らせた行により、小さい id が後から流れることがある）。
+func (s *Store) RelayOutbox(ctx context.Context, limit int, publish func([]OutboxMessage) error) (int, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, event_type, chain, tx_hash, ts, payload::text, attempts
+		FROM event_outbox
+		WHERE published_at IS NULL AND parked_at IS NULL
+		  AND (next_attempt_at IS NULL OR next_attempt_at <= now())
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+	var msgs []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		var payload string
+		if err := rows.Scan(&m.ID, &m.EventType, &m.Chain, &m.TxHash, &m.TS, &payload, &m.Attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		m.Payload = json.RawMessage(payload)
+		msgs = append(msgs, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+
+	perr := publish(msgs)
+	var rejected OutboxRejected
+	if perr != nil && !errors.As(perr, &rejected) {
+		ids := make([]int64, len(msgs))
+		for i, m := range msgs {
+			ids[i] = m.ID
+		}
+		if _, err := tx.Exec(ctx, `UPDATE event_outbox SET last_error = $2 WHERE id = ANY($1)`, ids, perr.Error()); err != nil {
+			return 0, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return 0, err
+		}
+		return 0, perr
+	}
+
+	published := []int64{}
+	for _, m := range msgs {
+		if reason, ok := rejected[m.ID]; ok {
+			// 10 秒から倍々に遅らせる（最大 1 時間）
+			if _, err := tx.Exec(ctx, `
+				UPDATE event_outbox
+				   SET attempts = attempts + 1, last_error = $2,
+				       next_attempt_at = now() + least(interval '1 hour', interval '10 seconds' * power(2, attempts)),
+				       parked_at = CASE WHEN attempts + 1 >= $3 THEN now() END
+				 WHERE id = $1
+			`, m.ID, reason.Error(), OutboxMaxAttempts); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		published = append(published, m.ID)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE event_outbox SET published_at = now(), last_error = NULL, next_attempt_at = NULL
+		WHERE id = ANY($1)
+	`, published); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(published), nil
+}
+
+// ParkedOutbox は保留中の行を id 順に返す（payload は含めない）
+func (s *Store) ParkedOutbox(ctx context.Context) ([]ParkedOutboxMessage, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT id, event_type, chain, tx_hash, ts, attempts, coalesce(last_error, ''), parked_at
+		FROM event_outbox
+		WHERE published_at IS NULL AND parked_at IS NOT NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ParkedOutboxMessage
+	for rows.Next() {
+		var m ParkedOutboxMessage
+		if err := rows.Scan(&m.ID, &m.EventType, &m.Chain, &m.TxHash, &m.TS, &m.Attempts, &m.LastError, &m.ParkedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// ParkedOutboxMessage は保留中の行
+type ParkedOutboxMessage struct {
+	OutboxMessage
+	LastError string
+	ParkedAt  time.Time
+}
+
+// RequeueOutbox は保留中の行（ids が空なら全件）を配信待ちに戻し、戻した件数を返す
+func (s *Store) RequeueOutbox(ctx context.Context, ids []int64) (int64, error) {
+	if ids == nil {
+		ids = []int64{}
+	}
+	tag, err := s.Pool.Exec(ctx, `
+		UPDATE event_outbox SET parked_at = NULL, next_attempt_at = NULL, attempts = 0
+		WHERE published_at IS NULL AND parked_at IS NOT NULL
+		  AND (cardinality($1::bigint[]) = 0 OR id = ANY($1))
+	`, ids)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeOutbox は配信済みで olderThan より古い行を削除する
+func (s *Store) PurgeOutbox(ctx context.Context, olderThan time.Duration) (int64, error) {
+	tag, err := s.Pool.Exec(ctx, `
+		DELETE FROM event_outbox
+		WHERE published_at IS NOT NULL AND published_at < now() - $1::interval
+	`, olderThan.String())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
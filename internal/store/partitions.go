@@ -0,0 +1,433 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PartitionName は chain の ts を含む月（UTC）のパーティション名（tx_events_<chain>_pYYYY_MM）
+func PartitionName(chain string, ts time.Time) string {
+	return historyTables[chain] + "_p" + ts.UTC().Format("2006_01")
+}
+
+// ParsePartitionName はパーティション名からチェーンと月の範囲 [from, to) を取り出す
+func ParsePartitionName(name string) (chain string, from, to time.Time, ok bool) {
+	for c, table := range historyTables {
+		suffix, found := strings.CutPrefix(name, table+"_p")
+		if !found {
+			continue
+		}
+		m, err := time.Parse("2006_01", suffix)
+		if err != nil {
+			return "", time.Time{}, time.Time{}, false
+		}
+		return c, m, m.AddDate(0, 1, 0), true
+	}
+	return "", time.Time{}, time.Time{}, false
+}
+
+// ensurePartition は ts を含む月のパーティションが無ければ作る（作成を確認した月は覚えておく）
+func (s *Store) ensurePartition(ctx context.Context, chain string, ts time.Time) error {
+	name := PartitionName(chain, ts)
+	if _, ok := s.partitions.Load(name); ok {
+		return nil
+	}
+	if _, err := s.Pool.Exec(ctx, `SELECT ensure_tx_events_partition($1, $2)`, historyTables[chain], ts); err != nil {
+		return err
+	}
+	s.partitions.Store(name, true)
+	return nil
+}
+
+// isNoPartition は行の入るパーティションが無かったエラーか（23514 check_violation）
+func isNoPartition(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23514"
+}
+
+// EnsurePartitions は chain の当月から ahead か月先までのパーティションを作る
+func (s *Store) EnsurePartitions(ctx context.Context, chain string, ahead int) error {
+	table, ok := historyTables[chain]
+	if !ok {
+		return fmt.Errorf("unsupported chain: %s", chain)
+	}
+	_, err := s.Pool.Exec(ctx, `SELECT ensure_tx_events_partitions($1, $2)`, table, ahead)
+	return err
+}
+
+// TxPartition は tx_events_* の月パーティション 1 つ
+type TxPartition struct {
+	Chain string
+	Name  string
+	// From / To は月の範囲 [From, To)（UTC）
+	From time.Time
+	To   time.Time
+	// Rows は行数の見積もり（pg_class.reltuples。ANALYZE 前は 0）
+	Rows int64
+	// Bytes はインデックス等を含むサイズ
+	Bytes int64
+}
+
+// TxPartitions は chain のイベントテーブルに付いている月パーティションを古い順に返す
+// （名前が <テーブル>_pYYYY_MM でないものは含めない）
+func (s *Store) TxPartitions(ctx context.Context, chain string) ([]TxPartition, error) {
+	table, ok := historyTables[chain]
+	if !ok {
+		return nil, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT c.relname, GREATEST(c.reltuples, 0)::bigint, pg_total_relation_size(c.oid)
+		  FROM pg_inherits i
+		  JOIN pg_class c ON c.oid = i.inhrelid
+		 WHERE i.inhparent = to_regclass($1)
+		 ORDER BY c.relname
+	`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []TxPartition
+	for rows.Next() {
+		p := TxPartition{Chain: chain}
+		if err := rows.Scan(&p.Name, &p.Rows, &p.Bytes); err != nil {
+			return nil, err
+		}
+		c, from, to, ok := ParsePartitionName(p.Name)
+		if !ok || c != chain {
+			continue
+		}
+		p.From, p.To = from, to
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// CompressRaw は ts が before より前で raw の残っている行を最大 limit 件、raw を gzip して raw_gz に移す。
+// 移した件数を返す（limit 未満なら残りは無い）。
+func (s *Store) CompressRaw(ctx context.Context, chain string, before time.Time, limit int) (int, error) {
+	table, ok := historyTables[chain]
+	if !ok {
+		return 0, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT tx_hash, ts, raw::text
+		  FROM `+table+`
+		 WHERE ts < $1 AND raw IS NOT NULL
+		 LIMIT $2
+		   FOR UPDATE
+	`, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		hashes []string
+		tss    []time.Time
+		gzs    [][]byte
+	)
+	for rows.Next() {
+		var (
+			h   string
+			ts  time.Time
+			raw string
+		)
+		if err := rows.Scan(&h, &ts, &raw); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		gz, err := gzipBytes([]byte(raw))
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		hashes, tss, gzs = append(hashes, h), append(tss, ts), append(gzs, gz)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(hashes) == 0 {
+		return 0, nil
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE `+table+` t
+		   SET raw_gz = u.gz, raw = NULL
+		  FROM unnest($1::text[], $2::timestamptz[], $3::bytea[]) AS u(tx_hash, ts, gz)
+		 WHERE t.tx_hash = u.tx_hash AND t.ts = u.ts
+	`, hashes, tss, gzs); err != nil {
+		return 0, err
+	}
+	return len(hashes), tx.Commit(ctx)
+}
+
+// DropRaw は ts が before より前の行を最大 limit 件、raw / raw_gz ごと消す。消した件数を返す。
+func (s *Store) DropRaw(ctx context.Context, chain string, before time.Time, limit int) (int, error) {
+	table, ok := historyTables[chain]
+	if !ok {
+		return 0, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	ct, err := s.Pool.Exec(ctx, `
+		UPDATE `+table+`
+		   SET raw = NULL, raw_gz = NULL
+		 WHERE (tx_hash, ts) IN (
+		   SELECT tx_hash, ts FROM `+table+`
+		    WHERE ts < $1 AND (raw IS NOT NULL OR raw_gz IS NOT NULL)
+		    LIMIT $2
+		 )
+	`, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return int(ct.RowsAffected()), nil
+}
+
+// rawBytes は raw（jsonb のテキスト）か、保持期限を過ぎて gzip した raw_gz を JSON のバイト列にする
+func rawBytes(raw *string, gz []byte) []byte {
+	if raw != nil {
+		return []byte(*raw)
+	}
+	if gz == nil {
+		return nil
+	}
+	b, err := gunzipBytes(gz)
+	if err != nil {
+		// 壊れている場合は raw が無いものとして扱う
+		return nil
+	}
+	return b
+}
+
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(b []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// ArchiveRow はアーカイブに書き出すイベント 1 件（NDJSON の 1 行）
+type ArchiveRow struct {
+	Seq      int64     `json:"seq"`
+	TxHash   string    `json:"tx_hash"`
+	TS       time.Time `json:"ts"`
+	Sender   *string   `json:"sender"`
+	Receiver *string   `json:"receiver"`
+	Token    *string   `json:"token"`
+	// Amount / Fee は numeric(78,0) を文字列のまま
+	Amount       *string         `json:"amount"`
+	Fee          *string         `json:"fee"`
+	Method       *string         `json:"method"`
+	Status       *string         `json:"status"`
+	Raw          json.RawMessage `json:"raw,omitempty"`
+	Participants []Participant   `json:"participants,omitempty"`
+}
+
+// ArchiveFile は書き出したアーカイブ
+type ArchiveFile struct {
+	Path   string
+	Bytes  int64
+	SHA256 string
+}
+
+// ArchiveSink はアーカイブの書き出し先
+type ArchiveSink interface {
+	Write(r ArchiveRow) error
+	// Commit は書き出しを確定する
+	Commit() (ArchiveFile, error)
+	// Abort は書きかけ（または Commit 済み）のものを消す。切り離しに失敗したときに呼ばれる
+	Abort()
+}
+
+// ArchivedPartition は書き出して切り離したパーティションの記録
+type ArchivedPartition struct {
+	ID    int64
+	Chain string
+	Name  string
+	From  time.Time
+	To    time.Time
+	Rows  int64
+	ArchiveFile
+	ArchivedAt time.Time
+}
+
+// archivedUntil は chain（$1）の書き出し済みの月の終わり（無ければ -infinity）。
+// それより前の tx_events_* は消えているので、集計を作り直すときもその範囲の集計は残す
+const archivedUntil = `COALESCE((SELECT max(range_to) FROM archived_partitions WHERE chain = $1), '-infinity'::timestamptz)`
+
+// ErrCostBasisDepends は切り離そうとした月のイベントを取得原価（cost_lots / cost_disposals）が使っていることを表す
+var ErrCostBasisDepends = errors.New("cost basis depends on this partition")
+
+// ArchivePartition はパーティション p の全行を古い順に sink へ書き出して確定し、
+// パーティションを切り離して消す（関与アドレスも消し、archived_partitions に記録する）。
+// 書き出しの間はパーティションへの書き込みを止める。失敗したら何も消さず sink を Abort する。
+// /pnl は全イベントを再生し直すため、その月に取得原価のロット・処分があるときは ErrCostBasisDepends を返して切り離さない。
+func (s *Store) ArchivePartition(ctx context.Context, p TxPartition, sink ArchiveSink) (ArchivedPartition, error) {
+	rec := ArchivedPartition{Chain: p.Chain, Name: p.Name, From: p.From, To: p.To}
+	table, ok := historyTables[p.Chain]
+	if !ok {
+		return rec, fmt.Errorf("unsupported chain: %s", p.Chain)
+	}
+	if p.Name != PartitionName(p.Chain, p.From) {
+		return rec, fmt.Errorf("not a partition of %s: %s", table, p.Name)
+	}
+	done := false
+	defer func() {
+		if !done {
+			sink.Abort()
+		}
+	}()
+
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return rec, err
+	}
+	defer tx.Rollback(ctx)
+
+	part := pgx.Identifier{p.Name}.Sanitize()
+	if _, err := tx.Exec(ctx, `LOCK TABLE `+part+` IN SHARE MODE`); err != nil {
+		return rec, err
+	}
+	var used bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM cost_lots      WHERE chain = $1 AND ts >= $2 AND ts < $3)
+		    OR EXISTS (SELECT 1 FROM cost_disposals WHERE chain = $1 AND ts >= $2 AND ts < $3)
+	`, p.Chain, p.From, p.To).Scan(&used); err != nil {
+		return rec, err
+	}
+	if used {
+		return rec, fmt.Errorf("%s: %w", p.Name, ErrCostBasisDepends)
+	}
+	if _, err := tx.Exec(ctx, `
+		DECLARE archive_cur NO SCROLL CURSOR FOR
+		SELECT e.seq, e.tx_hash, e.ts, e.sender, e.receiver, e.token, e.amount::text, e.fee::text,
+		       e.method, e.status, e.raw::text, e.raw_gz,
+		       (SELECT json_agg(json_build_object('address', p.address, 'role', p.role) ORDER BY p.role, p.address)
+		          FROM tx_participants p
+		         WHERE p.chain = $1 AND p.tx_hash = e.tx_hash AND p.ts = e.ts)::text
+		  FROM `+part+` e
+		 ORDER BY e.ts, e.tx_hash
+	`, p.Chain); err != nil {
+		return rec, err
+	}
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf("FETCH FORWARD %d FROM archive_cur", exportFetchSize))
+		if err != nil {
+			return rec, err
+		}
+		n := 0
+		for rows.Next() {
+			n++
+			var (
+				r         ArchiveRow
+				raw, prts *string
+				gz        []byte
+			)
+			if err := rows.Scan(&r.Seq, &r.TxHash, &r.TS, &r.Sender, &r.Receiver, &r.Token, &r.Amount, &r.Fee,
+				&r.Method, &r.Status, &raw, &gz, &prts); err != nil {
+				rows.Close()
+				return rec, err
+			}
+			r.Raw = rawBytes(raw, gz)
+			if prts != nil {
+				if err := json.Unmarshal([]byte(*prts), &r.Participants); err != nil {
+					rows.Close()
+					return rec, err
+				}
+			}
+			if err := sink.Write(r); err != nil {
+				rows.Close()
+				return rec, err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return rec, err
+		}
+		rec.Rows += int64(n)
+		if n < exportFetchSize {
+			break
+		}
+	}
+	if _, err := tx.Exec(ctx, `CLOSE archive_cur`); err != nil {
+		return rec, err
+	}
+
+	if rec.ArchiveFile, err = sink.Commit(); err != nil {
+		return rec, err
+	}
+	for _, q := range []string{
+		`ALTER TABLE ` + table + ` DETACH PARTITION ` + part,
+		`DROP TABLE ` + part,
+	} {
+		if _, err := tx.Exec(ctx, q); err != nil {
+			return rec, err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM tx_participants WHERE chain = $1 AND ts >= $2 AND ts < $3`, p.Chain, p.From, p.To); err != nil {
+		return rec, err
+	}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO archived_partitions (chain, name, range_from, range_to, rows, path, bytes, sha256)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, archived_at
+	`, rec.Chain, rec.Name, rec.From, rec.To, rec.Rows, rec.Path, rec.Bytes, rec.SHA256).Scan(&rec.ID, &rec.ArchivedAt); err != nil {
+		return rec, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return rec, err
+	}
+	done = true
+	s.partitions.Delete(p.Name)
+	return rec, nil
+}
+
+// ArchivedPartitions は chain（空なら全チェーン）の書き出し済みパーティションを古い月から返す
+func (s *Store) ArchivedPartitions(ctx context.Context, chain string) ([]ArchivedPartition, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT id, chain, name, range_from, range_to, rows, path, bytes, sha256, archived_at
+		  FROM archived_partitions
+		 WHERE $1 = '' OR chain = $1
+		 ORDER BY chain, range_from, id
+	`, chain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ArchivedPartition
+	for rows.Next() {
+		var a ArchivedPartition
+		if err := rows.Scan(&a.ID, &a.Chain, &a.Name, &a.From, &a.To, &a.Rows, &a.Path, &a.Bytes, &a.SHA256, &a.ArchivedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TokenPrice は 1 トークンあたりの USD 価格
+type TokenPrice struct {
+	Token  string    `json:"token"`
+	TS     time.Time `json:"ts"`
+	USD    float64   `json:"usd"`
+	Source string    `json:"source,omitempty"`
+}
+
+// PriceLookup は「token の at 時点の価格」の問い合わせ
+type PriceLookup struct {
+	Token string
+	At    time.Time
+}
+
+// UpsertTokenPrices は価格を保存する（同じ token・ts は上書き）
+func (s *Store) UpsertTokenPrices(ctx context.Context, prices []TokenPrice) error {
+	if len(prices) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, p := range prices {
+		batch.Queue(`
+			INSERT INTO token_prices (token, ts, usd, source)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (token, ts) DO UPDATE SET usd = EXCLUDED.usd, source = EXCLUDED.source
+		`, p.Token, p.TS, p.USD, p.Source)
+	}
+	return s.Pool.SendBatch(ctx, batch).Close()
+}
+
+// TokenPricesAt は各 lookup について at 以前 tolerance 以内で最も新しい価格を返す。
+// 結果は lookups と同じ並びで、見つからないものは nil。
+func (s *Store) TokenPricesAt(ctx context.Context, lookups []PriceLookup, tolerance time.Duration) ([]*TokenPrice, error) {
+	out := make([]*TokenPrice, len(lookups))
+	if len(lookups) == 0 {
+		return out, nil
+	}
+	tokens := make([]string, len(lookups))
+	ats := make([]time.Time, len(lookups))
+	for i, l := range lookups {
+		tokens[i], ats[i] = l.Token, l.At
+	}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT k.i, p.token, p.ts, p.usd::float8, p.source
+		FROM unnest($1::text[], $2::timestamptz[]) WITH ORDINALITY AS k(token, at, i)
+		JOIN LATERAL (
+			SELECT token, ts, usd, source
+			FROM token_prices
+			WHERE token = k.token AND ts <= k.at AND ts > k.at - make_interval(secs => $3)
+			ORDER BY ts DESC
+			LIMIT 1
+		) p ON true
+	`, tokens, ats, tolerance.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i int64
+		var p TokenPrice
+		if err := rows.Scan(&i, &p.Token, &p.TS, &p.USD, &p.Source); err != nil {
+			return nil, err
+		}
+		out[i-1] = &p
+	}
+	return out, rows.Err()
+}
+
+// TokenDecimals は tokens のうち桁数が登録されているものを返す
+func (s *Store) TokenDecimals(ctx context.Context, tokens []string) (map[string]int, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT token, decimals FROM token_decimals WHERE token = ANY($1)`, tokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var t string
+		var d int
+		if err := rows.Scan(&t, &d); err != nil {
+			return nil, err
+		}
+		out[t] = d
+	}
+	return out, rows.Err()
+}
+
+// SetTokenDecimals はトークンの桁数を登録する（既存は上書き）
+func (s *Store) SetTokenDecimals(ctx context.Context, decimals map[string]int) error {
+	if len(decimals) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for t, d := range decimals {
+		batch.Queue(`
+			INSERT INTO token_decimals (token, decimals) VALUES ($1, $2)
+			ON CONFLICT (token) DO UPDATE SET decimals = EXCLUDED.decimals
+		`, t, d)
+	}
+	return s.Pool.SendBatch(ctx, batch).Close()
+}
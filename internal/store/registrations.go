@@ -46,18 +46,25 @@ func (s *Store) UpsertWatchedAddress(ctx context.Context, chain string, address
 	}
 }
 
-// RemoveWatchedAddress は監視を解除（該当行を削除）し、削除件数を返す
+// RemoveWatchedAddress は監視を解除（該当行とグループへの所属を削除）し、削除件数を返す
 func (s *Store) RemoveWatchedAddress(ctx context.Context, chain string, address string) (int64, error) {
-	switch chain {
-	case string(ChainSolana):
-		ct, err := s.Pool.Exec(ctx, `DELETE FROM watched_addresses_solana WHERE address = $1;`, address)
-		return ct.RowsAffected(), err
-	case string(ChainSui):
-		ct, err := s.Pool.Exec(ctx, `DELETE FROM watched_addresses_sui WHERE address = $1;`, address)
-		return ct.RowsAffected(), err
-	default:
+	t, ok := watchedTables[chain]
+	if !ok {
 		return 0, fmt.Errorf("unsupported chain: %s", chain)
 	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	ct, err := tx.Exec(ctx, `DELETE FROM `+t.table+` WHERE address = $1;`, address)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM address_group_members WHERE chain = $1 AND address = $2;`, chain, address); err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), tx.Commit(ctx)
 }
 
 // GetWatchedAddress は単一アドレスの情報を取得
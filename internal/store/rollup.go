@@ -0,0 +1,517 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// nativeTokens はチェーンのネイティブトークン（token の無いイベントと手数料の計上先）
+var nativeTokens = map[string]string{"solana": "SOL", "sui": "SUI"}
+
+// RollupResult は RollupDaily / RefreshEdges 1 回分の結果
+type RollupResult struct {
+	// Events は読んだイベント数（0 なら追いついている）
+	Events int `json:"events"`
+	// Updated は集計し直した単位（日次集計は (address, day)、エッジは (from, to, token)）の数
+	Updated int   `json:"updated"`
+	LastSeq int64 `json:"last_seq"`
+}
+
+// dayBucket は集計の単位（正規化済みアドレスと UTC の日付）
+type dayBucket struct {
+	address string
+	day     time.Time
+}
+
+// settledCol は行を書いたトランザクションが、実行中のどのトランザクションよりも前に始まったか。
+// seq はコミット順ではないため、実行中のトランザクションが小さい seq の行を後からコミットすることがある。
+// 集計済み位置はこれが true の行までしか進めない（xid の無い 0019 以前の行はコミット済みとして扱う）。
+const settledCol = `(xid IS NULL OR xid < pg_snapshot_xmin(pg_current_snapshot()))`
+
+// RollupDaily は chain の集計済み位置（rollup_state.last_seq）より後のイベントを最大 limit 件読み、
+// その送信者・受信者の (address, day) と、取り直した Tx の古い行の (address, day)（rollup_stale）を
+// tx_events_* から丸ごと集計し直して位置を進める。
+// 実行中のトランザクションより後に書かれた行（settledCol）に着いたらそこで止め、次の回に読み直す。
+// バケット単位で作り直すので、同じイベントを何度読んでも結果は変わらない。
+// 同じチェーンの並行実行は rollup_state の行ロックで直列になる。
+func (s *Store) RollupDaily(ctx context.Context, chain string, limit int) (RollupResult, error) {
+	var res RollupResult
+	table, ok := historyTables[chain]
+	if !ok {
+		return res, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO rollup_state (chain) VALUES ($1) ON CONFLICT DO NOTHING`, chain); err != nil {
+		return res, err
+	}
+	if err := tx.QueryRow(ctx, `SELECT last_seq FROM rollup_state WHERE chain = $1 FOR UPDATE`, chain).Scan(&res.LastSeq); err != nil {
+		return res, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT seq, ts, sender, receiver, `+settledCol+` FROM `+table+` WHERE seq > $1 ORDER BY seq LIMIT $2`, res.LastSeq, limit)
+	if err != nil {
+		return res, err
+	}
+	seen := map[dayBucket]bool{}
+	var buckets []dayBucket
+	add := func(addr *string, day time.Time) {
+		if addr == nil || *addr == "" {
+			return
+		}
+		b := dayBucket{normAddr(chain, *addr), day}
+		if !seen[b] {
+			seen[b] = true
+			buckets = append(buckets, b)
+		}
+	}
+	for rows.Next() {
+		var (
+			seq              int64
+			ts               time.Time
+			sender, receiver *string
+			settled          bool
+		)
+		if err := rows.Scan(&seq, &ts, &sender, &receiver, &settled); err != nil {
+			rows.Close()
+			return res, err
+		}
+		if !settled {
+			break
+		}
+		day := utcDay(ts)
+		add(sender, day)
+		add(receiver, day)
+		res.Events++
+		res.LastSeq = seq
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	// 取り直した Tx の古い行の (address, day)（saveTxEvent が rollup_stale に記録）も集計し直す
+	rows, err = tx.Query(ctx, `DELETE FROM rollup_stale WHERE chain = $1 RETURNING address, day`, chain)
+	if err != nil {
+		return res, err
+	}
+	for rows.Next() {
+		var addr string
+		var day time.Time
+		if err := rows.Scan(&addr, &day); err != nil {
+			rows.Close()
+			return res, err
+		}
+		add(&addr, utcDay(day))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+	if len(buckets) == 0 {
+		return res, nil
+	}
+
+	if err := rebuildBuckets(ctx, tx, chain, table, buckets); err != nil {
+		return res, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE rollup_state SET last_seq = $2, updated_at = now() WHERE chain = $1`, chain, res.LastSeq); err != nil {
+		return res, err
+	}
+	res.Updated = len(buckets)
+	return res, tx.Commit(ctx)
+}
+
+// ResetRollup は chain の集計を消して集計済み位置を 0 に戻す（次の RollupDaily から全件を集計し直す）。
+// 書き出して切り離した月の集計は元のイベントが無いので残す
+func (s *Store) ResetRollup(ctx context.Context, chain string) error {
+	if _, ok := historyTables[chain]; !ok {
+		return fmt.Errorf("unsupported chain: %s", chain)
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	for _, q := range []string{
+		`INSERT INTO rollup_state (chain) VALUES ($1) ON CONFLICT (chain) DO UPDATE SET last_seq = 0, updated_at = now()`,
+		`DELETE FROM address_daily WHERE chain = $1 AND day >= (` + archivedUntil + ` AT TIME ZONE 'UTC')::date`,
+		`DELETE FROM address_daily_counterparties WHERE chain = $1 AND day >= (` + archivedUntil + ` AT TIME ZONE 'UTC')::date`,
+		`DELETE FROM rollup_stale WHERE chain = $1 AND day >= (` + archivedUntil + ` AT TIME ZONE 'UTC')::date`,
+	} {
+		if _, err := tx.Exec(ctx, q, chain); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// rebuildBuckets は buckets の集計行を消し、各 (address, day) に関わるイベントを tx_events_* から読んで
+// AggregateDaily で集計し直した行を書き込む。
+func rebuildBuckets(ctx context.Context, tx pgx.Tx, chain, table string, buckets []dayBucket) error {
+	addrs := make([]string, len(buckets))
+	days := make([]time.Time, len(buckets))
+	for i, b := range buckets {
+		addrs[i], days[i] = b.address, b.day
+	}
+	for _, t := range []string{"address_daily", "address_daily_counterparties"} {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM `+t+` d
+			USING unnest($2::text[], $3::date[]) AS b(address, day)
+			WHERE d.chain = $1 AND d.address = b.address AND d.day = b.day
+		`, chain, addrs, days); err != nil {
+			return err
+		}
+	}
+
+	sender, receiver := addrExpr(chain, "e.sender"), addrExpr(chain, "e.receiver")
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT b.address, b.day, e.tx_hash, e.ts, e.sender, e.receiver, e.token,
+		       NULLIF(e.amount::text,'')::bigint, NULLIF(e.fee::text,'')::bigint, e.status
+		  FROM unnest($1::text[], $2::date[]) AS b(address, day)
+		  JOIN %[3]s e
+		    ON e.ts >= (b.day::timestamp AT TIME ZONE 'UTC')
+		   AND e.ts <  ((b.day + 1)::timestamp AT TIME ZONE 'UTC')
+		 WHERE %[1]s = b.address OR %[2]s = b.address
+	`, sender, receiver, table), addrs, days)
+	if err != nil {
+		return err
+	}
+	events := map[dayBucket][]TxEvent{}
+	for rows.Next() {
+		var (
+			b dayBucket
+			e = TxEvent{Chain: chain}
+		)
+		if err := rows.Scan(&b.address, &b.day, &e.TxHash, &e.TS, &e.Sender, &e.Receiver, &e.Token, &e.Amount, &e.Fee, &e.Status); err != nil {
+			rows.Close()
+			return err
+		}
+		b.day = utcDay(b.day)
+		events[b] = append(events[b], e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var daily, counterparties [][]any
+	for _, b := range buckets {
+		for _, d := range AggregateDaily(chain, b.address, b.day, events[b]) {
+			daily = append(daily, []any{chain, b.address, b.day, d.Token, d.Inflow, d.Outflow, d.TxCount, d.FeesPaid, len(d.Counterparties), d.FirstTS, d.LastTS})
+			for _, c := range d.Counterparties {
+				counterparties = append(counterparties, []any{chain, b.address, b.day, d.Token, c})
+			}
+		}
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"address_daily"},
+		[]string{"chain", "address", "day", "token", "inflow", "outflow", "tx_count", "fees_paid", "counterparties", "first_ts", "last_ts"},
+		pgx.CopyFromRows(daily),
+	); err != nil {
+		return err
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"address_daily_counterparties"},
+		[]string{"chain", "address", "day", "token", "counterparty"},
+		pgx.CopyFromRows(counterparties),
+	)
+	return err
+}
+
+// DailyRollup はアドレスの 1 日・1 トークン分の集計（address_daily の 1 行）
+type DailyRollup struct {
+	Token    string
+	Inflow   int64
+	Outflow  int64
+	TxCount  int
+	FeesPaid int64
+	// Counterparties は相手アドレス（正規化済み・昇順）
+	Counterparties []string
+	FirstTS        time.Time
+	LastTS         time.Time
+}
+
+// AggregateDaily は address（正規化済み）の day（UTC）の集計をトークンごとに求める（並びはトークン名の順）。
+// events のうち day に含まれ、address が送信者か受信者のものだけを数える。
+//   - token の無いイベントはネイティブトークン（SOL / SUI）に計上する
+//   - 受信者としての amount を inflow、送信者としての amount を outflow に足す（失敗した Tx・自己送金は 0）
+//   - 送信者が払った手数料はネイティブトークンの行の fees_paid に足す。ネイティブ以外のトークンの Tx では
+//     ネイティブトークンの行を手数料だけで作る（その Tx は tx_count に数えない）
+//   - tx_count は Tx の異なり数、counterparties は送受信の相手（自己送金は除く）
+func AggregateDaily(chain, address string, day time.Time, events []TxEvent) []DailyRollup {
+	native := nativeTokens[chain]
+	day = utcDay(day)
+	type agg struct {
+		DailyRollup
+		txs  map[string]bool
+		cps  map[string]bool
+		seen bool
+	}
+	byToken := map[string]*agg{}
+	get := func(token string) *agg {
+		a := byToken[token]
+		if a == nil {
+			a = &agg{DailyRollup: DailyRollup{Token: token}, txs: map[string]bool{}, cps: map[string]bool{}}
+			byToken[token] = a
+		}
+		return a
+	}
+	touch := func(a *agg, ts time.Time) {
+		if !a.seen || ts.Before(a.FirstTS) {
+			a.FirstTS = ts
+		}
+		if !a.seen || ts.After(a.LastTS) {
+			a.LastTS = ts
+		}
+		a.seen = true
+	}
+	side := func(p *string) string {
+		if p == nil {
+			return ""
+		}
+		return normAddr(chain, *p)
+	}
+
+	for _, e := range events {
+		if !utcDay(e.TS).Equal(day) {
+			continue
+		}
+		s, r := side(e.Sender), side(e.Receiver)
+		isSender, isReceiver := s != "" && s == address, r != "" && r == address
+		if !isSender && !isReceiver {
+			continue
+		}
+		token := native
+		if e.Token != nil {
+			token = *e.Token
+		}
+		var amount, fee int64
+		if e.Amount != nil && (e.Status == nil || *e.Status != TxStatusFailed) {
+			amount = *e.Amount
+		}
+		if e.Fee != nil {
+			fee = *e.Fee
+		}
+
+		a := get(token)
+		touch(a, e.TS)
+		a.txs[e.TxHash] = true
+		switch {
+		case isReceiver && !isSender:
+			a.Inflow += amount
+			if s != "" {
+				a.cps[s] = true
+			}
+		case isSender && !isReceiver:
+			a.Outflow += amount
+			if r != "" {
+				a.cps[r] = true
+			}
+		}
+		if isSender && fee > 0 {
+			if token == native {
+				a.FeesPaid += fee
+			} else {
+				n := get(native)
+				touch(n, e.TS)
+				n.FeesPaid += fee
+			}
+		}
+	}
+
+	out := make([]DailyRollup, 0, len(byToken))
+	for _, a := range byToken {
+		a.TxCount = len(a.txs)
+		a.Counterparties = make([]string, 0, len(a.cps))
+		for c := range a.cps {
+			a.Counterparties = append(a.Counterparties, c)
+		}
+		sort.Strings(a.Counterparties)
+		out = append(out, a.DailyRollup)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Token < out[j].Token })
+	return out
+}
+
+func utcDay(ts time.Time) time.Time {
+	y, m, d := ts.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// DailyActivity はアドレスの 1 日・1 トークン分の集計
+type DailyActivity struct {
+	Day            string `json:"day"`
+	Token          string `json:"token"`
+	Inflow         int64  `json:"inflow"`
+	Outflow        int64  `json:"outflow"`
+	TxCount        int    `json:"tx_count"`
+	FeesPaid       int64  `json:"fees_paid"`
+	Counterparties int    `json:"counterparties"`
+}
+
+// TokenActivity はトークンごとの通算
+type TokenActivity struct {
+	Token    string `json:"token"`
+	Inflow   int64  `json:"inflow"`
+	Outflow  int64  `json:"outflow"`
+	Net      int64  `json:"net"`
+	TxCount  int    `json:"tx_count"`
+	FeesPaid int64  `json:"fees_paid"`
+	// Counterparties は通算の相手アドレスの異なり数
+	Counterparties int       `json:"counterparties"`
+	ActiveDays     int       `json:"active_days"`
+	FirstSeen      time.Time `json:"first_seen"`
+	LastSeen       time.Time `json:"last_seen"`
+}
+
+// AddressSummary は GET /addresses/{chain}/{address}/summary の応答
+type AddressSummary struct {
+	Chain   string `json:"chain"`
+	Address string `json:"address"`
+	// FirstSeen / LastSeen は集計済みのイベントが無ければ nil
+	FirstSeen *time.Time `json:"first_seen"`
+	LastSeen  *time.Time `json:"last_seen"`
+	// Counterparties は全トークンを通した相手アドレスの異なり数
+	Counterparties int             `json:"counterparties"`
+	Totals         []TokenActivity `json:"totals"`
+	Daily          []DailyActivity `json:"daily"`
+	// RolledUpAt は集計を最後に進めた時刻（集計がまだ無ければ nil）
+	RolledUpAt *time.Time `json:"rolled_up_at"`
+}
+
+// SummaryQuery は AddressSummary の条件
+type SummaryQuery struct {
+	// Since 以降（UTC の日付）を日次の系列に含める
+	Since time.Time
+	// Token を指定するとそのトークンだけ
+	Token *string
+}
+
+// GetAddressSummary は address_daily からアドレスの通算と日次の系列を読む
+func (s *Store) GetAddressSummary(ctx context.Context, chain, address string, q SummaryQuery) (*AddressSummary, error) {
+	if _, ok := historyTables[chain]; !ok {
+		return nil, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	sum := &AddressSummary{Chain: chain, Address: address, Totals: []TokenActivity{}, Daily: []DailyActivity{}}
+	address = normAddr(chain, address)
+	args := []any{chain, address}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := "chain = $1 AND address = $2"
+	if q.Token != nil {
+		where += " AND token = " + arg(*q.Token)
+	}
+
+	byToken := map[string]*TokenActivity{}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT token, SUM(inflow)::bigint, SUM(outflow)::bigint, SUM(tx_count)::int, SUM(fees_paid)::bigint,
+		       COUNT(*)::int, MIN(first_ts), MAX(last_ts)
+		  FROM address_daily
+		 WHERE `+where+`
+		 GROUP BY token
+		 ORDER BY token
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var t TokenActivity
+		if err := rows.Scan(&t.Token, &t.Inflow, &t.Outflow, &t.TxCount, &t.FeesPaid, &t.ActiveDays, &t.FirstSeen, &t.LastSeen); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t.Net = t.Inflow - t.Outflow
+		sum.Totals = append(sum.Totals, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range sum.Totals {
+		t := &sum.Totals[i]
+		byToken[t.Token] = t
+		if sum.FirstSeen == nil || t.FirstSeen.Before(*sum.FirstSeen) {
+			sum.FirstSeen = &t.FirstSeen
+		}
+		if sum.LastSeen == nil || t.LastSeen.After(*sum.LastSeen) {
+			sum.LastSeen = &t.LastSeen
+		}
+	}
+
+	// 相手アドレスの異なり数（トークン別と全体）
+	rows, err = s.Pool.Query(ctx, `
+		SELECT token, COUNT(DISTINCT counterparty)::int
+		  FROM address_daily_counterparties
+		 WHERE `+where+`
+		 GROUP BY ROLLUP (token)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			token *string
+			n     int
+		)
+		if err := rows.Scan(&token, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if token == nil {
+			sum.Counterparties = n
+		} else if t := byToken[*token]; t != nil {
+			t.Counterparties = n
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.Pool.Query(ctx, `
+		SELECT day, token, inflow::bigint, outflow::bigint, tx_count, fees_paid::bigint, counterparties
+		  FROM address_daily
+		 WHERE `+where+` AND day >= `+arg(utcDay(q.Since))+`
+		 ORDER BY day, token
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			d   DailyActivity
+			day time.Time
+		)
+		if err := rows.Scan(&day, &d.Token, &d.Inflow, &d.Outflow, &d.TxCount, &d.FeesPaid, &d.Counterparties); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.Day = day.Format(time.DateOnly)
+		sum.Daily = append(sum.Daily, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var at time.Time
+	err = s.Pool.QueryRow(ctx, `SELECT updated_at FROM rollup_state WHERE chain = $1`, chain).Scan(&at)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return nil, err
+	default:
+		sum.RolledUpAt = &at
+	}
+	return sum, nil
+}
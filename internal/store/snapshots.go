@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// TokenBalance は 1 トークン分の残高（最小単位）
+type TokenBalance struct {
+	Token  string `json:"token"`
+	Amount int64  `json:"amount"`
+}
+
+// BalanceSnapshot は直近に RPC から取得した残高
+type BalanceSnapshot struct {
+	Balances  []TokenBalance
+	FetchedAt time.Time
+}
+
+// GetBalanceSnapshots は keys のスナップショットを返す（無いものは含まない）
+func (s *Store) GetBalanceSnapshots(ctx context.Context, keys []WatchKey) (map[WatchKey]BalanceSnapshot, error) {
+	chains := make([]string, len(keys))
+	addrs := make([]string, len(keys))
+	for i, k := range keys {
+		chains[i], addrs[i] = k.Chain, k.Address
+	}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT b.chain, b.address, b.balances::text, b.fetched_at
+		FROM balance_snapshots b
+		JOIN unnest($1::text[], $2::text[]) AS k(chain, address)
+		  ON b.chain = k.chain AND b.address = k.address
+	`, chains, addrs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[WatchKey]BalanceSnapshot{}
+	for rows.Next() {
+		var k WatchKey
+		var raw string
+		var snap BalanceSnapshot
+		if err := rows.Scan(&k.Chain, &k.Address, &raw, &snap.FetchedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(raw), &snap.Balances); err != nil {
+			return nil, err
+		}
+		out[k] = snap
+	}
+	return out, rows.Err()
+}
+
+// SaveBalanceSnapshot はアドレスの残高を上書き保存する（fetched_at は DB の now()）
+func (s *Store) SaveBalanceSnapshot(ctx context.Context, chain, address string, balances []TokenBalance) error {
+	if balances == nil {
+		balances = []TokenBalance{}
+	}
+	b, err := json.Marshal(balances)
+	if err != nil {
+		return err
+	}
+	_, err = s.Pool.Exec(ctx, `
+		INSERT INTO balance_snapshots (chain, address, balances, fetched_at)
+		VALUES ($1, $2, $3::jsonb, now())
+		ON CONFLICT (chain, address) DO UPDATE SET balances = EXCLUDED.balances, fetched_at = EXCLUDED.fetched_at
+	`, chain, address, string(b))
+	return err
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxEventsChannel は新着イベントを通知する LISTEN/NOTIFY のチャンネル名
+const TxEventsChannel = "tx_events"
+
+// TxNotification は TxEventsChannel に流す通知の中身。
+// NOTIFY のペイロードは 8000 バイト未満に制限されるためキーだけを載せ、
+// 受信側は GetStreamEvent で本体を読み直す。
+// Watermark だけを持つ通知は NotifyStreamWatermark が流す再開位置（Chain は空）。
+type TxNotification struct {
+	Chain     string `json:"chain,omitempty"`
+	Seq       int64  `json:"seq,omitempty"`
+	TxHash    string `json:"tx_hash,omitempty"`
+	Watermark int64  `json:"watermark,omitempty"`
+}
+
+// ParseTxNotification は NOTIFY のペイロードを解釈する
+func ParseTxNotification(payload string) (TxNotification, error) {
+	var n TxNotification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return n, err
+	}
+	if n.Chain == "" && n.Watermark > 0 {
+		return n, nil
+	}
+	if _, ok := historyTables[n.Chain]; !ok || n.Seq <= 0 {
+		return n, errors.New("invalid tx notification")
+	}
+	return n, nil
+}
+
+// StreamEvent は /stream で配信するイベント。
+// Seq は全チェーン共通の挿入順番号（重複排除用）。seq はコミット順ではないため、
+// 再接続時のカーソルには Seq ではなく Cursor（それ以下の行はすべて届いている位置）を使う。
+type StreamEvent struct {
+	Seq    int64 `json:"seq"`
+	Cursor int64 `json:"cursor"`
+	TxEvent
+	// Participants は購読フィルタ用の関与アドレス（正規化済み）
+	Participants []string `json:"-"`
+}
+
+// Involves は addr がこのイベントに関与しているかを返す
+func (e StreamEvent) Involves(addr string) bool {
+	a := normAddr(e.Chain, addr)
+	for _, p := range e.Participants {
+		if p == a {
+			return true
+		}
+	}
+	return false
+}
+
+// streamSelect は 1 チェーン分のストリーム用 SELECT を組み立てる
+func streamSelect(chain string, arg func(any) string) string {
+	c := arg(chain)
+	return fmt.Sprintf(`
+          SELECT e.seq, %s::text AS chain, e.tx_hash, e.ts, e.sender, e.receiver, e.token,
+                 NULLIF(e.amount::text,'')::bigint AS amount,
+                 NULLIF(e.fee::text,'')::bigint    AS fee,
+                 e.method, e.status, %s,
+                 ARRAY(SELECT p.address FROM tx_participants p
+                       WHERE p.chain = %s AND p.tx_hash = e.tx_hash AND p.ts = e.ts) AS participants
+          FROM %s e
+          WHERE 1=1
+        `, c, labelCols(c, "e."), c, historyTables[chain])
+}
+
+func scanStreamEvents(rows pgx.Rows) ([]StreamEvent, error) {
+	defer rows.Close()
+	var out []StreamEvent
+	for rows.Next() {
+		var e StreamEvent
+		if err := rows.Scan(&e.Seq, &e.Chain, &e.TxHash, &e.TS, &e.Sender, &e.Receiver, &e.Token, &e.Amount, &e.Fee, &e.Method, &e.Status, &e.SenderLabel, &e.ReceiverLabel, &e.Participants); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// GetStreamEvent は通知を受けたイベントを seq で読み出す
+func (s *Store) GetStreamEvent(ctx context.Context, chain string, seq int64) (*StreamEvent, error) {
+	if _, ok := historyTables[chain]; !ok {
+		return nil, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	q := streamSelect(chain, arg) + " AND e.seq = " + arg(seq)
+	rows, err := s.Pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	evs, err := scanStreamEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(evs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &evs[0], nil
+}
+
+// ListStreamEvents は afterSeq より後に挿入されたイベントを挿入順に返す（再接続時の補填用）。
+// addresses を指定するとそのいずれかが関与するイベントに絞る。
+func (s *Store) ListStreamEvents(ctx context.Context, chain string, addresses []string, afterSeq int64, limit int) ([]StreamEvent, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 500
+	}
+	chains, err := historyChains(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	lim := arg(limit)
+	parts := make([]string, 0, len(chains))
+	for _, c := range chains {
+		q := streamSelect(c, arg) + " AND e.seq > " + arg(afterSeq)
+		if len(addresses) > 0 {
+			norm := make([]string, len(addresses))
+			for i, a := range addresses {
+				norm[i] = normAddr(c, a)
+			}
+			q += fmt.Sprintf(`
+              AND EXISTS (
+                SELECT 1 FROM tx_participants p
+                WHERE p.chain = %s AND p.address = ANY(%s)
+                  AND p.tx_hash = e.tx_hash AND p.ts = e.ts
+              )`, arg(c), arg(norm))
+		}
+		parts = append(parts, "("+q+" ORDER BY e.seq LIMIT "+lim+")")
+	}
+	q := "SELECT * FROM (" + strings.Join(parts, " UNION ALL ") + ") u ORDER BY seq LIMIT " + lim
+
+	rows, err := s.Pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanStreamEvents(rows)
+}
+
+// StreamWatermark は、それ以下の seq の行がすべてコミット（または中止）済みになっている位置を返す。
+// settledCol が true の行（実行中のどのトランザクションよりも前に書かれた行）の最大の seq で、
+// それより小さい seq を採番したトランザクションはもう実行中ではない。行が無ければ 0。
+func (s *Store) StreamWatermark(ctx context.Context) (int64, error) {
+	parts := make([]string, 0, len(historyTables))
+	for _, table := range historyTables {
+		parts = append(parts, `(SELECT seq FROM `+table+` WHERE `+settledCol+` ORDER BY seq DESC LIMIT 1)`)
+	}
+	var w int64
+	err := s.Pool.QueryRow(ctx, `SELECT COALESCE(max(seq), 0) FROM (`+strings.Join(parts, " UNION ALL ")+`) w`).Scan(&w)
+	return w, err
+}
+
+// NotifyStreamWatermark は StreamWatermark で得た位置を TxEventsChannel に流す。
+// 通知はコミット順に届くため、受信側がこれを受け取った時点で、w 以下の行の通知はすべて受け取り済みになる。
+func (s *Store) NotifyStreamWatermark(ctx context.Context, w int64) error {
+	b, err := json.Marshal(TxNotification{Watermark: w})
+	if err != nil {
+		return err
+	}
+	_, err = s.Pool.Exec(ctx, `SELECT pg_notify($1, $2)`, TxEventsChannel, string(b))
+	return err
+}
+
+// ListenTxEvents は専用コネクションで TxEventsChannel を LISTEN し、通知ごとに fn を呼ぶ。
+// ctx がキャンセルされるかコネクションが切れるまで戻らない（再接続は呼び出し側）。
+func (s *Store) ListenTxEvents(ctx context.Context, fn func(TxNotification)) error {
+	pc, err := s.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// LISTEN 状態のコネクションはプールへ戻さず、終了時に閉じる
+	conn := pc.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+TxEventsChannel); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		tn, err := ParseTxNotification(n.Payload)
+		if err != nil {
+			continue
+		}
+		fn(tn)
+	}
+}
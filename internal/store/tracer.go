@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/you/wallet-watcher/internal/metrics"
+	"github.com/you/wallet-watcher/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer は pgx のクエリ / バッチごとにスパンを作り、所要時間を metrics.DBQueryDuration に記録する
+type queryTracer struct{}
+
+type traceKey struct{}
+
+type traceStart struct {
+	op    string
+	start time.Time
+	span  trace.Span
+}
+
+var dbTracer = tracing.Tracer("store")
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	op := sqlOp(data.SQL)
+	ctx, span := dbTracer.Start(ctx, "db."+op, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(op),
+			semconv.DBQueryText(strings.TrimSpace(data.SQL)),
+		))
+	return context.WithValue(ctx, traceKey{}, traceStart{op: op, start: time.Now(), span: span})
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	observeQuery(ctx, data.Err)
+}
+
+func (queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, span := dbTracer.Start(ctx, "db.batch", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName("batch"),
+			attribute.Int("db.batch.size", data.Batch.Len()),
+		))
+	return context.WithValue(ctx, traceKey{}, traceStart{op: "batch", start: time.Now(), span: span})
+}
+
+func (queryTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+func (queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	observeQuery(ctx, data.Err)
+}
+
+func observeQuery(ctx context.Context, err error) {
+	t, ok := ctx.Value(traceKey{}).(traceStart)
+	if !ok {
+		return
+	}
+	result := "ok"
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		result = "error"
+	} else {
+		err = nil
+	}
+	metrics.DBQueryDuration.WithLabelValues(t.op, result).Observe(time.Since(t.start).Seconds())
+	tracing.End(t.span, err)
+}
+
+// sqlOp は SQL の先頭キーワード（select / insert / update / delete / with ...）を返す。
+// 文そのものはラベルにしない（系列数が増えすぎるため）。
+func sqlOp(sql string) string {
+	f := strings.Fields(sql)
+	if len(f) == 0 {
+		return "other"
+	}
+	op := strings.ToLower(strings.TrimRight(f[0], ";("))
+	switch op {
+	case "select", "insert", "update", "delete", "with", "begin", "commit", "rollback",
+		"declare", "fetch", "close", "listen", "unlisten", "copy":
+		return op
+	}
+	return "other"
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// TxDetail は保存済みイベント 1 件と関連データ
+type TxDetail struct {
+	Event        TxEvent
+	Raw          []byte
+	Participants []Participant
+}
+
+// GetTxEvent は tx_hash で保存済みイベントを取得する（無ければ ErrNotFound）
+func (s *Store) GetTxEvent(ctx context.Context, chain, txHash string) (*TxDetail, error) {
+	table, ok := historyTables[chain]
+	if !ok {
+		return nil, fmt.Errorf("unsupported chain: %s", chain)
+	}
+
+	d := TxDetail{Event: TxEvent{Chain: chain}}
+	var (
+		raw *string
+		gz  []byte
+	)
+	err := s.Pool.QueryRow(ctx, fmt.Sprintf(`
+		SELECT tx_hash, ts, sender, receiver, token,
+		       NULLIF(amount::text,'')::bigint AS amount,
+		       NULLIF(fee::text,'')::bigint    AS fee,
+		       method, status, %s, raw::text, raw_gz
+		FROM %s
+		WHERE tx_hash = $1
+		ORDER BY ts DESC
+		LIMIT 1
+	`, labelCols("$2", ""), table), txHash, chain).Scan(&d.Event.TxHash, &d.Event.TS, &d.Event.Sender, &d.Event.Receiver, &d.Event.Token,
+		&d.Event.Amount, &d.Event.Fee, &d.Event.Method, &d.Event.Status, &d.Event.SenderLabel, &d.Event.ReceiverLabel, &raw, &gz)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.Raw = rawBytes(raw, gz)
+
+	rows, err := s.Pool.Query(ctx, `
+		SELECT address, role
+		FROM tx_participants
+		WHERE chain = $1 AND tx_hash = $2 AND ts = $3
+		ORDER BY role, address
+	`, chain, d.Event.TxHash, d.Event.TS)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Participant
+		if err := rows.Scan(&p.Address, &p.Role); err != nil {
+			return nil, err
+		}
+		d.Participants = append(d.Participants, p)
+	}
+	return &d, rows.Err()
+}
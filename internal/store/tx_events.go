@@ -2,39 +2,246 @@ package store
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/you/wallet-watcher/internal/metrics"
 )
 
-func (s *Store) InsertTxEventSolana(
-	ctx context.Context,
-	txHash string,
-	ts time.Time,
-	sender, receiver *string,
-	fee *int64,
-	method *string,
-	rawJSON []byte,
-) error {
-	_, err := s.Pool.Exec(ctx, `
-		INSERT INTO tx_events_solana (tx_hash, ts, sender, receiver, fee, method, raw)
-		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7::text,'')::jsonb)
-		ON CONFLICT (tx_hash, ts) DO NOTHING;
-	`, txHash, ts, sender, receiver, fee, method, string(rawJSON))
-	return err
+// tx_participants.role
+const (
+	RoleSender   = "sender"
+	RoleReceiver = "receiver"
+	// RoleAccount は送受信者以外で Tx に登場したアカウント（Solana の accountKeys 等）
+	RoleAccount = "account"
+)
+
+// Participant は Tx に関与したアドレスとその役割
+type Participant struct {
+	Address string `json:"address"`
+	Role    string `json:"role"`
+}
+
+// TxEventInput はワーカーが保存する正規化済みイベント
+type TxEventInput struct {
+	TxHash   string
+	TS       time.Time
+	Sender   *string
+	Receiver *string
+	Token    *string
+	Amount   *int64
+	Fee      *int64
+	Method   *string
+	Status   *string
+	Raw      []byte
+	// Participants は Sender/Receiver 以外の関与アドレス（Sender/Receiver は自動で追加される）
+	Participants []Participant
+}
+
+// ParticipantRows は tx_participants に書き込む行を返す。
+// Sender / Receiver と Participants をまとめ、アドレスを正規化してから (address, role) で重複排除する。
+func (ev TxEventInput) ParticipantRows(chain string) []Participant {
+	var all []Participant
+	if ev.Sender != nil && *ev.Sender != "" {
+		all = append(all, Participant{Address: *ev.Sender, Role: RoleSender})
+	}
+	if ev.Receiver != nil && *ev.Receiver != "" {
+		all = append(all, Participant{Address: *ev.Receiver, Role: RoleReceiver})
+	}
+	all = append(all, ev.Participants...)
+
+	seen := make(map[Participant]bool, len(all))
+	out := make([]Participant, 0, len(all))
+	for _, p := range all {
+		if p.Address == "" {
+			continue
+		}
+		p.Address = normAddr(chain, p.Address)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	return out
+}
+
+func (s *Store) InsertTxEventSolana(ctx context.Context, ev TxEventInput) error {
+	return s.insertTxEvent(ctx, "solana", false, insertSolanaSQL, ev)
+}
+
+func (s *Store) InsertTxEventSui(ctx context.Context, ev TxEventInput) error {
+	return s.insertTxEvent(ctx, "sui", false, insertSuiSQL, ev)
 }
 
-func (s *Store) InsertTxEventSui(
-	ctx context.Context,
-	txHash string,
-	ts time.Time,
-	sender, receiver *string,
-	fee *int64,
-	method *string,
-	rawJSON []byte,
-) error {
-	_, err := s.Pool.Exec(ctx, `
-		INSERT INTO tx_events_sui (tx_hash, ts, sender, receiver, fee, method, raw)
-		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7::text,'')::jsonb)
-		ON CONFLICT (tx_hash, ts) DO NOTHING;
-	`, txHash, ts, sender, receiver, fee, method, string(rawJSON))
+// ReplaceTxEvent は保存済みの同じ Tx（全 ts）と関与アドレスを消してから保存し直す（walletctl の再処理用）。
+// 新規挿入として扱うため、アウトボックスと NOTIFY にも改めて流れる。
+func (s *Store) ReplaceTxEvent(ctx context.Context, chain string, ev TxEventInput) error {
+	switch chain {
+	case "solana":
+		return s.insertTxEvent(ctx, chain, true, insertSolanaSQL, ev)
+	case "sui":
+		return s.insertTxEvent(ctx, chain, true, insertSuiSQL, ev)
+	}
+	return fmt.Errorf("unsupported chain: %s", chain)
+}
+
+// LegacySuiTxHashes は raw に transaction.data.sender が無い（Tx の送信者を取れなかった旧形式で保存した）
+// Sui の行のダイジェストを古い順に limit 件返す。これらの行は sender に transfer の宛先（pure 引数の
+// アドレス）が入っており、receiver が空のため、walletctl reprocess -legacy で取り直す。
+// raw を圧縮・破棄済みの行は判定できないため含めない。
+func (s *Store) LegacySuiTxHashes(ctx context.Context, limit int) ([]string, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT tx_hash FROM tx_events_sui
+		WHERE raw IS NOT NULL AND raw->'transaction'->'data'->>'sender' IS NULL
+		ORDER BY ts, tx_hash
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+const (
+	insertSolanaSQL = `
+		INSERT INTO tx_events_solana (tx_hash, ts, sender, receiver, token, amount, fee, method, status, raw)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10::text,'')::jsonb)
+		ON CONFLICT (tx_hash, ts) DO NOTHING
+		RETURNING seq;
+	`
+	insertSuiSQL = `
+		INSERT INTO tx_events_sui (tx_hash, ts, sender, receiver, token, amount, fee, method, status, raw)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10::text,'')::jsonb)
+		ON CONFLICT (tx_hash, ts) DO NOTHING
+		RETURNING seq;
+	`
+)
+
+// insertTxEvent は ev.TS の月のパーティションを用意してから saveTxEvent で保存する。
+// 別のプロセスがその月を切り離していた（パーティションが無い）場合は作り直して 1 回だけやり直す。
+func (s *Store) insertTxEvent(ctx context.Context, chain string, replace bool, insertSQL string, ev TxEventInput) error {
+	if err := s.ensurePartition(ctx, chain, ev.TS); err != nil {
+		return err
+	}
+	err := s.saveTxEvent(ctx, chain, replace, insertSQL, ev)
+	if isNoPartition(err) {
+		s.partitions.Delete(PartitionName(chain, ev.TS))
+		if err := s.ensurePartition(ctx, chain, ev.TS); err != nil {
+			return err
+		}
+		err = s.saveTxEvent(ctx, chain, replace, insertSQL, ev)
+	}
 	return err
 }
+
+// saveTxEvent はイベント本体と tx_participants を同一トランザクションで保存する。
+// 新規に挿入できた場合は event_outbox への 1 行（publisher が外部へ中継）と
+// TxEventsChannel への NOTIFY も同じトランザクションで行う（通知はコミット時に配送される）。
+// replace なら先に同じ tx_hash の行を消し、その (送信者・受信者, 日) を rollup_stale に記録する。
+func (s *Store) saveTxEvent(ctx context.Context, chain string, replace bool, insertSQL string, ev TxEventInput) error {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if replace {
+		// 古い行の (送信者・受信者, 日) は日次集計から外れるので、次の RollupDaily で集計し直す
+		rows, err := tx.Query(ctx, `DELETE FROM `+historyTables[chain]+` WHERE tx_hash = $1 RETURNING ts, sender, receiver`, ev.TxHash)
+		if err != nil {
+			return err
+		}
+		var stale []dayBucket
+		for rows.Next() {
+			var ts time.Time
+			var sender, receiver *string
+			if err := rows.Scan(&ts, &sender, &receiver); err != nil {
+				rows.Close()
+				return err
+			}
+			for _, a := range []*string{sender, receiver} {
+				if a != nil && *a != "" {
+					stale = append(stale, dayBucket{normAddr(chain, *a), utcDay(ts)})
+				}
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, b := range stale {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO rollup_stale (chain, address, day) VALUES ($1, $2, $3)
+				ON CONFLICT DO NOTHING
+			`, chain, b.address, b.day); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM tx_participants WHERE chain = $1 AND tx_hash = $2`, chain, ev.TxHash); err != nil {
+			return err
+		}
+	}
+
+	var seq int64
+	inserted := true
+	if err := tx.QueryRow(ctx, insertSQL,
+		ev.TxHash, ev.TS, ev.Sender, ev.Receiver, ev.Token, ev.Amount, ev.Fee, ev.Method, ev.Status, string(ev.Raw),
+	).Scan(&seq); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		// 既に保存済み（ON CONFLICT DO NOTHING）
+		inserted = false
+	}
+
+	batch := &pgx.Batch{}
+	for _, p := range ev.ParticipantRows(chain) {
+		batch.Queue(`
+			INSERT INTO tx_participants (chain, tx_hash, ts, address, role)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT DO NOTHING
+		`, chain, ev.TxHash, ev.TS, p.Address, p.Role)
+	}
+	if inserted {
+		var senderLabel, receiverLabel *string
+		if err := tx.QueryRow(ctx, `SELECT address_label($1, $2), address_label($1, $3)`, chain, ev.Sender, ev.Receiver).Scan(&senderLabel, &receiverLabel); err != nil {
+			return err
+		}
+		payload, err := ev.outboxPayload(chain, senderLabel, receiverLabel)
+		if err != nil {
+			return err
+		}
+		batch.Queue(`
+			INSERT INTO event_outbox (event_type, chain, tx_hash, ts, payload)
+			VALUES ($1, $2, $3, $4, $5::jsonb)
+		`, EventTxInserted, chain, ev.TxHash, ev.TS, string(payload))
+
+		notify, _ := json.Marshal(TxNotification{Chain: chain, Seq: seq, TxHash: ev.TxHash})
+		batch.Queue(`SELECT pg_notify($1, $2)`, TxEventsChannel, string(notify))
+	}
+	if batch.Len() > 0 {
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if inserted {
+		metrics.EventsInserted.WithLabelValues(chain).Inc()
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"fmt"
+	"time"
 )
 
 type WatchedSolana struct {
@@ -75,3 +77,135 @@ func (s *Store) UpdateSuiCursor(ctx context.Context, address string, lastCheckpo
 	`, address, lastCheckpoint)
 	return err
 }
+
+// watchedTables はチェーンごとの監視アドレスのテーブルとカーソル列
+var watchedTables = map[string]struct{ table, cursor string }{
+	"solana": {"watched_addresses_solana", "last_slot"},
+	"sui":    {"watched_addresses_sui", "last_checkpoint"},
+}
+
+// WatchedStatus は監視アドレスとカーソル（Solana は slot、Sui は checkpoint）
+type WatchedStatus struct {
+	Chain     string    `json:"chain"`
+	Address   string    `json:"address"`
+	Cursor    *int64    `json:"cursor"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AddWatchedAddress は監視対象に追加し、新規に追加したかを返す（既存なら false）
+func (s *Store) AddWatchedAddress(ctx context.Context, chain, address string) (bool, error) {
+	t, ok := watchedTables[chain]
+	if !ok {
+		return false, fmt.Errorf("unsupported chain: %s", chain)
+	}
+	ct, err := s.Pool.Exec(ctx, `INSERT INTO `+t.table+` (address) VALUES ($1) ON CONFLICT (address) DO NOTHING`, address)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+// WatchKey は監視アドレスの (chain, address)
+type WatchKey struct {
+	Chain   string
+	Address string
+}
+
+// AddWatchedAddresses は keys を 1 トランザクションでまとめて追加し、新規に追加したものを返す
+// （既存のものは含まない）。チェーンごとに配列 1 つの INSERT ... SELECT unnest で流す。
+func (s *Store) AddWatchedAddresses(ctx context.Context, keys []WatchKey) (map[WatchKey]bool, error) {
+	byChain := map[string][]string{}
+	for _, k := range keys {
+		if _, ok := watchedTables[k.Chain]; !ok {
+			return nil, fmt.Errorf("unsupported chain: %s", k.Chain)
+		}
+		byChain[k.Chain] = append(byChain[k.Chain], k.Address)
+	}
+	created := map[WatchKey]bool{}
+	if len(byChain) == 0 {
+		return created, nil
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	for chain, addrs := range byChain {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO `+watchedTables[chain].table+` (address)
+			SELECT unnest($1::text[])
+			ON CONFLICT (address) DO NOTHING
+			RETURNING address
+		`, addrs)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var a string
+			if err := rows.Scan(&a); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			created[WatchKey{Chain: chain, Address: a}] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ListWatched は監視アドレスを登録順に返す（chain が ChainAll なら全チェーン）
+func (s *Store) ListWatched(ctx context.Context, chain string) ([]WatchedStatus, error) {
+	chains, err := historyChains(chain)
+	if err != nil {
+		return nil, err
+	}
+	out := []WatchedStatus{}
+	for _, c := range chains {
+		t := watchedTables[c]
+		rows, err := s.Pool.Query(ctx, `
+			SELECT address, `+t.cursor+`, created_at, updated_at
+			FROM `+t.table+`
+			ORDER BY created_at, address
+		`)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			w := WatchedStatus{Chain: c}
+			if err := rows.Scan(&w.Address, &w.Cursor, &w.CreatedAt, &w.UpdatedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			out = append(out, w)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// SetCursor はカーソルをそのまま書き換える（nil なら未設定に戻す）。
+// Update*Cursor と違い巻き戻しもできる。対象が無ければ ErrNotFound。
+func (s *Store) SetCursor(ctx context.Context, chain, address string, cursor *int64) error {
+	t, ok := watchedTables[chain]
+	if !ok {
+		return fmt.Errorf("unsupported chain: %s", chain)
+	}
+	ct, err := s.Pool.Exec(ctx, `UPDATE `+t.table+` SET `+t.cursor+` = $2, updated_at = NOW() WHERE address = $1`, address, cursor)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
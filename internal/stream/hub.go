@@ -0,0 +1,314 @@
+// Package stream は新着イベントを購読者（/stream の SSE・WebSocket 接続）へ配信する。
+// ワーカーと API は別プロセスのため、ワーカーの INSERT 時に発行される
+// Postgres の NOTIFY を Hub が LISTEN し、購読フィルタに合う接続へ振り分ける。
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// Source はイベントの読み出し元（*store.Store が満たす。テストでは差し替える）
+type Source interface {
+	GetStreamEvent(ctx context.Context, chain string, seq int64) (*store.StreamEvent, error)
+	ListStreamEvents(ctx context.Context, chain string, addresses []string, afterSeq int64, limit int) ([]store.StreamEvent, error)
+	ListenTxEvents(ctx context.Context, fn func(store.TxNotification)) error
+	StreamWatermark(ctx context.Context) (int64, error)
+	NotifyStreamWatermark(ctx context.Context, w int64) error
+}
+
+const (
+	// subBuffer は購読ごとの送信待ちバッファ。溢れた購読は切断する（再接続＋カーソルで補填）
+	subBuffer = 256
+	// backfillPage は再接続時の補填で 1 回に読む件数
+	backfillPage = 500
+	// reconnectWait は LISTEN が切れたときの再接続待ち
+	reconnectWait = 2 * time.Second
+	// watermarkInterval は再開位置（store.StreamWatermark）を NOTIFY で流す間隔。
+	// 配信するイベントの Cursor はこの間隔ぶん遅れて進む
+	watermarkInterval = time.Second
+)
+
+var (
+	// ErrLagged は購読側の読み出しが追いつかずバッファが溢れたことを表す
+	ErrLagged = errors.New("stream: subscriber too slow")
+	// ErrInterrupted は LISTEN が切れ、その間の新着を配れなかったことを表す（再接続＋カーソルで補填）
+	ErrInterrupted = errors.New("stream: event feed interrupted")
+)
+
+// Filter は購読条件。空ならすべてに一致する。
+type Filter struct {
+	// Chain: "solana" / "sui" / store.ChainAll
+	Chain     string
+	Addresses []string
+}
+
+// Match はイベントが購読条件に合うかを返す
+func (f Filter) Match(e store.StreamEvent) bool {
+	if f.Chain != "" && f.Chain != store.ChainAll && f.Chain != e.Chain {
+		return false
+	}
+	if len(f.Addresses) == 0 {
+		return true
+	}
+	for _, a := range f.Addresses {
+		if e.Involves(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription は 1 接続分の購読
+type Subscription struct {
+	C      chan store.StreamEvent
+	filter Filter
+	hub    *Hub
+	once   sync.Once
+	err    error
+}
+
+// Close は購読を解除する（複数回呼んでもよい）
+func (s *Subscription) Close() { s.hub.remove(s, nil) }
+
+// Err は C が閉じられた理由を返す（溢れによる切断なら ErrLagged、LISTEN の切断なら ErrInterrupted）
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+// Hub は購読の管理と配信を行う
+type Hub struct {
+	src    Source
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+	// down は LISTEN が切れて再接続を待っている間 true（その間の新着は届かないので購読を受け付けない）
+	down bool
+	// watermark は最後に受け取った再開位置の通知。Publish するイベントの Cursor になる
+	watermark int64
+}
+
+func NewHub(src Source) *Hub {
+	return &Hub{src: src, subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe は購読を登録する。使い終わったら Close すること。
+// Hub が閉じられた後や LISTEN の再接続待ちの間は、C が閉じた状態の購読を返す。
+func (h *Hub) Subscribe(f Filter) *Subscription {
+	s := &Subscription{C: make(chan store.StreamEvent, subBuffer), filter: f, hub: h}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed || h.down {
+		if !h.closed {
+			s.err = ErrInterrupted
+		}
+		s.once.Do(func() { close(s.C) })
+		return s
+	}
+	h.subs[s] = struct{}{}
+	return s
+}
+
+// Close はすべての購読を終わらせ、以降の購読を受け付けない（API の停止時に呼ぶ）。
+// Serve は nil を返して戻るので、各接続は正常終了として閉じられる。
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subs {
+		delete(h.subs, s)
+		s.once.Do(func() { close(s.C) })
+	}
+}
+
+func (h *Hub) remove(s *Subscription, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[s]; !ok {
+		return
+	}
+	delete(h.subs, s)
+	s.err = err
+	s.once.Do(func() { close(s.C) })
+}
+
+// interrupt は LISTEN が切れたときに全購読を ErrInterrupted で切断し、再接続まで新しい購読を断る。
+// 切れている間の新着は通知されず、後で進む Cursor がそれを飛び越えてしまうため、接続し直させて補填する。
+func (h *Hub) interrupt() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.down = true
+	for s := range h.subs {
+		delete(h.subs, s)
+		s.err = ErrInterrupted
+		s.once.Do(func() { close(s.C) })
+	}
+}
+
+func (h *Hub) setDown(down bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.down = down
+}
+
+// advance は再開位置の通知を反映する。通知はコミット順に届くので、
+// これを受け取った時点で w 以下の行はすべて Publish 済みになっている。
+func (h *Hub) advance(w int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watermark = max(h.watermark, w)
+}
+
+// Publish は条件に合う購読へイベントを配る。送信待ちが溢れた購読は切断する。
+// e.Cursor には最後に受け取った再開位置を載せる。
+func (h *Hub) Publish(e store.StreamEvent) {
+	h.mu.Lock()
+	e.Cursor = h.watermark
+	var slow []*Subscription
+	for s := range h.subs {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.C <- e:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.Unlock()
+	for _, s := range slow {
+		h.remove(s, ErrLagged)
+	}
+}
+
+// Run は LISTEN を続け、通知ごとにイベントを読み直して Publish する。
+// 再開位置の通知は advance で反映し、流すのは notifyWatermark が受け持つ。
+// 接続が切れたら購読をすべて切断し、待って再接続する。ctx がキャンセルされるまで戻らない。
+func (h *Hub) Run(ctx context.Context) {
+	go h.notifyWatermark(ctx)
+	for {
+		h.setDown(false)
+		err := h.src.ListenTxEvents(ctx, func(n store.TxNotification) {
+			if n.Watermark > 0 {
+				h.advance(n.Watermark)
+				return
+			}
+			e, err := h.src.GetStreamEvent(ctx, n.Chain, n.Seq)
+			if err != nil {
+				logging.FromContext(ctx).Error("load stream event", "chain", n.Chain, "seq", n.Seq, "err", err)
+				return
+			}
+			h.Publish(*e)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		h.interrupt()
+		logging.FromContext(ctx).Warn("listen tx events, reconnecting", "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectWait):
+		}
+	}
+}
+
+// notifyWatermark は watermarkInterval ごとに store.StreamWatermark を読み、進んでいれば NOTIFY で流す。
+// イベントの通知と同じチャンネルをコミット順に通るので、Run が受け取った時点で
+// それ以下の行の通知はすべて処理済みになっている。
+func (h *Hub) notifyWatermark(ctx context.Context) {
+	t := time.NewTicker(watermarkInterval)
+	defer t.Stop()
+	var last int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		w, err := h.src.StreamWatermark(ctx)
+		if err == nil && w > last {
+			err = h.src.NotifyStreamWatermark(ctx, w)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				logging.FromContext(ctx).Warn("notify stream watermark", "err", err)
+			}
+			continue
+		}
+		last = max(last, w)
+	}
+}
+
+// Serve は 1 接続分の配信を行う。
+// after が指定されていれば seq が after より後のイベントを先に補填してから新着を流す。
+// 送るイベントの Cursor は「それ以下の seq の行はこの接続ですべて送った（または購読前の分）」位置で、
+// 実行中のトランザクションが採番した seq より先へは進まない。再接続時に最後の Cursor を渡せば取りこぼしが出ない。
+// Cursor より後のイベントは送り直すことがあるので、受け取る側は seq で重複を除くこと。
+// send がエラーを返すか ctx が終わるまで戻らない。
+func (h *Hub) Serve(ctx context.Context, f Filter, after *int64, send func(store.StreamEvent) error) error {
+	// mark 以下の行は購読より前にコミット済み（補填ですべて読め、新着としては届かない）
+	mark, err := h.src.StreamWatermark(ctx)
+	if err != nil {
+		return err
+	}
+	// 補填中に届いた新着を取りこぼさないよう、先に購読してから過去分を読む
+	sub := h.Subscribe(f)
+	defer sub.Close()
+
+	cursor := mark
+	var sent map[int64]bool
+	if after != nil {
+		sent = map[int64]bool{}
+		cursor = *after
+		cur := *after
+		for {
+			evs, err := h.src.ListStreamEvents(ctx, f.Chain, f.Addresses, cur, backfillPage)
+			if err != nil {
+				return err
+			}
+			for _, e := range evs {
+				// 補填は seq 順なので、mark までは送ったところまで進められる
+				cursor = max(cursor, min(e.Seq, mark))
+				e.Cursor = cursor
+				if err := send(e); err != nil {
+					return err
+				}
+				sent[e.Seq] = true
+				cur = e.Seq
+			}
+			if len(evs) < backfillPage {
+				break
+			}
+		}
+		cursor = max(cursor, mark)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-sub.C:
+			if !ok {
+				return sub.Err()
+			}
+			cursor = max(cursor, e.Cursor)
+			// 補填で送信済みのものは飛ばす
+			if sent[e.Seq] {
+				delete(sent, e.Seq)
+				continue
+			}
+			e.Cursor = cursor
+			if err := send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package tracing は OpenTelemetry のトレース設定を行う。
+// 既定では何も出力しない（no-op）。OTEL_TRACES_EXPORTER=otlp のときだけ OTLP でエクスポートするため、
+// テストやローカル実行ではコレクターが要らない。
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/you/wallet-watcher"
+
+// Setup は環境変数からトレーサーを設定し、終了時に呼ぶ shutdown を返す。
+//
+//   - OTEL_TRACES_EXPORTER: "otlp" で有効、"none" / 未設定なら no-op
+//   - OTEL_EXPORTER_OTLP_PROTOCOL: "http/protobuf"（既定）または "grpc"
+//   - OTEL_EXPORTER_OTLP_ENDPOINT / OTEL_EXPORTER_OTLP_HEADERS など: エクスポーターが直接読む
+//   - OTEL_SERVICE_NAME: 未設定なら service
+//   - OTEL_TRACES_SAMPLER / OTEL_TRACES_SAMPLER_ARG: SDK が直接読む
+func Setup(ctx context.Context, service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	switch v := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER"))); v {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q (use otlp or none)", v)
+	}
+
+	exp, err := newExporter(ctx, os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+	if err != nil {
+		return nil, err
+	}
+	name := os.Getenv("OTEL_SERVICE_NAME")
+	if name == "" {
+		name = service
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(name)))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, protocol string) (*otlptrace.Exporter, error) {
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "", "http/protobuf", "http":
+		return otlptracehttp.New(ctx)
+	case "grpc":
+		return otlptracegrpc.New(ctx)
+	}
+	return nil, fmt.Errorf("unsupported OTEL_EXPORTER_OTLP_PROTOCOL %q (use http/protobuf or grpc)", protocol)
+}
+
+// Tracer はこのリポジトリのパッケージ用トレーサー（name は "store" / "worker" などの区分）
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(instrumentation + "/" + name)
+}
+
+// End はエラーがあれば記録してスパンを閉じる
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// RPCAttrs は JSON-RPC 呼び出しスパンの属性
+func RPCAttrs(chain, method, endpoint string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.RPCSystemKey.String("jsonrpc"),
+		semconv.RPCMethod(method),
+		attribute.String("chain", chain),
+		attribute.String("rpc.endpoint", endpoint),
+	}
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/you/wallet-watcher/internal/alerts"
+	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/notify"
+)
+
+// evaluateAlerts は保存したイベントをアラートルールで評価し、新しいアラートを通知チャネルへ送る
+// （失敗しても取り込みは止めない）
+func evaluateAlerts(ctx context.Context, chain string, e *alerts.Engine, d *notify.Dispatcher, ev normalize.Event) {
+	if e == nil {
+		return
+	}
+	matched, err := e.Process(ctx, ev)
+	if err != nil {
+		logging.FromContext(ctx).Error("evaluate alerts", "tx", ev.TxHash, "err", err)
+		return
+	}
+	for _, a := range matched {
+		logging.FromContext(ctx).Info("alert", "tenant", a.Tenant, "rule_id", *a.RuleID, "tx", a.TxHash, "message", a.Message)
+	}
+	if d != nil && len(matched) > 0 {
+		d.Dispatch(ctx, matched)
+	}
+}
+
+// balancesOfSolana は balance_below 用に RPC から現在残高を引く
+func balancesOfSolana(cl *sol.Client) alerts.BalanceFunc {
+	return func(ctx context.Context, address string) (map[string]int64, error) {
+		bs, err := cl.GetBalances(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]int64, len(bs))
+		for _, b := range bs {
+			out[b.Token] += b.Amount
+		}
+		return out, nil
+	}
+}
+
+func balancesOfSui(cl *sui.Client) alerts.BalanceFunc {
+	return func(ctx context.Context, address string) (map[string]int64, error) {
+		bs, err := cl.GetBalances(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]int64, len(bs))
+		for _, b := range bs {
+			out[b.Token] += b.Amount
+		}
+		return out, nil
+	}
+}
@@ -0,0 +1,153 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/normalize"
+)
+
+// BackfillResult はバックフィル 1 回分の結果
+type BackfillResult struct {
+	Chain   string `json:"chain"`
+	Address string `json:"address"`
+	From    int64  `json:"from"`
+	To      int64  `json:"to"`
+	// Scanned は範囲内で見つかった Tx 数、Stored は保存できた数（保存済みのものも含む）
+	Scanned int `json:"scanned"`
+	Stored  int `json:"stored"`
+	Failed  int `json:"failed"`
+}
+
+// Backfill は address の slot が [from, to] の Tx を新しい順に遡って保存する（walletctl 用）。
+// カーソルは動かさず、アラートも評価しない（過去分の再取り込みで通知を出さないため）。
+func (w *SolanaWorker) Backfill(ctx context.Context, address string, from, to int64) (BackfillResult, error) {
+	res := BackfillResult{Chain: "solana", Address: address, From: from, To: to}
+	before := ""
+	for {
+		sigs, err := w.cl.GetSignaturesForAddressBefore(ctx, address, w.batch, before)
+		if err != nil {
+			return res, err
+		}
+		if len(sigs) == 0 {
+			return res, nil
+		}
+		for _, s := range sigs {
+			slot := int64(s.Slot)
+			if slot > to {
+				continue
+			}
+			if slot < from {
+				return res, nil
+			}
+			res.Scanned++
+			tx, err := w.cl.GetTransaction(ctx, s.Signature)
+			if err == nil && tx == nil {
+				err = fmt.Errorf("transaction not found")
+			}
+			if err == nil {
+				ev := normalize.Solana(s.Signature, tx)
+				err = w.st.InsertTxEventSolana(ctx, ev.Input())
+			}
+			if err != nil {
+				res.Failed++
+				logging.FromContext(ctx).Warn("backfill transaction", "tx", s.Signature, "err", err)
+				continue
+			}
+			res.Stored++
+		}
+		before = sigs[len(sigs)-1].Signature
+	}
+}
+
+// Reprocess は Tx を RPC から取り直し、正規化し直して保存済みの行を置き換える（walletctl 用）
+func (w *SolanaWorker) Reprocess(ctx context.Context, signature string) (*normalize.Event, error) {
+	tx, err := w.cl.GetTransaction(ctx, signature)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction not found: %s", signature)
+	}
+	ev := normalize.Solana(signature, tx)
+	if err := w.st.ReplaceTxEvent(ctx, "solana", ev.Input()); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// Backfill は checkpoint が [from, to] の範囲を新しい順に遡り、address が関与する Tx を保存する（walletctl 用）。
+// Sui にはアドレスごとの Tx 一覧が無いため checkpoint 内の Tx をすべて取得し、送信者・残高が変わった
+// アドレスに address が含まれるものだけを残す（Scanned もその件数）。カーソルは動かさず、アラートも評価しない。
+func (w *SuiWorker) Backfill(ctx context.Context, address string, from, to int64) (BackfillResult, error) {
+	res := BackfillResult{Chain: "sui", Address: address, From: from, To: to}
+	// 降順の取得は cursor より前（小さい番号）から返る
+	cur := fmt.Sprintf("%d", to+1)
+	cursor := &cur
+	for {
+		page, err := w.cl.GetCheckpointSummary(ctx, cursor, w.batch)
+		if err != nil {
+			return res, err
+		}
+		for _, cp := range page.Data {
+			var seq int64 = -1
+			if cp.SequenceNumber != nil {
+				if v, ok := cp.SequenceNumber.Value(); ok {
+					seq = int64(v)
+				}
+			}
+			if seq > to {
+				continue
+			}
+			if seq < from {
+				return res, nil
+			}
+			var tsMs uint64
+			if cp.TimestampMs != nil {
+				tsMs, _ = cp.TimestampMs.Value()
+			}
+			for _, digest := range cp.Transactions {
+				tx, err := w.cl.GetTransactionBlockDetailed(ctx, digest)
+				if err == nil {
+					ev := normalize.Sui(tx, tsMs)
+					if !ev.Involves(address) {
+						continue
+					}
+					res.Scanned++
+					ev.TxHash = digest
+					w.decimals.Apply(ctx, &ev)
+					err = w.st.InsertTxEventSui(ctx, ev.Input())
+				} else {
+					// 取得できなかった Tx は関与するか分からないが、取りこぼしとして数える
+					res.Scanned++
+				}
+				if err != nil {
+					res.Failed++
+					logging.FromContext(ctx).Warn("backfill transaction", "tx", digest, "err", err)
+					continue
+				}
+				res.Stored++
+			}
+		}
+		if !page.HasNextPage || page.NextCursor == nil || len(page.Data) == 0 {
+			return res, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// Reprocess は Tx を RPC から取り直し、正規化し直して保存済みの行を置き換える（walletctl 用）
+func (w *SuiWorker) Reprocess(ctx context.Context, digest string) (*normalize.Event, error) {
+	tx, err := w.cl.GetTransactionBlockDetailed(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+	ev := normalize.Sui(tx, 0)
+	ev.TxHash = digest
+	w.decimals.Apply(ctx, &ev)
+	if err := w.st.ReplaceTxEvent(ctx, "sui", ev.Input()); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// instance はハートビートに載せるワーカーの識別子（コンテナではホスト名 = コンテナ ID）
+var instance = func() string {
+	h, err := os.Hostname()
+	if err != nil || h == "" {
+		return "unknown"
+	}
+	return h
+}()
+
+// recordHeartbeat は tick の結果を worker_heartbeats に記録する（/readyz が参照する）。
+// 停止要求後の最後の tick も記録するため、ctx のキャンセルは引き継がない。
+func recordHeartbeat(ctx context.Context, st *store.Store, chain string, addresses, failed int, lag *int64, tickErr error) {
+	hctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	hb := store.WorkerHeartbeat{
+		Chain:        chain,
+		Instance:     instance,
+		Addresses:    addresses,
+		Failed:       failed,
+		MaxCursorLag: lag,
+	}
+	if tickErr != nil {
+		msg := tickErr.Error()
+		hb.LastError = &msg
+	}
+	if err := st.RecordHeartbeat(hctx, hb); err != nil {
+		logging.FromContext(ctx).Warn("record heartbeat", "err", err)
+	}
+}
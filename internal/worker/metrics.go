@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"github.com/you/wallet-watcher/internal/metrics"
+	"github.com/you/wallet-watcher/internal/tracing"
+)
+
+var workerTracer = tracing.Tracer("worker")
+
+// lagGauge はアドレスごとのカーソル遅れを metrics.CursorLag に載せ、
+// 監視対象から外れたアドレスの系列を消す
+type lagGauge struct {
+	chain string
+	prev  map[string]bool
+	cur   map[string]bool
+	// max は今回の tick で記録した遅れの最大値（ハートビート用）
+	max int64
+}
+
+func newLagGauge(chain string) *lagGauge {
+	return &lagGauge{chain: chain, prev: map[string]bool{}}
+}
+
+// begin は tick の開始時に呼ぶ
+func (g *lagGauge) begin() { g.cur, g.max = map[string]bool{}, 0 }
+
+// set は head（slot / checkpoint）と保存済みカーソルの差を記録する
+func (g *lagGauge) set(address string, head, cursor int64) {
+	lag := head - cursor
+	if lag < 0 {
+		lag = 0
+	}
+	metrics.CursorLag.WithLabelValues(g.chain, address).Set(float64(lag))
+	g.cur[address] = true
+	if lag > g.max {
+		g.max = lag
+	}
+}
+
+// end は tick の終了時に呼び、今回記録しなかったアドレスの系列を消す
+func (g *lagGauge) end() {
+	for a := range g.prev {
+		if !g.cur[a] {
+			metrics.CursorLag.DeleteLabelValues(g.chain, a)
+		}
+	}
+	g.prev = g.cur
+}
+
+// maxLag は今回の tick の遅れの最大値を返す（1 件も記録していなければ nil）
+func (g *lagGauge) maxLag() *int64 {
+	if len(g.cur) == 0 {
+		return nil
+	}
+	m := g.max
+	return &m
+}
+
+func addressResult(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/graph"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// rollupBatch は 1 tick で日次集計・エッジに取り込むイベント数の上限（残りは次の tick で続きから）
+const rollupBatch = 5000
+
+// rollupDaily は tick で保存したイベントを日次集計（address_daily）に取り込む。
+// 失敗しても tick は失敗にせず、次の tick で同じ位置から取り込み直す。
+// 停止要求が来ていれば行わず、途中で来たら打ち切る（1 トランザクションなので位置は進まず、停止の猶予を使い切らない）。
+func rollupDaily(ctx context.Context, st *store.Store, chain string) {
+	if ctx.Err() != nil {
+		return
+	}
+	rctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	res, err := st.RollupDaily(rctx, chain, rollupBatch)
+	if err != nil {
+		// 停止要求による打ち切りは失敗として残さない
+		if ctx.Err() == nil {
+			logging.FromContext(ctx).Warn("rollup daily", "err", err)
+		}
+		return
+	}
+	if res.Events > 0 {
+		logging.FromContext(ctx).Debug("rollup daily", "events", res.Events, "updated", res.Updated, "last_seq", res.LastSeq)
+	}
+}
+
+// refreshEdges は tick で保存したイベントの移動をアドレス間のエッジ（counterparty_edges）に取り込む。
+// 失敗・停止要求の扱いは rollupDaily と同じ。
+func refreshEdges(ctx context.Context, st *store.Store, chain string) {
+	if ctx.Err() != nil {
+		return
+	}
+	rctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	res, err := st.RefreshEdges(rctx, chain, rollupBatch, graph.Transfers)
+	if err != nil {
+		// 停止要求による打ち切りは失敗として残さない
+		if ctx.Err() == nil {
+			logging.FromContext(ctx).Warn("refresh edges", "err", err)
+		}
+		return
+	}
+	if res.Events > 0 {
+		logging.FromContext(ctx).Debug("refresh edges", "events", res.Events, "updated", res.Updated, "last_seq", res.LastSeq)
+	}
+}
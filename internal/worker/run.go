@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/logging"
+)
+
+// ErrGraceExceeded は停止の猶予内に実行中の tick が終わらなかったことを表す
+var ErrGraceExceeded = errors.New("worker: shutdown grace period exceeded")
+
+// Ticker は 1 回分の処理（*SolanaWorker / *SuiWorker が満たす）
+type Ticker interface {
+	Tick(ctx context.Context) error
+}
+
+// Run は interval ごとに Tick を呼ぶ。ctx がキャンセルされると新しい tick は始めず、
+// 実行中の tick（処理中のアドレスの保存とカーソル更新）の完了を最大 grace 待って戻る。
+func Run(ctx context.Context, t Ticker, interval, grace time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tk := time.NewTicker(interval)
+		defer tk.Stop()
+		for {
+			if err := t.Tick(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).Error("tick", "err", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-tk.C:
+				// 停止要求と tick が同時に来ていたら停止を優先する
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	logging.FromContext(ctx).Info("shutting down, waiting for the current tick", "grace", grace.String())
+	select {
+	case <-done:
+		return nil
+	case <-time.After(grace):
+		return ErrGraceExceeded
+	}
+}
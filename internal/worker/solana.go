@@ -2,42 +2,114 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
-	"log"
 	"time"
 
+	"github.com/you/wallet-watcher/internal/alerts"
 	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/metrics"
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/notify"
 	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SolanaWorker struct {
-	st    *store.Store
-	cl    *sol.Client
-	batch int
+	st           *store.Store
+	cl           *sol.Client
+	batch        int
+	maxAddresses int
+	alerts       *alerts.Engine
+	notify       *notify.Dispatcher
+	lag          *lagGauge
+	// grace は停止要求後に処理中のアドレスを続ける時間の上限
+	grace time.Duration
 }
 
-func NewSolana(st *store.Store, cl *sol.Client, batch int) *SolanaWorker {
-	if batch <= 0 { batch = 10 }
-	return &SolanaWorker{st: st, cl: cl, batch: batch}
+func NewSolana(st *store.Store, cl *sol.Client, wc config.Worker, nc config.Notify) *SolanaWorker {
+	w := &SolanaWorker{
+		st:           st,
+		cl:           cl,
+		batch:        wc.BatchSize,
+		maxAddresses: wc.MaxAddresses,
+		alerts:       alerts.NewEngine(st, "solana", balancesOfSolana(cl)),
+		lag:          newLagGauge("solana"),
+		grace:        wc.ShutdownGrace.D(),
+	}
+	if nc.Enabled {
+		w.notify = notify.NewDispatcher(st, nc.SendTimeout.D())
+	}
+	return w
 }
 
 // 1回分の処理：登録アドレスを列挙→各アドレスの新着Txを取得→保存→カーソル更新
-func (w *SolanaWorker) Tick(ctx context.Context) error {
-	addrs, err := w.st.ListWatchedSolana(ctx, 200)
+func (w *SolanaWorker) Tick(ctx context.Context) (err error) {
+	// この tick のスパンとログ（tick_id / trace_id が全行に付く）
+	ctx, span := workerTracer.Start(ctx, "solana.tick")
+	ctx = logging.WithTrace(logging.With(ctx, "chain", "solana", "tick_id", logging.NewID()))
+	start := time.Now()
+	var addresses, failed int
+	var lag *int64
+	defer func() {
+		metrics.TickDuration.WithLabelValues("solana").Observe(time.Since(start).Seconds())
+		rollupDaily(ctx, w.st, "solana")
+		refreshEdges(ctx, w.st, "solana")
+		recordHeartbeat(ctx, w.st, "solana", addresses, failed, lag, err)
+		tracing.End(span, err)
+	}()
+
+	addrs, err := w.st.ListWatchedSolana(ctx, w.maxAddresses)
 	if err != nil { return err }
+	addresses = len(addrs)
 
-	for _, a := range addrs {
-		if err := w.processAddress(ctx, a.Address, a.LastSlot); err != nil {
-			log.Printf("[solana] address=%s err=%v", a.Address, err)
+	// カーソル遅れの基準（取得できなければ今回は記録しない）
+	head, herr := w.cl.GetSlot(ctx)
+	if herr != nil {
+		logging.FromContext(ctx).Warn("get slot", "err", herr)
+	}
+	// 停止要求後も処理中のアドレスは続けるが、猶予を過ぎたら打ち切る
+	pctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	defer context.AfterFunc(ctx, func() { time.AfterFunc(w.grace, cancel) })()
+	w.lag.begin()
+	for i, a := range addrs {
+		// 停止要求が来たら次のアドレスには進まない。処理中のアドレスはキャンセルせずに
+		// 最後まで保存し、カーソルを更新してから戻る（猶予を過ぎたら打ち切り、次の tick でやり直す）
+		if ctx.Err() != nil {
+			logging.FromContext(ctx).Info("stopping tick", "remaining", len(addrs)-i)
+			break
 		}
+		actx, aspan := workerTracer.Start(pctx, "solana.process_address", trace.WithAttributes(attribute.String("wallet.address", a.Address)))
+		actx = logging.With(actx, "address", a.Address)
+		cursor, err := w.processAddress(actx, a.Address, a.LastSlot)
+		tracing.End(aspan, err)
+		metrics.AddressesProcessed.WithLabelValues("solana", addressResult(err)).Inc()
+		if err != nil {
+			failed++
+			logging.FromContext(actx).Error("process address", "err", err)
+		}
+		if herr == nil && cursor != nil {
+			w.lag.set(a.Address, head, *cursor)
+		}
+	}
+	if herr == nil {
+		w.lag.end()
+		lag = w.lag.maxLag()
 	}
+	span.SetAttributes(attribute.Int("worker.addresses", len(addrs)), attribute.Int("worker.failed", failed))
+	logging.FromContext(ctx).Debug("tick done", "addresses", len(addrs), "failed", failed,
+		"duration_ms", time.Since(start).Milliseconds())
 	return nil
 }
 
-func (w *SolanaWorker) processAddress(ctx context.Context, address string, lastSlot *int64) error {
+// processAddress は 1 アドレス分を処理し、処理後に保存されているカーソル（slot）を返す
+func (w *SolanaWorker) processAddress(ctx context.Context, address string, lastSlot *int64) (*int64, error) {
 	// 新しいものから最大batch件を取得し、lastSlotより新しいものを抽出
 	sigs, err := w.cl.GetSignaturesForAddress(ctx, address, w.batch)
-	if err != nil { return err }
+	if err != nil { return lastSlot, err }
 
 	var newestSlot int64 = -1
 	for _, s := range sigs {
@@ -48,31 +120,16 @@ func (w *SolanaWorker) processAddress(ctx context.Context, address string, lastS
 		// 詳細取得
 		tx, err := w.cl.GetTransaction(ctx, s.Signature)
 		if err != nil {
-			log.Printf("[solana] get tx %s: %v", s.Signature, err)
+			logging.FromContext(ctx).Warn("get transaction", "tx", s.Signature, "err", err)
 			continue
 		}
 
-		// 正規化して保存（最小）
-		var ts time.Time
-		if tx.BlockTime != nil && *tx.BlockTime > 0 {
-			ts = time.Unix(*tx.BlockTime, 0).UTC()
+		// 正規化して保存 → アラートルールを評価
+		ev := normalize.Solana(s.Signature, tx)
+		if err := w.st.InsertTxEventSolana(ctx, ev.Input()); err != nil {
+			logging.FromContext(ctx).Error("insert tx event", "tx", s.Signature, "err", err)
 		} else {
-			ts = time.Now().UTC()
-		}
-		var sender, receiver *string
-		if len(tx.Transaction.Message.AccountKeys) > 0 {
-			s := tx.Transaction.Message.AccountKeys[0]; sender = &s
-		}
-		if len(tx.Transaction.Message.AccountKeys) > 1 {
-			r := tx.Transaction.Message.AccountKeys[1]; receiver = &r
-		}
-		var fee *int64
-		if tx.Meta != nil {
-			f := int64(tx.Meta.Fee); fee = &f
-		}
-		raw, _ := json.Marshal(tx)
-		if err := w.st.InsertTxEventSolana(ctx, s.Signature, ts, sender, receiver, fee, nil, raw); err != nil {
-			log.Printf("[solana] insert tx %s: %v", s.Signature, err)
+			evaluateAlerts(ctx, "solana", w.alerts, w.notify, ev)
 		}
 		if int64(s.Slot) > newestSlot {
 			newestSlot = int64(s.Slot)
@@ -81,7 +138,10 @@ func (w *SolanaWorker) processAddress(ctx context.Context, address string, lastS
 
 	// カーソル更新
 	if newestSlot >= 0 {
-		return w.st.UpdateSolanaCursor(ctx, address, newestSlot)
+		if err := w.st.UpdateSolanaCursor(ctx, address, newestSlot); err != nil {
+			return lastSlot, err
+		}
+		return &newestSlot, nil
 	}
-	return nil
+	return lastSlot, nil
 }
@@ -2,40 +2,115 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
+	"github.com/you/wallet-watcher/internal/alerts"
 	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/logging"
+	"github.com/you/wallet-watcher/internal/metrics"
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/notify"
 	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SuiWorker struct {
-	st    *store.Store
-	cl    *sui.Client
-	batch int
+	st           *store.Store
+	cl           *sui.Client
+	batch        int
+	maxAddresses int
+	alerts       *alerts.Engine
+	notify       *notify.Dispatcher
+	lag          *lagGauge
+	// grace は停止要求後に処理中のアドレスを続ける時間の上限
+	grace time.Duration
+	// decimals は SUI 以外のコインの桁数（token_decimals → suix_getCoinMetadata）
+	decimals *normalize.SuiDecimals
 }
 
-func NewSui(st *store.Store, cl *sui.Client, batch int) *SuiWorker {
-	if batch <= 0 { batch = 10 }
-	return &SuiWorker{st: st, cl: cl, batch: batch}
+func NewSui(st *store.Store, cl *sui.Client, wc config.Worker, nc config.Notify) *SuiWorker {
+	w := &SuiWorker{
+		st:           st,
+		cl:           cl,
+		batch:        wc.BatchSize,
+		maxAddresses: wc.MaxAddresses,
+		alerts:       alerts.NewEngine(st, "sui", balancesOfSui(cl)),
+		lag:          newLagGauge("sui"),
+		grace:        wc.ShutdownGrace.D(),
+		decimals:     &normalize.SuiDecimals{Store: st, Client: cl},
+	}
+	if nc.Enabled {
+		w.notify = notify.NewDispatcher(st, nc.SendTimeout.D())
+	}
+	return w
 }
 
 // 1回分の処理：登録アドレスを列挙→各アドレスの新着Checkpointを取得→保存→カーソル更新
-func (w *SuiWorker) Tick(ctx context.Context) error {
-	addrs, err := w.st.ListWatchedSui(ctx, 200)
+func (w *SuiWorker) Tick(ctx context.Context) (err error) {
+	// この tick のスパンとログ（tick_id / trace_id が全行に付く）
+	ctx, span := workerTracer.Start(ctx, "sui.tick")
+	ctx = logging.WithTrace(logging.With(ctx, "chain", "sui", "tick_id", logging.NewID()))
+	start := time.Now()
+	var addresses, failed int
+	var lag *int64
+	defer func() {
+		metrics.TickDuration.WithLabelValues("sui").Observe(time.Since(start).Seconds())
+		rollupDaily(ctx, w.st, "sui")
+		refreshEdges(ctx, w.st, "sui")
+		recordHeartbeat(ctx, w.st, "sui", addresses, failed, lag, err)
+		tracing.End(span, err)
+	}()
+
+	addrs, err := w.st.ListWatchedSui(ctx, w.maxAddresses)
 	if err != nil { return err }
+	addresses = len(addrs)
 
-	for _, a := range addrs {
-		if err := w.processAddress(ctx, a.Address, a.LastCheckpoint); err != nil {
-			log.Printf("[sui] address=%s err=%v", a.Address, err)
+	// カーソル遅れの基準（取得できなければ今回は記録しない）
+	head, herr := w.cl.GetLatestCheckpoint(ctx)
+	if herr != nil {
+		logging.FromContext(ctx).Warn("get latest checkpoint", "err", herr)
+	}
+	// 停止要求後も処理中のアドレスは続けるが、猶予を過ぎたら打ち切る
+	pctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	defer context.AfterFunc(ctx, func() { time.AfterFunc(w.grace, cancel) })()
+	w.lag.begin()
+	for i, a := range addrs {
+		// 停止要求が来たら次のアドレスには進まない。処理中のアドレスはキャンセルせずに
+		// 最後まで保存し、カーソルを更新してから戻る（猶予を過ぎたら打ち切り、次の tick でやり直す）
+		if ctx.Err() != nil {
+			logging.FromContext(ctx).Info("stopping tick", "remaining", len(addrs)-i)
+			break
+		}
+		actx, aspan := workerTracer.Start(pctx, "sui.process_address", trace.WithAttributes(attribute.String("wallet.address", a.Address)))
+		actx = logging.With(actx, "address", a.Address)
+		cursor, err := w.processAddress(actx, a.Address, a.LastCheckpoint)
+		tracing.End(aspan, err)
+		metrics.AddressesProcessed.WithLabelValues("sui", addressResult(err)).Inc()
+		if err != nil {
+			failed++
+			logging.FromContext(actx).Error("process address", "err", err)
 		}
+		if herr == nil && cursor != nil {
+			w.lag.set(a.Address, head, *cursor)
+		}
+	}
+	if herr == nil {
+		w.lag.end()
+		lag = w.lag.maxLag()
 	}
+	span.SetAttributes(attribute.Int("worker.addresses", len(addrs)), attribute.Int("worker.failed", failed))
+	logging.FromContext(ctx).Debug("tick done", "addresses", len(addrs), "failed", failed,
+		"duration_ms", time.Since(start).Milliseconds())
 	return nil
 }
 
-func (w *SuiWorker) processAddress(ctx context.Context, address string, lastCheckpoint *int64) error {
+// processAddress は 1 アドレス分を処理し、処理後に保存されているカーソル（checkpoint）を返す
+func (w *SuiWorker) processAddress(ctx context.Context, address string, lastCheckpoint *int64) (*int64, error) {
 	// 最新のCheckpointから取得開始
 	var cursor *string
 	if lastCheckpoint != nil {
@@ -45,7 +120,7 @@ func (w *SuiWorker) processAddress(ctx context.Context, address string, lastChec
 
 	// Checkpointを取得
 	checkpoints, err := w.cl.GetCheckpointSummary(ctx, cursor, w.batch)
-	if err != nil { return err }
+	if err != nil { return lastCheckpoint, err }
 
 	var newestCheckpoint int64 = -1
 	for _, cp := range checkpoints.Data {
@@ -71,7 +146,7 @@ func (w *SuiWorker) processAddress(ctx context.Context, address string, lastChec
 		}
 		for _, txDigest := range cp.Transactions {
 			if err := w.processTransaction(ctx, address, txDigest, timestampMs); err != nil {
-				log.Printf("[sui] process tx %s: %v", txDigest, err)
+				logging.FromContext(ctx).Warn("process transaction", "tx", txDigest, "err", err)
 			}
 		}
 
@@ -82,9 +157,12 @@ func (w *SuiWorker) processAddress(ctx context.Context, address string, lastChec
 
 	// カーソル更新
 	if newestCheckpoint >= 0 {
-		return w.st.UpdateSuiCursor(ctx, address, newestCheckpoint)
+		if err := w.st.UpdateSuiCursor(ctx, address, newestCheckpoint); err != nil {
+			return lastCheckpoint, err
+		}
+		return &newestCheckpoint, nil
 	}
-	return nil
+	return lastCheckpoint, nil
 }
 
 func (w *SuiWorker) processTransaction(ctx context.Context, address string, txDigest string, timestampMs uint64) error {
@@ -94,60 +172,15 @@ func (w *SuiWorker) processTransaction(ctx context.Context, address string, txDi
 		return err
 	}
 
-	// トランザクションが成功していない場合はスキップ
-	if tx.Effects.Status.Status != "success" {
-		return nil
-	}
-
-	// タイムスタンプを設定
-	var ts time.Time
-	if timestampMs > 0 {
-		ts = time.Unix(int64(timestampMs/1000), int64((timestampMs%1000)*1000000)).UTC()
-	} else {
-		ts = time.Now().UTC()
-	}
-
-	// 送信者と受信者を抽出（簡易版）
-	var sender, receiver *string
-	if len(tx.Transaction.Data.Message.Inputs) > 0 {
-		// 最初のInputから送信者を推定
-		for _, input := range tx.Transaction.Data.Message.Inputs {
-			if input.Type == "pure" && input.ValueType == "address" {
-				s := input.Value
-				sender = &s
-				break
-			}
-		}
-	}
+	// 正規化（失敗 Tx も status=failed として保存する）
+	ev := normalize.Sui(tx, timestampMs)
+	ev.TxHash = txDigest
+	w.decimals.Apply(ctx, &ev)
 
-	// ガス代を計算
-	var fee *int64
-	var totalGas uint64
-	if tx.Effects.GasUsed.ComputationCost != nil {
-		if val, ok := tx.Effects.GasUsed.ComputationCost.Value(); ok {
-			totalGas += val
-		}
-	}
-	if tx.Effects.GasUsed.StorageCost != nil {
-		if val, ok := tx.Effects.GasUsed.StorageCost.Value(); ok {
-			totalGas += val
-		}
-	}
-	if totalGas > 0 {
-		f := int64(totalGas)
-		fee = &f
-	}
-
-	// メソッド名を抽出（簡易版）
-	var method *string
-	if len(tx.Transaction.Data.Message.Transactions) > 0 {
-		kind := tx.Transaction.Data.Message.Transactions[0].Kind
-		method = &kind
+	// データベースに保存 → アラートルールを評価
+	if err := w.st.InsertTxEventSui(ctx, ev.Input()); err != nil {
+		return err
 	}
-
-	// 生データをJSON化
-	raw, _ := json.Marshal(tx)
-
-	// データベースに保存
-	return w.st.InsertTxEventSui(ctx, txDigest, ts, sender, receiver, fee, method, raw)
+	evaluateAlerts(ctx, "sui", w.alerts, w.notify, ev)
+	return nil
 }
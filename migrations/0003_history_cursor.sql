@@ -0,0 +1,6 @@
+-- 0003_history_cursor.sql
+-- /history のキーセットページング（(ts, tx_hash) の行比較）用インデックス
+-- 何度流しても安全
+
+CREATE INDEX IF NOT EXISTS idx_tx_solana_ts_hash ON tx_events_solana (ts DESC, tx_hash DESC);
+CREATE INDEX IF NOT EXISTS idx_tx_sui_ts_hash    ON tx_events_sui (ts DESC, tx_hash DESC);
@@ -0,0 +1,42 @@
+-- 0004_history_filters.sql
+-- /history の絞り込み（direction / token / method / amount / 期間 / status / counterparty）用
+-- 何度流しても安全
+
+-- ===========================
+-- status 列（success / failed）
+-- ===========================
+ALTER TABLE tx_events_solana ADD COLUMN IF NOT EXISTS status text;
+ALTER TABLE tx_events_sui    ADD COLUMN IF NOT EXISTS status text;
+
+-- 既存行の補完
+-- Solana: raw.meta.err が null なら成功
+UPDATE tx_events_solana
+SET status = CASE
+  WHEN raw->'meta'->'err' IS NULL OR raw->'meta'->'err' = 'null'::jsonb THEN 'success'
+  ELSE 'failed'
+END
+WHERE status IS NULL AND raw ? 'meta';
+
+-- Sui: これまで失敗 Tx は保存していなかったため成功扱い
+UPDATE tx_events_sui
+SET status = COALESCE(raw->'effects'->'status'->>'status', 'success')
+WHERE status IS NULL AND raw ? 'effects';
+
+-- ===========================
+-- インデックス
+-- ===========================
+CREATE INDEX IF NOT EXISTS idx_tx_solana_token_ts  ON tx_events_solana (token, ts DESC);
+CREATE INDEX IF NOT EXISTS idx_tx_solana_method_ts ON tx_events_solana (method, ts DESC);
+CREATE INDEX IF NOT EXISTS idx_tx_solana_amount    ON tx_events_solana (amount);
+CREATE INDEX IF NOT EXISTS idx_tx_solana_failed_ts ON tx_events_solana (ts DESC) WHERE status = 'failed';
+
+CREATE INDEX IF NOT EXISTS idx_tx_sui_token_ts  ON tx_events_sui (token, ts DESC);
+CREATE INDEX IF NOT EXISTS idx_tx_sui_method_ts ON tx_events_sui (method, ts DESC);
+CREATE INDEX IF NOT EXISTS idx_tx_sui_amount    ON tx_events_sui (amount);
+CREATE INDEX IF NOT EXISTS idx_tx_sui_failed_ts ON tx_events_sui (ts DESC) WHERE status = 'failed';
+
+-- Sui は 0x 有無・大小文字を正規化して比較するため式インデックスを用意
+CREATE INDEX IF NOT EXISTS idx_tx_sui_sender_norm_ts
+  ON tx_events_sui ((lower(regexp_replace(COALESCE(sender,''), '^0x', ''))), ts DESC);
+CREATE INDEX IF NOT EXISTS idx_tx_sui_receiver_norm_ts
+  ON tx_events_sui ((lower(regexp_replace(COALESCE(receiver,''), '^0x', ''))), ts DESC);
@@ -0,0 +1,48 @@
+-- 0005_tx_participants.sql
+-- Tx に関与したアドレスの正規化テーブル
+-- /history のアドレス検索を raw::text ILIKE の全件走査から索引検索に置き換える
+-- 何度流しても安全
+
+-- ===========================
+-- tx_participants
+-- ===========================
+-- address は比較用に正規化済み（Sui は 0x 無し・小文字）
+-- role: sender / receiver / account
+CREATE TABLE IF NOT EXISTS tx_participants (
+  chain    text        NOT NULL,
+  tx_hash  text        NOT NULL,
+  ts       timestamptz NOT NULL,
+  address  text        NOT NULL,
+  role     text        NOT NULL,
+  CONSTRAINT pk_tx_participants PRIMARY KEY (chain, tx_hash, ts, address, role)
+);
+
+-- アドレス別の時系列検索用
+CREATE INDEX IF NOT EXISTS idx_tx_participants_addr_ts
+  ON tx_participants (chain, address, ts DESC, tx_hash DESC);
+
+-- ===========================
+-- 既存データの補完
+-- ===========================
+INSERT INTO tx_participants (chain, tx_hash, ts, address, role)
+SELECT 'solana', tx_hash, ts, sender, 'sender' FROM tx_events_solana WHERE sender IS NOT NULL AND sender <> ''
+UNION ALL
+SELECT 'solana', tx_hash, ts, receiver, 'receiver' FROM tx_events_solana WHERE receiver IS NOT NULL AND receiver <> ''
+ON CONFLICT DO NOTHING;
+
+-- Solana: raw に保存済みの accountKeys も取り込む（従来の raw 検索でヒットしていた分）
+INSERT INTO tx_participants (chain, tx_hash, ts, address, role)
+SELECT 'solana', e.tx_hash, e.ts, k.address, 'account'
+FROM tx_events_solana e
+CROSS JOIN LATERAL jsonb_array_elements_text(
+  CASE WHEN jsonb_typeof(e.raw->'transaction'->'message'->'accountKeys') = 'array'
+       THEN e.raw->'transaction'->'message'->'accountKeys'
+       ELSE '[]'::jsonb END
+) AS k(address)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO tx_participants (chain, tx_hash, ts, address, role)
+SELECT 'sui', tx_hash, ts, lower(regexp_replace(sender, '^0x', '')), 'sender' FROM tx_events_sui WHERE sender IS NOT NULL AND sender <> ''
+UNION ALL
+SELECT 'sui', tx_hash, ts, lower(regexp_replace(receiver, '^0x', '')), 'receiver' FROM tx_events_sui WHERE receiver IS NOT NULL AND receiver <> ''
+ON CONFLICT DO NOTHING;
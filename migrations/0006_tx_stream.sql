@@ -0,0 +1,15 @@
+-- 0006_tx_stream.sql
+-- 新着イベントのストリーム配信（/stream）用の挿入順シーケンス
+-- ts は Tx の時刻で挿入順とは一致しない（ワーカーは新しい Tx から保存する）ため、
+-- 再接続時の取りこぼし防止には全チェーン共通の挿入順番号を使う
+-- 何度流しても安全
+
+CREATE SEQUENCE IF NOT EXISTS tx_events_seq;
+
+-- 既存行にも採番される（DEFAULT が volatile のため ADD COLUMN 時に行ごとに評価）
+ALTER TABLE tx_events_solana ADD COLUMN IF NOT EXISTS seq bigint NOT NULL DEFAULT nextval('tx_events_seq');
+ALTER TABLE tx_events_sui    ADD COLUMN IF NOT EXISTS seq bigint NOT NULL DEFAULT nextval('tx_events_seq');
+
+-- 再開位置（seq > cursor）からの読み出し用
+CREATE INDEX IF NOT EXISTS idx_tx_events_solana_seq ON tx_events_solana (seq);
+CREATE INDEX IF NOT EXISTS idx_tx_events_sui_seq    ON tx_events_sui (seq);
@@ -0,0 +1,31 @@
+-- 0007_event_outbox.sql
+-- トランザクショナル・アウトボックス
+-- ワーカーは tx_events_* への INSERT と同じトランザクションで event_outbox に 1 行書き、
+-- publisher が未配信の行を外部のイベントバス（Redis Streams / NATS / Kafka / ファイル）へ中継する
+-- 何度流しても安全
+
+-- ===========================
+-- event_outbox
+-- ===========================
+-- id は購読側に見えるシーケンス番号（メッセージの seq）。少なくとも 1 回配信のため重複排除に使う
+-- published_at が NULL の行が未配信
+CREATE TABLE IF NOT EXISTS event_outbox (
+  id            bigserial   PRIMARY KEY,
+  event_type    text        NOT NULL,
+  chain         text        NOT NULL,
+  tx_hash       text        NOT NULL,
+  ts            timestamptz NOT NULL,
+  payload       jsonb       NOT NULL,
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  published_at  timestamptz,
+  attempts      integer     NOT NULL DEFAULT 0,
+  last_error    text
+);
+
+-- 未配信行の取り出し用（配信済みは対象外）
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending
+  ON event_outbox (id) WHERE published_at IS NULL;
+
+-- 配信済みの古い行の掃除用
+CREATE INDEX IF NOT EXISTS idx_event_outbox_published_at
+  ON event_outbox (published_at) WHERE published_at IS NOT NULL;
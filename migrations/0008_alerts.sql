@@ -0,0 +1,55 @@
+-- 0008_alerts.sql
+-- アラートルール（テナント単位）と、ルールに一致したアラートの記録
+-- ワーカーが正規化直後にルールを評価して alerts に書き込む
+-- 何度流しても安全
+
+-- ===========================
+-- alert_rules
+-- ===========================
+-- rule_type:
+--   incoming_transfer   : address への入金（token / threshold で絞り込み）
+--   outgoing_transfer   : address からの出金（token / threshold で絞り込み）
+--   program_interaction : program の呼び出し（address 指定時はその関与 Tx のみ）
+--   balance_below       : address の token 残高が threshold 未満に下がった
+--   fee_above           : 手数料が threshold を超えた（address 指定時はその送信 Tx のみ）
+-- threshold は最小単位（lamports / MIST / トークンの最小単位）
+CREATE TABLE IF NOT EXISTS alert_rules (
+  id          bigserial   PRIMARY KEY,
+  tenant      text        NOT NULL,
+  name        text        NOT NULL DEFAULT '',
+  rule_type   text        NOT NULL,
+  chain       text        NOT NULL,
+  address     text,
+  token       text,
+  program     text,
+  threshold   bigint,
+  enabled     boolean     NOT NULL DEFAULT true,
+  created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_tenant ON alert_rules (tenant, id);
+-- ワーカーはチェーンごとに有効なルールを読む
+CREATE INDEX IF NOT EXISTS idx_alert_rules_chain_enabled ON alert_rules (chain) WHERE enabled;
+
+-- ===========================
+-- alerts
+-- ===========================
+-- dedupe_key はルール × Tx（× トークン）で一意。再処理しても同じアラートは 1 件のまま
+CREATE TABLE IF NOT EXISTS alerts (
+  id          bigserial   PRIMARY KEY,
+  tenant      text        NOT NULL,
+  rule_id     bigint      REFERENCES alert_rules(id) ON DELETE SET NULL,
+  rule_type   text        NOT NULL,
+  chain       text        NOT NULL,
+  tx_hash     text        NOT NULL,
+  ts          timestamptz NOT NULL,
+  address     text,
+  token       text,
+  amount      bigint,
+  message     text        NOT NULL,
+  dedupe_key  text        NOT NULL,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT uq_alerts_dedupe UNIQUE (tenant, dedupe_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_tenant_id ON alerts (tenant, id DESC);
@@ -0,0 +1,34 @@
+-- 0009_notification_channels.sql
+-- 通知チャネル（Slack / Discord / Telegram / メール）とチャネルごとの失敗状況
+-- ワーカーが記録したアラートをテナントの有効なチャネルへ送る
+-- 何度流しても安全
+
+-- ===========================
+-- notification_channels
+-- ===========================
+-- channel_type: slack / discord / telegram / email
+-- config（種別ごと）:
+--   slack / discord : {"webhook_url": "..."}
+--   telegram        : {"bot_token": "...", "chat_id": "...", "api_url": 任意}
+--   email           : {"smtp_addr": "host:port", "username": 任意, "password": 任意, "from": "...", "to": ["..."]}
+--   共通            : {"template": 任意の text/template}
+CREATE TABLE IF NOT EXISTS notification_channels (
+  id                    bigserial   PRIMARY KEY,
+  tenant                text        NOT NULL,
+  name                  text        NOT NULL DEFAULT '',
+  channel_type          text        NOT NULL,
+  config                jsonb       NOT NULL,
+  enabled               boolean     NOT NULL DEFAULT true,
+  -- 失敗の記録（送信成功で consecutive_failures は 0 に戻る）
+  failure_count         bigint      NOT NULL DEFAULT 0,
+  consecutive_failures  integer     NOT NULL DEFAULT 0,
+  last_error            text,
+  last_failure_at       timestamptz,
+  last_success_at       timestamptz,
+  created_at            timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_channels_tenant ON notification_channels (tenant, id);
+
+-- 通知文で金額を decimals 適用済みで表示するため、アラートに桁数を持たせる
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS decimals integer;
@@ -0,0 +1,19 @@
+-- 0010_worker_heartbeats.sql
+-- ワーカーの tick ごとのハートビート（/readyz が鮮度とカーソル遅れを確認する）
+-- 何度流しても安全
+
+-- ===========================
+-- worker_heartbeats
+-- ===========================
+-- instance: ワーカーのホスト名（同じチェーンを複数台で動かしても 1 台 1 行）
+-- max_cursor_lag: 直近の tick でのチェーン先頭 − カーソルの最大値（先頭を取得できなかったときは NULL）
+CREATE TABLE IF NOT EXISTS worker_heartbeats (
+  chain           text        NOT NULL,
+  instance        text        NOT NULL,
+  tick_at         timestamptz NOT NULL DEFAULT now(),
+  addresses       integer     NOT NULL DEFAULT 0,
+  failed          integer     NOT NULL DEFAULT 0,
+  max_cursor_lag  bigint,
+  last_error      text,
+  PRIMARY KEY (chain, instance)
+);
@@ -0,0 +1,61 @@
+-- 0011_address_labels.sql
+-- 監視アドレスのラベル・タグ・グループ
+-- ラベルは /history・/history/export・/stream・アウトボックスの payload・通知に載せ、
+-- タグ / グループは /history・/balances の絞り込みに使う
+-- 何度流しても安全
+
+-- ===========================
+-- ラベルとタグ（watched_addresses_* に同居）
+-- ===========================
+ALTER TABLE watched_addresses_solana ADD COLUMN IF NOT EXISTS label text;
+ALTER TABLE watched_addresses_solana ADD COLUMN IF NOT EXISTS tags  text[] NOT NULL DEFAULT '{}';
+ALTER TABLE watched_addresses_sui    ADD COLUMN IF NOT EXISTS label text;
+ALTER TABLE watched_addresses_sui    ADD COLUMN IF NOT EXISTS tags  text[] NOT NULL DEFAULT '{}';
+
+-- タグでの絞り込み用（tags @> ARRAY[...]）
+CREATE INDEX IF NOT EXISTS idx_watched_addresses_solana_tags ON watched_addresses_solana USING gin (tags);
+CREATE INDEX IF NOT EXISTS idx_watched_addresses_sui_tags    ON watched_addresses_sui    USING gin (tags);
+
+-- Sui は 0x の有無・大文字小文字を揃えて引く（tx_participants・store.addrExpr と同じ式）
+CREATE INDEX IF NOT EXISTS idx_watched_addresses_sui_norm
+  ON watched_addresses_sui (lower(regexp_replace(COALESCE(address, ''), '^0x', '')));
+
+-- ===========================
+-- グループ
+-- ===========================
+CREATE TABLE IF NOT EXISTS address_groups (
+  id           bigserial   PRIMARY KEY,
+  name         text        NOT NULL UNIQUE,
+  description  text,
+  created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+-- address は watched_addresses_* に登録された表記のまま持つ（監視解除で一緒に消す）
+CREATE TABLE IF NOT EXISTS address_group_members (
+  group_id    bigint      NOT NULL REFERENCES address_groups (id) ON DELETE CASCADE,
+  chain       text        NOT NULL,
+  address     text        NOT NULL,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT pk_address_group_members PRIMARY KEY (group_id, chain, address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_address_group_members_addr
+  ON address_group_members (chain, address);
+
+-- ===========================
+-- ラベルの参照
+-- ===========================
+-- address_label(chain, address) は監視アドレスのラベル（未登録・未設定なら NULL）
+CREATE OR REPLACE FUNCTION address_label(p_chain text, p_address text) RETURNS text
+LANGUAGE sql STABLE AS $$
+  SELECT label FROM watched_addresses_solana
+  WHERE p_chain = 'solana' AND address = p_address
+  UNION ALL
+  SELECT label FROM watched_addresses_sui
+  WHERE p_chain = 'sui'
+    AND lower(regexp_replace(COALESCE(address, ''), '^0x', '')) = lower(regexp_replace(p_address, '^0x', ''))
+  LIMIT 1
+$$;
+
+-- アラートには発生時点のラベルを残す（通知に載せる）
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS address_label text;
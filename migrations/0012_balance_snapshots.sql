@@ -0,0 +1,14 @@
+-- 0012_balance_snapshots.sql
+-- アドレスごとの直近の残高スナップショット
+-- /portfolio・/balances（tag / group 指定）が RPC から取得するたびに上書きし、
+-- max_age 以内なら RPC を呼ばずにこれを返す
+-- 何度流しても安全
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+  chain       text        NOT NULL,
+  address     text        NOT NULL,
+  -- [{"token": "...", "amount": 123}, ...]（最小単位）
+  balances    jsonb       NOT NULL,
+  fetched_at  timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT pk_balance_snapshots PRIMARY KEY (chain, address)
+);
@@ -0,0 +1,25 @@
+-- 0013_token_prices.sql
+-- トークンの USD 価格（token × 時刻）と小数点桁数
+-- 価格はファイルの取り込み（walletctl prices import）か HTTP の価格プロバイダから入る。
+-- 評価時は「その時刻以前で最も新しい価格」を使う
+-- 何度流しても安全
+
+CREATE TABLE IF NOT EXISTS token_prices (
+  -- イベント・残高と同じ表記（"SOL" / "SUI" / SPL の mint / Sui の coin type）
+  token   text        NOT NULL,
+  ts      timestamptz NOT NULL,
+  -- 1 トークン（最小単位ではない）あたりの USD
+  usd     numeric     NOT NULL CHECK (usd >= 0),
+  -- 取得元（"file" / "http" など）
+  source  text        NOT NULL DEFAULT '',
+  CONSTRAINT pk_token_prices PRIMARY KEY (token, ts)
+);
+
+-- 最小単位 → 1 トークンの換算に使う桁数
+CREATE TABLE IF NOT EXISTS token_decimals (
+  token     text PRIMARY KEY,
+  decimals  int  NOT NULL CHECK (decimals BETWEEN 0 AND 30)
+);
+
+INSERT INTO token_decimals (token, decimals) VALUES ('SOL', 9), ('SUI', 9)
+ON CONFLICT (token) DO NOTHING;
@@ -0,0 +1,52 @@
+-- 0014_cost_basis.sql
+-- アドレスごとの取得原価（ロット）と処分の記録
+-- GET /pnl がアドレスのイベントを古い順に再生（FIFO / LIFO / 移動平均）するたびに
+-- (chain, address, method) 単位で丸ごと書き直す
+-- 何度流しても安全
+
+-- 取得ロット（受取 1 件 = 1 ロット）
+CREATE TABLE IF NOT EXISTS cost_lots (
+  chain               text        NOT NULL,
+  address             text        NOT NULL,
+  method              text        NOT NULL CHECK (method IN ('fifo', 'lifo', 'average')),
+  seq                 int         NOT NULL,
+  token               text        NOT NULL,
+  tx_hash             text        NOT NULL,
+  ts                  timestamptz NOT NULL,
+  -- 最小単位
+  amount              bigint      NOT NULL,
+  cost_usd            numeric     NOT NULL,
+  remaining           bigint      NOT NULL,
+  remaining_cost_usd  numeric     NOT NULL,
+  -- 取得時の価格が分からず原価 0 として扱ったか
+  unpriced            boolean     NOT NULL DEFAULT false,
+  computed_at         timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT pk_cost_lots PRIMARY KEY (chain, address, method, seq)
+);
+
+-- 処分（送付・手数料）。FIFO / LIFO では消費したロットごとに 1 行
+CREATE TABLE IF NOT EXISTS cost_disposals (
+  chain         text        NOT NULL,
+  address       text        NOT NULL,
+  method        text        NOT NULL CHECK (method IN ('fifo', 'lifo', 'average')),
+  seq           int         NOT NULL,
+  token         text        NOT NULL,
+  tx_hash       text        NOT NULL,
+  ts            timestamptz NOT NULL,
+  kind          text        NOT NULL CHECK (kind IN ('transfer', 'fee')),
+  amount        bigint      NOT NULL,
+  proceeds_usd  numeric     NOT NULL,
+  cost_usd      numeric     NOT NULL,
+  gain_usd      numeric     NOT NULL,
+  -- 対応する取得ロット（移動平均・取得記録の無い処分では NULL）
+  lot_seq       int,
+  acquired_at   timestamptz,
+  -- short / long（保有 1 年超）。移動平均では空
+  term          text        NOT NULL DEFAULT '',
+  -- 取得記録の無い数量を処分した（原価 0）
+  unmatched     boolean     NOT NULL DEFAULT false,
+  computed_at   timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT pk_cost_disposals PRIMARY KEY (chain, address, method, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cost_disposals_ts ON cost_disposals (chain, address, method, ts);
@@ -0,0 +1,42 @@
+-- 0015_address_daily.sql
+-- アドレス × 日（UTC）× トークンの集計（ダッシュボード・GET /addresses/{chain}/{address}/summary 用）
+-- ワーカーが tick ごとに tx_events_* の seq の続きから読み、触れた (address, day) を丸ごと集計し直す
+-- 何度流しても安全
+
+CREATE TABLE IF NOT EXISTS address_daily (
+  chain           text        NOT NULL,
+  -- 正規化済み（Sui は小文字・0x なし）
+  address         text        NOT NULL,
+  day             date        NOT NULL,
+  -- token が無いイベントはチェーンのネイティブトークン（SOL / SUI）
+  token           text        NOT NULL,
+  -- 最小単位。失敗した Tx・自己送金は 0
+  inflow          numeric     NOT NULL DEFAULT 0,
+  outflow         numeric     NOT NULL DEFAULT 0,
+  tx_count        int         NOT NULL DEFAULT 0,
+  -- 送信者として払った手数料（ネイティブトークンの行に計上）
+  fees_paid       numeric     NOT NULL DEFAULT 0,
+  -- その日の相手アドレスの異なり数
+  counterparties  int         NOT NULL DEFAULT 0,
+  first_ts        timestamptz NOT NULL,
+  last_ts         timestamptz NOT NULL,
+  updated_at      timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT pk_address_daily PRIMARY KEY (chain, address, day, token)
+);
+
+-- 相手アドレス（期間をまたいだ異なり数の集計用）
+CREATE TABLE IF NOT EXISTS address_daily_counterparties (
+  chain         text NOT NULL,
+  address       text NOT NULL,
+  day           date NOT NULL,
+  token         text NOT NULL,
+  counterparty  text NOT NULL,
+  CONSTRAINT pk_address_daily_counterparties PRIMARY KEY (chain, address, day, token, counterparty)
+);
+
+-- チェーンごとの集計済み位置（tx_events_* の seq）
+CREATE TABLE IF NOT EXISTS rollup_state (
+  chain       text        PRIMARY KEY,
+  last_seq    bigint      NOT NULL DEFAULT 0,
+  updated_at  timestamptz NOT NULL DEFAULT now()
+);
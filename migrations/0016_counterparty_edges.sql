@@ -0,0 +1,46 @@
+-- 0016_counterparty_edges.sql
+-- アドレス間の資金の流れ（GET /graph/{chain}/{address} 用）
+-- ワーカーが tick ごとに tx_events_* の seq の続きから読み、Tx の移動（raw を正規化した transfers、
+-- raw が無ければ sender / receiver）を edge_transfers に書き直して、触れた (from, to, token) を集計し直す
+-- アドレスは正規化済み（Sui は小文字・0x なし）
+-- 何度流しても安全
+
+-- Tx 内の移動（集計の元。Tx を取り直したら tx_hash 単位で置き換える）
+CREATE TABLE IF NOT EXISTS edge_transfers (
+  chain      text        NOT NULL,
+  tx_hash    text        NOT NULL,
+  idx        int         NOT NULL,
+  ts         timestamptz NOT NULL,
+  from_addr  text        NOT NULL,
+  to_addr    text        NOT NULL,
+  token      text        NOT NULL,
+  -- 最小単位
+  amount     bigint      NOT NULL,
+  CONSTRAINT pk_edge_transfers PRIMARY KEY (chain, tx_hash, idx)
+);
+
+CREATE INDEX IF NOT EXISTS idx_edge_transfers_pair ON edge_transfers (chain, from_addr, to_addr, token);
+
+-- (from, to, token) ごとの通算
+CREATE TABLE IF NOT EXISTS counterparty_edges (
+  chain         text        NOT NULL,
+  from_addr     text        NOT NULL,
+  to_addr       text        NOT NULL,
+  token         text        NOT NULL,
+  total_amount  numeric     NOT NULL,
+  tx_count      int         NOT NULL,
+  first_ts      timestamptz NOT NULL,
+  last_ts       timestamptz NOT NULL,
+  updated_at    timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT pk_counterparty_edges PRIMARY KEY (chain, from_addr, to_addr, token)
+);
+
+-- 受け取り側からの近傍の探索用
+CREATE INDEX IF NOT EXISTS idx_counterparty_edges_to ON counterparty_edges (chain, to_addr);
+
+-- エッジの集計済み位置（tx_events_* の seq）。日次集計（rollup_state）とは別に進める
+CREATE TABLE IF NOT EXISTS edge_state (
+  chain       text        PRIMARY KEY,
+  last_seq    bigint      NOT NULL DEFAULT 0,
+  updated_at  timestamptz NOT NULL DEFAULT now()
+);
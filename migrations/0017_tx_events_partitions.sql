@@ -0,0 +1,102 @@
+-- 0017_tx_events_partitions.sql
+-- tx_events_solana / tx_events_sui を ts の月ごとの宣言的パーティション（UTC の月初〜翌月初）に切り替える
+-- パーティション名は <テーブル>_pYYYY_MM。当月から先の分はワーカーのメンテナンスが作っておき、
+-- 範囲外の Tx（古い範囲のバックフィルなど）を保存するときは store が ensure_tx_events_partition で作る
+-- raw の保持期限を過ぎた行は raw を gzip して raw_gz に移す（または捨てる）。古い月は NDJSON.gz に書き出して切り離す
+-- 既存の行は同じ名前の分割テーブルに移し替える（このファイルを流す間は書き込みを止めること）
+-- 何度流しても安全（分割済みなら何もしない）
+
+-- raw を gzip した JSON（保持期限を過ぎて raw_mode=compress で移したもの）
+ALTER TABLE tx_events_solana ADD COLUMN IF NOT EXISTS raw_gz bytea;
+ALTER TABLE tx_events_sui    ADD COLUMN IF NOT EXISTS raw_gz bytea;
+
+-- ensure_tx_events_partition(table, ts) は ts を含む月のパーティションを作り、その名前を返す
+CREATE OR REPLACE FUNCTION ensure_tx_events_partition(p_table text, p_ts timestamptz) RETURNS text
+LANGUAGE plpgsql AS $$
+DECLARE
+  m0   timestamptz := date_trunc('month', p_ts AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+  m1   timestamptz := (date_trunc('month', p_ts AT TIME ZONE 'UTC') + interval '1 month') AT TIME ZONE 'UTC';
+  part text := p_table || '_p' || to_char(p_ts AT TIME ZONE 'UTC', 'YYYY_MM');
+BEGIN
+  IF to_regclass(part) IS NULL THEN
+    BEGIN
+      EXECUTE format('CREATE TABLE %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)', part, p_table, m0, m1);
+    EXCEPTION WHEN duplicate_table OR unique_violation THEN
+      -- 別の接続が先に作った
+      NULL;
+    END;
+  END IF;
+  RETURN part;
+END $$;
+
+-- ensure_tx_events_partitions(table, ahead) は当月から ahead か月先までのパーティションを作る
+CREATE OR REPLACE FUNCTION ensure_tx_events_partitions(p_table text, p_ahead int) RETURNS void
+LANGUAGE plpgsql AS $$
+BEGIN
+  FOR i IN 0..p_ahead LOOP
+    PERFORM ensure_tx_events_partition(p_table, now() + make_interval(months => i));
+  END LOOP;
+END $$;
+
+-- partition_tx_events(table, ahead) は通常のテーブルを月パーティションの分割テーブルに移し替える
+-- （列・既定値・主キー・インデックスはそのまま。分割済みなら何もしない）
+CREATE OR REPLACE FUNCTION partition_tx_events(p_table text, p_ahead int) RETURNS void
+LANGUAGE plpgsql AS $$
+DECLARE
+  legacy text := p_table || '_legacy';
+  defs   text[];
+  def    text;
+  lo     timestamptz;
+  hi     timestamptz;
+  m      timestamptz;
+BEGIN
+  IF (SELECT relkind FROM pg_class WHERE oid = to_regclass(p_table)) = 'p' THEN
+    RETURN;
+  END IF;
+
+  -- 主キー・一意制約以外のインデックス定義を控える（移し替え後に同じ名前で作り直す）
+  SELECT COALESCE(array_agg(i.indexdef), '{}') INTO defs
+    FROM pg_indexes i
+   WHERE i.schemaname = current_schema() AND i.tablename = p_table
+     AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conrelid = to_regclass(p_table) AND c.conname = i.indexname);
+
+  EXECUTE format('ALTER TABLE %I RENAME TO %I', p_table, legacy);
+  EXECUTE format('CREATE TABLE %I (LIKE %I INCLUDING DEFAULTS) PARTITION BY RANGE (ts)', p_table, legacy);
+
+  -- 既存の行の範囲と当月から先の分のパーティション
+  EXECUTE format('SELECT min(ts), max(ts) FROM %I', legacy) INTO lo, hi;
+  m := date_trunc('month', COALESCE(lo, now()) AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+  hi := GREATEST(COALESCE(hi, now()), now() + make_interval(months => p_ahead));
+  WHILE m <= hi LOOP
+    PERFORM ensure_tx_events_partition(p_table, m);
+    m := ((m AT TIME ZONE 'UTC') + interval '1 month') AT TIME ZONE 'UTC';
+  END LOOP;
+
+  EXECUTE format('INSERT INTO %I SELECT * FROM %I', p_table, legacy);
+  EXECUTE format('DROP TABLE %I', legacy);
+
+  EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I PRIMARY KEY (tx_hash, ts)', p_table, 'pk_' || p_table);
+  FOREACH def IN ARRAY defs LOOP
+    EXECUTE def;
+  END LOOP;
+END $$;
+
+SELECT partition_tx_events('tx_events_solana', 3);
+SELECT partition_tx_events('tx_events_sui', 3);
+
+-- 書き出して切り離したパーティションの記録
+-- 切り離した月に後から Tx が入ると同じ名前のパーティションが作り直されるため、名前ごとに複数回あり得る
+CREATE TABLE IF NOT EXISTS archived_partitions (
+  id           bigserial   PRIMARY KEY,
+  chain        text        NOT NULL,
+  name         text        NOT NULL,
+  range_from   timestamptz NOT NULL,
+  range_to     timestamptz NOT NULL,
+  rows         bigint      NOT NULL,
+  path         text        NOT NULL,
+  bytes        bigint      NOT NULL,
+  sha256       text        NOT NULL,
+  archived_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_archived_partitions_chain ON archived_partitions (chain, range_from);
@@ -0,0 +1,15 @@
+-- 0018_sui_participants_backfill.sql
+-- 0005 の補完は Sui の sender / receiver しか取り込んでおらず、新しい行（normalize.Sui）と違って
+-- balanceChanges の所有者アドレス（role = account）が無かった。従来の raw 検索でヒットしていた分を補う
+-- 何度流しても安全
+
+INSERT INTO tx_participants (chain, tx_hash, ts, address, role)
+SELECT 'sui', e.tx_hash, e.ts, lower(regexp_replace(bc->'owner'->>'AddressOwner', '^0x', '')), 'account'
+FROM tx_events_sui e
+CROSS JOIN LATERAL jsonb_array_elements(
+  CASE WHEN jsonb_typeof(e.raw->'balanceChanges') = 'array'
+       THEN e.raw->'balanceChanges'
+       ELSE '[]'::jsonb END
+) AS bc
+WHERE COALESCE(bc->'owner'->>'AddressOwner', '') <> ''
+ON CONFLICT DO NOTHING;
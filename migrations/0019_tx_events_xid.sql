@@ -0,0 +1,13 @@
+-- 0019_tx_events_xid.sql
+-- tx_events_* に保存したトランザクションの ID（xid8）を持たせる
+-- seq は INSERT 時に採番されるがコミット順ではないため、日次集計（rollup_state）・エッジ（edge_state）の
+-- 集計済み位置を seq だけで進めると、小さい seq の行が後からコミットされたときに読み飛ばす。
+-- 集計は xid が pg_snapshot_xmin（実行中で最も古いトランザクション）より前の行までで止め、
+-- 実行中のトランザクションがあり得る位置より先へは進めない
+-- 既存の行は NULL（コミット済みとして扱う）。列の既定値だけを付けるので書き換えは起きない
+-- 何度流しても安全
+
+ALTER TABLE tx_events_solana ADD COLUMN IF NOT EXISTS xid xid8;
+ALTER TABLE tx_events_sui    ADD COLUMN IF NOT EXISTS xid xid8;
+ALTER TABLE tx_events_solana ALTER COLUMN xid SET DEFAULT pg_current_xact_id();
+ALTER TABLE tx_events_sui    ALTER COLUMN xid SET DEFAULT pg_current_xact_id();
@@ -0,0 +1,19 @@
+-- 0020_event_outbox_retry.sql
+-- 配信先が受け付けない行（壊れた payload・サイズ超過など）が先頭に残り続けて後続の行を止めないよう、
+-- 行ごとに再試行を遅らせ、何度も受け付けられなかった行は保留（parked）にして取り出しから外す
+-- attempts : その行が配信先に受け付けられなかった回数（配信先が落ちていてバッチ全体が失敗した回は数えない）
+-- next_attempt_at : この時刻までは取り出さない（NULL ならすぐ）
+-- parked_at : 保留にした時刻。walletctl outbox requeue で戻すまで配信しない
+-- 何度流しても安全
+
+ALTER TABLE event_outbox ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz;
+ALTER TABLE event_outbox ADD COLUMN IF NOT EXISTS parked_at       timestamptz;
+
+-- 未配信行の取り出し用（保留中は対象外）
+DROP INDEX IF EXISTS idx_event_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending
+  ON event_outbox (id) WHERE published_at IS NULL AND parked_at IS NULL;
+
+-- 保留中の行の一覧用
+CREATE INDEX IF NOT EXISTS idx_event_outbox_parked
+  ON event_outbox (id) WHERE published_at IS NULL AND parked_at IS NOT NULL;
@@ -0,0 +1,13 @@
+-- 0021_rollup_stale.sql
+-- Tx を取り直して保存し直す（walletctl reprocess）と、送信者・受信者・時刻が変わることがある。
+-- 日次集計は新しい行の (address, day) しか集計し直さないため、古い行の (address, day) をここに記録し、
+-- 次の RollupDaily で一緒に集計し直す（古いアドレスに Tx が残り続けないように）
+-- 何度流しても安全
+
+CREATE TABLE IF NOT EXISTS rollup_stale (
+  chain    text NOT NULL,
+  -- 正規化済み（Sui は小文字・0x なし）
+  address  text NOT NULL,
+  day      date NOT NULL,
+  CONSTRAINT pk_rollup_stale PRIMARY KEY (chain, address, day)
+);
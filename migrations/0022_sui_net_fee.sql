@@ -0,0 +1,16 @@
+-- 0022_sui_net_fee.sql
+-- Sui の fee を、ガス代の合計（computation + storage）からストレージリベートを差し引いた実質の額に揃える
+-- （移動は実質の額を除いて求めており、エクスポートも実質の額を使う。/pnl と日次集計の手数料を合わせる）
+-- raw を圧縮・破棄済みの行（raw が NULL）は元の値のまま
+-- 流した後は walletctl rollup -job daily -rebuild で日次集計の fees_paid を作り直すこと
+-- 何度流しても安全
+
+UPDATE tx_events_sui
+   SET fee = (raw->'effects'->'gasUsed'->>'computationCost')::bigint
+           + (raw->'effects'->'gasUsed'->>'storageCost')::bigint
+           - COALESCE((raw->'effects'->'gasUsed'->>'storageRebate')::bigint, 0)
+ WHERE raw->'effects'->'gasUsed'->>'computationCost' ~ '^[0-9]+$'
+   AND raw->'effects'->'gasUsed'->>'storageCost' ~ '^[0-9]+$'
+   AND COALESCE(raw->'effects'->'gasUsed'->>'storageRebate', '0') ~ '^[0-9]+$'
+   AND (raw->'effects'->'gasUsed'->>'computationCost')::bigint
+     + (raw->'effects'->'gasUsed'->>'storageCost')::bigint > 0;
@@ -0,0 +1,222 @@
+package alertstest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/alerts"
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+const (
+	cold     = "ColdWa11et1111111111111111111111"
+	hot      = "HotWa11et11111111111111111111111"
+	program  = "JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4"
+	usdcMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func solEvent() normalize.Event {
+	return normalize.Event{
+		Chain:    "solana",
+		TxHash:   "sig1",
+		TS:       time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC),
+		Sender:   ptr(cold),
+		Receiver: ptr(hot),
+		Fee:      ptr(int64(2_000_000)),
+		Status:   store.TxStatusSuccess,
+		Transfers: []normalize.Transfer{
+			// PairTransfers で分割された入金は合算して判定する
+			{From: cold, To: hot, Token: "SOL", Amount: 60_000_000_000, Decimals: 9},
+			{From: cold, To: hot, Token: "SOL", Amount: 50_000_000_000, Decimals: 9},
+			{From: cold, To: hot, Token: usdcMint, Amount: 5_000_000, Decimals: 6},
+		},
+		Programs: []string{"11111111111111111111111111111111", program},
+	}
+}
+
+// TestEvaluate は各ルール種別の一致 / 不一致を確認します
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+	ev := solEvent()
+	balances := func(ctx context.Context, address string) (map[string]int64, error) {
+		return map[string]int64{"SOL": 1_000_000_000, usdcMint: 10_000_000}, nil
+	}
+
+	tests := []struct {
+		name    string
+		rule    store.AlertRule
+		want    int
+		message string
+	}{
+		{"incoming SOL > 100", store.AlertRule{Type: store.RuleIncomingTransfer, Address: ptr(hot), Token: ptr("SOL"), Threshold: ptr(int64(100_000_000_000))},
+			1, "incoming 110 SOL to " + hot},
+		{"incoming SOL > 200", store.AlertRule{Type: store.RuleIncomingTransfer, Address: ptr(hot), Token: ptr("SOL"), Threshold: ptr(int64(200_000_000_000))}, 0, ""},
+		{"any outgoing from cold wallet", store.AlertRule{Type: store.RuleOutgoingTransfer, Address: ptr(cold)}, 2, ""},
+		{"no outgoing from hot wallet", store.AlertRule{Type: store.RuleOutgoingTransfer, Address: ptr(hot)}, 0, ""},
+		{"program interaction", store.AlertRule{Type: store.RuleProgramInteraction, Program: ptr(program)}, 1, "interaction with program " + program},
+		{"program with unrelated address", store.AlertRule{Type: store.RuleProgramInteraction, Program: ptr(program), Address: ptr("Other111111111111111111111111111")}, 0, ""},
+		{"USDC below 20", store.AlertRule{Type: store.RuleBalanceBelow, Address: ptr(cold), Token: ptr(usdcMint), Threshold: ptr(int64(20_000_000))},
+			1, usdcMint + " balance of " + cold + " is 10, below 20"},
+		{"USDC not below 5", store.AlertRule{Type: store.RuleBalanceBelow, Address: ptr(cold), Token: ptr(usdcMint), Threshold: ptr(int64(5_000_000))}, 0, ""},
+		{"USDC balance of receiver is checked", store.AlertRule{Type: store.RuleBalanceBelow, Address: ptr(hot), Token: ptr(usdcMint), Threshold: ptr(int64(20_000_000))}, 1, ""},
+		{"USDC balance of unrelated address is not checked", store.AlertRule{Type: store.RuleBalanceBelow, Address: ptr("Other111111111111111111111111111"), Token: ptr(usdcMint), Threshold: ptr(int64(20_000_000))}, 0, ""},
+		{"fee above 0.001", store.AlertRule{Type: store.RuleFeeAbove, Threshold: ptr(int64(1_000_000))}, 1, "fee 0.002 SOL above 0.001"},
+		{"fee above for other sender", store.AlertRule{Type: store.RuleFeeAbove, Threshold: ptr(int64(1_000_000)), Address: ptr(hot)}, 0, ""},
+		{"other chain", store.AlertRule{Type: store.RuleFeeAbove, Chain: "sui", Threshold: ptr(int64(0))}, 0, ""},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.rule
+			r.ID, r.Tenant = int64(i+1), "acme"
+			if r.Chain == "" {
+				r.Chain = "solana"
+			}
+			got, err := alerts.Evaluate(ctx, r, ev, balances)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("got %d alerts, want %d: %+v", len(got), tt.want, got)
+			}
+			if tt.message != "" && got[0].Message != tt.message {
+				t.Fatalf("message = %q, want %q", got[0].Message, tt.message)
+			}
+			for _, a := range got {
+				if a.Tenant != "acme" || a.TxHash != "sig1" || a.DedupeKey == "" {
+					t.Fatalf("unexpected alert: %+v", a)
+				}
+			}
+		})
+	}
+}
+
+// fakeStore は alerts テーブルの一意制約（tenant, dedupe_key）を再現する
+type fakeStore struct {
+	rules  []store.AlertRule
+	seen   map[string]bool
+	loads  int
+	clears int
+}
+
+func (f *fakeStore) ListEnabledAlertRules(ctx context.Context, chain string) ([]store.AlertRule, error) {
+	f.loads++
+	return f.rules, nil
+}
+
+func (f *fakeStore) InsertAlert(ctx context.Context, a *store.Alert) (bool, error) {
+	k := a.Tenant + "|" + a.DedupeKey
+	if f.seen[k] {
+		return false, nil
+	}
+	f.seen[k] = true
+	a.ID = int64(len(f.seen))
+	return true, nil
+}
+
+func (f *fakeStore) ClearAlertDedupe(ctx context.Context, tenant, dedupeKey string) error {
+	f.clears++
+	delete(f.seen, tenant+"|"+dedupeKey)
+	return nil
+}
+
+// TestEngine_Dedupe は同じ Tx を再処理してもアラートが重複しないことを確認します
+func TestEngine_Dedupe(t *testing.T) {
+	st := &fakeStore{seen: map[string]bool{}, rules: []store.AlertRule{
+		{ID: 1, Tenant: "acme", Type: store.RuleOutgoingTransfer, Chain: "solana", Address: ptr(cold)},
+		{ID: 2, Tenant: "other", Type: store.RuleFeeAbove, Chain: "solana", Threshold: ptr(int64(0))},
+	}}
+	e := alerts.NewEngine(st, "solana", nil)
+	ctx := context.Background()
+
+	first, err := e.Process(ctx, solEvent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 3 {
+		t.Fatalf("first pass: %d alerts, want 3", len(first))
+	}
+	again, err := e.Process(ctx, solEvent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("reprocessing produced %d alerts", len(again))
+	}
+	if st.loads != 1 {
+		t.Fatalf("rules loaded %d times, want cached", st.loads)
+	}
+}
+
+// TestEngine_BalanceBelowOnce は balance_below が下回っている間は Tx が変わっても 1 件だけ記録され、
+// 閾値以上に戻ったのを確認した後に再び下回ると改めて記録されることを確認します
+func TestEngine_BalanceBelowOnce(t *testing.T) {
+	st := &fakeStore{seen: map[string]bool{}, rules: []store.AlertRule{
+		{ID: 7, Tenant: "acme", Type: store.RuleBalanceBelow, Chain: "solana", Address: ptr(cold), Token: ptr("SOL"), Threshold: ptr(int64(5_000_000_000))},
+	}}
+	balance := int64(1_000_000_000)
+	e := alerts.NewEngine(st, "solana", func(ctx context.Context, address string) (map[string]int64, error) {
+		return map[string]int64{"SOL": balance}, nil
+	})
+	ctx := context.Background()
+	tx := func(hash string) normalize.Event {
+		ev := solEvent()
+		ev.TxHash = hash
+		return ev
+	}
+
+	steps := []struct {
+		hash    string
+		balance int64
+		want    int
+	}{
+		{"sig1", 1_000_000_000, 1}, // 下回った
+		{"sig2", 900_000_000, 0},   // 下回ったまま（別の Tx でも記録しない）
+		{"sig3", 6_000_000_000, 0}, // 戻った
+		{"sig4", 2_000_000_000, 1}, // 再び下回った
+	}
+	for _, s := range steps {
+		balance = s.balance
+		got, err := e.Process(ctx, tx(s.hash))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != s.want {
+			t.Fatalf("%s: %d alerts, want %d", s.hash, len(got), s.want)
+		}
+		for _, a := range got {
+			if a.DedupeKey != alerts.BalanceDedupeKey(st.rules[0]) || a.TxHash != s.hash {
+				t.Fatalf("%s: unexpected alert %+v", s.hash, a)
+			}
+		}
+	}
+	if st.clears != 1 {
+		t.Fatalf("clears = %d, want 1", st.clears)
+	}
+}
+
+// TestValidateRule は rule_type ごとの必須項目を確認します
+func TestValidateRule(t *testing.T) {
+	bad := []store.AlertRule{
+		{Type: "unknown", Chain: "solana"},
+		{Type: store.RuleIncomingTransfer, Chain: "solana"},
+		{Type: store.RuleProgramInteraction, Chain: "sui"},
+		{Type: store.RuleBalanceBelow, Chain: "solana", Address: ptr(cold), Token: ptr("SOL")},
+		{Type: store.RuleFeeAbove, Chain: "solana"},
+		{Type: store.RuleFeeAbove, Chain: "ethereum", Threshold: ptr(int64(1))},
+		{Type: store.RuleFeeAbove, Chain: "solana", Threshold: ptr(int64(-1))},
+	}
+	for i, r := range bad {
+		if err := alerts.ValidateRule(&r); err == nil {
+			t.Errorf("case %d: expected error for %+v", i, r)
+		}
+	}
+	ok := store.AlertRule{Type: store.RuleBalanceBelow, Chain: "solana", Address: ptr(cold), Token: ptr("SOL"), Threshold: ptr(int64(1))}
+	if err := alerts.ValidateRule(&ok); err != nil {
+		t.Fatal(fmt.Errorf("valid rule rejected: %w", err))
+	}
+}
@@ -0,0 +1,49 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+// TestAlertRules_Validation は不正なルール・テナント・ID が DB に触れる前に 400 になることを確認します
+func TestAlertRules_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+	const sol = "11111111111111111111111111111112"
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		tenant string
+	}{
+		{"invalid json", http.MethodPost, "/alerts/rules", "{", ""},
+		{"unknown type", http.MethodPost, "/alerts/rules", `{"type":"whale","chain":"solana"}`, ""},
+		{"missing address", http.MethodPost, "/alerts/rules", `{"type":"incoming_transfer","chain":"solana"}`, ""},
+		{"address of other chain", http.MethodPost, "/alerts/rules", `{"type":"incoming_transfer","chain":"sui","address":"` + sol + `"}`, ""},
+		{"balance_below without threshold", http.MethodPost, "/alerts/rules", `{"type":"balance_below","chain":"solana","address":"` + sol + `","token":"SOL"}`, ""},
+		{"negative threshold", http.MethodPost, "/alerts/rules", `{"type":"fee_above","chain":"solana","threshold":-5}`, ""},
+		{"invalid program", http.MethodPost, "/alerts/rules", `{"type":"program_interaction","chain":"solana","program":"not-base58!"}`, ""},
+		{"invalid tenant", http.MethodGet, "/alerts/rules", "", "bad tenant!"},
+		{"invalid rule id", http.MethodDelete, "/alerts/rules/abc", "", ""},
+		{"invalid before", http.MethodGet, "/alerts?before=x", "", ""},
+		{"invalid alerts chain", http.MethodGet, "/alerts?chain=eth", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant-ID", tt.tenant)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (body: %s)", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+//go:build integration
+
+package apitest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// dbStore は DATABASE_URL の DB に接続する（未設定ならスキップ）
+func dbStore(t *testing.T) *store.Store {
+	t.Helper()
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("set DATABASE_URL to run this test")
+	}
+	st, err := store.New(context.Background())
+	if err != nil {
+		t.Fatalf("db connect: %v", err)
+	}
+	t.Cleanup(st.Close)
+	return st
+}
+
+// testSuiAddrs は実行ごとに異なる Sui アドレスを n 個返す（既存のデータと重ならない）
+func testSuiAddrs(n int) []string {
+	base := time.Now().UnixNano()
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("0x%048x%016x", base, i)
+	}
+	return out
+}
+
+// suiTransfer は from → to の成功した移動 1 件（raw なし）
+func suiTransfer(hash string, ts time.Time, from, to, token string, amount, fee int64) store.TxEventInput {
+	status := store.TxStatusSuccess
+	ev := store.TxEventInput{TxHash: hash, TS: ts, Sender: &from, Receiver: &to, Token: &token, Amount: &amount, Status: &status}
+	if fee > 0 {
+		ev.Fee = &fee
+	}
+	return ev
+}
+
+// seedSui はイベントをワーカーと同じ経路（InsertTxEventSui）で保存する。
+// テスト終了時に、そのイベントと addrs について集計・原価のテーブルに書かれた行を消す。
+func seedSui(t *testing.T, st *store.Store, addrs []string, evs ...store.TxEventInput) {
+	t.Helper()
+	ctx := context.Background()
+	hashes := make([]string, len(evs))
+	for i, ev := range evs {
+		hashes[i] = ev.TxHash
+	}
+	// 派生テーブルのアドレスは 0x 無し
+	norm := make([]string, len(addrs))
+	for i, a := range addrs {
+		norm[i] = strings.TrimPrefix(store.CanonicalAddress("sui", a), "0x")
+	}
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM tx_events_sui WHERE tx_hash = ANY($1)`,
+			`DELETE FROM tx_participants WHERE chain = 'sui' AND tx_hash = ANY($1)`,
+			`DELETE FROM event_outbox WHERE chain = 'sui' AND tx_hash = ANY($1)`,
+			`DELETE FROM edge_transfers WHERE chain = 'sui' AND tx_hash = ANY($1)`,
+		} {
+			if _, err := st.Pool.Exec(ctx, q, hashes); err != nil {
+				t.Logf("cleanup: %v", err)
+			}
+		}
+		for _, q := range []string{
+			`DELETE FROM address_daily WHERE chain = 'sui' AND address = ANY($1)`,
+			`DELETE FROM address_daily_counterparties WHERE chain = 'sui' AND address = ANY($1)`,
+			`DELETE FROM rollup_stale WHERE chain = 'sui' AND address = ANY($1)`,
+			`DELETE FROM counterparty_edges WHERE chain = 'sui' AND (from_addr = ANY($1) OR to_addr = ANY($1))`,
+			`DELETE FROM cost_lots WHERE chain = 'sui' AND address = ANY($1)`,
+			`DELETE FROM cost_disposals WHERE chain = 'sui' AND address = ANY($1)`,
+		} {
+			if _, err := st.Pool.Exec(ctx, q, norm); err != nil {
+				t.Logf("cleanup: %v", err)
+			}
+		}
+	})
+	for _, ev := range evs {
+		if err := st.InsertTxEventSui(ctx, ev); err != nil {
+			t.Fatalf("insert %s: %v", ev.TxHash, err)
+		}
+	}
+}
+
+// catchUp は集計（RollupDaily / RefreshEdges）を追いつくまで進める
+func catchUp(t *testing.T, step func(ctx context.Context) (store.RollupResult, error)) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < 1000; i++ {
+		res, err := step(ctx)
+		if err != nil {
+			t.Fatalf("rollup: %v", err)
+		}
+		if res.Events == 0 {
+			return
+		}
+	}
+	t.Fatal("rollup did not catch up")
+}
@@ -0,0 +1,128 @@
+//go:build integration
+
+package apitest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	api "github.com/you/wallet-watcher/internal/api"
+	"github.com/you/wallet-watcher/internal/graph"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// TestGraph_DB は保存したイベントからエッジを集計し、/graph が depth 1 では直接の相手だけ、
+// depth 2 では 2 ホップ先までのノードとエッジを JSON / GraphML / DOT で返すことを確認します。
+// A→B（2 回）、B→C、C→D の流れで、D は 3 ホップ先なので含まれない。
+func TestGraph_DB(t *testing.T) {
+	st := dbStore(t)
+	addrs := testSuiAddrs(4)
+	a, b, c, d := addrs[0], addrs[1], addrs[2], addrs[3]
+	ts := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	id := fmt.Sprintf("apitest-graph-%d", time.Now().UnixNano())
+	seedSui(t, st, addrs,
+		suiTransfer(id+"-1", ts, a, b, "SUI", 100, 0),
+		suiTransfer(id+"-2", ts.Add(time.Hour), a, b, "SUI", 50, 0),
+		suiTransfer(id+"-3", ts.Add(2*time.Hour), b, c, "SUI", 30, 0),
+		suiTransfer(id+"-4", ts.Add(3*time.Hour), c, d, "SUI", 5, 0),
+	)
+	catchUp(t, func(ctx context.Context) (store.RollupResult, error) {
+		return st.RefreshEdges(ctx, "sui", 500, graph.Transfers)
+	})
+
+	srv := httptest.NewServer(api.Routes(&api.Server{Store: st}))
+	defer srv.Close()
+	get := func(query string) (string, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/graph/sui/" + a + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status = %d body = %s", query, resp.StatusCode, body)
+		}
+		return resp.Header.Get("Content-Type"), string(body)
+	}
+	decode := func(query string) graph.Graph {
+		t.Helper()
+		_, body := get(query)
+		var g graph.Graph
+		if err := json.Unmarshal([]byte(body), &g); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return g
+	}
+	depths := func(g graph.Graph) map[string]int {
+		out := map[string]int{}
+		for _, n := range g.Nodes {
+			out[n.Address] = n.Depth
+		}
+		return out
+	}
+
+	g1 := decode("?depth=1")
+	if g1.Root != a || len(g1.Nodes) != 2 || len(g1.Edges) != 1 {
+		t.Fatalf("depth 1: %+v", g1)
+	}
+	if got := depths(g1); got[a] != 0 || got[b] != 1 {
+		t.Fatalf("depth 1 nodes = %v", got)
+	}
+	if e := g1.Edges[0]; e.From != a || e.To != b || e.Token != "SUI" || e.TotalAmount != 150 || e.TxCount != 2 {
+		t.Fatalf("depth 1 edge = %+v", e)
+	}
+
+	g2 := decode("?depth=2")
+	got := depths(g2)
+	if len(g2.Nodes) != 3 || got[a] != 0 || got[b] != 1 || got[c] != 2 {
+		t.Fatalf("depth 2 nodes = %v", got)
+	}
+	if _, ok := got[d]; ok {
+		t.Fatal("node 3 hops away included at depth 2")
+	}
+	if len(g2.Edges) != 2 || g2.Edges[1].From != b || g2.Edges[1].To != c || g2.Edges[1].TotalAmount != 30 {
+		t.Fatalf("depth 2 edges = %+v", g2.Edges)
+	}
+
+	ct, gml := get("?depth=2&format=graphml")
+	if ct != graph.ContentType(graph.FormatGraphML) {
+		t.Errorf("graphml Content-Type = %q", ct)
+	}
+	for _, want := range []string{
+		`<node id="` + c + `">`,
+		`source="` + a + `" target="` + b + `"`,
+		`<data key="e_total_amount">150</data>`,
+		`source="` + b + `" target="` + c + `"`,
+	} {
+		if !strings.Contains(gml, want) {
+			t.Errorf("graphml missing %q", want)
+		}
+	}
+	if strings.Contains(gml, d) {
+		t.Error("graphml includes the node 3 hops away")
+	}
+
+	ct, dot := get("?depth=2&format=dot")
+	if ct != graph.ContentType(graph.FormatDOT) {
+		t.Errorf("dot Content-Type = %q", ct)
+	}
+	for _, want := range []string{
+		`"` + a + `" -> "` + b + `" [label="SUI ×2"`,
+		`"` + b + `" -> "` + c + `" [label="SUI ×1"`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot missing %q\n%s", want, dot)
+		}
+	}
+	if strings.Contains(dot, d) {
+		t.Error("dot includes the node 3 hops away")
+	}
+}
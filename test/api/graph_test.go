@@ -0,0 +1,40 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+// TestGraph_Validation は depth / limit / format / token の範囲外やパスの誤りを、Store の無いサーバーでも
+// 400 と理由付きで弾くことを確認します（応答の中身は graph_db_test.go で確認）
+func TestGraph_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+	const sol = "11111111111111111111111111111112"
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"unknown chain", "/graph/eth/" + sol, "chain must be"},
+		{"invalid address", "/graph/sui/zz", "invalid sui address"},
+		{"depth zero", "/graph/solana/" + sol + "?depth=0", "invalid 'depth'"},
+		{"depth too deep", "/graph/solana/" + sol + "?depth=4", "invalid 'depth'"},
+		{"limit too large", "/graph/solana/" + sol + "?limit=101", "invalid 'limit'"},
+		{"unknown format", "/graph/solana/" + sol + "?format=gexf", "format must be"},
+		{"token too long", "/graph/solana/" + sol + "?token=" + strings.Repeat("x", 257), "'token' is too long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), tt.want) {
+				t.Fatalf("status = %d body = %q, want 400 mentioning %q", rr.Code, rr.Body.String(), tt.want)
+			}
+		})
+	}
+}
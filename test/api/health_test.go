@@ -0,0 +1,62 @@
+package apitest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	api "github.com/you/wallet-watcher/internal/api"
+	"github.com/you/wallet-watcher/internal/health"
+)
+
+// TestHealthz は liveness が依存先に関係なく 200 を返すことを確認します
+func TestHealthz(t *testing.T) {
+	h := api.Routes(&api.Server{})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+}
+
+// TestReadyz はチェックの結果が JSON で返り、失敗があれば 503 になることを確認します
+func TestReadyz(t *testing.T) {
+	dbErr := error(nil)
+	c := health.NewChecker(time.Second)
+	c.Add("db", func(ctx context.Context) (any, error) { return nil, dbErr })
+	h := api.Routes(&api.Server{Ready: c})
+
+	get := func() (int, health.Report) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var rep health.Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &rep); err != nil {
+			t.Fatalf("decode: %v (%s)", err, rec.Body.String())
+		}
+		return rec.Code, rep
+	}
+
+	if code, rep := get(); code != http.StatusOK || rep.Status != health.StatusOK {
+		t.Fatalf("healthy: code = %d, status = %s", code, rep.Status)
+	}
+
+	dbErr = errors.New("connection refused")
+	code, rep := get()
+	if code != http.StatusServiceUnavailable || rep.Status != health.StatusFail {
+		t.Fatalf("unhealthy: code = %d, status = %s", code, rep.Status)
+	}
+	if len(rep.Checks) != 1 || rep.Checks[0].Error != "connection refused" {
+		t.Fatalf("checks = %+v", rep.Checks)
+	}
+
+	// チェック未設定なら 503
+	rec := httptest.NewRecorder()
+	api.Routes(&api.Server{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unconfigured: code = %d", rec.Code)
+	}
+}
@@ -0,0 +1,54 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	api "github.com/you/wallet-watcher/internal/api"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// TestHistoryCursor_RoundTrip は /history のカーソルが (ts, tx_hash) を
+// 欠落なく往復できることを確認します（同一秒内の Tx を区別できること）。
+func TestHistoryCursor_RoundTrip(t *testing.T) {
+	ts := time.Date(2025, 8, 28, 12, 34, 56, 123456000, time.UTC)
+	c := store.HistoryCursor{TS: ts, TxHash: "5xYz|withpipe"}
+
+	got, err := store.DecodeHistoryCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !got.TS.Equal(ts) || got.TxHash != c.TxHash {
+		t.Fatalf("round trip mismatch: got %+v want %+v", got, c)
+	}
+
+	other := store.HistoryCursor{TS: ts, TxHash: "5xYa"}
+	if other.Encode() == c.Encode() {
+		t.Fatalf("cursors with same ts must differ by tx_hash")
+	}
+
+	for _, bad := range []string{"!!!", "bm9waXBl", "fGFiYw"} {
+		if _, err := store.DecodeHistoryCursor(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+// TestHistory_InvalidCursor は不正な before/after が DB に触れる前に 400 になることを確認します。
+func TestHistory_InvalidCursor(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+
+	for _, url := range []string{
+		"/history?chain=solana&before=not-a-cursor",
+		"/history?chain=sui&after=not-a-cursor",
+	} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, rr.Code)
+		}
+	}
+}
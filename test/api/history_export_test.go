@@ -0,0 +1,29 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+// TestHistoryExport_Validation は /history/export の必須パラメータと形式の検証を確認します。
+func TestHistoryExport_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+	const sol = "11111111111111111111111111111112"
+
+	for _, url := range []string{
+		"/history/export?chain=solana",
+		"/history/export?chain=solana&address=bad%22name",
+		"/history/export?chain=solana&address=" + sol + "&format=xlsx",
+		"/history/export?chain=solana&address=" + sol + "&from=bad",
+	} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+// TestHistory_FilterValidation は /history の絞り込みパラメータの検証を確認します。
+// 不正値はすべて DB に問い合わせる前に 400 Bad Request になる必要があります。
+func TestHistory_FilterValidation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+	const sol = "11111111111111111111111111111112"
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"invalid address", "/history?chain=solana&address=xyz"},
+		{"address of other chain", "/history?chain=sui&address=" + sol},
+		{"invalid address across chains", "/history?chain=all&address=xyz"},
+		{"unknown direction", "/history?chain=solana&address=" + sol + "&direction=sideways"},
+		{"direction without address", "/history?chain=solana&direction=in"},
+		{"invalid counterparty", "/history?chain=solana&counterparty=xyz"},
+		{"counterparty of other chain", "/history?chain=sui&counterparty=" + sol},
+		{"unknown status", "/history?chain=solana&status=pending"},
+		{"negative min_amount", "/history?chain=solana&min_amount=-1"},
+		{"non-numeric max_amount", "/history?chain=solana&max_amount=1e9"},
+		{"min greater than max", "/history?chain=solana&min_amount=10&max_amount=5"},
+		{"invalid from", "/history?chain=solana&from=yesterday"},
+		{"unknown chain", "/history?chain=ethereum"},
+		{"invalid counterparty across chains", "/history?chain=all&counterparty=xyz"},
+		{"from after to", "/history?chain=solana&from=2025-09-02T00:00:00Z&to=2025-09-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d. Body: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
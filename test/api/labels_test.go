@@ -0,0 +1,63 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+// TestLabels_Validation は不正なラベル・タグ・グループ名が DB に触れる前に 400 になることを確認します
+func TestLabels_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+	const sol = "11111111111111111111111111111112"
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+	}{
+		{"list invalid chain", http.MethodGet, "/addresses?chain=eth", ""},
+		{"list invalid tag", http.MethodGet, "/addresses?tag=" + "bad%20tag", ""},
+		{"list invalid group", http.MethodGet, "/addresses?group=" + "a/b", ""},
+		{"get invalid address", http.MethodGet, "/addresses/solana/not-base58!", ""},
+		{"set invalid chain", http.MethodPut, "/addresses/eth/" + sol, `{"label":"x"}`},
+		{"set invalid json", http.MethodPut, "/addresses/solana/" + sol, "{"},
+		{"set label too long", http.MethodPut, "/addresses/solana/" + sol, `{"label":"` + strings.Repeat("a", 129) + `"}`},
+		{"set label control char", http.MethodPut, "/addresses/solana/" + sol, `{"label":"a\nb"}`},
+		{"set invalid tag", http.MethodPut, "/addresses/solana/" + sol, `{"tags":["ok","has space"]}`},
+		{"set too many tags", http.MethodPut, "/addresses/solana/" + sol, `{"tags":[` + manyTags(33) + `]}`},
+		{"create group invalid json", http.MethodPost, "/groups", "{"},
+		{"create group invalid name", http.MethodPost, "/groups", `{"name":"has space"}`},
+		{"create group empty name", http.MethodPost, "/groups", `{"name":""}`},
+		{"get group invalid name", http.MethodGet, "/groups/-x", ""},
+		{"member invalid address", http.MethodPut, "/groups/treasury/members/sui/zz", ""},
+		{"history invalid tag", http.MethodGet, "/history?tag=Bad!", ""},
+		{"history invalid group", http.MethodGet, "/history?group=a%20b", ""},
+		{"balances invalid tag", http.MethodGet, "/balances?tag=Bad!", ""},
+		{"balances invalid chain", http.MethodGet, "/balances?group=treasury&chain=eth", ""},
+		{"portfolio invalid group", http.MethodGet, "/portfolio?group=a%20b", ""},
+		{"portfolio invalid max_age", http.MethodGet, "/portfolio?group=treasury&max_age=-1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (body: %s)", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func manyTags(n int) string {
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = `"t` + strings.Repeat("x", i) + `"`
+	}
+	return strings.Join(tags, ",")
+}
@@ -0,0 +1,171 @@
+//go:build integration
+
+package apitest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	api "github.com/you/wallet-watcher/internal/api"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// TestNotifyChannels_DB はチャネルの作成・一覧・テスト送信・削除を実際に DB に保存して確かめる。
+// 応答では webhook URL のパスを伏せること、テスト送信が webhook に届いて成否がチャネルに記録されること、
+// 別テナントからは見えず消せないことを確認します。
+func TestNotifyChannels_DB(t *testing.T) {
+	st := dbStore(t)
+	tenant := fmt.Sprintf("apitest-notify-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		if _, err := st.Pool.Exec(context.Background(), `DELETE FROM notification_channels WHERE tenant = $1`, tenant); err != nil {
+			t.Logf("cleanup: %v", err)
+		}
+	})
+
+	var mu sync.Mutex
+	var received []string
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		received = append(received, r.URL.Path+" "+body.Text)
+		mu.Unlock()
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	srv := httptest.NewServer(api.Routes(&api.Server{Store: st}))
+	defer srv.Close()
+	do := func(method, path, body, tenantID string) (int, []byte) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Tenant-ID", tenantID)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, b
+	}
+	create := func(body string) store.NotificationChannel {
+		t.Helper()
+		code, b := do(http.MethodPost, "/notify/channels", body, tenant)
+		if code != http.StatusCreated {
+			t.Fatalf("create: status = %d body = %s", code, b)
+		}
+		var ch store.NotificationChannel
+		if err := json.Unmarshal(b, &ch); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), "SECRET") {
+			t.Errorf("create response leaks the webhook path: %s", b)
+		}
+		return ch
+	}
+	list := func(tenantID string) []store.NotificationChannel {
+		t.Helper()
+		code, b := do(http.MethodGet, "/notify/channels", "", tenantID)
+		if code != http.StatusOK {
+			t.Fatalf("list: status = %d body = %s", code, b)
+		}
+		if strings.Contains(string(b), "SECRET") {
+			t.Errorf("list response leaks a secret: %s", b)
+		}
+		var res struct {
+			Channels []store.NotificationChannel `json:"channels"`
+		}
+		if err := json.Unmarshal(b, &res); err != nil {
+			t.Fatal(err)
+		}
+		return res.Channels
+	}
+	testSend := func(id int64) (int, map[string]any) {
+		t.Helper()
+		code, b := do(http.MethodPost, "/notify/channels/"+strconv.FormatInt(id, 10)+"/test", "", tenant)
+		var res map[string]any
+		_ = json.Unmarshal(b, &res)
+		return code, res
+	}
+
+	slack := create(`{"name":"ops","type":"slack","config":{"webhook_url":"` + ok.URL + `/services/T0/SECRET"}}`)
+	if slack.ID <= 0 || slack.Tenant != tenant || slack.Type != "slack" || !slack.Enabled ||
+		!strings.Contains(string(slack.Config), ok.URL+"/***") {
+		t.Fatalf("created = %+v config = %s", slack, slack.Config)
+	}
+	discord := create(`{"name":"broken","type":"discord","config":{"webhook_url":"` + failing.URL + `/api/webhooks/1/SECRET"}}`)
+
+	if chs := list(tenant); len(chs) != 2 {
+		t.Fatalf("channels = %+v", chs)
+	}
+	if chs := list(tenant + "-other"); len(chs) != 0 {
+		t.Fatalf("other tenant sees %+v", chs)
+	}
+
+	// 成功するテスト送信は webhook に届き、last_success_at が入る
+	code, res := testSend(slack.ID)
+	if code != http.StatusOK || res["ok"] != true {
+		t.Fatalf("test send: status = %d body = %v", code, res)
+	}
+	mu.Lock()
+	got := received
+	mu.Unlock()
+	if len(got) != 1 || !strings.HasPrefix(got[0], "/services/T0/SECRET ") || !strings.Contains(got[0], "test notification from wallet-watcher") {
+		t.Fatalf("webhook received %q", got)
+	}
+
+	// 失敗するテスト送信は 502 で、失敗がチャネルに記録される（エラーに URL のパスを載せない）
+	code, res = testSend(discord.ID)
+	if code != http.StatusBadGateway || res["ok"] != false || !strings.Contains(fmt.Sprint(res["error"]), "status 500") {
+		t.Fatalf("failing test send: status = %d body = %v", code, res)
+	}
+	if strings.Contains(fmt.Sprint(res["error"]), "SECRET") {
+		t.Errorf("error leaks the webhook path: %v", res["error"])
+	}
+	byID := map[int64]store.NotificationChannel{}
+	for _, ch := range list(tenant) {
+		byID[ch.ID] = ch
+	}
+	if ch := byID[slack.ID]; ch.LastSuccessAt == nil || ch.FailureCount != 0 || ch.ConsecutiveFailures != 0 {
+		t.Errorf("slack after test = %+v", ch)
+	}
+	if ch := byID[discord.ID]; ch.FailureCount != 1 || ch.ConsecutiveFailures != 1 || ch.LastError == nil ||
+		!strings.Contains(*ch.LastError, "status 500") || ch.LastFailureAt == nil {
+		t.Errorf("discord after test = %+v", ch)
+	}
+
+	// 別テナントからは消せない。消したあとは 404
+	path := "/notify/channels/" + strconv.FormatInt(slack.ID, 10)
+	if code, _ := do(http.MethodDelete, path, "", tenant+"-other"); code != http.StatusNotFound {
+		t.Fatalf("delete from other tenant: status = %d", code)
+	}
+	if code, _ := do(http.MethodDelete, path, "", tenant); code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", code)
+	}
+	if code, _ := do(http.MethodDelete, path, "", tenant); code != http.StatusNotFound {
+		t.Fatalf("delete again: status = %d", code)
+	}
+	if code, _ := testSend(slack.ID); code != http.StatusNotFound {
+		t.Fatalf("test send after delete: status = %d", code)
+	}
+	if chs := list(tenant); len(chs) != 1 || chs[0].ID != discord.ID {
+		t.Fatalf("channels after delete = %+v", chs)
+	}
+}
@@ -0,0 +1,48 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+// TestNotifyChannels_Validation は種別ごとの設定・テンプレート・テナント・チャネル ID の誤りを 400 で返すことを確認します。
+// 保存・テスト送信・削除の応答は notify_db_test.go で確かめる。
+func TestNotifyChannels_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		tenant string
+	}{
+		{"invalid json", http.MethodPost, "/notify/channels", "{", ""},
+		{"unknown type", http.MethodPost, "/notify/channels", `{"type":"pager","config":{}}`, ""},
+		{"missing config", http.MethodPost, "/notify/channels", `{"type":"slack"}`, ""},
+		{"invalid webhook", http.MethodPost, "/notify/channels", `{"type":"discord","config":{"webhook_url":"not a url"}}`, ""},
+		{"telegram without chat", http.MethodPost, "/notify/channels", `{"type":"telegram","config":{"bot_token":"t"}}`, ""},
+		{"email without to", http.MethodPost, "/notify/channels", `{"type":"email","config":{"smtp_addr":"mail:25","from":"a@example.com"}}`, ""},
+		{"broken template", http.MethodPost, "/notify/channels", `{"type":"slack","config":{"webhook_url":"https://h/x","template":"{{"}}`, ""},
+		{"invalid tenant", http.MethodGet, "/notify/channels", "", "bad tenant!"},
+		{"invalid channel id", http.MethodDelete, "/notify/channels/abc", "", ""},
+		{"invalid test id", http.MethodPost, "/notify/channels/0/test", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant-ID", tt.tenant)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (body: %s)", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
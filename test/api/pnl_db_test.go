@@ -0,0 +1,138 @@
+//go:build integration
+
+package apitest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	api "github.com/you/wallet-watcher/internal/api"
+	"github.com/you/wallet-watcher/internal/pnl"
+	"github.com/you/wallet-watcher/internal/pricing"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// priceTable は価格表のスタブ（token_prices を汚さないため。検索規則は token_prices と同じ）
+type priceTable struct {
+	prices   []store.TokenPrice
+	decimals map[string]int
+}
+
+func (p *priceTable) TokenPricesAt(ctx context.Context, lookups []store.PriceLookup, tolerance time.Duration) ([]*store.TokenPrice, error) {
+	out := make([]*store.TokenPrice, len(lookups))
+	for i, l := range lookups {
+		for j := range p.prices {
+			tp := p.prices[j]
+			if tp.Token != l.Token || tp.TS.After(l.At) || l.At.Sub(tp.TS) >= tolerance {
+				continue
+			}
+			if out[i] == nil || tp.TS.After(out[i].TS) {
+				out[i] = &tp
+			}
+		}
+	}
+	return out, nil
+}
+
+func (p *priceTable) UpsertTokenPrices(ctx context.Context, prices []store.TokenPrice) error {
+	return nil
+}
+
+func (p *priceTable) TokenDecimals(ctx context.Context, tokens []string) (map[string]int, error) {
+	out := map[string]int{}
+	for _, t := range tokens {
+		if d, ok := p.decimals[t]; ok {
+			out[t] = d
+		}
+	}
+	return out, nil
+}
+
+// TestPnL_DB は保存した取得 2 回・処分 1 回の履歴から、/pnl が方式ごとの実現・含み損益を返し、
+// 未処分のロットが残ることを確認します。
+// $1 で 10、$3 で 10 を受け取り、$4 で 5 を送る。現在価格は $5（桁数 0 のトークン）。
+func TestPnL_DB(t *testing.T) {
+	st := dbStore(t)
+	addrs := testSuiAddrs(2)
+	a, b := addrs[0], addrs[1]
+	now := time.Now().UTC().Truncate(time.Second)
+	t1, t2, t3 := now.Add(-72*time.Hour), now.Add(-48*time.Hour), now.Add(-24*time.Hour)
+	token := fmt.Sprintf("APITEST%d", now.UnixNano())
+	id := fmt.Sprintf("apitest-pnl-%d", time.Now().UnixNano())
+	seedSui(t, st, addrs,
+		suiTransfer(id+"-1", t1, b, a, token, 10, 0),
+		suiTransfer(id+"-2", t2, b, a, token, 10, 0),
+		suiTransfer(id+"-3", t3, a, b, token, 5, 0),
+	)
+	table := &priceTable{
+		prices: []store.TokenPrice{
+			{Token: token, TS: t1, USD: 1},
+			{Token: token, TS: t2, USD: 3},
+			{Token: token, TS: t3, USD: 4},
+			{Token: token, TS: now.Add(-time.Minute), USD: 5},
+		},
+		decimals: map[string]int{token: 0},
+	}
+	srv := httptest.NewServer(api.Routes(&api.Server{Store: st, Prices: pricing.NewService(table, pricing.Options{})}))
+	defer srv.Close()
+
+	tests := []struct {
+		method               string
+		realized, unrealized float64
+		costBasis            float64
+	}{
+		// 最初のロット（$1）から 5 を処分: 20 − 5
+		{pnl.FIFO, 15, 40, 35},
+		// 後のロット（$3）から 5 を処分: 20 − 15
+		{pnl.LIFO, 5, 50, 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + "/pnl?chain=sui&address=" + a + "&method=" + tt.method + "&lots=true")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d", resp.StatusCode)
+			}
+			var rep pnl.Report
+			if err := json.NewDecoder(resp.Body).Decode(&rep); err != nil {
+				t.Fatal(err)
+			}
+			if rep.Method != tt.method || len(rep.Tokens) != 1 {
+				t.Fatalf("report = %+v", rep)
+			}
+			ts := rep.Tokens[0]
+			if ts.Token != token || ts.Held != 15 || ts.Acquired != 20 || ts.Disposed != 5 || !near(ts.ProceedsUSD, 20) {
+				t.Errorf("token = %+v", ts)
+			}
+			if !near(ts.CostBasisUSD, tt.costBasis) || !near(ts.RealizedUSD, tt.realized) ||
+				ts.MarketValueUSD == nil || !near(*ts.MarketValueUSD, 75) || ts.UnrealizedUSD == nil || !near(*ts.UnrealizedUSD, tt.unrealized) {
+				t.Errorf("token = %+v", ts)
+			}
+			if !near(rep.Totals.RealizedUSD, tt.realized) || !near(rep.Totals.UnrealizedUSD, tt.unrealized) || rep.Totals.FeesUSD != 0 {
+				t.Errorf("totals = %+v", rep.Totals)
+			}
+			if len(rep.TaxYears) != 1 || rep.TaxYears[0].Disposals != 1 || !near(rep.TaxYears[0].GainUSD, tt.realized) {
+				t.Errorf("tax years = %+v", rep.TaxYears)
+			}
+			// どちらの方式でも 2 つのロットが残る（FIFO は 5 + 10、LIFO は 10 + 5）
+			var open int64
+			for _, l := range rep.OpenLots {
+				open += l.Remaining
+			}
+			if len(rep.OpenLots) != 2 || open != 15 {
+				t.Errorf("open lots = %+v", rep.OpenLots)
+			}
+		})
+	}
+}
+
+func near(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
@@ -0,0 +1,39 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+// TestPnL_Validation は chain・address・method・year・lots の誤りを 400 で返すことを確認します。
+// 損益の計算結果は pnl_db_test.go で保存した履歴から確かめる。
+func TestPnL_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+	const sol = "11111111111111111111111111111112"
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"missing address", "/pnl?chain=solana", "required"},
+		{"chain all", "/pnl?chain=all&address=" + sol, "chain must be"},
+		{"invalid address", "/pnl?chain=sui&address=zz", "invalid sui address"},
+		{"unknown method", "/pnl?chain=solana&address=" + sol + "&method=hifo", "method must be"},
+		{"invalid year", "/pnl?chain=solana&address=" + sol + "&year=25", "invalid 'year'"},
+		{"invalid lots", "/pnl?chain=solana&address=" + sol + "&lots=maybe", "invalid 'lots'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), tt.want) {
+				t.Fatalf("status = %d body = %q, want 400 mentioning %q", rr.Code, rr.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+type bulkResp struct {
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+	Invalid  int `json:"invalid"`
+	Results  []struct {
+		Index   int    `json:"index"`
+		Chain   string `json:"chain"`
+		Address string `json:"address"`
+		Result  string `json:"result"`
+		Error   string `json:"error"`
+	} `json:"results"`
+}
+
+// TestRegisterBulk_Validation は全件が不正なら DB に触れずに項目ごとの invalid を返すことを確認します
+func TestRegisterBulk_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+
+	multi := func() (string, *bytes.Buffer) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fw, _ := mw.CreateFormFile("file", "addresses.csv")
+		fw.Write([]byte("chain,address\neth,0xabc\nsolana,not-base58!\n"))
+		mw.Close()
+		return mw.FormDataContentType(), &buf
+	}
+	mct, mbody := multi()
+
+	tests := []struct {
+		name     string
+		ct       string
+		body     *bytes.Buffer
+		invalid  int
+		firstErr string
+	}{
+		{"json", "application/json", bytes.NewBufferString(`[{"chain":"eth","address":"0x1"},{"chain":" Sui ","address":"zz"}]`), 2, "chain must be"},
+		{"csv body", "text/csv", bytes.NewBufferString("# comment\nsolana\nsui,0xzz\n"), 2, "want 2 columns"},
+		{"csv upload", mct, mbody, 2, "chain must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/register/bulk", tt.body)
+			req.Header.Set("Content-Type", tt.ct)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %d (body: %s)", rr.Code, rr.Body.String())
+			}
+			var got bulkResp
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Invalid != tt.invalid || got.Created != 0 || got.Existing != 0 || len(got.Results) != tt.invalid {
+				t.Fatalf("resp = %+v", got)
+			}
+			for i, r := range got.Results {
+				if r.Index != i || r.Result != "invalid" || r.Error == "" {
+					t.Errorf("results[%d] = %+v", i, r)
+				}
+			}
+			if !strings.Contains(got.Results[0].Error, tt.firstErr) {
+				t.Errorf("results[0].error = %q, want %q", got.Results[0].Error, tt.firstErr)
+			}
+		})
+	}
+}
+
+// TestRegisterBulk_Limits は本文の不正・件数とサイズの上限を確認します
+func TestRegisterBulk_Limits(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+
+	many := "[" + strings.TrimSuffix(strings.Repeat(`{"chain":"sui","address":"0x1"},`, 10001), ",") + "]"
+	huge := strings.Repeat("sui,0x"+strings.Repeat("a", 1000)+"\n", 5000)
+
+	tests := []struct {
+		name string
+		ct   string
+		body string
+		want int
+	}{
+		{"invalid json", "application/json", "{", http.StatusBadRequest},
+		{"object instead of array", "application/json", `{"chain":"sui","address":"0x1"}`, http.StatusBadRequest},
+		{"empty array", "application/json", "[]", http.StatusBadRequest},
+		{"unsupported content type", "application/xml", "<a/>", http.StatusBadRequest},
+		{"multipart without file", "multipart/form-data; boundary=x", "--x--\r\n", http.StatusBadRequest},
+		{"too many items", "application/json", many, http.StatusRequestEntityTooLarge},
+		{"too large body", "text/csv", huge, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/register/bulk", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.ct)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body: %.200s)", rr.Code, tt.want, rr.Body.String())
+			}
+		})
+	}
+}
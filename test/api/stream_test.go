@@ -0,0 +1,146 @@
+package apitest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	api "github.com/you/wallet-watcher/internal/api"
+	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/stream"
+)
+
+// streamSource は /stream テスト用の stream.Source（補填分だけを返す）
+type streamSource struct{ events []store.StreamEvent }
+
+func (s *streamSource) GetStreamEvent(ctx context.Context, chain string, seq int64) (*store.StreamEvent, error) {
+	return nil, store.ErrNotFound
+}
+
+func (s *streamSource) ListStreamEvents(ctx context.Context, chain string, addresses []string, afterSeq int64, limit int) ([]store.StreamEvent, error) {
+	var out []store.StreamEvent
+	for _, e := range s.events {
+		if e.Seq > afterSeq {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s *streamSource) ListenTxEvents(ctx context.Context, fn func(store.TxNotification)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *streamSource) StreamWatermark(ctx context.Context) (int64, error) { return 8, nil }
+
+func (s *streamSource) NotifyStreamWatermark(ctx context.Context, w int64) error { return nil }
+
+func streamEvents() []store.StreamEvent {
+	ts := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	return []store.StreamEvent{
+		{Seq: 7, TxEvent: store.TxEvent{Chain: "solana", TxHash: "sigA", TS: ts}},
+		{Seq: 9, TxEvent: store.TxEvent{Chain: "solana", TxHash: "sigB", TS: ts.Add(time.Second)}},
+	}
+}
+
+// TestStream_Validation は /stream・/stream/ws の不正パラメータが 400 になることを確認します
+func TestStream_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+	for _, u := range []string{
+		"/stream?chain=ethereum",
+		"/stream?chain=solana&address=xyz",
+		"/stream?cursor=abc",
+		"/stream?cursor=-1",
+		"/stream/ws?chain=sui&address=11111111111111111111111111111112",
+	} {
+		req := httptest.NewRequest(http.MethodGet, u, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", u, rr.Code)
+		}
+	}
+
+	// Hub 未設定なら 503
+	req := httptest.NewRequest(http.MethodGet, "/stream?chain=solana", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("without hub: status = %d, want 503", rr.Code)
+	}
+}
+
+// TestStream_SSEResume は Last-Event-ID より後のイベントが id=cursor 付きで届くことを確認します
+// （確定済みの位置は 8 なので、seq 9 の id は 8 で止まる）
+func TestStream_SSEResume(t *testing.T) {
+	hub := stream.NewHub(&streamSource{events: streamEvents()})
+	srv := httptest.NewServer(api.Routes(&api.Server{Hub: hub}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/stream?chain=solana", nil)
+	req.Header.Set("Last-Event-ID", "6")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	var ids, data []string
+	sc := bufio.NewScanner(resp.Body)
+	for len(data) < 2 && sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+	if len(ids) != 2 || ids[0] != "7" || ids[1] != "8" {
+		t.Fatalf("ids = %v, want [7 8]", ids)
+	}
+	var e map[string]any
+	if err := json.Unmarshal([]byte(data[1]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e["tx_hash"] != "sigB" || e["seq"] != float64(9) || e["cursor"] != float64(8) {
+		t.Fatalf("unexpected event: %v", e)
+	}
+}
+
+// TestStream_WebSocket は cursor 指定で補填分が WebSocket メッセージとして届くことを確認します
+func TestStream_WebSocket(t *testing.T) {
+	hub := stream.NewHub(&streamSource{events: streamEvents()})
+	srv := httptest.NewServer(api.Routes(&api.Server{Hub: hub}))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/stream/ws?chain=solana&cursor=0"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+
+	for _, want := range []string{"sigA", "sigB"} {
+		var e store.StreamEvent
+		if err := conn.ReadJSON(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.TxHash != want {
+			t.Fatalf("tx_hash = %q, want %q", e.TxHash, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+//go:build integration
+
+package apitest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	api "github.com/you/wallet-watcher/internal/api"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// TestAddressSummary_DB は日次集計を進めたあと、/addresses/.../summary がトークンごとの通算と
+// 日次の系列を返すこと、Tx を別の日で取り直すと元の日の集計から外れることを確認します。
+func TestAddressSummary_DB(t *testing.T) {
+	st := dbStore(t)
+	addrs := testSuiAddrs(3)
+	a, b, c := addrs[0], addrs[1], addrs[2]
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+	id := fmt.Sprintf("apitest-summary-%d", time.Now().UnixNano())
+	seedSui(t, st, addrs,
+		suiTransfer(id+"-1", yesterday.Add(10*time.Hour), a, b, "SUI", 1000, 10),
+		suiTransfer(id+"-2", yesterday.Add(12*time.Hour), b, a, "SUI", 300, 5),
+		// SUI 以外の Tx の手数料は SUI の行に手数料だけで載る
+		suiTransfer(id+"-3", today.Add(time.Hour), a, c, "USDC", 50, 7),
+	)
+	rollup := func(ctx context.Context) (store.RollupResult, error) { return st.RollupDaily(ctx, "sui", 500) }
+	catchUp(t, rollup)
+
+	srv := httptest.NewServer(api.Routes(&api.Server{Store: st}))
+	defer srv.Close()
+	summary := func() store.AddressSummary {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/addresses/sui/" + a + "/summary?days=7")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d", resp.StatusCode)
+		}
+		var sum store.AddressSummary
+		if err := json.NewDecoder(resp.Body).Decode(&sum); err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+	day := func(d time.Time) string { return d.Format(time.DateOnly) }
+
+	sum := summary()
+	if sum.Counterparties != 2 || sum.FirstSeen == nil || !sum.FirstSeen.Equal(yesterday.Add(10*time.Hour)) ||
+		sum.LastSeen == nil || !sum.LastSeen.Equal(today.Add(time.Hour)) || sum.RolledUpAt == nil {
+		t.Fatalf("summary = %+v", sum)
+	}
+	wantTotals := []store.TokenActivity{
+		{Token: "SUI", Inflow: 300, Outflow: 1000, Net: -700, TxCount: 2, FeesPaid: 17, Counterparties: 1, ActiveDays: 2},
+		{Token: "USDC", Outflow: 50, Net: -50, TxCount: 1, Counterparties: 1, ActiveDays: 1},
+	}
+	if len(sum.Totals) != len(wantTotals) {
+		t.Fatalf("totals = %+v", sum.Totals)
+	}
+	for i, w := range wantTotals {
+		got := sum.Totals[i]
+		got.FirstSeen, got.LastSeen = time.Time{}, time.Time{}
+		if got != w {
+			t.Errorf("totals[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+	wantDaily := []store.DailyActivity{
+		{Day: day(yesterday), Token: "SUI", Inflow: 300, Outflow: 1000, TxCount: 2, FeesPaid: 10, Counterparties: 1},
+		{Day: day(today), Token: "SUI", FeesPaid: 7},
+		{Day: day(today), Token: "USDC", Outflow: 50, TxCount: 1, Counterparties: 1},
+	}
+	if len(sum.Daily) != len(wantDaily) {
+		t.Fatalf("daily = %+v", sum.Daily)
+	}
+	for i, w := range wantDaily {
+		if sum.Daily[i] != w {
+			t.Errorf("daily[%d] = %+v, want %+v", i, sum.Daily[i], w)
+		}
+	}
+
+	// B → A の Tx を今日の時刻で取り直すと、昨日の受取は消えて今日に移る
+	moved := suiTransfer(id+"-2", today.Add(2*time.Hour), b, a, "SUI", 300, 5)
+	if err := st.ReplaceTxEvent(context.Background(), "sui", moved); err != nil {
+		t.Fatal(err)
+	}
+	catchUp(t, rollup)
+	sum = summary()
+	wantDaily = []store.DailyActivity{
+		{Day: day(yesterday), Token: "SUI", Outflow: 1000, TxCount: 1, FeesPaid: 10, Counterparties: 1},
+		{Day: day(today), Token: "SUI", Inflow: 300, TxCount: 1, FeesPaid: 7, Counterparties: 1},
+		{Day: day(today), Token: "USDC", Outflow: 50, TxCount: 1, Counterparties: 1},
+	}
+	if len(sum.Daily) != len(wantDaily) {
+		t.Fatalf("daily after replace = %+v", sum.Daily)
+	}
+	for i, w := range wantDaily {
+		if sum.Daily[i] != w {
+			t.Errorf("daily after replace[%d] = %+v, want %+v", i, sum.Daily[i], w)
+		}
+	}
+	if sui := sum.Totals[0]; sui.Inflow != 300 || sui.TxCount != 2 || sui.ActiveDays != 2 {
+		t.Errorf("SUI totals after replace = %+v", sui)
+	}
+}
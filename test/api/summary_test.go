@@ -0,0 +1,39 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+// TestAddressSummary_Validation はチェーン・アドレス・days の誤りを 400 で返すことを確認します。
+// 応答の中身は summary_db_test.go で DB に入れたイベントから確かめる。
+func TestAddressSummary_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+	const sol = "11111111111111111111111111111112"
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"unknown chain", "/addresses/eth/" + sol + "/summary", "chain must be"},
+		{"invalid address", "/addresses/sui/zz/summary", "invalid sui address"},
+		{"days zero", "/addresses/solana/" + sol + "/summary?days=0", "invalid 'days'"},
+		{"days too many", "/addresses/solana/" + sol + "/summary?days=367", "invalid 'days'"},
+		{"days not a number", "/addresses/solana/" + sol + "/summary?days=week", "invalid 'days'"},
+		{"token too long", "/addresses/solana/" + sol + "/summary?token=" + strings.Repeat("x", 257), "'token' is too long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), tt.want) {
+				t.Fatalf("status = %d body = %q, want 400 mentioning %q", rr.Code, rr.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+)
+
+// TestTxDetail_Validation は /tx/{chain}/{hash} の不正なパラメータが 400 になることを確認します。
+func TestTxDetail_Validation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+
+	for _, url := range []string{
+		"/tx/ethereum/5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW",
+		"/tx/solana/0xnot-base58",
+		"/tx/sui/short",
+	} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package configtest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/config"
+)
+
+// clearEnv は上書き用の環境変数をすべて空にする（実行環境の .env に左右されないように）
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, k := range config.EnvKeys() {
+		t.Setenv(k, "")
+	}
+	t.Setenv("DATABASE_URL", "postgres://u:secret@db:5432/ww?sslmode=disable")
+}
+
+func writeFile(t *testing.T, name, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// TestLoad_Defaults は設定ファイルなしで従来の既定値になることを確認します
+func TestLoad_Defaults(t *testing.T) {
+	clearEnv(t)
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DB.MaxConns != 5 || cfg.Worker.PollInterval.D() != 5*time.Second || cfg.Worker.BatchSize != 10 || cfg.API.Port != 8080 {
+		t.Fatalf("defaults = %+v", cfg)
+	}
+	if got := cfg.Chains.RPC("solana"); got != "https://api.mainnet-beta.solana.com" {
+		t.Fatalf("solana rpc = %s", got)
+	}
+}
+
+// TestLoad_FileAndEnv は YAML / TOML のファイルを読み、環境変数がそれを上書きすることを確認します
+func TestLoad_FileAndEnv(t *testing.T) {
+	files := map[string]string{
+		"c.yaml": `
+db:
+  max_conns: 12
+chains:
+  sui:
+    network: testnet
+worker:
+  poll_interval: 1m30s
+  batch_size: 25
+`,
+		"c.toml": `
+[db]
+max_conns = 12
+[chains.sui]
+network = "testnet"
+[worker]
+poll_interval = "1m30s"
+batch_size = 25
+`,
+	}
+	for name, body := range files {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("BATCH_SIZE", "40")
+			cfg, err := config.Load(writeFile(t, name, body))
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.DB.MaxConns != 12 || cfg.Worker.PollInterval.D() != 90*time.Second {
+				t.Fatalf("file values not applied: %+v", cfg)
+			}
+			if cfg.Worker.BatchSize != 40 {
+				t.Fatalf("batch_size = %d, want env override 40", cfg.Worker.BatchSize)
+			}
+			if got := cfg.Chains.RPC("sui"); got != "https://fullnode.testnet.sui.io:443" {
+				t.Fatalf("sui rpc = %s", got)
+			}
+		})
+	}
+}
+
+// TestLoad_RetentionEnv は保持期限の環境変数が日数・分として読まれることを確認します
+func TestLoad_RetentionEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("RAW_RETENTION_DAYS", "30")
+	t.Setenv("ARCHIVE_AFTER_DAYS", "180")
+	t.Setenv("RETENTION_INTERVAL_MIN", "15")
+	t.Setenv("RAW_RETENTION_MODE", "DROP")
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	r := cfg.Retention
+	if r.RawAfter.D() != 30*24*time.Hour || r.ArchiveAfter.D() != 180*24*time.Hour || r.Interval.D() != 15*time.Minute || r.RawMode != config.RawDrop {
+		t.Fatalf("retention = %+v", r)
+	}
+	if r.PartitionsAhead != 3 || r.ArchiveDir != "archive" {
+		t.Fatalf("retention defaults = %+v", r)
+	}
+}
+
+// TestWarnings は archive_after を有効にしたときだけ、切り離した月が再生・再集計されない旨を警告することを確認します
+func TestWarnings(t *testing.T) {
+	cfg := config.Default()
+	if w := cfg.Warnings(); len(w) != 0 {
+		t.Fatalf("default warnings = %v", w)
+	}
+	cfg.Retention.ArchiveAfter = config.Duration(180 * 24 * time.Hour)
+	w := cfg.Warnings()
+	if len(w) != 1 || !strings.Contains(w[0], "retention.archive_after") || !strings.Contains(w[0], "/pnl") {
+		t.Fatalf("warnings = %v", w)
+	}
+}
+
+// TestLoad_Invalid は未知のキー・読めない環境変数・範囲外の値で起動を止めることを確認します
+func TestLoad_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{name: "unknown yaml key", file: "worker:\n  batchsize: 3\n", want: "batchsize"},
+		{name: "bad duration", file: "worker:\n  poll_interval: 5\n", want: "invalid duration"},
+		{name: "poll interval with unit", env: map[string]string{"POLL_INTERVAL_SEC": "5s"}, want: "POLL_INTERVAL_SEC"},
+		{name: "non-numeric batch", env: map[string]string{"BATCH_SIZE": "ten"}, want: "BATCH_SIZE"},
+		{name: "zero conns", env: map[string]string{"DB_MAX_CONNS": "0"}, want: "db.max_conns"},
+		{name: "unknown network", env: map[string]string{"SOLANA_NETWORK": "mainnet-beta"}, want: "chains.solana.network"},
+		{name: "unknown sink", env: map[string]string{"OUTBOX_SINK": "rabbit"}, want: "publisher.sink"},
+		{name: "missing db", env: map[string]string{"DATABASE_URL": ""}, want: "db.url"},
+		{name: "unknown price source", env: map[string]string{"PRICE_SOURCE": "oracle"}, want: "pricing.source"},
+		{name: "file price source without file", env: map[string]string{"PRICE_SOURCE": "file"}, want: "pricing.file"},
+		{name: "malformed price ids", env: map[string]string{"PRICE_IDS": "SOL"}, want: "PRICE_IDS"},
+		{name: "unknown pnl method", env: map[string]string{"PNL_METHOD": "hifo"}, want: "pnl.method"},
+		{name: "unknown raw mode", env: map[string]string{"RAW_RETENTION_MODE": "truncate"}, want: "retention.raw_mode"},
+		{name: "raw retention with unit", env: map[string]string{"RAW_RETENTION_DAYS": "30d"}, want: "RAW_RETENTION_DAYS"},
+		{name: "archive without dir", file: "retention:\n  archive_after: 2160h\n  archive_dir: \"\"\n", want: "retention.archive_dir"},
+		{name: "too many partitions ahead", env: map[string]string{"PARTITIONS_AHEAD": "36"}, want: "retention.partitions_ahead"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			path := ""
+			if tc.file != "" {
+				path = writeFile(t, "c.yaml", tc.file)
+			}
+			_, err := config.Load(path)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want mention of %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// TestRedacted は表示用の設定で接続文字列のパスワードと RPC の API キーが伏せられることを確認します
+func TestRedacted(t *testing.T) {
+	cfg := config.Default()
+	cfg.DB.URL = "postgres://u:secret@db:5432/ww?sslmode=disable"
+	cfg.Chains.Solana.RPCURL = "https://mainnet.helius-rpc.com/?api-key=abc123"
+	cfg.Publisher.Redis.URL = "redis://:hunter2@redis:6379/0"
+	cfg.Pricing.HTTP.APIKey = "cg-key-42"
+
+	var buf bytes.Buffer
+	if err := cfg.Redacted().Print(&buf, "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, secret := range []string{"secret", "abc123", "hunter2", "cg-key-42"} {
+		if strings.Contains(out, secret) {
+			t.Fatalf("%q leaked:\n%s", secret, out)
+		}
+	}
+	for _, keep := range []string{"u:***@db:5432/ww", "mainnet.helius-rpc.com", "poll_interval: 5s"} {
+		if !strings.Contains(out, keep) {
+			t.Fatalf("%q missing:\n%s", keep, out)
+		}
+	}
+}
+
+// TestRedacted_PasswordParams は URL のクエリや key=value 形式の DSN に書かれた password= も伏せ、
+// ほかの項目は残すことを確認します
+func TestRedacted_PasswordParams(t *testing.T) {
+	tests := []struct {
+		dsn, secret string
+		keep        []string
+	}{
+		{"postgres://u@db:5432/ww?password=qwerty&sslmode=disable", "qwerty", []string{"password=***", "sslmode=disable"}},
+		{"postgres://u:pw1@db/ww?sslpassword=keypass", "keypass", []string{"u:***@db", "sslpassword=***"}},
+		{"host=db user=u password=s3cr3t dbname=ww sslmode=disable", "s3cr3t", []string{"host=db", "password=***", "dbname=ww"}},
+		{"host=db password='with space' dbname=ww", "with space", []string{"password=***", "dbname=ww"}},
+		{"Password = p@ss user=u", "p@ss", []string{"Password = ***", "user=u"}},
+	}
+	for _, tt := range tests {
+		cfg := config.Default()
+		cfg.DB.URL = tt.dsn
+		got := cfg.Redacted().DB.URL
+		if strings.Contains(got, tt.secret) {
+			t.Errorf("%q: secret leaked: %q", tt.dsn, got)
+		}
+		for _, k := range tt.keep {
+			if !strings.Contains(got, k) {
+				t.Errorf("%q: %q missing from %q", tt.dsn, k, got)
+			}
+		}
+	}
+}
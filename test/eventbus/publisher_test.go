@@ -0,0 +1,340 @@
+package eventbustest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/you/wallet-watcher/internal/eventbus"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// fakeOutbox は event_outbox テーブルの代わり（RelayOutbox と同じく、publish 成功時だけ配信済みにし、
+// 受け付けられなかった行は attempts を数えて OutboxMaxAttempts 回で保留にする。再試行の遅延は模さない）
+type fakeOutbox struct {
+	mu        sync.Mutex
+	rows      []store.OutboxMessage
+	published map[int64]bool
+	parked    map[int64]bool
+}
+
+func newFakeOutbox(n int) *fakeOutbox {
+	ob := &fakeOutbox{published: map[int64]bool{}, parked: map[int64]bool{}}
+	ts := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		ob.rows = append(ob.rows, store.OutboxMessage{
+			ID:        int64(i),
+			EventType: store.EventTxInserted,
+			Chain:     "solana",
+			TxHash:    "sig" + strconv.Itoa(i),
+			TS:        ts.Add(time.Duration(i) * time.Second),
+			Payload:   json.RawMessage(`{"chain":"solana","tx_hash":"sig` + strconv.Itoa(i) + `"}`),
+		})
+	}
+	return ob
+}
+
+func (ob *fakeOutbox) RelayOutbox(ctx context.Context, limit int, publish func([]store.OutboxMessage) error) (int, error) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	var batch []store.OutboxMessage
+	for _, r := range ob.rows {
+		if !ob.published[r.ID] && !ob.parked[r.ID] && len(batch) < limit {
+			batch = append(batch, r)
+		}
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	err := publish(batch)
+	var rejected store.OutboxRejected
+	if err != nil && !errors.As(err, &rejected) {
+		return 0, err
+	}
+	n := 0
+	for _, r := range batch {
+		if _, ok := rejected[r.ID]; ok {
+			for i := range ob.rows {
+				if ob.rows[i].ID == r.ID {
+					ob.rows[i].Attempts++
+					ob.parked[r.ID] = ob.rows[i].Attempts >= store.OutboxMaxAttempts
+				}
+			}
+			continue
+		}
+		ob.published[r.ID] = true
+		n++
+	}
+	return n, nil
+}
+
+// flakySink は最初の fail 回の Publish を失敗させる（途中まで受け付けてから失敗する）
+type flakySink struct {
+	fail int
+	got  []eventbus.Message
+}
+
+func (s *flakySink) Publish(ctx context.Context, msgs []eventbus.Message) error {
+	if s.fail > 0 {
+		s.fail--
+		s.got = append(s.got, msgs[0])
+		return errors.New("broker unavailable")
+	}
+	s.got = append(s.got, msgs...)
+	return nil
+}
+func (s *flakySink) Close() error { return nil }
+
+// TestPublisher_AtLeastOnce は Publish 失敗時に行が配信済みにならず、
+// 再試行で全件が seq 昇順に届く（重複はありうる）ことを確認します。
+func TestPublisher_AtLeastOnce(t *testing.T) {
+	ob := newFakeOutbox(5)
+	// バッチ 1 回と 1 件ずつの送り直し 3 回がすべて失敗する（配信先の障害）
+	sink := &flakySink{fail: 4}
+	p := eventbus.NewPublisher(ob, sink, 3, time.Millisecond, time.Second)
+	ctx := context.Background()
+
+	if _, err := p.RelayOnce(ctx); err == nil {
+		t.Fatal("expected error from failing sink")
+	}
+	if len(ob.published) != 0 || ob.rows[0].Attempts != 0 {
+		t.Fatalf("published = %v, attempts = %d", ob.published, ob.rows[0].Attempts)
+	}
+	for {
+		n, err := p.RelayOnce(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	seen := map[int64]bool{}
+	var last int64
+	for _, m := range sink.got[4:] {
+		if m.Seq <= last {
+			t.Fatalf("seq not increasing: %d after %d", m.Seq, last)
+		}
+		last = m.Seq
+		seen[m.Seq] = true
+	}
+	if len(seen) != 5 {
+		t.Fatalf("delivered %d distinct messages, want 5", len(seen))
+	}
+	// 失敗したバッチの先頭は再配信されている（購読側で seq により重複排除する）
+	if sink.got[0].Seq != 1 || !seen[1] {
+		t.Fatalf("seq 1 should be delivered again after failure")
+	}
+}
+
+// poisonSink は bad を含むバッチを受け付けない（壊れたメッセージを模す）
+type poisonSink struct {
+	bad     int64
+	batches [][]int64
+}
+
+func (s *poisonSink) Publish(ctx context.Context, msgs []eventbus.Message) error {
+	var seqs []int64
+	for _, m := range msgs {
+		if m.Seq == s.bad {
+			return errors.New("message too large")
+		}
+		seqs = append(seqs, m.Seq)
+	}
+	s.batches = append(s.batches, seqs)
+	return nil
+}
+func (s *poisonSink) Close() error { return nil }
+
+// TestPublisher_PoisonMessage は受け付けられないメッセージが後続を止めず、
+// 後続はバッチ内で seq の昇順に届き、失敗が OutboxMaxAttempts 回になったら保留になることを確認します
+func TestPublisher_PoisonMessage(t *testing.T) {
+	ob := newFakeOutbox(5)
+	sink := &poisonSink{bad: 2}
+	p := eventbus.NewPublisher(ob, sink, 10, time.Millisecond, time.Second)
+
+	n, err := p.RelayOnce(context.Background())
+	if err != nil || n != 4 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	var delivered []int64
+	for _, b := range sink.batches {
+		for i := 1; i < len(b); i++ {
+			if b[i] <= b[i-1] {
+				t.Fatalf("batch not in seq order: %v", b)
+			}
+		}
+		delivered = append(delivered, b...)
+	}
+	if len(delivered) != 4 || ob.published[2] || !ob.published[1] || !ob.published[5] {
+		t.Fatalf("delivered = %v, published = %v", delivered, ob.published)
+	}
+
+	// 残るのは seq 2 だけ。単独のバッチでは配信先の障害と区別できないので数えない
+	if _, err := p.RelayOnce(context.Background()); err == nil {
+		t.Fatal("expected error for the poison message alone")
+	}
+	if ob.rows[1].Attempts != 1 || ob.parked[2] {
+		t.Fatalf("attempts = %d, parked = %v", ob.rows[1].Attempts, ob.parked[2])
+	}
+
+	// 新しい行と一緒に失敗し続けると保留になり、取り出されなくなる
+	for i := 0; i < store.OutboxMaxAttempts-1; i++ {
+		id := int64(len(ob.rows) + 1)
+		ob.rows = append(ob.rows, store.OutboxMessage{ID: id, EventType: store.EventTxInserted, Chain: "solana", TxHash: "sig" + strconv.FormatInt(id, 10)})
+		if n, err := p.RelayOnce(context.Background()); err != nil || n != 1 {
+			t.Fatalf("RelayOnce #%d = %d, %v", i, n, err)
+		}
+	}
+	if !ob.parked[2] || ob.rows[1].Attempts != store.OutboxMaxAttempts {
+		t.Fatalf("attempts = %d, parked = %v", ob.rows[1].Attempts, ob.parked[2])
+	}
+	if n, err := p.RelayOnce(context.Background()); err != nil || n != 0 {
+		t.Fatalf("RelayOnce after parking = %d, %v", n, err)
+	}
+}
+
+// blockingSink は ctx が終わるまで Publish を返さない（応答しないブローカーを模す）
+type blockingSink struct {
+	once    sync.Once
+	started chan struct{}
+}
+
+func (s *blockingSink) Publish(ctx context.Context, msgs []eventbus.Message) error {
+	s.once.Do(func() { close(s.started) })
+	<-ctx.Done()
+	return ctx.Err()
+}
+func (s *blockingSink) Close() error { return nil }
+
+// TestPublisher_ShutdownGrace は停止要求後、中継中のバッチを猶予の間だけ待ってから打ち切り、
+// 行を配信済みにせずに戻ることを確認します
+func TestPublisher_ShutdownGrace(t *testing.T) {
+	ob := newFakeOutbox(2)
+	sink := &blockingSink{started: make(chan struct{})}
+	p := eventbus.NewPublisher(ob, sink, 10, time.Millisecond, 50*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Run(ctx)
+	}()
+	<-sink.started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the shutdown grace")
+	}
+	if len(ob.published) != 0 {
+		t.Fatalf("rows marked published: %v", ob.published)
+	}
+}
+
+// TestRedisSink はインプロセスの Redis（miniredis）に XADD され、seq がフィールドに載ることを確認します
+func TestRedisSink(t *testing.T) {
+	mr := miniredis.RunT(t)
+	sink, err := eventbus.NewRedisSink("redis://"+mr.Addr()+"/0", "events", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	ob := newFakeOutbox(4)
+	p := eventbus.NewPublisher(ob, sink, 10, time.Millisecond, time.Second)
+	if n, err := p.RelayOnce(context.Background()); err != nil || n != 4 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	entries, err := rdb.XRange(context.Background(), "events", "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("stream length = %d, want 4", len(entries))
+	}
+	for i, e := range entries {
+		if e.Values["seq"] != strconv.Itoa(i+1) || e.Values["type"] != store.EventTxInserted {
+			t.Fatalf("entry %d: %v", i, e.Values)
+		}
+		var m eventbus.Message
+		if err := json.Unmarshal([]byte(e.Values["message"].(string)), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m.TxHash != "sig"+strconv.Itoa(i+1) || len(m.Data) == 0 {
+			t.Fatalf("entry %d message: %+v", i, m)
+		}
+	}
+}
+
+// TestRedisSink_Unavailable は Redis が落ちていれば行が配信済みにならないことを確認します
+func TestRedisSink_Unavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	sink, err := eventbus.NewRedisSink("redis://"+mr.Addr()+"/0", "events", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	mr.Close()
+
+	ob := newFakeOutbox(2)
+	p := eventbus.NewPublisher(ob, sink, 10, time.Millisecond, time.Second)
+	if _, err := p.RelayOnce(context.Background()); err == nil {
+		t.Fatal("expected error while redis is down")
+	}
+	if len(ob.published) != 0 {
+		t.Fatalf("rows marked published despite failure: %v", ob.published)
+	}
+}
+
+// TestFileSink は NDJSON で追記されることを確認します
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.ndjson")
+	sink, err := eventbus.NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ob := newFakeOutbox(3)
+	p := eventbus.NewPublisher(ob, sink, 2, time.Millisecond, time.Second)
+	for {
+		n, err := p.RelayOnce(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	sink.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var seqs []int64
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var m eventbus.Message
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, m.Seq)
+	}
+	if len(seqs) != 3 || seqs[0] != 1 || seqs[2] != 3 {
+		t.Fatalf("seqs = %v", seqs)
+	}
+}
@@ -0,0 +1,183 @@
+package eventbustest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/you/wallet-watcher/internal/eventbus"
+)
+
+// natsMsg は fakeNATS が受け取った HPUB 1 件
+type natsMsg struct {
+	subject string
+	header  string
+	data    []byte
+}
+
+// fakeNATS は NATS のテキストプロトコルのうち、接続・PING / PONG・HPUB だけを受け付けるサーバー
+type fakeNATS struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	msgs []natsMsg
+}
+
+func newFakeNATS(t *testing.T) *fakeNATS {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeNATS{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *fakeNATS) url() string { return "nats://" + s.ln.Addr().String() }
+
+func (s *fakeNATS) serve(c net.Conn) {
+	defer c.Close()
+	fmt.Fprintf(c, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"proto\":1,\"headers\":true,\"max_payload\":1048576}\r\n")
+	r := bufio.NewReader(c)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		switch strings.ToUpper(f[0]) {
+		case "PING":
+			fmt.Fprintf(c, "PONG\r\n")
+		case "HPUB":
+			// HPUB <subject> [reply] <header bytes> <total bytes>
+			hdr, _ := strconv.Atoi(f[len(f)-2])
+			total, _ := strconv.Atoi(f[len(f)-1])
+			buf := make([]byte, total+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.msgs = append(s.msgs, natsMsg{subject: f[1], header: string(buf[:hdr]), data: buf[hdr:total]})
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *fakeNATS) received() []natsMsg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]natsMsg(nil), s.msgs...)
+}
+
+// TestNATSSink は subject.<chain> に Nats-Msg-Id 付きで publish され、
+// 期限の無い context（停止時の WithoutCancel と同じ）でもバッチが配信済みになることを確認します
+func TestNATSSink(t *testing.T) {
+	srv := newFakeNATS(t)
+	sink, err := eventbus.NewNATSSink(srv.url(), "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	ob := newFakeOutbox(3)
+	p := eventbus.NewPublisher(ob, sink, 10, time.Millisecond, time.Second)
+	if n, err := p.RelayOnce(context.WithoutCancel(context.Background())); err != nil || n != 3 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if len(ob.published) != 3 {
+		t.Fatalf("published = %v", ob.published)
+	}
+
+	msgs := srv.received()
+	if len(msgs) != 3 {
+		t.Fatalf("received %d messages, want 3", len(msgs))
+	}
+	for i, m := range msgs {
+		seq := strconv.Itoa(i + 1)
+		if m.subject != "wallet.solana" || !strings.Contains(m.header, "Nats-Msg-Id: "+seq) {
+			t.Fatalf("message %d: subject %q header %q", i, m.subject, m.header)
+		}
+		var got eventbus.Message
+		if err := json.Unmarshal(m.data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if strconv.FormatInt(got.Seq, 10) != seq || got.TxHash != "sig"+seq {
+			t.Fatalf("message %d: %+v", i, got)
+		}
+	}
+}
+
+// fakeKafkaWriter は書き込まれたメッセージを覚える（err があれば失敗する）
+type fakeKafkaWriter struct {
+	msgs []kafka.Message
+	err  error
+	// deadline は WriteMessages に渡された context に期限があったか
+	deadline bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	_, w.deadline = ctx.Deadline()
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error { return nil }
+
+// TestKafkaSink はキーが chain:tx_hash、ヘッダー seq にシーケンス番号が載り、失敗時は配信済みにならないことを確認します
+func TestKafkaSink(t *testing.T) {
+	w := &fakeKafkaWriter{}
+	ob := newFakeOutbox(2)
+	p := eventbus.NewPublisher(ob, eventbus.NewKafkaSinkWriter(w), 10, time.Millisecond, time.Second)
+	if n, err := p.RelayOnce(context.Background()); err != nil || n != 2 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if !w.deadline {
+		t.Fatal("publish context has no deadline")
+	}
+	if len(w.msgs) != 2 {
+		t.Fatalf("messages = %d", len(w.msgs))
+	}
+	for i, m := range w.msgs {
+		seq := strconv.Itoa(i + 1)
+		if string(m.Key) != "solana:sig"+seq || len(m.Headers) != 1 || m.Headers[0].Key != "seq" || string(m.Headers[0].Value) != seq {
+			t.Fatalf("message %d: key %q headers %v", i, m.Key, m.Headers)
+		}
+		var got eventbus.Message
+		if err := json.Unmarshal(m.Value, &got); err != nil || got.TxHash != "sig"+seq {
+			t.Fatalf("message %d value: %+v, %v", i, got, err)
+		}
+	}
+
+	w = &fakeKafkaWriter{err: kafka.LeaderNotAvailable}
+	ob = newFakeOutbox(2)
+	p = eventbus.NewPublisher(ob, eventbus.NewKafkaSinkWriter(w), 10, time.Millisecond, time.Second)
+	if _, err := p.RelayOnce(context.Background()); err == nil {
+		t.Fatal("expected error from failing writer")
+	}
+	if len(ob.published) != 0 {
+		t.Fatalf("rows marked published despite failure: %v", ob.published)
+	}
+}
@@ -0,0 +1,219 @@
+package graphtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/graph"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// fakeSource はメモリ上のエッジを tx_count の多い順に返す
+type fakeSource struct {
+	edges  []store.Edge
+	labels map[string]string
+	calls  [][]string
+}
+
+func (f *fakeSource) NeighborEdges(ctx context.Context, chain string, addrs []string, q store.EdgeQuery) ([]store.Edge, error) {
+	f.calls = append(f.calls, addrs)
+	var out []store.Edge
+	for _, a := range addrs {
+		n := 0
+		for _, e := range f.edges {
+			if (e.From == a || e.To == a) && (q.Token == nil || *q.Token == e.Token) && n < q.PerAddress {
+				out = append(out, e)
+				n++
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSource) AddressLabels(ctx context.Context, chain string, addrs []string) (map[string]string, error) {
+	return f.labels, nil
+}
+
+func edge(from, to, token string, n int) store.Edge {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return store.Edge{From: from, To: to, Token: token, TotalAmount: int64(n) * 100, TxCount: n, FirstTS: ts, LastTS: ts}
+}
+
+// chainEdges は A → B → C → D の一本道と A ⇄ E
+func chainEdges() []store.Edge {
+	return []store.Edge{
+		edge("0xa", "0xb", "SUI", 5),
+		edge("0xe", "0xa", "SUI", 3),
+		edge("0xa", "0xe", "SUI", 2),
+		edge("0xb", "0xc", "SUI", 4),
+		edge("0xc", "0xd", "SUI", 1),
+	}
+}
+
+func addresses(g *graph.Graph) map[string]int {
+	out := map[string]int{}
+	for _, n := range g.Nodes {
+		out[n.Address] = n.Depth
+	}
+	return out
+}
+
+// TestBuild_Depth は depth ホップまでたどり、ノードの深さとラベル・tx 数を付けることを確認します
+func TestBuild_Depth(t *testing.T) {
+	src := &fakeSource{edges: chainEdges(), labels: map[string]string{"0xa": "Treasury"}}
+	// 中心は Sui の表記揺れ（大文字・0x なし）でも正規化される
+	g, err := graph.Build(context.Background(), src, "sui", "A", graph.Options{Depth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Root != "0xa" {
+		t.Fatalf("root = %q", g.Root)
+	}
+	got := addresses(g)
+	want := map[string]int{"0xa": 0, "0xb": 1, "0xe": 1, "0xc": 2}
+	if len(got) != len(want) {
+		t.Fatalf("nodes = %v, want %v", got, want)
+	}
+	for a, d := range want {
+		if got[a] != d {
+			t.Fatalf("nodes = %v, want %v", got, want)
+		}
+	}
+	// A-B / E-A / A-E / B-C（C-D は 3 ホップ目）。同じエッジは 1 回だけ
+	if len(g.Edges) != 4 {
+		t.Fatalf("edges = %+v", g.Edges)
+	}
+	root := g.Nodes[0]
+	if root.Label != "Treasury" || root.TxCount != 10 {
+		t.Fatalf("root = %+v", root)
+	}
+	if len(src.calls) != 2 {
+		t.Fatalf("NeighborEdges calls = %v", src.calls)
+	}
+}
+
+// TestBuild_Limits はアドレスごとの件数・ノード数の上限・トークンの絞り込みを確認します
+func TestBuild_Limits(t *testing.T) {
+	src := &fakeSource{edges: chainEdges()}
+	g, err := graph.Build(context.Background(), src, "sui", "0xa", graph.Options{Depth: 1, PerAddress: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Edges) != 1 || g.Edges[0].To != "0xb" || g.Truncated {
+		t.Fatalf("per address: %+v", g)
+	}
+
+	g, err = graph.Build(context.Background(), src, "sui", "0xa", graph.Options{Depth: 3, MaxNodes: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 3 || !g.Truncated {
+		t.Fatalf("max nodes: %+v", g)
+	}
+	// 両端がグラフに入ったエッジだけを返す
+	for _, e := range g.Edges {
+		got := addresses(g)
+		if _, ok := got[e.From]; !ok {
+			t.Fatalf("dangling edge %+v", e)
+		}
+		if _, ok := got[e.To]; !ok {
+			t.Fatalf("dangling edge %+v", e)
+		}
+	}
+
+	usdc := "USDC"
+	g, err = graph.Build(context.Background(), src, "sui", "0xa", graph.Options{Token: &usdc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 1 || len(g.Edges) != 0 {
+		t.Fatalf("token filter: %+v", g)
+	}
+}
+
+// TestWriteGraphML は GraphML が XML として読めて、ノード・エッジの数と属性が揃うことを確認します
+func TestWriteGraphML(t *testing.T) {
+	src := &fakeSource{edges: chainEdges(), labels: map[string]string{"0xa": `Ops <"hot">`}}
+	g, err := graph.Build(context.Background(), src, "sui", "0xa", graph.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := graph.WriteGraphML(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Graph struct {
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []struct {
+				ID   string `xml:"id,attr"`
+				Data []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid xml: %v\n%s", err, buf.String())
+	}
+	if doc.Graph.EdgeDefault != "directed" || len(doc.Graph.Nodes) != len(g.Nodes) || len(doc.Graph.Edges) != len(g.Edges) {
+		t.Fatalf("graphml = %+v", doc.Graph)
+	}
+	if n := doc.Graph.Nodes[0]; n.ID != "0xa" || n.Data[0].Key != "n_label" || n.Data[0].Value != `Ops <"hot">` {
+		t.Fatalf("root node = %+v", n)
+	}
+}
+
+// TestWriteDOT は DOT の ID・ラベルを引用符付きでエスケープすることを確認します
+func TestWriteDOT(t *testing.T) {
+	src := &fakeSource{edges: chainEdges()[:1], labels: map[string]string{"0xb": `say "hi"`}}
+	g, err := graph.Build(context.Background(), src, "sui", "0xa", graph.Options{Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := graph.WriteDOT(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`digraph "sui:0xa" {`,
+		`"0xa" [label="0xa", tooltip="0xa", style=bold];`,
+		`"0xb" [label="say \"hi\"", tooltip="0xb"];`,
+		`"0xa" -> "0xb" [label="SUI ×5", weight=5,`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("dot missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// TestTransfers は raw が無いイベントは送受信者と金額を使い、失敗した Tx は移動にしないことを確認します
+func TestTransfers(t *testing.T) {
+	str := func(s string) *string { return &s }
+	amount := int64(7)
+	e := store.TxEvent{Chain: "solana", TxHash: "t", Sender: str("S"), Receiver: str("R"), Token: str("SOL"), Amount: &amount}
+	got := graph.Transfers(e, nil)
+	if len(got) != 1 || got[0] != (store.EdgeTransfer{From: "S", To: "R", Token: "SOL", Amount: 7}) {
+		t.Fatalf("transfers = %+v", got)
+	}
+
+	e.Status = str(store.TxStatusFailed)
+	if got := graph.Transfers(e, nil); len(got) != 0 {
+		t.Fatalf("failed tx transfers = %+v", got)
+	}
+
+	e.Status, e.Receiver = nil, nil
+	if got := graph.Transfers(e, nil); len(got) != 0 {
+		t.Fatalf("no receiver transfers = %+v", got)
+	}
+}
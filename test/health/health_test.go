@@ -0,0 +1,91 @@
+package healthtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/health"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// fakeHeartbeats は DB の代わりに保持しているハートビートを返す health.HeartbeatSource
+type fakeHeartbeats map[string]store.WorkerHeartbeat
+
+func (f fakeHeartbeats) LatestHeartbeats(ctx context.Context) (map[string]store.WorkerHeartbeat, error) {
+	return f, nil
+}
+
+func i64(v int64) *int64 { return &v }
+
+// TestChecker_Run は 1 つでも失敗すれば全体が fail になり、結果が登録順に並ぶことを確認します
+func TestChecker_Run(t *testing.T) {
+	c := health.NewChecker(time.Second)
+	c.Add("a", func(ctx context.Context) (any, error) { return "fine", nil })
+	c.Add("b", func(ctx context.Context) (any, error) { return nil, errors.New("down") })
+
+	rep := c.Run(context.Background())
+	if rep.OK() || rep.Status != health.StatusFail {
+		t.Fatalf("status = %s, want fail", rep.Status)
+	}
+	if len(rep.Checks) != 2 || rep.Checks[0].Name != "a" || rep.Checks[1].Name != "b" {
+		t.Fatalf("checks = %+v", rep.Checks)
+	}
+	if rep.Checks[0].Status != health.StatusOK || rep.Checks[0].Detail != "fine" {
+		t.Fatalf("a = %+v", rep.Checks[0])
+	}
+	if rep.Checks[1].Status != health.StatusFail || rep.Checks[1].Error != "down" {
+		t.Fatalf("b = %+v", rep.Checks[1])
+	}
+}
+
+// TestChecker_Timeout は ctx を見ないチェックでもタイムアウトで打ち切られることを確認します
+func TestChecker_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	c := health.NewChecker(50 * time.Millisecond)
+	c.Add("slow", func(ctx context.Context) (any, error) {
+		<-release
+		return nil, nil
+	})
+
+	start := time.Now()
+	rep := c.Run(context.Background())
+	if rep.OK() {
+		t.Fatal("slow check passed")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("Run took %s", time.Since(start))
+	}
+}
+
+// TestHeartbeat はハートビートの有無・鮮度・カーソル遅れの閾値を確認します
+func TestHeartbeat(t *testing.T) {
+	src := fakeHeartbeats{
+		"solana": {Chain: "solana", Instance: "w1", Age: 10 * time.Second, MaxCursorLag: i64(50)},
+		"sui":    {Chain: "sui", Instance: "w2", Age: 10 * time.Minute},
+	}
+	lim := health.HeartbeatLimits{MaxAge: time.Minute, MaxCursorLag: 100}
+
+	cases := []struct {
+		name  string
+		chain string
+		lim   health.HeartbeatLimits
+		ok    bool
+	}{
+		{"fresh", "solana", lim, true},
+		{"stale", "sui", lim, false},
+		{"missing", "aptos", lim, false},
+		{"lagging", "solana", health.HeartbeatLimits{MaxCursorLag: 10}, false},
+		{"age not checked", "sui", health.HeartbeatLimits{}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := health.Heartbeat(src, tc.chain, tc.lim)(context.Background())
+			if (err == nil) != tc.ok {
+				t.Fatalf("err = %v, want ok=%v", err, tc.ok)
+			}
+		})
+	}
+}
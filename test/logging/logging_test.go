@@ -0,0 +1,105 @@
+package loggingtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+	"github.com/you/wallet-watcher/internal/logging"
+)
+
+// capture は既定のロガーを JSON のバッファに差し替え、テスト後に戻す
+func capture(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(logging.New(&buf, "json", level))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	sc := bufio.NewScanner(buf)
+	for sc.Scan() {
+		var m map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("not a JSON line: %q", sc.Text())
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestMiddleware_RequestID(t *testing.T) {
+	buf := capture(t, slog.LevelInfo)
+	handler := api.Routes(&api.Server{})
+
+	// 指定がなければ採番する
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/tx/eth/abc", nil))
+	id := rr.Header().Get(logging.RequestIDHeader)
+	if len(id) != 16 {
+		t.Fatalf("X-Request-ID = %q, want a generated id", id)
+	}
+
+	// クライアント指定の ID を引き継ぐ
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set(logging.RequestIDHeader, "client-req-1")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if got := rr.Header().Get(logging.RequestIDHeader); got != "client-req-1" {
+		t.Fatalf("X-Request-ID = %q, want client-req-1", got)
+	}
+
+	ls := lines(t, buf)
+	if len(ls) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(ls))
+	}
+	first := ls[0]
+	if first["request_id"] != id || first["route"] != "/tx/{chain}/{hash}" || first["status"] != float64(400) || first["level"] != "WARN" {
+		t.Errorf("access log = %v", first)
+	}
+	if ls[1]["request_id"] != "client-req-1" || ls[1]["level"] != "INFO" {
+		t.Errorf("access log = %v", ls[1])
+	}
+}
+
+func TestWith_CarriesFields(t *testing.T) {
+	buf := capture(t, slog.LevelDebug)
+
+	ctx := logging.With(context.Background(), "tick_id", "t1")
+	actx := logging.With(ctx, "address", "addr1")
+	logging.FromContext(actx).Debug("process address")
+	logging.FromContext(ctx).Info("tick done")
+
+	ls := lines(t, buf)
+	if len(ls) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(ls))
+	}
+	if ls[0]["tick_id"] != "t1" || ls[0]["address"] != "addr1" {
+		t.Errorf("address line = %v", ls[0])
+	}
+	if ls[1]["tick_id"] != "t1" || ls[1]["address"] != nil {
+		t.Errorf("tick line = %v", ls[1])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"": slog.LevelInfo, "DEBUG": slog.LevelDebug, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		got, err := logging.ParseLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := logging.ParseLevel("verbose"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
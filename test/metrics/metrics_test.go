@@ -0,0 +1,128 @@
+package metricstest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	api "github.com/you/wallet-watcher/internal/api"
+	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/metrics"
+)
+
+// rpcServer は method ごとに result を返し、fail に含まれる method は JSON-RPC エラーにするモック
+func rpcServer(t *testing.T, results map[string]any, fail map[string]bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q struct {
+			Method string `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&q)
+		if fail[q.Method] {
+			json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "error": map[string]any{"code": -32000, "message": "boom"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": results[q.Method]})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRPCMetrics_ByMethodAndEndpoint(t *testing.T) {
+	srv := rpcServer(t, map[string]any{"getSlot": 321, "sui_getLatestCheckpointSequenceNumber": "987"},
+		map[string]bool{"getTransaction": true})
+	// パスやクエリ（API キー）はラベルに載せない
+	url := srv.URL + "/v1/secret?api-key=xyz"
+	if ep := metrics.Endpoint(url); ep != srv.URL {
+		t.Fatalf("Endpoint = %q, want %q", ep, srv.URL)
+	}
+	// 設定済みのエンドポイントはその名前で記録する
+	metrics.RegisterEndpoint("testnet", srv.URL)
+	ep := metrics.EndpointLabel(url)
+	if ep != "testnet" {
+		t.Fatalf("EndpointLabel = %q, want testnet", ep)
+	}
+
+	ctx := context.Background()
+	slot, err := sol.New(url).GetSlot(ctx)
+	if err != nil || slot != 321 {
+		t.Fatalf("GetSlot = %d, %v", slot, err)
+	}
+	if _, err := sol.New(url).GetTransaction(ctx, "sig"); err == nil {
+		t.Fatal("expected rpc error")
+	}
+	cp, err := sui.New(url).GetLatestCheckpoint(ctx)
+	if err != nil || cp != 987 {
+		t.Fatalf("GetLatestCheckpoint = %d, %v", cp, err)
+	}
+
+	if n := testutil.CollectAndCount(metrics.RPCDuration); n < 3 {
+		t.Errorf("rpc duration series = %d, want >= 3", n)
+	}
+	if v := testutil.ToFloat64(metrics.RPCErrors.WithLabelValues("solana", "getTransaction", ep)); v != 1 {
+		t.Errorf("getTransaction errors = %v, want 1", v)
+	}
+	if v := testutil.ToFloat64(metrics.RPCErrors.WithLabelValues("solana", "getSlot", ep)); v != 0 {
+		t.Errorf("getSlot errors = %v, want 0", v)
+	}
+}
+
+// TestRPCMetrics_CustomEndpoint は設定に無い RPC（rpc_url クエリでの差し替え）が
+// ホストごとに系列を増やさず "custom" にまとまることを確認します
+func TestRPCMetrics_CustomEndpoint(t *testing.T) {
+	metrics.RegisterEndpoints(config.Chains{Solana: config.Chain{Network: config.NetworkDevnet}})
+	if got := metrics.EndpointLabel("https://api.devnet.solana.com"); got != config.NetworkDevnet {
+		t.Errorf("configured label = %q", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		srv := rpcServer(t, map[string]any{"getSlot": 1}, nil)
+		if got := metrics.EndpointLabel(srv.URL); got != metrics.CustomEndpoint {
+			t.Fatalf("EndpointLabel(%s) = %q, want custom", srv.URL, got)
+		}
+		if _, err := sol.New(srv.URL).GetSlot(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := testutil.CollectAndCount(metrics.RPCDuration)
+	srv := rpcServer(t, map[string]any{"getSlot": 1}, nil)
+	if _, err := sol.New(srv.URL).GetSlot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if after := testutil.CollectAndCount(metrics.RPCDuration); after != before {
+		t.Errorf("rpc duration series grew %d → %d for a custom endpoint", before, after)
+	}
+}
+
+func TestHTTPMetrics_RoutePattern(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+
+	// 検証エラーで DB に触れずに返るリクエスト
+	for _, u := range []string{"/tx/eth/abc", "/tx/btc/def", "/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("/metrics status = %d", rr.Code)
+	}
+	body, _ := io.ReadAll(rr.Body)
+	out := string(body)
+	for _, want := range []string{
+		// パスの値ではなくルートパターンで集計される
+		`wallet_watcher_http_request_duration_seconds_count{method="GET",route="/tx/{chain}/{hash}",status="400"} 2`,
+		`route="unmatched",status="404"`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("/metrics does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package normalizetest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/normalize"
+)
+
+const (
+	usdc  = "0xdba3::usdc::USDC"
+	cetus = "0x06864a::cetus::CETUS"
+	junk  = "0xbad::junk::JUNK"
+)
+
+// memDecimals は token_decimals をメモリ上に持つ
+type memDecimals struct {
+	m       map[string]int
+	lookups int
+}
+
+func (d *memDecimals) TokenDecimals(ctx context.Context, tokens []string) (map[string]int, error) {
+	d.lookups++
+	out := map[string]int{}
+	for _, t := range tokens {
+		if v, ok := d.m[t]; ok {
+			out[t] = v
+		}
+	}
+	return out, nil
+}
+
+func (d *memDecimals) SetTokenDecimals(ctx context.Context, dec map[string]int) error {
+	for t, v := range dec {
+		d.m[t] = v
+	}
+	return nil
+}
+
+func coinEvent() normalize.Event {
+	return normalize.Event{Chain: "sui", Transfers: []normalize.Transfer{
+		{From: "0xa", To: "0xb", Token: "SUI", Amount: 5, Decimals: 9},
+		{From: "0xa", To: "0xb", Token: usdc, Amount: 1500000},
+		{From: "0xa", To: "0xb", Token: cetus, Amount: 7},
+		{From: "0xa", To: "0xb", Token: junk, Amount: 3},
+	}}
+}
+
+// TestSuiDecimals は token_decimals にあるコインはそのまま、無いコインは suix_getCoinMetadata で引いて
+// 登録し、メタデータの無いコインは最小単位のまま残ることを確認します。
+func TestSuiDecimals(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var q struct {
+			Method string   `json:"method"`
+			Params []string `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&q)
+		var result any
+		if q.Method == "suix_getCoinMetadata" && q.Params[0] == cetus {
+			result = map[string]any{"decimals": 9, "symbol": "CETUS"}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+	defer srv.Close()
+
+	st := &memDecimals{m: map[string]int{usdc: 6}}
+	d := &normalize.SuiDecimals{Store: st, Client: sui.New(srv.URL)}
+	ev := coinEvent()
+	d.Apply(context.Background(), &ev)
+
+	want := []int{9, 6, 9, 0}
+	for i, w := range want {
+		if ev.Transfers[i].Decimals != w {
+			t.Errorf("%s decimals = %d, want %d", ev.Transfers[i].Token, ev.Transfers[i].Decimals, w)
+		}
+	}
+	if got := normalize.FormatAmount(ev.Transfers[1].Amount, ev.Transfers[1].Decimals); got != "1.5" {
+		t.Errorf("usdc amount = %s", got)
+	}
+	if st.m[cetus] != 9 {
+		t.Errorf("cetus not saved: %v", st.m)
+	}
+	if _, ok := st.m[junk]; ok {
+		t.Errorf("junk saved: %v", st.m)
+	}
+
+	// 2 回目は覚えた結果を使い、DB にも RPC にも問い合わせない
+	n, lookups := calls.Load(), st.lookups
+	ev = coinEvent()
+	d.Apply(context.Background(), &ev)
+	if calls.Load() != n || st.lookups != lookups || ev.Transfers[2].Decimals != 9 {
+		t.Errorf("second apply: rpc %d→%d, db %d→%d, transfers %+v", n, calls.Load(), lookups, st.lookups, ev.Transfers)
+	}
+}
+
+// TestSuiDecimals_TableOnly は Client が無いとき token_decimals だけを見ることを確認します。
+func TestSuiDecimals_TableOnly(t *testing.T) {
+	d := &normalize.SuiDecimals{Store: &memDecimals{m: map[string]int{usdc: 6}}}
+	ev := coinEvent()
+	d.Apply(context.Background(), &ev)
+	if ev.Transfers[1].Decimals != 6 || ev.Transfers[2].Decimals != 0 {
+		t.Errorf("transfers = %+v", ev.Transfers)
+	}
+
+	// Solana のイベントには触らない
+	sol := normalize.Event{Chain: "solana", Transfers: []normalize.Transfer{{Token: usdc}}}
+	d.Apply(context.Background(), &sol)
+	if sol.Transfers[0].Decimals != 0 {
+		t.Errorf("solana transfers = %+v", sol.Transfers)
+	}
+}
@@ -0,0 +1,254 @@
+package normalizetest
+
+import (
+	"encoding/json"
+	"testing"
+
+	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// TestSolana_NativeAndTokenTransfer は SOL 送金 + SPL トークン送金を含む Tx から
+// 手数料を除いた移動・手数料内訳・participants が得られることを確認します。
+func TestSolana_NativeAndTokenTransfer(t *testing.T) {
+	const payload = `{
+	  "slot": 1, "blockTime": 1756384496,
+	  "meta": {
+	    "fee": 10000, "err": null,
+	    "preBalances":  [1000000000, 0, 1],
+	    "postBalances": [ 899990000, 100000000, 1],
+	    "preTokenBalances": [
+	      {"accountIndex": 3, "mint": "MintA", "owner": "Alice", "uiTokenAmount": {"amount": "500", "decimals": 6}}
+	    ],
+	    "postTokenBalances": [
+	      {"accountIndex": 3, "mint": "MintA", "owner": "Alice", "uiTokenAmount": {"amount": "200", "decimals": 6}},
+	      {"accountIndex": 4, "mint": "MintA", "owner": "Bob",   "uiTokenAmount": {"amount": "300", "decimals": 6}}
+	    ]
+	  },
+	  "transaction": {
+	    "message": {
+	      "accountKeys": ["Alice", "Bob", "Program", "AliceATA", "BobATA"],
+	      "instructions": [{"programIdIndex": 2}, {"programIdIndex": 2}]
+	    },
+	    "signatures": ["SIG"]
+	  }
+	}`
+	var tx sol.TransactionWithMeta
+	if err := json.Unmarshal([]byte(payload), &tx); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	ev := normalize.Solana("SIG", &tx)
+
+	if ev.Status != store.TxStatusSuccess {
+		t.Errorf("status = %s", ev.Status)
+	}
+	if ev.Token == nil || *ev.Token != "SOL" || ev.Amount == nil || *ev.Amount != 100000000 {
+		t.Errorf("primary token/amount = %v/%v", ev.Token, ev.Amount)
+	}
+	if ev.FeeBreakdown["total"] != 10000 || ev.FeeBreakdown["base"] != 5000 || ev.FeeBreakdown["priority"] != 5000 {
+		t.Errorf("fee breakdown = %v", ev.FeeBreakdown)
+	}
+
+	want := []normalize.Transfer{
+		{From: "Alice", To: "Bob", Token: "SOL", Amount: 100000000, Decimals: 9},
+		{From: "Alice", To: "Bob", Token: "MintA", Amount: 300, Decimals: 6},
+	}
+	if len(ev.Transfers) != len(want) {
+		t.Fatalf("transfers = %+v", ev.Transfers)
+	}
+	for i := range want {
+		if ev.Transfers[i] != want[i] {
+			t.Errorf("transfer[%d] = %+v, want %+v", i, ev.Transfers[i], want[i])
+		}
+	}
+	if len(ev.Participants) != 5 {
+		t.Errorf("participants = %+v", ev.Participants)
+	}
+	if len(ev.Programs) != 1 || ev.Programs[0] != "Program" {
+		t.Errorf("programs = %v", ev.Programs)
+	}
+}
+
+// TestSolana_FailedTx は失敗 Tx が status=failed になり、移動を持たないことを確認します。
+func TestSolana_FailedTx(t *testing.T) {
+	tx := sol.TransactionWithMeta{Meta: &sol.TxMeta{
+		Fee:          5000,
+		Err:          map[string]any{"InstructionError": []any{0, "Custom"}},
+		PreBalances:  []uint64{10000},
+		PostBalances: []uint64{5000},
+	}}
+	tx.Transaction.Message.AccountKeys = []string{"Payer"}
+	tx.Transaction.Signatures = []string{"SIG"}
+
+	ev := normalize.Solana("SIG", &tx)
+	if ev.Status != store.TxStatusFailed {
+		t.Errorf("status = %s", ev.Status)
+	}
+	if len(ev.Transfers) != 0 {
+		t.Errorf("failed tx must not have transfers: %+v", ev.Transfers)
+	}
+}
+
+// TestSolana_V0LookupTable はアドレス参照テーブル経由のアカウント（loadedAddresses）が
+// 残高変化・トークン所有者・participants に含まれることを確認します。
+func TestSolana_V0LookupTable(t *testing.T) {
+	const payload = `{
+	  "slot": 2, "blockTime": 1756384496, "version": 0,
+	  "meta": {
+	    "fee": 5000, "err": null,
+	    "preBalances":  [1000000000, 1, 0, 1],
+	    "postBalances": [ 949995000, 1, 50000000, 1],
+	    "preTokenBalances": [],
+	    "postTokenBalances": [
+	      {"accountIndex": 3, "mint": "MintA", "uiTokenAmount": {"amount": "0", "decimals": 6}}
+	    ],
+	    "loadedAddresses": {"writable": ["Carol"], "readonly": ["CarolATA"]}
+	  },
+	  "transaction": {
+	    "message": {
+	      "accountKeys": ["Alice", "Program"],
+	      "instructions": [{"programIdIndex": 1}]
+	    },
+	    "signatures": ["SIG"]
+	  }
+	}`
+	var tx sol.TransactionWithMeta
+	if err := json.Unmarshal([]byte(payload), &tx); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	ev := normalize.Solana("SIG", &tx)
+
+	want := normalize.Transfer{From: "Alice", To: "Carol", Token: "SOL", Amount: 50000000, Decimals: 9}
+	if len(ev.Transfers) != 1 || ev.Transfers[0] != want {
+		t.Fatalf("transfers = %+v, want %+v", ev.Transfers, want)
+	}
+	got := map[string]bool{}
+	for _, p := range ev.Participants {
+		got[p.Address] = true
+	}
+	for _, a := range []string{"Alice", "Program", "Carol", "CarolATA"} {
+		if !got[a] {
+			t.Errorf("participant %s missing: %+v", a, ev.Participants)
+		}
+	}
+	if !ev.Involves("Carol") {
+		t.Error("event must involve the lookup-table account")
+	}
+
+	// raw から再正規化しても同じ結果になる（loadedAddresses が raw に残る）
+	var again sol.TransactionWithMeta
+	if err := json.Unmarshal(ev.Raw, &again); err != nil {
+		t.Fatal(err)
+	}
+	if ev2 := normalize.Solana("SIG", &again); len(ev2.Transfers) != 1 || ev2.Transfers[0] != want {
+		t.Errorf("transfers from raw = %+v", ev2.Transfers)
+	}
+}
+
+// TestSui_TransferExcludesGas は送信者の SUI 変化からガス代が除かれることを確認します。
+func TestSui_TransferExcludesGas(t *testing.T) {
+	const payload = `{
+	  "digest": "DIGEST",
+	  "timestampMs": "1756384496000",
+	  "transaction": {"data": {
+	    "sender": "0xa11ce",
+	    "message": {
+	      "inputs": [{"type": "pure", "valueType": "address", "value": "0xb0b"}],
+	      "transactions": [{"kind": "TransferObjects"}]
+	    }
+	  }},
+	  "effects": {
+	    "status": {"status": "success"},
+	    "gasUsed": {"computationCost": "1000", "storageCost": "2000", "storageRebate": "500", "nonRefundableStorageFee": "5"}
+	  },
+	  "balanceChanges": [
+	    {"owner": {"AddressOwner": "0xa11ce"}, "coinType": "0x2::sui::SUI", "amount": "-1002500"},
+	    {"owner": {"AddressOwner": "0xb0b"},   "coinType": "0x2::sui::SUI", "amount": "1000000"},
+	    {"owner": "Immutable", "coinType": "0x2::sui::SUI", "amount": "0"}
+	  ]
+	}`
+	var tx sui.TransactionBlockDetailed
+	if err := json.Unmarshal([]byte(payload), &tx); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	ev := normalize.Sui(&tx, 0)
+
+	if ev.Sender == nil || *ev.Sender != "0xa11ce" || ev.Receiver == nil || *ev.Receiver != "0xb0b" {
+		t.Errorf("sender/receiver = %v/%v", ev.Sender, ev.Receiver)
+	}
+	if ev.FeeBreakdown["net"] != 2500 || ev.Fee == nil || *ev.Fee != 2500 {
+		t.Errorf("net fee = %d, fee = %v", ev.FeeBreakdown["net"], ev.Fee)
+	}
+	if ev.TS.UnixMilli() != 1756384496000 {
+		t.Errorf("ts = %v", ev.TS)
+	}
+	want := normalize.Transfer{From: "0xa11ce", To: "0xb0b", Token: "SUI", Amount: 1000000, Decimals: 9}
+	if len(ev.Transfers) != 1 || ev.Transfers[0] != want {
+		t.Errorf("transfers = %+v", ev.Transfers)
+	}
+}
+
+// TestEvent_Involves は送信者・受信者・関与アドレスで一致を見て、Sui の表記（大文字・0x の有無）の違いを
+// 無視することを確認します（walletctl backfill sui の絞り込みに使う）
+func TestEvent_Involves(t *testing.T) {
+	sender := "0xa11ce"
+	ev := normalize.Event{Chain: "sui", Sender: &sender, Participants: []store.Participant{
+		{Address: "0xb0b", Role: store.RoleAccount},
+	}}
+	for _, addr := range []string{"0xa11ce", "A11CE", "0xB0B"} {
+		if !ev.Involves(addr) {
+			t.Errorf("Involves(%q) = false", addr)
+		}
+	}
+	if ev.Involves("0xca401") {
+		t.Error("unrelated address matched")
+	}
+}
+
+// TestPairTransfers_MintAndBurn は対応の取れない増減がミント / バーンとして残ることを確認します。
+func TestPairTransfers_MintAndBurn(t *testing.T) {
+	got := normalize.PairTransfers([]normalize.BalanceChange{
+		{Address: "A", Token: "T", Delta: -30},
+		{Address: "B", Token: "T", Delta: 50},
+		{Address: "C", Token: "U", Delta: -7},
+	})
+	want := []normalize.Transfer{
+		{From: "A", To: "B", Token: "T", Amount: 30},
+		{To: "B", Token: "T", Amount: 20},
+		{From: "C", Token: "U", Amount: 7},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("[%d] got %+v want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestFormatAmount は最小単位の整数に decimals を適用した表記を確認します。
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		decimals int
+		want     string
+	}{
+		{1500000000, 9, "1.5"},
+		{5000, 9, "0.000005"},
+		{1000000000, 9, "1"},
+		{-250, 2, "-2.5"},
+		{42, 0, "42"},
+		{0, 6, "0"},
+	}
+	for _, tt := range tests {
+		if got := normalize.FormatAmount(tt.amount, tt.decimals); got != tt.want {
+			t.Errorf("FormatAmount(%d, %d) = %q, want %q", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package notifytest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/notify"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+const (
+	hot = "HotWa11et11111111111111111111111"
+	sig = "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func sampleAlert() store.Alert {
+	return store.Alert{
+		ID:       7,
+		Tenant:   "acme",
+		RuleType: store.RuleIncomingTransfer,
+		Chain:    "solana",
+		TxHash:   sig,
+		TS:       time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC),
+		Address:  ptr(hot),
+		Token:    ptr("SOL"),
+		Amount:   ptr(int64(1_500_000_000)),
+		Decimals: ptr(9),
+		Message:  "incoming 1.5 SOL to " + hot,
+	}
+}
+
+func rawConfig(t *testing.T, c notify.Config) json.RawMessage {
+	t.Helper()
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// hookServer は受け取った JSON を記録し、fail なら 500 を返すスタンドイン
+type hookServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	paths  []string
+	bodies []map[string]any
+}
+
+func newHookServer(t *testing.T, fail bool) *hookServer {
+	h := &hookServer{}
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		h.mu.Lock()
+		h.paths = append(h.paths, r.URL.Path)
+		h.bodies = append(h.bodies, body)
+		h.mu.Unlock()
+		if fail {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(h.Close)
+	return h
+}
+
+func (h *hookServer) last(t *testing.T) (string, map[string]any) {
+	t.Helper()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.bodies) == 0 {
+		t.Fatal("no request received")
+	}
+	return h.paths[len(h.paths)-1], h.bodies[len(h.bodies)-1]
+}
+
+func TestSendAlert_Webhooks(t *testing.T) {
+	srv := newHookServer(t, false)
+
+	tests := []struct {
+		name  string
+		ch    store.NotificationChannel
+		path  string
+		field string
+	}{
+		{"slack", store.NotificationChannel{Type: store.ChannelSlack,
+			Config: rawConfig(t, notify.Config{WebhookURL: srv.URL + "/services/T/B/X"})}, "/services/T/B/X", "text"},
+		{"discord", store.NotificationChannel{Type: store.ChannelDiscord,
+			Config: rawConfig(t, notify.Config{WebhookURL: srv.URL + "/api/webhooks/1/abc"})}, "/api/webhooks/1/abc", "content"},
+		{"telegram", store.NotificationChannel{Type: store.ChannelTelegram,
+			Config: rawConfig(t, notify.Config{BotToken: "123:tok", ChatID: "-100", APIURL: srv.URL})}, "/bot123:tok/sendMessage", "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := notify.SendAlert(context.Background(), tt.ch, sampleAlert()); err != nil {
+				t.Fatalf("SendAlert: %v", err)
+			}
+			path, body := srv.last(t)
+			if path != tt.path {
+				t.Errorf("path = %q, want %q", path, tt.path)
+			}
+			text, _ := body[tt.field].(string)
+			for _, want := range []string{"Amount: 1.5 SOL", "https://solscan.io/tx/" + sig} {
+				if !strings.Contains(text, want) {
+					t.Errorf("%s = %q, want it to contain %q", tt.field, text, want)
+				}
+			}
+			if tt.name == "telegram" && body["chat_id"] != "-100" {
+				t.Errorf("chat_id = %v", body["chat_id"])
+			}
+		})
+	}
+}
+
+// TestSendAlert_DialErrorHidesSecrets は接続できないときのエラー（last_error・ログに残る）に
+// Telegram のトークンや webhook URL のパスが含まれないことを確認します
+func TestSendAlert_DialErrorHidesSecrets(t *testing.T) {
+	// 閉じたポートを用意して接続を失敗させる
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := "http://" + ln.Addr().String()
+	ln.Close()
+
+	tests := []struct {
+		name   string
+		ch     store.NotificationChannel
+		secret string
+	}{
+		{"telegram", store.NotificationChannel{Type: store.ChannelTelegram,
+			Config: rawConfig(t, notify.Config{BotToken: "123456:SECRET-TOKEN", ChatID: "-100", APIURL: dead})}, "SECRET-TOKEN"},
+		{"slack", store.NotificationChannel{Type: store.ChannelSlack,
+			Config: rawConfig(t, notify.Config{WebhookURL: dead + "/services/T000/B000/SECRETPATH"})}, "SECRETPATH"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := notify.SendAlert(context.Background(), tt.ch, sampleAlert())
+			if err == nil {
+				t.Fatal("want a dial error")
+			}
+			if strings.Contains(err.Error(), tt.secret) {
+				t.Fatalf("error leaks the secret: %v", err)
+			}
+			if !strings.Contains(err.Error(), ln.Addr().String()) {
+				t.Errorf("error should still name the host: %v", err)
+			}
+		})
+	}
+
+	// 伏せる前に記録された last_error も API の表示では伏せる
+	cfg := notify.Config{BotToken: "123456:SECRET-TOKEN"}
+	msg := cfg.RedactText(`Post "https://api.telegram.org/bot123456:SECRET-TOKEN/sendMessage": dial tcp: i/o timeout`)
+	if strings.Contains(msg, "SECRET-TOKEN") {
+		t.Fatalf("RedactText = %q", msg)
+	}
+}
+
+func TestRender_CustomTemplate(t *testing.T) {
+	a := sampleAlert()
+	a.Chain = "sui"
+	m, err := notify.Render(store.ChannelSlack, "{{.Amount}} {{.Token}} {{.TxURL}}", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1.5 SOL https://suiscan.xyz/mainnet/tx/" + sig; m.Text != want {
+		t.Fatalf("text = %q, want %q", m.Text, want)
+	}
+	// 存在しない項目はエラーにする
+	if _, err := notify.Render(store.ChannelSlack, "{{.Nope}}", a); err == nil {
+		t.Fatal("expected error for unknown template field")
+	}
+}
+
+// fakeChannels は ChannelStore のスタブ。送信結果をチャネルごとに数える
+type fakeChannels struct {
+	mu       sync.Mutex
+	channels []store.NotificationChannel
+	ok, fail map[int64]int
+	lastErr  map[int64]string
+}
+
+func (f *fakeChannels) ListNotificationChannels(ctx context.Context, tenant string, enabledOnly bool) ([]store.NotificationChannel, error) {
+	var out []store.NotificationChannel
+	for _, c := range f.channels {
+		if c.Tenant == tenant && (!enabledOnly || c.Enabled) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeChannels) RecordNotificationResult(ctx context.Context, id int64, sendErr error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if sendErr != nil {
+		f.fail[id]++
+		f.lastErr[id] = sendErr.Error()
+	} else {
+		f.ok[id]++
+	}
+	return nil
+}
+
+func TestDispatch_RecordsFailuresPerChannel(t *testing.T) {
+	good := newHookServer(t, false)
+	bad := newHookServer(t, true)
+	disabled := newHookServer(t, false)
+
+	st := &fakeChannels{
+		channels: []store.NotificationChannel{
+			{ID: 1, Tenant: "acme", Type: store.ChannelSlack, Enabled: true, Config: rawConfig(t, notify.Config{WebhookURL: bad.URL})},
+			{ID: 2, Tenant: "acme", Type: store.ChannelDiscord, Enabled: true, Config: rawConfig(t, notify.Config{WebhookURL: good.URL})},
+			{ID: 3, Tenant: "acme", Type: store.ChannelSlack, Enabled: false, Config: rawConfig(t, notify.Config{WebhookURL: disabled.URL})},
+			{ID: 4, Tenant: "other", Type: store.ChannelSlack, Enabled: true, Config: rawConfig(t, notify.Config{WebhookURL: disabled.URL})},
+		},
+		ok: map[int64]int{}, fail: map[int64]int{}, lastErr: map[int64]string{},
+	}
+	a1, a2 := sampleAlert(), sampleAlert()
+	a2.ID, a2.TxHash = 8, "sig2"
+	notify.NewDispatcher(st, 0).Dispatch(context.Background(), []store.Alert{a1, a2})
+
+	// 失敗したチャネルがあっても他のチャネルには届く
+	if st.fail[1] != 2 || !strings.Contains(st.lastErr[1], "500") {
+		t.Errorf("channel 1 failures = %d (%q), want 2 with status 500", st.fail[1], st.lastErr[1])
+	}
+	if st.ok[2] != 2 || len(good.bodies) != 2 {
+		t.Errorf("channel 2 ok = %d, received = %d, want 2", st.ok[2], len(good.bodies))
+	}
+	if len(disabled.bodies) != 0 || st.ok[3]+st.fail[3]+st.ok[4]+st.fail[4] != 0 {
+		t.Errorf("disabled / other-tenant channels must not be used")
+	}
+}
+
+// smtpServer は 1 通を受け取るだけの最小限の SMTP スタンドイン
+func smtpServer(t *testing.T) (addr string, got <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM"), strings.HasPrefix(cmd, "RCPT TO"):
+				data.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				reply("250 queued")
+				ch <- data.String()
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+func TestSendAlert_Email(t *testing.T) {
+	addr, got := smtpServer(t)
+	ch := store.NotificationChannel{Type: store.ChannelEmail, Config: rawConfig(t, notify.Config{
+		SMTPAddr: addr, From: "watcher@example.com", To: []string{"ops@example.com"},
+	})}
+	if err := notify.SendAlert(context.Background(), ch, sampleAlert()); err != nil {
+		t.Fatalf("SendAlert: %v", err)
+	}
+	select {
+	case msg := <-got:
+		for _, want := range []string{
+			"MAIL FROM:<watcher@example.com>",
+			"RCPT TO:<ops@example.com>",
+			"Subject: [wallet-watcher] [solana] incoming_transfer alert",
+			"Amount:  1.5 SOL",
+			"https://solscan.io/tx/" + sig,
+			"https://solscan.io/account/" + hot,
+		} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("message does not contain %q:\n%s", want, msg)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	bad := []struct {
+		typ, raw string
+	}{
+		{"slack", `{}`},
+		{"slack", `{"webhook_url":"ftp://x/y"}`},
+		{"telegram", `{"bot_token":"t"}`},
+		{"email", `{"smtp_addr":"mail","from":"a@example.com","to":["b@example.com"]}`},
+		{"email", `{"smtp_addr":"mail:25","from":"a@example.com","to":["nope"]}`},
+		{"discord", `{"webhook_url":"https://d/x","template":"{{.Amount"}`},
+		{"pager", `{}`},
+		{"slack", ``},
+	}
+	for _, tt := range bad {
+		if _, err := notify.ParseConfig(tt.typ, json.RawMessage(tt.raw)); err == nil {
+			t.Errorf("ParseConfig(%s, %s): expected error", tt.typ, tt.raw)
+		}
+	}
+
+	c, err := notify.ParseConfig("slack", json.RawMessage(`{"webhook_url":"https://hooks.slack.com/services/T/B/secret"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := c.Redacted(); r.WebhookURL != "https://hooks.slack.com/***" {
+		t.Errorf("redacted webhook_url = %q", r.WebhookURL)
+	}
+	tg := notify.Config{BotToken: "123:tok", ChatID: "1", Password: "pw"}.Redacted()
+	if tg.BotToken != "***" || tg.Password != "***" || tg.ChatID != "1" {
+		t.Errorf("redacted = %+v", tg)
+	}
+}
+
+// TestRender_AddressLabel は監視アドレスのラベルが既定テンプレートに載ることを確認する
+func TestRender_AddressLabel(t *testing.T) {
+	a := sampleAlert()
+	a.AddressLabel = ptr("Hot wallet 3")
+	for typ, want := range map[string]string{
+		store.ChannelSlack:    "Address: Hot wallet 3 <https://solscan.io/account/" + hot + "|" + hot + ">",
+		store.ChannelDiscord:  "Address: Hot wallet 3 [" + hot + "]",
+		store.ChannelTelegram: "Address: Hot wallet 3",
+		store.ChannelEmail:    "Address: Hot wallet 3 (" + hot + ")",
+	} {
+		m, err := notify.Render(typ, "", a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(m.Text, want) {
+			t.Errorf("%s: text = %q, want it to contain %q", typ, m.Text, want)
+		}
+	}
+	// ラベルが無ければ従来どおり
+	m, err := notify.Render(store.ChannelEmail, "", sampleAlert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(m.Text, "Address: "+hot+"\n") {
+		t.Errorf("text = %q", m.Text)
+	}
+}
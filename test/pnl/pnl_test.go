@@ -0,0 +1,275 @@
+package pnltest
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/normalize"
+	"github.com/you/wallet-watcher/internal/pnl"
+	"github.com/you/wallet-watcher/internal/pricing"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+func day(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 12, 0, 0, 0, time.UTC) }
+
+func usd(v float64) *float64 { return &v }
+
+// scenario は 2 回取得して 1 回で 15 を送付し、その後に失敗した Tx の手数料を払う
+func scenario() []pnl.Event {
+	return []pnl.Event{
+		{TxHash: "buy1", TS: day(2024, 1, 10), Token: "TOK", Delta: 10, ValueUSD: usd(100)},
+		{TxHash: "buy2", TS: day(2024, 6, 1), Token: "TOK", Delta: 10, ValueUSD: usd(200)},
+		{TxHash: "sell", TS: day(2025, 3, 1), Token: "TOK", Delta: -15, ValueUSD: usd(450), FeeToken: "SOL", Fee: 5000, FeeUSD: usd(1)},
+		{TxHash: "failed", TS: day(2025, 4, 1), FeeToken: "SOL", Fee: 5000, FeeUSD: usd(1)},
+	}
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+func token(t *testing.T, rep pnl.Report, name string) pnl.TokenSummary {
+	t.Helper()
+	for _, s := range rep.Tokens {
+		if s.Token == name {
+			return s
+		}
+	}
+	t.Fatalf("token %s missing in %+v", name, rep.Tokens)
+	return pnl.TokenSummary{}
+}
+
+// TestReplay_Methods は FIFO / LIFO / 移動平均で実現損益・保有原価・保有期間の区分が変わることを確認します
+func TestReplay_Methods(t *testing.T) {
+	cases := []struct {
+		method            string
+		realized, basis   float64
+		disposals         int
+		shortTerm, longTm float64
+	}{
+		// 対価 450 - 手数料 1 = 449。FIFO は原価 100 + 50（2 つ目の半分）
+		{pnl.FIFO, 249, 100, 2, 149.666666667 - 100, 299.333333333 - 100},
+		// LIFO は原価 200 + 50（1 つ目の半分）
+		{pnl.LIFO, 199, 50, 2, 299.333333333 - 200, 149.666666667 - 50},
+		// 移動平均は単価 15 × 15 = 225。保有期間は区分しない
+		{pnl.Average, 224, 75, 1, 0, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			res := pnl.Replay(tc.method, scenario())
+			rep := pnl.BuildReport("solana", "Addr", res, pnl.ReportOptions{MarketUSD: map[string]float64{"TOK": 250}})
+
+			tok := token(t, rep, "TOK")
+			if !near(tok.RealizedUSD, tc.realized) || !near(tok.CostBasisUSD, tc.basis) || tok.Held != 5 {
+				t.Fatalf("TOK = %+v", tok)
+			}
+			if tok.UnrealizedUSD == nil || !near(*tok.UnrealizedUSD, 250-tc.basis) {
+				t.Fatalf("unrealized = %v", tok.UnrealizedUSD)
+			}
+			n := 0
+			for _, d := range res.Disposals {
+				if d.Token == "TOK" {
+					n++
+				}
+			}
+			if n != tc.disposals {
+				t.Fatalf("TOK disposals = %d, want %d: %+v", n, tc.disposals, res.Disposals)
+			}
+
+			if len(rep.TaxYears) != 1 || rep.TaxYears[0].Year != 2025 {
+				t.Fatalf("tax years = %+v", rep.TaxYears)
+			}
+			ty := rep.TaxYears[0]
+			if !near(ty.ShortTermUSD, tc.shortTerm) || !near(ty.LongTermUSD, tc.longTm) {
+				t.Fatalf("short/long = %v / %v, want %v / %v", ty.ShortTermUSD, ty.LongTermUSD, tc.shortTerm, tc.longTm)
+			}
+			// 送付を伴わない失敗 Tx の手数料は経費
+			if !near(ty.ExpensesUSD, 1) || !near(rep.Totals.FeesUSD, 2) {
+				t.Fatalf("expenses = %v, fees = %v", ty.ExpensesUSD, rep.Totals.FeesUSD)
+			}
+		})
+	}
+}
+
+// TestReplay_FeesAndUnmatched は手数料がネイティブトークンの処分になり、取得記録の無い数量は原価 0 になることを確認します
+func TestReplay_FeesAndUnmatched(t *testing.T) {
+	res := pnl.Replay(pnl.FIFO, scenario())
+	var fees []store.CostDisposal
+	for _, d := range res.Disposals {
+		if d.Kind == pnl.KindFee {
+			fees = append(fees, d)
+		}
+	}
+	if len(fees) != 2 {
+		t.Fatalf("fee disposals = %+v", fees)
+	}
+	for _, d := range fees {
+		if d.Token != "SOL" || d.Amount != 5000 || !d.Unmatched || d.CostUSD != 0 || !near(d.GainUSD, 1) || d.LotSeq != nil {
+			t.Fatalf("fee disposal = %+v", d)
+		}
+	}
+
+	sol := token(t, pnl.BuildReport("solana", "Addr", res, pnl.ReportOptions{}), "SOL")
+	if sol.Unmatched != 10000 || sol.Held != 0 || !near(sol.FeesUSD, 2) {
+		t.Fatalf("SOL = %+v", sol)
+	}
+}
+
+// TestReplay_Unpriced は価格の分からない取得を原価 0 のロットとして数えることを確認します
+func TestReplay_Unpriced(t *testing.T) {
+	res := pnl.Replay(pnl.FIFO, []pnl.Event{
+		{TxHash: "a", TS: day(2025, 1, 1), Token: "RARE", Delta: 3},
+		{TxHash: "b", TS: day(2025, 2, 1), Token: "RARE", Delta: -1, ValueUSD: usd(9)},
+	})
+	if len(res.Lots) != 1 || !res.Lots[0].Unpriced || res.Lots[0].Remaining != 2 {
+		t.Fatalf("lots = %+v", res.Lots)
+	}
+	rep := pnl.BuildReport("solana", "Addr", res, pnl.ReportOptions{Year: 2025, OpenLots: true})
+	if tok := token(t, rep, "RARE"); tok.Unpriced != 1 || !near(tok.RealizedUSD, 9) {
+		t.Fatalf("RARE = %+v", tok)
+	}
+	if len(rep.OpenLots) != 1 || len(rep.TaxYears) != 1 || len(rep.TaxYears[0].Details) != 1 {
+		t.Fatalf("report = %+v", rep)
+	}
+}
+
+// TestBuildReport_YearWithoutDisposals は指定した年に処分が無くても空の集計を返すことを確認します
+func TestBuildReport_YearWithoutDisposals(t *testing.T) {
+	rep := pnl.BuildReport("solana", "Addr", pnl.Replay(pnl.FIFO, scenario()), pnl.ReportOptions{Year: 2024})
+	if len(rep.TaxYears) != 1 || rep.TaxYears[0].Year != 2024 || rep.TaxYears[0].Disposals != 0 {
+		t.Fatalf("tax years = %+v", rep.TaxYears)
+	}
+}
+
+// TestFromTxEvent はアドレスから見た向き・失敗 Tx・Sui のアドレス表記揺れを確認します
+func TestFromTxEvent(t *testing.T) {
+	str := func(s string) *string { return &s }
+	i64 := func(v int64) *int64 { return &v }
+	me, other := "0xABCDEF", "0x1234"
+	base := store.TxEvent{TxHash: "t", TS: day(2025, 1, 1), Token: str("SUI"), Amount: i64(7), Fee: i64(3)}
+
+	out := base
+	out.Sender, out.Receiver = str("abcdef"), str(other)
+	if e := pnl.FromTxEvent("sui", me, out); e.Delta != -7 || e.Fee != 3 || e.FeeToken != "SUI" {
+		t.Fatalf("out = %+v", e)
+	}
+
+	in := base
+	in.Sender, in.Receiver = str(other), str(me)
+	if e := pnl.FromTxEvent("sui", me, in); e.Delta != 7 || e.Fee != 0 {
+		t.Fatalf("in = %+v", e)
+	}
+
+	failed := out
+	failed.Status = str(store.TxStatusFailed)
+	if e := pnl.FromTxEvent("sui", me, failed); e.Delta != 0 || e.Fee != 3 {
+		t.Fatalf("failed = %+v", e)
+	}
+
+	self := base
+	self.Sender, self.Receiver = str(me), str(me)
+	if e := pnl.FromTxEvent("sui", me, self); e.Delta != 0 || e.Fee != 3 {
+		t.Fatalf("self = %+v", e)
+	}
+}
+
+// TestFromEvent はスワップの両方の脚が取得と処分になり、手数料が処分の側に付くことを確認します
+func TestFromEvent(t *testing.T) {
+	me, pool := "0xabc", "0xpool"
+	fee := int64(3)
+	swap := normalize.Event{Chain: "sui", TxHash: "swap", TS: day(2025, 2, 1), Sender: &me, Fee: &fee, Status: store.TxStatusSuccess,
+		Transfers: []normalize.Transfer{
+			{From: "0xABC", To: pool, Token: "SUI", Amount: 10},
+			{From: pool, To: me, Token: "USDC", Amount: 25},
+			// 自己送金は差し引き 0
+			{From: me, To: me, Token: "USDC", Amount: 5},
+		}}
+	got := pnl.FromEvent(me, swap)
+	if len(got) != 2 {
+		t.Fatalf("events = %+v", got)
+	}
+	if got[0].Token != "SUI" || got[0].Delta != -10 || got[0].Fee != 3 || got[0].FeeToken != "SUI" {
+		t.Errorf("sell leg = %+v", got[0])
+	}
+	if got[1].Token != "USDC" || got[1].Delta != 25 || got[1].Fee != 0 {
+		t.Errorf("buy leg = %+v", got[1])
+	}
+
+	// 失敗した Tx は手数料だけ
+	failed := swap
+	failed.Status = store.TxStatusFailed
+	if got := pnl.FromEvent(me, failed); len(got) != 1 || got[0].Delta != 0 || got[0].Fee != 3 {
+		t.Errorf("failed = %+v", got)
+	}
+	// 送信者でなければ手数料は付かない
+	if got := pnl.FromEvent(pool, swap); len(got) != 2 || got[0].Fee != 0 || got[1].Fee != 0 {
+		t.Errorf("pool = %+v", got)
+	}
+}
+
+// TestFromEvent_SuiStorageRebate は Sui の手数料をストレージリベートを差し引いた実質の額で処分し、
+// 送付した SUI と合わせて送信者の残高の減りと一致することを確認します
+func TestFromEvent_SuiStorageRebate(t *testing.T) {
+	payload := `{
+	  "digest": "rebate", "timestampMs": "1756384496000",
+	  "transaction": {"data": {
+	    "sender": "0xa11ce",
+	    "message": {"inputs": [{"type": "pure", "valueType": "address", "value": "0xb0b"}], "transactions": [{"kind": "TransferObjects"}]}
+	  }},
+	  "effects": {
+	    "status": {"status": "success"},
+	    "gasUsed": {"computationCost": "1000", "storageCost": "2000", "storageRebate": "1800", "nonRefundableStorageFee": "18"}
+	  },
+	  "balanceChanges": [
+	    {"owner": {"AddressOwner": "0xa11ce"}, "coinType": "0x2::sui::SUI", "amount": "-1001200"},
+	    {"owner": {"AddressOwner": "0xb0b"},   "coinType": "0x2::sui::SUI", "amount": "1000000"}
+	  ]
+	}`
+	ev, ok := normalize.FromRaw("sui", "rebate", day(2025, 3, 1), []byte(payload))
+	if !ok {
+		t.Fatal("FromRaw failed")
+	}
+	got := pnl.FromEvent("0xa11ce", ev)
+	if len(got) != 1 || got[0].Token != "SUI" || got[0].Delta != -1000000 || got[0].Fee != 1200 {
+		t.Fatalf("events = %+v", got)
+	}
+	if got[0].Delta-got[0].Fee != -1001200 {
+		t.Fatalf("sender balance change = %d", got[0].Delta-got[0].Fee)
+	}
+
+	// 保存済みの行（fee は normalize が入れた実質の額）からも同じ手数料になる
+	in := ev.Input()
+	te := store.TxEvent{Chain: "sui", TxHash: "rebate", TS: ev.TS, Sender: in.Sender, Receiver: in.Receiver,
+		Token: in.Token, Amount: in.Amount, Fee: in.Fee, Status: in.Status}
+	if e := pnl.FromTxEvent("sui", "0xa11ce", te); e.Fee != 1200 || e.Delta != -1000000 {
+		t.Fatalf("from tx event = %+v", e)
+	}
+}
+
+// fakeValuer は 1 最小単位 = 1 USD として評価し、FREE は価格不明にする
+type fakeValuer struct{}
+
+func (fakeValuer) ValuesAt(ctx context.Context, items []pricing.Item) []*float64 {
+	out := make([]*float64, len(items))
+	for i, it := range items {
+		if it.Token != "FREE" {
+			out[i] = usd(float64(it.Amount))
+		}
+	}
+	return out
+}
+
+// TestPrice は移動量と手数料をそれぞれ評価することを確認します
+func TestPrice(t *testing.T) {
+	events := []pnl.Event{
+		{Token: "TOK", Delta: -4, FeeToken: "SOL", Fee: 2},
+		{Token: "FREE", Delta: 5},
+	}
+	pnl.Price(context.Background(), fakeValuer{}, events)
+	if events[0].ValueUSD == nil || *events[0].ValueUSD != 4 || events[0].FeeUSD == nil || *events[0].FeeUSD != 2 {
+		t.Fatalf("priced = %+v", events[0])
+	}
+	if events[1].ValueUSD != nil {
+		t.Fatalf("FREE priced: %v", *events[1].ValueUSD)
+	}
+}
@@ -0,0 +1,160 @@
+package portfoliotest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/portfolio"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// fakeSnapshots は Snapshots のスタブ
+type fakeSnapshots struct {
+	mu    sync.Mutex
+	snaps map[store.WatchKey]store.BalanceSnapshot
+	saved []store.WatchKey
+}
+
+func (f *fakeSnapshots) GetBalanceSnapshots(ctx context.Context, keys []store.WatchKey) (map[store.WatchKey]store.BalanceSnapshot, error) {
+	out := map[store.WatchKey]store.BalanceSnapshot{}
+	for _, k := range keys {
+		if s, ok := f.snaps[k]; ok {
+			out[k] = s
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSnapshots) SaveBalanceSnapshot(ctx context.Context, chain, address string, balances []store.TokenBalance) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.saved = append(f.saved, store.WatchKey{Chain: chain, Address: address})
+	return nil
+}
+
+var wallets = []portfolio.Wallet{
+	{Chain: "solana", Address: "SolA"},
+	{Chain: "solana", Address: "SolB"},
+	{Chain: "sui", Address: "0xa"},
+	{Chain: "sui", Address: "0xbroken"},
+}
+
+func fakeFetch(ctx context.Context, chain, address string) ([]store.TokenBalance, error) {
+	switch address {
+	case "SolA":
+		return []store.TokenBalance{{Token: "SOL", Amount: 1_000}, {Token: "USDCmint", Amount: 50}}, nil
+	case "SolB":
+		return []store.TokenBalance{{Token: "SOL", Amount: 2_000}}, nil
+	case "0xa":
+		return []store.TokenBalance{{Token: "SUI", Amount: 7}}, nil
+	}
+	return nil, errors.New("rpc timeout")
+}
+
+// TestPortfolio_AggregatesAndReportsFailures はチェーン × トークンの合算と、失敗したアドレスだけのエラーを確認する
+func TestPortfolio_AggregatesAndReportsFailures(t *testing.T) {
+	p := portfolio.Aggregate(portfolio.Collect(context.Background(), wallets, fakeFetch, portfolio.Options{}))
+
+	want := []portfolio.TokenTotal{
+		{Chain: "solana", Token: "SOL", Amount: 3_000, Wallets: 2},
+		{Chain: "solana", Token: "USDCmint", Amount: 50, Wallets: 1},
+		{Chain: "sui", Token: "SUI", Amount: 7, Wallets: 1},
+	}
+	if len(p.Totals) != len(want) {
+		t.Fatalf("totals = %+v", p.Totals)
+	}
+	for i := range want {
+		if p.Totals[i] != want[i] {
+			t.Errorf("totals[%d] = %+v, want %+v", i, p.Totals[i], want[i])
+		}
+	}
+	if p.Failed != 1 || len(p.Wallets) != 4 {
+		t.Fatalf("failed = %d, wallets = %d", p.Failed, len(p.Wallets))
+	}
+	broken := p.Wallets[3]
+	if broken.Address != "0xbroken" || broken.Error != "rpc timeout" || len(broken.Balances) != 0 || broken.Source != "" {
+		t.Errorf("broken wallet = %+v", broken)
+	}
+	if ok := p.Wallets[0]; ok.Error != "" || ok.Source != portfolio.SourceLive || ok.FetchedAt == nil {
+		t.Errorf("wallet[0] = %+v", ok)
+	}
+}
+
+// TestCollect_BoundedConcurrency は RPC の同時呼び出し数が Concurrency を超えないことを確認する
+func TestCollect_BoundedConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	fetch := func(ctx context.Context, chain, address string) ([]store.TokenBalance, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return nil, nil
+	}
+	many := make([]portfolio.Wallet, 20)
+	for i := range many {
+		many[i] = portfolio.Wallet{Chain: "solana", Address: string(rune('A' + i))}
+	}
+	out := portfolio.Collect(context.Background(), many, fetch, portfolio.Options{Concurrency: 3})
+	if peak > 3 {
+		t.Fatalf("peak concurrency = %d, want <= 3", peak)
+	}
+	for _, w := range out {
+		if w.Error != "" || w.Balances == nil {
+			t.Fatalf("wallet = %+v", w)
+		}
+	}
+}
+
+// TestCollect_Snapshots は max_age 以内のスナップショットを使い、RPC で取った分を保存することを確認する
+func TestCollect_Snapshots(t *testing.T) {
+	snaps := &fakeSnapshots{snaps: map[store.WatchKey]store.BalanceSnapshot{
+		// 新しいスナップショット → RPC を呼ばない
+		{Chain: "solana", Address: "SolA"}: {Balances: []store.TokenBalance{{Token: "SOL", Amount: 42}}, FetchedAt: time.Now().Add(-10 * time.Second)},
+		// 古いスナップショット → RPC で取り直す
+		{Chain: "solana", Address: "SolB"}: {Balances: []store.TokenBalance{{Token: "SOL", Amount: 1}}, FetchedAt: time.Now().Add(-time.Hour)},
+	}}
+	var calls []string
+	var mu sync.Mutex
+	fetch := func(ctx context.Context, chain, address string) ([]store.TokenBalance, error) {
+		mu.Lock()
+		calls = append(calls, address)
+		mu.Unlock()
+		return fakeFetch(ctx, chain, address)
+	}
+	out := portfolio.Collect(context.Background(), wallets[:2], fetch, portfolio.Options{MaxAge: time.Minute, Snapshots: snaps})
+
+	if len(calls) != 1 || calls[0] != "SolB" {
+		t.Fatalf("rpc calls = %v, want [SolB]", calls)
+	}
+	if out[0].Source != portfolio.SourceCache || out[0].Balances[0].Amount != 42 {
+		t.Errorf("wallet[0] = %+v", out[0])
+	}
+	if out[1].Source != portfolio.SourceLive || out[1].Balances[0].Amount != 2_000 {
+		t.Errorf("wallet[1] = %+v", out[1])
+	}
+	if len(snaps.saved) != 1 || snaps.saved[0].Address != "SolB" {
+		t.Errorf("saved = %v", snaps.saved)
+	}
+}
+
+// TestCollect_Cancelled は打ち切られたときも未取得のアドレスをエラーとして返すことを確認する
+func TestCollect_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fetch := func(ctx context.Context, chain, address string) ([]store.TokenBalance, error) {
+		return nil, ctx.Err()
+	}
+	p := portfolio.Aggregate(portfolio.Collect(ctx, wallets, fetch, portfolio.Options{Concurrency: 1}))
+	if p.Failed != len(wallets) || len(p.Totals) != 0 {
+		t.Fatalf("portfolio = %+v", p)
+	}
+}
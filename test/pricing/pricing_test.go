@@ -0,0 +1,315 @@
+package pricingtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/pricing"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+// fakeTable は価格表のスタブ（token_prices / token_decimals と同じ検索規則）
+type fakeTable struct {
+	mu       sync.Mutex
+	prices   []store.TokenPrice
+	decimals map[string]int
+	upserts  int
+}
+
+func (f *fakeTable) TokenPricesAt(ctx context.Context, lookups []store.PriceLookup, tolerance time.Duration) ([]*store.TokenPrice, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]*store.TokenPrice, len(lookups))
+	for i, l := range lookups {
+		for j := range f.prices {
+			p := f.prices[j]
+			if p.Token != l.Token || p.TS.After(l.At) || l.At.Sub(p.TS) >= tolerance {
+				continue
+			}
+			if out[i] == nil || p.TS.After(out[i].TS) {
+				out[i] = &p
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeTable) UpsertTokenPrices(ctx context.Context, prices []store.TokenPrice) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.upserts++
+	f.prices = append(f.prices, prices...)
+	return nil
+}
+
+func (f *fakeTable) TokenDecimals(ctx context.Context, tokens []string) (map[string]int, error) {
+	out := map[string]int{}
+	for _, t := range tokens {
+		if d, ok := f.decimals[t]; ok {
+			out[t] = d
+		}
+	}
+	return out, nil
+}
+
+// fakeSource は呼び出し回数を数える PriceSource
+type fakeSource struct {
+	usd   map[string]float64
+	calls int
+}
+
+func (f *fakeSource) Name() string { return "fake" }
+
+func (f *fakeSource) Prices(ctx context.Context, tokens []string, at time.Time) ([]store.TokenPrice, error) {
+	f.calls++
+	var out []store.TokenPrice
+	for _, t := range tokens {
+		if v, ok := f.usd[t]; ok {
+			out = append(out, store.TokenPrice{Token: t, TS: at, USD: v, Source: "fake"})
+		}
+	}
+	return out, nil
+}
+
+var now = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func clock() time.Time { return now }
+
+// TestParseCSV_PricesAtOrBefore は CSV を読み、指定時刻以前で最も新しい価格を返すことを確認します
+func TestParseCSV_PricesAtOrBefore(t *testing.T) {
+	fs, err := pricing.ParseCSV(strings.NewReader(`token,ts,usd,decimals
+# コメント行
+SOL,2026-03-01,120.5,9
+SOL,2026-03-02T00:00:00Z,130,
+USDCmint,1772323200,1.0,6
+`))
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	got, _ := fs.Prices(context.Background(), []string{"SOL", "USDCmint", "BONK"}, time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC))
+	if len(got) != 2 || got[0].Token != "SOL" || got[0].USD != 120.5 || got[1].Token != "USDCmint" {
+		t.Fatalf("prices = %+v", got)
+	}
+	got, _ = fs.Prices(context.Background(), []string{"SOL"}, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	if len(got) != 0 {
+		t.Fatalf("price before first row = %+v", got)
+	}
+	if d := fs.Decimals(); d["SOL"] != 9 || d["USDCmint"] != 6 {
+		t.Fatalf("decimals = %v", d)
+	}
+	if all := fs.All(); len(all) != 3 || all[0].Token != "SOL" || !all[0].TS.Before(all[1].TS) {
+		t.Fatalf("all = %+v", all)
+	}
+}
+
+// TestParseFile_Invalid は不正な行を行番号付きのエラーにすることを確認します
+func TestParseFile_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		csv  bool
+		body string
+		want string
+	}{
+		{name: "negative usd", csv: true, body: "SOL,2026-03-01,-1\n", want: "line 1"},
+		{name: "bad ts", csv: true, body: "SOL,yesterday,1\n", want: "invalid ts"},
+		{name: "too few columns", csv: true, body: "SOL,1\n", want: "want 3 or 4 columns"},
+		{name: "conflicting decimals", body: `[{"token":"X","ts":"2026-03-01","usd":1,"decimals":6},{"token":"X","ts":"2026-03-02","usd":1,"decimals":9}]`, want: "conflicting decimals"},
+		{name: "missing token", body: `[{"ts":1772323200,"usd":1}]`, want: "item 0"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			if tc.csv {
+				_, err = pricing.ParseCSV(strings.NewReader(tc.body))
+			} else {
+				_, err = pricing.ParseJSON(strings.NewReader(tc.body))
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want mention of %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// TestHTTPSource は現在価格と日次の履歴をそれぞれのエンドポイントで引くことを確認します
+func TestHTTPSource(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-test-key") != "k" {
+			http.Error(w, "no key", http.StatusUnauthorized)
+			return
+		}
+		paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+		switch r.URL.Path {
+		case "/simple/price":
+			w.Write([]byte(`{"solana":{"usd":150.25,"last_updated_at":1773143940}}`))
+		case "/coins/sui/history":
+			w.Write([]byte(`{"market_data":{"current_price":{"usd":3.5,"eur":3.2}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	h := &pricing.HTTPSource{
+		BaseURL:      srv.URL,
+		APIKey:       "k",
+		APIKeyHeader: "x-test-key",
+		IDs:          map[string]string{"SOL": "solana", "SUI": "sui"},
+		Now:          clock,
+	}
+	got, err := h.Prices(context.Background(), []string{"SOL", "UNMAPPED"}, now)
+	if err != nil {
+		t.Fatalf("current: %v", err)
+	}
+	if len(got) != 1 || got[0].USD != 150.25 || !got[0].TS.Equal(time.Unix(1773143940, 0)) {
+		t.Fatalf("current = %+v", got)
+	}
+
+	at := time.Date(2026, 1, 5, 15, 30, 0, 0, time.UTC)
+	got, err = h.Prices(context.Background(), []string{"SUI"}, at)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(got) != 1 || got[0].USD != 3.5 || !got[0].TS.Equal(time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("history = %+v", got)
+	}
+	if len(paths) != 2 || !strings.Contains(paths[0], "ids=solana") || !strings.Contains(paths[1], "date=05-01-2026") {
+		t.Fatalf("requests = %v", paths)
+	}
+}
+
+// TestService_ValueBalances_CachesSourcePrices は価格表に無い現在価格を Source から取って保存し、
+// Fresh の間は Source を呼ばないことを確認します
+func TestService_ValueBalances_CachesSourcePrices(t *testing.T) {
+	table := &fakeTable{decimals: map[string]int{"USDCmint": 6}}
+	src := &fakeSource{usd: map[string]float64{"SOL": 100, "USDCmint": 1}}
+	svc := pricing.NewService(table, pricing.Options{Source: src, Now: clock})
+
+	bs := []store.TokenBalance{{Token: "SOL", Amount: 2_500_000_000}, {Token: "USDCmint", Amount: 12_340_000}, {Token: "BONK", Amount: 5}}
+	out := svc.ValueBalances(context.Background(), bs)
+	if out[0].USDValue == nil || *out[0].USDValue != 250 || *out[0].USDPrice != 100 {
+		t.Fatalf("SOL = %+v", out[0])
+	}
+	if out[1].USDValue == nil || *out[1].USDValue != 12.34 {
+		t.Fatalf("USDC = %+v", out[1])
+	}
+	if out[2].USDValue != nil || out[2].Amount != 5 {
+		t.Fatalf("unpriced token = %+v", out[2])
+	}
+	if src.calls != 1 || table.upserts != 1 {
+		t.Fatalf("source calls = %d, upserts = %d", src.calls, table.upserts)
+	}
+
+	// SOL / USDC は価格表から引けるので Source を呼ばない
+	svc.ValueBalances(context.Background(), bs[:2])
+	if src.calls != 1 {
+		t.Fatalf("source called again for cached prices (%d)", src.calls)
+	}
+}
+
+// TestService_ValueEvents_UsesTxTime は各イベントを Tx 時刻以前の価格で評価することを確認します
+func TestService_ValueEvents_UsesTxTime(t *testing.T) {
+	table := &fakeTable{prices: []store.TokenPrice{
+		{Token: "SOL", TS: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), USD: 100},
+		{Token: "SOL", TS: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), USD: 200},
+		{Token: "RAREmint", TS: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), USD: 5},
+	}}
+	svc := pricing.NewService(table, pricing.Options{Now: clock})
+
+	sol, rare := "SOL", "RAREmint"
+	amt := int64(1_000_000_000)
+	events := []store.TxEvent{
+		{TxHash: "a", TS: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Token: &sol, Amount: &amt},
+		{TxHash: "b", TS: time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC), Token: &sol, Amount: &amt},
+		// 許容範囲（24 時間）より古い価格しかない
+		{TxHash: "c", TS: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), Token: &sol, Amount: &amt},
+		// 桁数が分からないトークン
+		{TxHash: "d", TS: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Token: &rare, Amount: &amt},
+		{TxHash: "e", TS: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	svc.ValueEvents(context.Background(), events)
+	if events[0].USDValue == nil || *events[0].USDValue != 100 {
+		t.Fatalf("a = %v", events[0].USDValue)
+	}
+	if events[1].USDValue == nil || *events[1].USDValue != 200 {
+		t.Fatalf("b = %v", events[1].USDValue)
+	}
+	for _, e := range events[2:] {
+		if e.USDValue != nil {
+			t.Fatalf("%s valued unexpectedly: %v", e.TxHash, *e.USDValue)
+		}
+	}
+}
+
+// TestService_Nil は Service 未設定でも残高・イベントをそのまま返すことを確認します
+func TestService_Nil(t *testing.T) {
+	var svc *pricing.Service
+	out := svc.ValueBalances(context.Background(), []store.TokenBalance{{Token: "SOL", Amount: 1}})
+	if len(out) != 1 || out[0].USDValue != nil {
+		t.Fatalf("out = %+v", out)
+	}
+	svc.ValueEvents(context.Background(), []store.TxEvent{{TxHash: "a"}})
+}
+
+func TestValue(t *testing.T) {
+	if got := pricing.Value(1_500_000, 6, 0.1); got != 0.15 {
+		t.Fatalf("Value = %v", got)
+	}
+}
+
+// TestService_ValuesAt は数量と時刻の組を評価し、分からないものは nil にすることを確認します
+func TestService_ValuesAt(t *testing.T) {
+	table := &fakeTable{prices: []store.TokenPrice{{Token: "SUI", TS: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), USD: 2}}}
+	svc := pricing.NewService(table, pricing.Options{Now: clock})
+	got := svc.ValuesAt(context.Background(), []pricing.Item{
+		{Token: "SUI", Amount: 3_000_000_000, At: time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)},
+		{Token: "SUI", Amount: 1, At: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	if got[0] == nil || *got[0] != 6 || got[1] != nil {
+		t.Fatalf("values = %v", got)
+	}
+}
+
+// TestService_FillGroupsByDay は同じ日（UTC）の時刻を Source への 1 回の問い合わせにまとめ、
+// Source に無かったトークンは続けて問い合わせないことを確認します
+func TestService_FillGroupsByDay(t *testing.T) {
+	table := &fakeTable{decimals: map[string]int{"SOL": 9}}
+	src := &fakeSource{usd: map[string]float64{"SOL": 100}}
+	svc := pricing.NewService(table, pricing.Options{Source: src, Now: clock})
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	var items []pricing.Item
+	for h := 1; h < 24; h += 2 {
+		items = append(items, pricing.Item{Token: "SOL", Amount: 1_000_000_000, At: day.Add(time.Duration(h) * time.Hour)})
+	}
+	items = append(items, pricing.Item{Token: "NOPEmint", Amount: 1, At: day.Add(5 * time.Hour)})
+	got := svc.ValuesAt(context.Background(), items)
+	if src.calls != 1 {
+		t.Fatalf("source calls = %d, want 1 for one day", src.calls)
+	}
+	for i, v := range got[:len(got)-1] {
+		if v == nil || *v != 100 {
+			t.Fatalf("item %d = %v", i, v)
+		}
+	}
+	if got[len(got)-1] != nil {
+		t.Fatalf("unknown token valued: %v", *got[len(got)-1])
+	}
+
+	// SOL は価格表から引け、NOPEmint は無かったことを覚えているので Source を呼ばない
+	svc.ValuesAt(context.Background(), items)
+	if src.calls != 1 {
+		t.Fatalf("source called again (%d)", src.calls)
+	}
+	// 別の日は改めて問い合わせる
+	svc.ValuesAt(context.Background(), []pricing.Item{{Token: "NOPEmint", Amount: 1, At: day.Add(48 * time.Hour)}})
+	if src.calls != 2 {
+		t.Fatalf("source calls = %d, want 2", src.calls)
+	}
+}
@@ -0,0 +1,274 @@
+package retentiontest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/config"
+	"github.com/you/wallet-watcher/internal/retention"
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+func month(y int, m time.Month) store.TxPartition {
+	from := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	return store.TxPartition{Chain: "sui", Name: store.PartitionName("sui", from), From: from, To: from.AddDate(0, 1, 0)}
+}
+
+// fakeStore は raw の残り行数とパーティションをメモリ上に持つ
+type fakeStore struct {
+	ahead    int
+	raw      int
+	mode     string
+	before   time.Time
+	parts    []store.TxPartition
+	archived []string
+	failOn   string
+	costOn   string
+}
+
+func (f *fakeStore) EnsurePartitions(ctx context.Context, chain string, ahead int) error {
+	f.ahead = ahead
+	return nil
+}
+
+func (f *fakeStore) TxPartitions(ctx context.Context, chain string) ([]store.TxPartition, error) {
+	return f.parts, nil
+}
+
+func (f *fakeStore) process(mode string, before time.Time, limit int) int {
+	f.mode, f.before = mode, before
+	n := min(f.raw, limit)
+	f.raw -= n
+	return n
+}
+
+func (f *fakeStore) CompressRaw(ctx context.Context, chain string, before time.Time, limit int) (int, error) {
+	return f.process(config.RawCompress, before, limit), nil
+}
+
+func (f *fakeStore) DropRaw(ctx context.Context, chain string, before time.Time, limit int) (int, error) {
+	return f.process(config.RawDrop, before, limit), nil
+}
+
+func (f *fakeStore) ArchivePartition(ctx context.Context, p store.TxPartition, sink store.ArchiveSink) (store.ArchivedPartition, error) {
+	if p.Name == f.failOn {
+		sink.Abort()
+		return store.ArchivedPartition{}, errors.New("detach failed")
+	}
+	if p.Name == f.costOn {
+		sink.Abort()
+		return store.ArchivedPartition{}, fmt.Errorf("%s: %w", p.Name, store.ErrCostBasisDepends)
+	}
+	if err := sink.Write(store.ArchiveRow{TxHash: "h-" + p.Name, TS: p.From}); err != nil {
+		return store.ArchivedPartition{}, err
+	}
+	file, err := sink.Commit()
+	if err != nil {
+		return store.ArchivedPartition{}, err
+	}
+	f.archived = append(f.archived, p.Name)
+	return store.ArchivedPartition{Chain: p.Chain, Name: p.Name, From: p.From, To: p.To, Rows: 1, ArchiveFile: file}, nil
+}
+
+// TestPartitionName はパーティション名と月の範囲の対応を確認します
+func TestPartitionName(t *testing.T) {
+	ts := time.Date(2025, 12, 31, 23, 30, 0, 0, time.FixedZone("JST", 9*3600))
+	name := store.PartitionName("solana", ts)
+	if name != "tx_events_solana_p2025_12" {
+		t.Fatalf("name = %s", name)
+	}
+	chain, from, to, ok := store.ParsePartitionName("tx_events_sui_p2025_12")
+	if !ok || chain != "sui" || !from.Equal(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("parse = %s %v %v %v", chain, from, to, ok)
+	}
+	for _, bad := range []string{"tx_events_sui", "tx_events_sui_p2025", "tx_events_sui_legacy", "outbox_p2025_01"} {
+		if _, _, _, ok := store.ParsePartitionName(bad); ok {
+			t.Fatalf("%s parsed as a partition", bad)
+		}
+	}
+}
+
+// TestDue は期限を過ぎた月だけ（当月は期限が 0 に近くても含めない）を書き出し対象にすることを確認します
+func TestDue(t *testing.T) {
+	parts := []store.TxPartition{month(2025, 1), month(2025, 2), month(2025, 3), month(2025, 4), month(2025, 5)}
+	now := time.Date(2025, 4, 15, 12, 0, 0, 0, time.UTC)
+
+	due := retention.Due(parts, now, 30*24*time.Hour)
+	if len(due) != 2 || due[0].Name != "tx_events_sui_p2025_01" || due[1].Name != "tx_events_sui_p2025_02" {
+		t.Fatalf("due(30d) = %+v", due)
+	}
+	if due := retention.Due(parts, now, time.Hour); len(due) != 3 {
+		t.Fatalf("due(1h) = %d partitions, want 3 (not the current month)", len(due))
+	}
+	if due := retention.Due(parts, now, 0); len(due) != 0 {
+		t.Fatalf("due(0) = %+v, want none", due)
+	}
+}
+
+// TestRunOnce は先の月の作成、raw の保持期限の処理（残りが無くなるまで）、期限を過ぎた月の書き出しを確認します
+func TestRunOnce(t *testing.T) {
+	now := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	fs := &fakeStore{raw: 25, parts: []store.TxPartition{month(2025, 1), month(2025, 2), month(2025, 5), month(2025, 6)}}
+	c := config.Default().Retention
+	c.RawAfter = config.Duration(7 * 24 * time.Hour)
+	c.RawMode = config.RawDrop
+	c.ArchiveAfter = config.Duration(90 * 24 * time.Hour)
+	c.ArchiveDir = dir
+	c.BatchSize = 10
+	m := &retention.Maintainer{Store: fs, Chain: "sui", Config: c, Now: func() time.Time { return now }}
+
+	res, err := m.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if fs.ahead != 3 {
+		t.Fatalf("partitions ahead = %d", fs.ahead)
+	}
+	if res.Raw != 25 || fs.raw != 0 || fs.mode != config.RawDrop || !fs.before.Equal(now.AddDate(0, 0, -7)) {
+		t.Fatalf("raw = %d (left %d, mode %s, before %v)", res.Raw, fs.raw, fs.mode, fs.before)
+	}
+	if len(fs.archived) != 2 || fs.archived[0] != "tx_events_sui_p2025_01" || fs.archived[1] != "tx_events_sui_p2025_02" {
+		t.Fatalf("archived = %v", fs.archived)
+	}
+	for _, a := range res.Archived {
+		if filepath.Dir(a.Path) != filepath.Join(dir, "sui") {
+			t.Fatalf("archive path = %s", a.Path)
+		}
+	}
+
+	// 既定（raw_after・archive_after が 0）ならパーティションを作るだけ
+	fs = &fakeStore{raw: 5, parts: fs.parts}
+	m = &retention.Maintainer{Store: fs, Chain: "sui", Config: config.Default().Retention, Now: func() time.Time { return now }}
+	if res, err := m.RunOnce(context.Background()); err != nil || res.Raw != 0 || len(res.Archived) != 0 || fs.raw != 5 {
+		t.Fatalf("default RunOnce = %+v, %v", res, err)
+	}
+}
+
+// TestRunOnce_ArchiveFailure は切り離しに失敗した月のファイルを残さず、エラーを返すことを確認します
+func TestRunOnce_ArchiveFailure(t *testing.T) {
+	dir := t.TempDir()
+	fs := &fakeStore{parts: []store.TxPartition{month(2025, 1)}, failOn: "tx_events_sui_p2025_01"}
+	c := config.Default().Retention
+	c.ArchiveAfter = config.Duration(24 * time.Hour)
+	c.ArchiveDir = dir
+	m := &retention.Maintainer{Store: fs, Chain: "sui", Config: c}
+	if _, err := m.RunOnce(context.Background()); err == nil {
+		t.Fatal("want error")
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "sui"))
+	if len(entries) != 0 {
+		t.Fatalf("left files: %v", entries)
+	}
+}
+
+// TestRunOnce_CostBasisHeld は取得原価が使っている月で切り離しを止め、エラーにせず以降の月も残すことを確認します
+func TestRunOnce_CostBasisHeld(t *testing.T) {
+	dir := t.TempDir()
+	fs := &fakeStore{parts: []store.TxPartition{month(2025, 1), month(2025, 2), month(2025, 3)}, costOn: "tx_events_sui_p2025_02"}
+	c := config.Default().Retention
+	c.ArchiveAfter = config.Duration(24 * time.Hour)
+	c.ArchiveDir = dir
+	m := &retention.Maintainer{Store: fs, Chain: "sui", Config: c, Now: func() time.Time { return time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC) }}
+	res, err := m.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(fs.archived) != 1 || fs.archived[0] != "tx_events_sui_p2025_01" || res.Held != "tx_events_sui_p2025_02" {
+		t.Fatalf("archived = %v, held = %q", fs.archived, res.Held)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "sui"))
+	if len(entries) != 1 {
+		t.Fatalf("files: %v", entries)
+	}
+}
+
+// TestFileSink は書き出したファイルを読み戻せること、SHA-256 とサイズ、同名のファイルを上書きしないことを確認します
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+	p := month(2025, 3)
+	sender, amount := "0xabc", "123456789012345678901234567890"
+	rows := []store.ArchiveRow{
+		{Seq: 1, TxHash: "h1", TS: p.From, Sender: &sender, Amount: &amount, Raw: json.RawMessage(`{"digest":"h1"}`),
+			Participants: []store.Participant{{Address: "abc", Role: store.RoleSender}}},
+		{Seq: 2, TxHash: "h2", TS: p.From.Add(time.Hour)},
+	}
+
+	write := func() store.ArchiveFile {
+		t.Helper()
+		sink, err := retention.NewFileSink(dir, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rows {
+			if err := sink.Write(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, err := sink.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	f := write()
+	if f.Path != filepath.Join(dir, "sui", "tx_events_sui_p2025_03.ndjson.gz") {
+		t.Fatalf("path = %s", f.Path)
+	}
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(b)
+	if int64(len(b)) != f.Bytes || hex.EncodeToString(sum[:]) != f.SHA256 {
+		t.Fatalf("bytes/sha256 = %d %s, file has %d %x", f.Bytes, f.SHA256, len(b), sum)
+	}
+
+	var got []store.ArchiveRow
+	if err := retention.ReadFile(f.Path, func(r store.ArchiveRow) error { got = append(got, r); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].TxHash != "h1" || *got[0].Amount != amount || string(got[0].Raw) != `{"digest":"h1"}` ||
+		len(got[0].Participants) != 1 || !got[1].TS.Equal(rows[1].TS) || got[1].Raw != nil {
+		t.Fatalf("read back = %+v", got)
+	}
+
+	// 同じ月をもう一度書き出しても前のファイルは残る
+	f2 := write()
+	if f2.Path != filepath.Join(dir, "sui", "tx_events_sui_p2025_03.2.ndjson.gz") {
+		t.Fatalf("second path = %s", f2.Path)
+	}
+	if _, err := os.Stat(f.Path); err != nil {
+		t.Fatalf("first archive: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "sui"))
+	if len(entries) != 2 {
+		t.Fatalf("files = %v (temporary files left?)", entries)
+	}
+}
+
+// TestFileSink_Abort は Abort で一時ファイルが消えることを確認します
+func TestFileSink_Abort(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := retention.NewFileSink(dir, month(2025, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(store.ArchiveRow{TxHash: "h1"}); err != nil {
+		t.Fatal(err)
+	}
+	sink.Abort()
+	entries, _ := os.ReadDir(filepath.Join(dir, "sui"))
+	if len(entries) != 0 {
+		t.Fatalf("left files: %v", entries)
+	}
+}
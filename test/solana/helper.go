@@ -90,10 +90,10 @@ func InsertTxEventSolanaForTest(
 	fee *int64, method *string,
 	rawJSON []byte,
 ) error {
-	_, err := st.Pool.Exec(ctx, `
-		INSERT INTO tx_events_solana (tx_hash, ts, sender, receiver, fee, method, raw)
-		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7::text, '')::jsonb)
-		ON CONFLICT (tx_hash, ts) DO NOTHING;
-	`, txHash, ts, sender, receiver, fee, method, string(rawJSON))
-	return err
+	// store 経由で保存し、tx_participants も同時に書き込む
+	return st.InsertTxEventSolana(ctx, store.TxEventInput{
+		TxHash: txHash, TS: ts,
+		Sender: sender, Receiver: receiver,
+		Fee: fee, Method: method, Raw: rawJSON,
+	})
 }
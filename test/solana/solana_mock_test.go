@@ -84,3 +84,41 @@ func TestSolanaClient_Mock(t *testing.T) {
 		t.Fatalf("unexpected tx: %+v", tx)
 	}
 }
+
+// TestSolanaClient_MockSignaturesBefore は before を指定したときだけ RPC に渡ることを確認する（walletctl backfill のページング）
+func TestSolanaClient_MockSignaturesBefore(t *testing.T) {
+	var got []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&q)
+		var opts map[string]any
+		if len(q.Params) == 2 {
+			_ = json.Unmarshal(q.Params[1], &opts)
+		}
+		got = append(got, opts)
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": []any{}})
+	}))
+	defer srv.Close()
+
+	cl := sol.New(srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := cl.GetSignaturesForAddress(ctx, "Addr111", 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cl.GetSignaturesForAddressBefore(ctx, "Addr111", 10, "SIG_OLDEST"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("requests = %d, want 2", len(got))
+	}
+	if _, ok := got[0]["before"]; ok {
+		t.Errorf("first request has before: %v", got[0])
+	}
+	if got[1]["before"] != "SIG_OLDEST" || got[1]["limit"] != float64(10) {
+		t.Errorf("second request opts = %v", got[1])
+	}
+}
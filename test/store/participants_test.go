@@ -0,0 +1,80 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+func strp(s string) *string { return &s }
+
+// TestParticipantRows_Sui は Sui のアドレスを小文字・0x なしに揃えてから (address, role) で重複排除することを確認します
+func TestParticipantRows_Sui(t *testing.T) {
+	ev := store.TxEventInput{
+		Sender:   strp("0xABCDEF"),
+		Receiver: strp("0x1234"),
+		Participants: []store.Participant{
+			{Address: "0xabcdef", Role: store.RoleSender},  // Sender と同じ（表記違い）
+			{Address: "ABCDEF", Role: store.RoleAccount},   // 役割が違えば別の行
+			{Address: "0xabcdef", Role: store.RoleAccount}, // 上と同じ
+			{Address: "", Role: store.RoleAccount},         // 空は捨てる
+		},
+	}
+	got := ev.ParticipantRows("sui")
+	want := []store.Participant{
+		{Address: "abcdef", Role: store.RoleSender},
+		{Address: "1234", Role: store.RoleReceiver},
+		{Address: "abcdef", Role: store.RoleAccount},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows = %+v, want %+v", got, want)
+	}
+}
+
+// TestParticipantRows_Solana は Solana のアドレスの大文字・小文字を区別したまま扱うことを確認します
+func TestParticipantRows_Solana(t *testing.T) {
+	ev := store.TxEventInput{
+		Sender:   strp("9xQeWvG816bUx9EPjHmaT23yvVM2ZWbrrpZb9PusVFin"),
+		Receiver: strp(""),
+		Participants: []store.Participant{
+			{Address: "9xqewvg816bux9epjhmat23yvvm2zwbrrpzb9pusvfin", Role: store.RoleSender},
+			{Address: "9xQeWvG816bUx9EPjHmaT23yvVM2ZWbrrpZb9PusVFin", Role: store.RoleSender},
+		},
+	}
+	got := ev.ParticipantRows("solana")
+	want := []store.Participant{
+		{Address: "9xQeWvG816bUx9EPjHmaT23yvVM2ZWbrrpZb9PusVFin", Role: store.RoleSender},
+		{Address: "9xqewvg816bux9epjhmat23yvvm2zwbrrpzb9pusvfin", Role: store.RoleSender},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows = %+v, want %+v", got, want)
+	}
+}
+
+// TestSameAddress は比較の規則（Sui は 0x と大文字・小文字を無視、Solana は完全一致）を確認します
+func TestSameAddress(t *testing.T) {
+	cases := []struct {
+		chain, a, b string
+		want        bool
+	}{
+		{"sui", "0xABC", "abc", true},
+		{"sui", "0xabc", "0xabd", false},
+		{"solana", "AbC", "AbC", true},
+		{"solana", "AbC", "abc", false},
+	}
+	for _, c := range cases {
+		if got := store.SameAddress(c.chain, c.a, c.b); got != c.want {
+			t.Errorf("SameAddress(%s, %q, %q) = %v", c.chain, c.a, c.b, got)
+		}
+	}
+	for _, c := range []struct{ chain, in, want string }{
+		{"sui", "ABC", "0xabc"},
+		{"sui", "0xABC", "0xabc"},
+		{"solana", "AbC", "AbC"},
+	} {
+		if got := store.CanonicalAddress(c.chain, c.in); got != c.want {
+			t.Errorf("CanonicalAddress(%s, %q) = %s, want %s", c.chain, c.in, got, c.want)
+		}
+	}
+}
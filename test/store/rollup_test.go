@@ -0,0 +1,84 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/store"
+)
+
+func i64p(v int64) *int64 { return &v }
+
+// rollupEvent は日次集計の入力にする保存済みイベント
+func rollupEvent(hash string, ts time.Time, from, to, token string, amount, fee int64, status string) store.TxEvent {
+	e := store.TxEvent{Chain: "sui", TxHash: hash, TS: ts, Amount: i64p(amount), Fee: i64p(fee), Status: strp(status)}
+	if from != "" {
+		e.Sender = strp(from)
+	}
+	if to != "" {
+		e.Receiver = strp(to)
+	}
+	if token != "" {
+		e.Token = strp(token)
+	}
+	return e
+}
+
+// TestAggregateDaily は 1 アドレス・1 日分の集計で、手数料だけの行・自己送金・失敗 Tx・
+// UTC の日付の境界・相手アドレスの数え方を確認します
+func TestAggregateDaily(t *testing.T) {
+	const (
+		me   = "abc"
+		usdc = "0x5d4b::coin::USDC"
+		ok   = store.TxStatusSuccess
+	)
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+
+	events := []store.TxEvent{
+		// 受取（token なしはネイティブトークン）。Sui の表記違いも同じアドレス
+		rollupEvent("in", at(1), "0xB0B", "0xABC", "", 100, 5, ok),
+		// 送付（手数料はネイティブトークンの行）
+		rollupEvent("out", at(2), "0xabc", "0xc4a1", "SUI", 30, 2, ok),
+		// ネイティブ以外のトークンの送付：USDC の行と、手数料だけの SUI の行
+		rollupEvent("usdc", at(3), "abc", "0xb0b", usdc, 7, 4, ok),
+		// 自己送金：数量は数えず、手数料と Tx 数は数える。相手にもしない
+		rollupEvent("self", at(4), "0xabc", "0xabc", "SUI", 50, 1, ok),
+		// 失敗 Tx：数量は 0、手数料は払う
+		rollupEvent("failed", at(5), "0xabc", "0xdead", "SUI", 999, 3, store.TxStatusFailed),
+		// 前日と翌日（UTC）は数えない
+		rollupEvent("before", day.Add(-time.Second), "0xb0b", "0xabc", "SUI", 1000, 0, ok),
+		rollupEvent("after", day.Add(24*time.Hour), "0xb0b", "0xabc", "SUI", 1000, 0, ok),
+		// 関与しない Tx
+		rollupEvent("other", at(6), "0xb0b", "0xc4a1", "SUI", 1000, 9, ok),
+	}
+	got := store.AggregateDaily("sui", me, day.Add(13*time.Hour), events)
+	want := []store.DailyRollup{
+		{Token: usdc, Outflow: 7, TxCount: 1, Counterparties: []string{"b0b"}, FirstTS: at(3), LastTS: at(3)},
+		{Token: "SUI", Inflow: 100, Outflow: 30, TxCount: 4, FeesPaid: 2 + 4 + 1 + 3,
+			Counterparties: []string{"b0b", "c4a1", "dead"}, FirstTS: at(1), LastTS: at(5)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rollup =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// TestAggregateDaily_FeeOnly はネイティブ以外のトークンの送付しか無い日に、手数料だけの
+// ネイティブトークンの行が Tx 数 0 で作られることを確認します
+func TestAggregateDaily_FeeOnly(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	ts := day.Add(23*time.Hour + 59*time.Minute)
+	got := store.AggregateDaily("solana", "Me", day, []store.TxEvent{
+		{Chain: "solana", TxHash: "t", TS: ts, Sender: strp("Me"), Receiver: strp("You"), Token: strp("USDCmint"), Amount: i64p(10), Fee: i64p(5000)},
+		// Solana は大文字・小文字を区別する
+		{Chain: "solana", TxHash: "u", TS: ts, Sender: strp("me"), Receiver: strp("You"), Amount: i64p(1), Fee: i64p(5000)},
+	})
+	want := []store.DailyRollup{
+		{Token: "SOL", FeesPaid: 5000, Counterparties: []string{}, FirstTS: ts, LastTS: ts},
+		{Token: "USDCmint", Outflow: 10, TxCount: 1, Counterparties: []string{"You"}, FirstTS: ts, LastTS: ts},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rollup =\n%+v\nwant\n%+v", got, want)
+	}
+}
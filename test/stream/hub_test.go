@@ -0,0 +1,288 @@
+package streamtest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/store"
+	"github.com/you/wallet-watcher/internal/stream"
+)
+
+// fakeSource は DB の代わりに保持しているイベントを返す stream.Source。
+// mark は StreamWatermark の値、notes に送った通知は LISTEN で受け取ったものとして扱い、
+// drop に送ると LISTEN が切れたものとして扱う。
+type fakeSource struct {
+	mu     sync.Mutex
+	events []store.StreamEvent
+	mark   int64
+	notes  chan store.TxNotification
+	drop   chan error
+}
+
+func (f *fakeSource) add(e store.StreamEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, e)
+}
+
+func (f *fakeSource) GetStreamEvent(ctx context.Context, chain string, seq int64) (*store.StreamEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.events {
+		if e.Chain == chain && e.Seq == seq {
+			return &e, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (f *fakeSource) ListStreamEvents(ctx context.Context, chain string, addresses []string, afterSeq int64, limit int) ([]store.StreamEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	filter := stream.Filter{Chain: chain, Addresses: addresses}
+	var out []store.StreamEvent
+	for _, e := range f.events {
+		if e.Seq > afterSeq && filter.Match(e) && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSource) ListenTxEvents(ctx context.Context, fn func(store.TxNotification)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-f.drop:
+			return err
+		case n := <-f.notes:
+			fn(n)
+		}
+	}
+}
+
+func (f *fakeSource) StreamWatermark(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mark, nil
+}
+
+func (f *fakeSource) NotifyStreamWatermark(ctx context.Context, w int64) error { return nil }
+
+func ev(seq int64, chain string, participants ...string) store.StreamEvent {
+	return store.StreamEvent{
+		Seq:          seq,
+		TxEvent:      store.TxEvent{Chain: chain, TxHash: "tx" + string(rune('a'+seq)), TS: time.Unix(1700000000+seq, 0).UTC()},
+		Participants: participants,
+	}
+}
+
+// TestFilter_Match はチェーン・アドレスによる購読条件の判定を確認します（Sui は 0x・大文字を吸収）
+func TestFilter_Match(t *testing.T) {
+	sol := ev(1, "solana", "SoLAddr1")
+	sui := ev(2, "sui", "abcdef")
+
+	tests := []struct {
+		name string
+		f    stream.Filter
+		e    store.StreamEvent
+		want bool
+	}{
+		{"empty matches all", stream.Filter{}, sol, true},
+		{"all chains", stream.Filter{Chain: store.ChainAll}, sui, true},
+		{"other chain", stream.Filter{Chain: "sui"}, sol, false},
+		{"solana address", stream.Filter{Chain: "solana", Addresses: []string{"SoLAddr1"}}, sol, true},
+		{"solana address is case sensitive", stream.Filter{Addresses: []string{"soladdr1"}}, sol, false},
+		{"sui address normalized", stream.Filter{Addresses: []string{"0xABCDEF"}}, sui, true},
+		{"unrelated address", stream.Filter{Addresses: []string{"0x1234"}}, sui, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.Match(tt.e); got != tt.want {
+				t.Fatalf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHub_ServeResume は cursor より後の補填 → 新着の順に届き、
+// 補填と重複した新着は二重に送られないことを確認します。
+func TestHub_ServeResume(t *testing.T) {
+	src := &fakeSource{mark: 4, events: []store.StreamEvent{
+		ev(1, "solana", "A"), ev(2, "solana", "B"), ev(3, "sui", "a"), ev(4, "solana", "A"),
+	}}
+	hub := stream.NewHub(src)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	got := make(chan int64, 10)
+	after := int64(1)
+	done := make(chan error, 1)
+	go func() {
+		done <- hub.Serve(ctx, stream.Filter{Chain: "solana", Addresses: []string{"A"}}, &after, func(e store.StreamEvent) error {
+			got <- e.Seq
+			return nil
+		})
+	}()
+
+	// 補填分: seq 4 だけ（1 は cursor 以前、2 と 3 は条件外）
+	for _, want := range []int64{4} {
+		if seq := <-got; seq != want {
+			t.Fatalf("backfill seq = %d, want %d", seq, want)
+		}
+	}
+
+	// 補填と同じイベントの通知は飛ばされ、新しいものだけ届く
+	// （Serve は補填の前に購読しているので、ここで Publish したものは必ず届く）
+	hub.Publish(ev(4, "solana", "A"))
+	hub.Publish(ev(5, "solana", "B"))
+	hub.Publish(ev(6, "solana", "A"))
+	select {
+	case seq := <-got:
+		if seq != 6 {
+			t.Fatalf("live seq = %d, want 6", seq)
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for live event")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Serve returned %v", err)
+	}
+}
+
+// TestHub_ServeCursorLateCommit は seq 1499 を採番したトランザクションが実行中の間、
+// 先にコミットされた 1500 を送っても cursor が 1499 を越えず、
+// 1499 がコミットされて再開位置の通知が届いてから進むことを確認します。
+func TestHub_ServeCursorLateCommit(t *testing.T) {
+	// 1499 は未コミット（見えない）ので、確定済みの位置は 1498
+	src := &fakeSource{mark: 1498, notes: make(chan store.TxNotification), events: []store.StreamEvent{
+		ev(400, "solana", "A"), ev(1450, "solana", "A"), ev(1500, "solana", "A"),
+	}}
+	hub := stream.NewHub(src)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	go hub.Run(ctx)
+
+	type sent struct{ seq, cursor int64 }
+	got := make(chan sent, 10)
+	after := int64(1400)
+	go hub.Serve(ctx, stream.Filter{}, &after, func(e store.StreamEvent) error {
+		got <- sent{e.Seq, e.Cursor}
+		return nil
+	})
+	next := func() sent {
+		select {
+		case s := <-got:
+			return s
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for event")
+			return sent{}
+		}
+	}
+
+	// 補填: cursor より後の 1450 と 1500。1500 の cursor は確定済みの 1498 で止まる
+	for _, want := range []sent{{1450, 1450}, {1500, 1498}} {
+		if s := next(); s != want {
+			t.Fatalf("backfill = %+v, want %+v", s, want)
+		}
+	}
+
+	// 1499 がコミットされて新着として届く。再開位置の通知が来るまで cursor は進まない
+	src.add(ev(1499, "solana", "A"))
+	src.notes <- store.TxNotification{Chain: "solana", Seq: 1499}
+	if s := next(); s != (sent{1499, 1498}) {
+		t.Fatalf("late commit = %+v", s)
+	}
+	src.notes <- store.TxNotification{Watermark: 1500}
+	src.add(ev(1501, "solana", "A"))
+	src.notes <- store.TxNotification{Chain: "solana", Seq: 1501}
+	if s := next(); s != (sent{1501, 1500}) {
+		t.Fatalf("after watermark = %+v", s)
+	}
+}
+
+// TestHub_ListenInterrupted は LISTEN が切れたら接続中の Serve が ErrInterrupted で戻り、
+// 再接続を待つ間の購読も ErrInterrupted で閉じることを確認します。
+func TestHub_ListenInterrupted(t *testing.T) {
+	src := &fakeSource{drop: make(chan error)}
+	hub := stream.NewHub(src)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	go hub.Run(ctx)
+
+	sub := hub.Subscribe(stream.Filter{})
+	src.drop <- errors.New("connection reset")
+	if _, ok := <-sub.C; ok {
+		t.Fatal("subscription still open after listen dropped")
+	}
+	if !errors.Is(sub.Err(), stream.ErrInterrupted) {
+		t.Fatalf("Err = %v, want ErrInterrupted", sub.Err())
+	}
+	err := hub.Serve(ctx, stream.Filter{}, nil, func(store.StreamEvent) error { return nil })
+	if !errors.Is(err, stream.ErrInterrupted) {
+		t.Fatalf("Serve during reconnect = %v, want ErrInterrupted", err)
+	}
+}
+
+// TestHub_SlowSubscriber はバッファが溢れた購読が ErrLagged で切断されることを確認します
+func TestHub_SlowSubscriber(t *testing.T) {
+	hub := stream.NewHub(&fakeSource{})
+	sub := hub.Subscribe(stream.Filter{})
+	defer sub.Close()
+
+	for i := int64(1); i <= 1000; i++ {
+		hub.Publish(ev(i, "solana", "A"))
+	}
+
+	n := 0
+	for range sub.C {
+		n++
+	}
+	if n == 0 || n >= 1000 {
+		t.Fatalf("received %d events before disconnect", n)
+	}
+	if !errors.Is(sub.Err(), stream.ErrLagged) {
+		t.Fatalf("Err = %v, want ErrLagged", sub.Err())
+	}
+}
+
+// TestHub_Close は Close で接続中の Serve が nil で戻り、以降の購読がすぐ閉じることを確認します
+func TestHub_Close(t *testing.T) {
+	hub := stream.NewHub(&fakeSource{})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	sub := hub.Subscribe(stream.Filter{})
+	done := make(chan error, 1)
+	go func() {
+		done <- hub.Serve(ctx, stream.Filter{}, nil, func(store.StreamEvent) error { return nil })
+	}()
+
+	// Serve が購読を登録するまで待つ必要はない（Close 後の Subscribe も閉じた購読を返す）
+	hub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Fatal("subscription still open after Close")
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Serve returned %v, want nil", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Serve did not return after Close")
+	}
+
+	late := hub.Subscribe(stream.Filter{})
+	if _, ok := <-late.C; ok {
+		t.Fatal("subscription after Close is open")
+	}
+	late.Close()
+}
@@ -87,10 +87,10 @@ func InsertTxEventSuiForTest(
 	sender, receiver *string,
 	rawJSON []byte,
 ) error {
-	_, err := st.Pool.Exec(ctx, `
-		INSERT INTO tx_events_sui (tx_hash, ts, sender, receiver, raw)
-		VALUES ($1, $2, $3, $4, NULLIF($5::text, '')::jsonb)
-		ON CONFLICT (tx_hash, ts) DO NOTHING;
-	`, txHash, ts, sender, receiver, string(rawJSON))
-	return err
+	// store 経由で保存し、tx_participants も同時に書き込む
+	return st.InsertTxEventSui(ctx, store.TxEventInput{
+		TxHash: txHash, TS: ts,
+		Sender: sender, Receiver: receiver,
+		Raw: rawJSON,
+	})
 }
\ No newline at end of file
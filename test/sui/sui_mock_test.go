@@ -3,6 +3,7 @@ package suitest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -81,3 +82,28 @@ func TestSuiClient_Mock(t *testing.T) {
 		t.Fatalf("get tx block failed: %v, %+v", err, tb)
 	}
 }
+
+// TestSuiClient_TxNotFound は、存在しないダイジェストの sui_getTransactionBlock のエラーが
+// sui.ErrTxNotFound として返り（/tx はこれを 404 にする）、それ以外の RPC エラーは区別されることを確認します。
+func TestSuiClient_TxNotFound(t *testing.T) {
+	msg := "Could not find the referenced transaction [TransactionDigest(MISSING)]."
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0", "id": 1,
+			"error": map[string]any{"code": -32602, "message": msg},
+		})
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	_, err := sui.New(srv.URL).GetTransactionBlockDetailed(ctx, "MISSING")
+	if !errors.Is(err, sui.ErrTxNotFound) {
+		t.Fatalf("err = %v, want ErrTxNotFound", err)
+	}
+
+	msg = "Invalid params"
+	_, err = sui.New(srv.URL).GetTransactionBlockDetailed(ctx, "BAD")
+	if err == nil || errors.Is(err, sui.ErrTxNotFound) {
+		t.Fatalf("err = %v, want a plain rpc error", err)
+	}
+}
@@ -0,0 +1,116 @@
+package tracingtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	api "github.com/you/wallet-watcher/internal/api"
+	sol "github.com/you/wallet-watcher/internal/chains/solana"
+	sui "github.com/you/wallet-watcher/internal/chains/sui"
+	"github.com/you/wallet-watcher/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recorder はテストバイナリ全体で共有する（グローバルのトレーサーは最初に設定したプロバイダーへ委譲されるため）
+var recorder = tracetest.NewSpanRecorder()
+
+func TestMain(m *testing.M) {
+	// 既定（OTEL_TRACES_EXPORTER 未設定）ではコレクター無しで Setup できる
+	os.Unsetenv("OTEL_TRACES_EXPORTER")
+	if _, err := tracing.Setup(context.Background(), "test"); err != nil {
+		panic(err)
+	}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	os.Exit(m.Run())
+}
+
+func findSpan(t *testing.T, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, s := range recorder.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	t.Fatalf("span %q not found", name)
+	return nil
+}
+
+func attr(s sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestSetup_Validation(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	if _, err := tracing.Setup(context.Background(), "test"); err == nil {
+		t.Error("expected error for unsupported exporter")
+	}
+	t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "carrier-pigeon")
+	if _, err := tracing.Setup(context.Background(), "test"); err == nil {
+		t.Error("expected error for unsupported protocol")
+	}
+}
+
+func TestRPCSpans(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q struct {
+			Method string `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&q)
+		if q.Method == "sui_getLatestCheckpointSequenceNumber" {
+			json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "error": map[string]any{"code": -32601, "message": "method not found"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": 42})
+	}))
+	defer srv.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	if _, err := sol.New(srv.URL).GetSlot(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sui.New(srv.URL).GetLatestCheckpoint(ctx); err == nil {
+		t.Fatal("expected rpc error")
+	}
+	parent.End()
+
+	s := findSpan(t, "solana.getSlot")
+	if attr(s, "rpc.method").AsString() != "getSlot" || attr(s, "chain").AsString() != "solana" {
+		t.Errorf("attributes = %v", s.Attributes())
+	}
+	if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("rpc span is not a child of the caller's span")
+	}
+	if e := findSpan(t, "sui.sui_getLatestCheckpointSequenceNumber"); e.Status().Code != codes.Error {
+		t.Errorf("status = %v, want Error", e.Status())
+	}
+}
+
+func TestHTTPSpans_RouteAndPropagation(t *testing.T) {
+	handler := api.Routes(&api.Server{})
+
+	req := httptest.NewRequest(http.MethodGet, "/tx/eth/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	s := findSpan(t, "GET /tx/{chain}/{hash}")
+	if got := s.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id = %s, want the one from traceparent", got)
+	}
+	if attr(s, "http.route").AsString() != "/tx/{chain}/{hash}" || attr(s, "http.response.status_code").AsInt64() != 400 {
+		t.Errorf("attributes = %v", s.Attributes())
+	}
+}
@@ -0,0 +1,66 @@
+package workertest
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/you/wallet-watcher/internal/worker"
+)
+
+// tickFunc は関数を worker.Ticker として使うためのアダプタ
+type tickFunc func(ctx context.Context) error
+
+func (f tickFunc) Tick(ctx context.Context) error { return f(ctx) }
+
+// TestRun_WaitsForCurrentTick は停止要求後も実行中の tick を最後まで待ち、新しい tick は始めないことを確認します
+func TestRun_WaitsForCurrentTick(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	var ticks, finished atomic.Int32
+	tk := tickFunc(func(ctx context.Context) error {
+		if ticks.Add(1) == 1 {
+			close(started)
+		}
+		// 処理中のアドレスの保存を模して、キャンセル後もしばらく続ける
+		time.Sleep(100 * time.Millisecond)
+		finished.Add(1)
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx, tk, time.Millisecond, time.Second) }()
+	<-started
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+	if ticks.Load() != 1 || finished.Load() != 1 {
+		t.Fatalf("ticks = %d, finished = %d, want 1 and 1", ticks.Load(), finished.Load())
+	}
+}
+
+// TestRun_GraceExceeded は猶予内に tick が終わらなければ ErrGraceExceeded を返すことを確認します
+func TestRun_GraceExceeded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	tk := tickFunc(func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx, tk, time.Hour, 50*time.Millisecond) }()
+	<-started
+	cancel()
+
+	if err := <-done; !errors.Is(err, worker.ErrGraceExceeded) {
+		t.Fatalf("Run returned %v, want ErrGraceExceeded", err)
+	}
+}